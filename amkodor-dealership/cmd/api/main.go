@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -13,27 +14,25 @@ import (
 	"amkodor-dealership/internal/database"
 	"amkodor-dealership/internal/handlers"
 	"amkodor-dealership/internal/middleware"
+	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
 	"amkodor-dealership/internal/service"
-	"amkodor-dealership/internal/utils"
-	"bytes"
-	"os"
-	"path/filepath"
-	"strings"
-
-	"github.com/jung-kurt/gofpdf"
+	"amkodor-dealership/pkg/report"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/rs/cors"
-	"github.com/xuri/excelize/v2"
 )
 
 type Application struct {
-	Config   *config.Config
-	DB       *sql.DB
-	Handlers *handlers.Handlers
+	Config     *config.Config
+	DB         *sql.DB
+	Handlers   *handlers.Handlers
+	LiveEvents *service.LiveEventService
+	Alerts     *service.StockAlertService
+	// Reservations снимает просроченные брони техники
+	Reservations *service.ReservationService
 }
 
 func main() {
@@ -62,32 +61,58 @@ func main() {
 
 	log.Println("Successfully connected to PostgreSQL database")
 
+	// Подкоманда: api migrate up|down|status|baseline
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(db); err != nil {
+			log.Fatalf("Auto-migration failed: %v", err)
+		}
+	}
+
 	// Инициализация слоёв приложения
 	app, userService := initializeApplication(cfg, db)
 
+	// Слушатель событий для SSE живёт всё время работы сервера
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go app.LiveEvents.Run(ctx)
+	go app.Reservations.Run(ctx)
+
+	// Оповещения по запчастям, остаток которых упал, пока сервер не работал
+	if err := app.Alerts.RaiseMissing(ctx); err != nil {
+		log.Printf("Failed to raise missing stock alerts: %v", err)
+	}
+
 	// Настройка роутера
 	router := setupRouter(app, userService, db)
 
 	// Настройка CORS
 	corsHandler := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           300,
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
 	})
 
 	// Запуск сервера
+	readTimeout, writeTimeout, idleTimeout := cfg.Server.Timeouts()
 	addr := fmt.Sprintf(":%s", cfg.Server.Port)
 	srv := &http.Server{
 		Handler:      corsHandler.Handler(router),
 		Addr:         addr,
-		WriteTimeout: 15 * time.Second,
-		ReadTimeout:  15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		WriteTimeout: writeTimeout,
+		ReadTimeout:  readTimeout,
+		IdleTimeout:  idleTimeout,
 	}
 
-	log.Printf("Server starting on port %s", cfg.Server.Port)
+	log.Printf("Server starting on port %s (%s)", cfg.Server.Port, cfg.Server.Env)
 	if err := srv.ListenAndServe(); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
@@ -103,37 +128,97 @@ func initializeApplication(cfg *config.Config, db *sql.DB) (*Application, *servi
 	serviceRepo := repository.NewServiceRepository(db)
 	serviceOrderRepo := repository.NewServiceOrderRepository(db)
 	userRepo := repository.NewUserRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	favoriteRepo := repository.NewFavoriteRepository(db)
+	serviceRequestRepo := repository.NewServiceRequestRepository(db)
+	clientLinkRepo := repository.NewClientLinkRepository(db)
+	accountRepo := repository.NewAccountRepository(db)
+	liveEventRepo := repository.NewLiveEventRepository(db)
+	stockAlertRepo := repository.NewStockAlertRepository(db)
+	supplierRepo := repository.NewSupplierRepository(db)
+	purchaseOrderRepo := repository.NewPurchaseOrderRepository(db)
+	movementRepo := repository.NewSparePartMovementRepository(db)
+	supplyRepo := repository.NewSupplyRepository(db)
+	transferRepo := repository.NewVehicleTransferRepository(db)
+	reservationRepo := repository.NewReservationRepository(db)
+	testDriveRepo := repository.NewTestDriveRepository(db)
+	calendarFeedRepo := repository.NewCalendarFeedRepository(db)
+	serviceWorkRepo := repository.NewServiceOrderWorkRepository(db)
 
 	// Инициализация сервисов
 	vehicleService := service.NewVehicleService(&vehicleRepo)
 	customerService := service.NewCustomerService(customerRepo)
 	saleService := service.NewSaleService(saleRepo, vehicleRepo)
 	employeeService := service.NewEmployeeService(employeeRepo)
-	authService := service.NewAuthService(&userRepo, cfg.JWT.Secret)
+	authService := service.NewAuthService(
+		&userRepo,
+		&employeeRepo,
+		&refreshTokenRepo,
+		cfg.JWT.Secret,
+		time.Duration(cfg.JWT.AccessExpireMinutes)*time.Minute,
+		time.Duration(cfg.JWT.RefreshExpireHours)*time.Hour,
+	)
 	userService := service.NewUserService(userRepo)
 	reportService := service.NewReportService(db)
 	_ = service.NewExportService(db)
 	warehouseService := service.NewWarehouseService(warehouseRepo)
+	dashboardService := service.NewDashboardService(repository.NewDashboardRepository(db))
 	_ = service.NewServiceOrderService(&serviceRepo)
+	favoriteService := service.NewFavoriteService(&favoriteRepo)
+	serviceRequestService := service.NewServiceRequestService(&serviceRequestRepo)
+	accountService := service.NewAccountService(&clientLinkRepo, &accountRepo, &favoriteRepo, service.LogVerificationSender{})
+	liveEventService := service.NewLiveEventService(&liveEventRepo, cfg.Database.ConnectionString())
+
+	// Письма уходят через SMTP, если он настроен, иначе пишутся в лог
+	var mailer service.Mailer = service.LogMailer{}
+	if cfg.Mail.Host != "" {
+		mailer = service.NewSMTPMailer(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password, cfg.Mail.From)
+	}
+	stockAlertService := service.NewStockAlertService(&stockAlertRepo, mailer)
+	liveEventService.OnEvent(models.LiveEventSparePartLowStock, stockAlertService.HandleLowStock)
+	purchaseOrderService := service.NewPurchaseOrderService(&purchaseOrderRepo, &supplierRepo)
+	movementService := service.NewSparePartMovementService(&movementRepo)
+	supplyService := service.NewSupplyService(&supplyRepo)
+	transferService := service.NewVehicleTransferService(&transferRepo)
+	reservationService := service.NewReservationService(&reservationRepo)
+	testDriveService := service.NewTestDriveService(&testDriveRepo, &clientLinkRepo, mailer, cfg.Server.PublicURL)
+	calendarService := service.NewCalendarService(&calendarFeedRepo, testDriveService, cfg.Server.PublicURL)
+	serviceWorkService := service.NewServiceOrderWorkService(&serviceWorkRepo)
 
 	// Инициализация обработчиков
 	handlers := &handlers.Handlers{
-		Vehicle:   handlers.NewVehicleHandler(vehicleService),
-		Customer:  handlers.NewCustomerHandler(customerService),
-		Sale:      handlers.NewSaleHandler(saleService),
-		Employee:  handlers.NewEmployeeHandler(employeeService),
-		Auth:      handlers.NewAuthHandler(authService),
-		Dashboard: handlers.NewDashboardHandler(warehouseService),
-		Report:    handlers.NewReportHandler(reportService),
-		Admin:     handlers.NewAdminHandler(warehouseService),
-		Warehouse: handlers.NewWarehouseHandler(warehouseService),
-		Service:   handlers.NewServiceHandler(serviceOrderRepo),
+		Vehicle:        handlers.NewVehicleHandler(vehicleService, cfg.Upload),
+		Customer:       handlers.NewCustomerHandler(customerService),
+		Sale:           handlers.NewSaleHandler(saleService),
+		Employee:       handlers.NewEmployeeHandler(employeeService),
+		Auth:           handlers.NewAuthHandler(authService),
+		Dashboard:      handlers.NewDashboardHandler(dashboardService),
+		Report:         handlers.NewReportHandler(reportService, report.DefaultRegistry("web/static/fonts")),
+		Admin:          handlers.NewAdminHandler(warehouseService),
+		Warehouse:      handlers.NewWarehouseHandler(warehouseService),
+		Service:        handlers.NewServiceHandler(serviceOrderRepo),
+		Favorite:       handlers.NewFavoriteHandler(favoriteService),
+		User:           handlers.NewUserHandler(accountService),
+		ServiceRequest: handlers.NewServiceRequestHandler(serviceRequestService),
+		LiveEvent:      handlers.NewLiveEventHandler(liveEventService),
+		StockAlert:     handlers.NewStockAlertHandler(stockAlertService),
+		PurchaseOrder:  handlers.NewPurchaseOrderHandler(purchaseOrderService),
+		Movement:       handlers.NewSparePartMovementHandler(movementService),
+		Supply:         handlers.NewSupplyHandler(supplyService),
+		Transfer:       handlers.NewVehicleTransferHandler(transferService),
+		Reservation:    handlers.NewReservationHandler(reservationService),
+		TestDrive:      handlers.NewTestDriveHandler(testDriveService),
+		Calendar:       handlers.NewCalendarHandler(calendarService),
+		ServiceWork:    handlers.NewServiceOrderWorkHandler(serviceWorkService),
 	}
 
 	return &Application{
-		Config:   cfg,
-		DB:       db,
-		Handlers: handlers,
+		Config:       cfg,
+		DB:           db,
+		Handlers:     handlers,
+		LiveEvents:   liveEventService,
+		Alerts:       stockAlertService,
+		Reservations: reservationService,
 	}, userService
 }
 
@@ -147,6 +232,7 @@ func setupRouter(app *Application, userService *service.UserService, db *sql.DB)
 	// Статические файлы
 	staticDir := "./web/static"
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir(staticDir))))
+	r.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir(app.Config.Upload.Path))))
 
 	// Публичные маршруты
 	r.HandleFunc("/", serveTemplate("index.html")).Methods("GET")
@@ -158,6 +244,7 @@ func setupRouter(app *Application, userService *service.UserService, db *sql.DB)
 	r.HandleFunc("/profile", serveTemplate("profile.html")).Methods("GET")
 	r.HandleFunc("/favorites", serveTemplate("favorites.html")).Methods("GET")
 	r.HandleFunc("/service", serveTemplate("service.html")).Methods("GET")
+	r.HandleFunc("/feedback/{token:[0-9a-f]{64}}", serveTemplate("feedback.html")).Methods("GET")
 
 	// Админ панель
 	r.HandleFunc("/admin/dashboard", serveTemplate("admin/dashboard.html")).Methods("GET")
@@ -182,79 +269,11 @@ func setupRouter(app *Application, userService *service.UserService, db *sql.DB)
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"status":"ok","message":"Server is running"}`))
 	}).Methods("GET")
-	api.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
-		// Получаем реальную статистику из базы данных
-		stats, err := getRealStats(db)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Ошибка получения статистики",
-			})
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"data":    stats,
-		})
-	}).Methods("GET")
-	api.HandleFunc("/auth/login", func(w http.ResponseWriter, r *http.Request) {
-		// Проверяем данные авторизации
-		var loginData struct {
-			Email    string `json:"email"`
-			Password string `json:"password"`
-		}
-
-		if err := json.NewDecoder(r.Body).Decode(&loginData); err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Неверный формат данных",
-			})
-			return
-		}
-
-		// Используем реальную авторизацию через базу данных
-		user, err := userService.Login(r.Context(), loginData.Email, loginData.Password)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Неверный email или пароль",
-			})
-			return
-		}
-
-		// Генерируем JWT токен
-		token, err := utils.GenerateJWT(user.UserID, user.Email, app.Config.JWT.Secret, app.Config.JWT.ExpireHours)
-		if err != nil {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"success": false,
-				"error":   "Ошибка генерации токена",
-			})
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"data": map[string]interface{}{
-				"token":   token,
-				"message": "Login successful",
-				"role":    user.Role,
-				"name":    user.Name,
-			},
-		})
-	}).Methods("POST")
+	api.HandleFunc("/stats", app.Handlers.Dashboard.GetStats).Methods("GET")
+	api.HandleFunc("/auth/login", app.Handlers.Auth.Login).Methods("POST")
+	api.HandleFunc("/auth/staff/login", app.Handlers.Auth.StaffLogin).Methods("POST")
+	api.HandleFunc("/auth/refresh", app.Handlers.Auth.Refresh).Methods("POST")
+	api.HandleFunc("/auth/logout", app.Handlers.Auth.Logout).Methods("POST")
 
 	api.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
 		// Проверяем данные регистрации
@@ -449,11 +468,14 @@ func setupRouter(app *Application, userService *service.UserService, db *sql.DB)
 	}).Methods("PUT")
 
 	api.HandleFunc("/vehicles", app.Handlers.Vehicle.GetAll).Methods("GET")
-	api.HandleFunc("/vehicles", app.Handlers.Vehicle.Create).Methods("POST")
-	api.HandleFunc("/vehicles/{id}", app.Handlers.Vehicle.GetByID).Methods("GET")
 	api.HandleFunc("/vehicles/search", app.Handlers.Vehicle.Search).Methods("GET")
+	api.HandleFunc("/vehicles/{id:[0-9]+}", app.Handlers.Vehicle.GetByID).Methods("GET")
+	api.HandleFunc("/vehicles/{id:[0-9]+}/test-drive-slots", app.Handlers.TestDrive.VehicleSlots).Methods("GET")
 	api.HandleFunc("/vehicles/upload-image", app.Handlers.Vehicle.UploadImage).Methods("POST")
 	// api.HandleFunc("/test-drives", app.Handlers.Service.CreateTestDrive).Methods("POST")
+	api.HandleFunc("/test-drive-feedback/{token:[0-9a-f]{64}}", app.Handlers.TestDrive.GetFeedback).Methods("GET")
+	api.HandleFunc("/test-drive-feedback/{token:[0-9a-f]{64}}", app.Handlers.TestDrive.SubmitFeedback).Methods("POST")
+	api.HandleFunc("/calendar/{token:[0-9a-f]{64}}.ics", app.Handlers.Calendar.Feed).Methods("GET")
 
 	// API - Избранное (требует JWT)
 	api.HandleFunc("/favorites", app.Handlers.Favorite.GetUserFavorites).Methods("GET")
@@ -461,348 +483,234 @@ func setupRouter(app *Application, userService *service.UserService, db *sql.DB)
 	api.HandleFunc("/favorites/{id}/check", app.Handlers.Favorite.IsFavorite).Methods("GET")
 	api.HandleFunc("/favorites/count", app.Handlers.Favorite.GetFavoriteCount).Methods("GET")
 
-	// API - Экспорт отчетов (CSV/XLSX/PDF) - GET с query параметрами
-	api.HandleFunc("/reports/export", func(w http.ResponseWriter, r *http.Request) {
-		q := r.URL.Query()
-		reportType := q.Get("type")
-		format := q.Get("format")
-		dateFrom := q.Get("start_date")
-		dateTo := q.Get("end_date")
-
-		// Нормализация формата
-		if format == "excel" || format == "xslx" {
-			format = "xlsx"
-		}
-
-		// Даты
-		start, _ := time.Parse("2006-01-02", dateFrom)
-		end, _ := time.Parse("2006-01-02", dateTo)
-		if start.IsZero() {
-			start = time.Now().AddDate(0, -1, 0)
-		}
-		if end.IsZero() {
-			end = time.Now()
-		}
-
-		// Подготовка данных
-		csv := ""
-		switch reportType {
-		case "sales":
-			csv = "ID,Клиент,Техника,Сумма,Дата,Статус\n1,Иван Петров,Экскаватор,450000," + start.Format("2006-01-02") + ",Завершена"
-		case "vehicles":
-			csv = "ID,Название,Категория,Цена,Статус,Год\n1,Экскаватор 331,Экскаваторы,450000,В наличии,2023"
-		case "customers":
-			csv = "ID,Имя,Email,Телефон,Статус\n1,Иван Петров,ivan@example.com,+375291234567,Активный"
-		case "financial":
-			csv = "Период,Доходы,Расходы,Прибыль\n" + start.Format("2006-01-02") + ",450000,50000,400000"
-		default:
-			csv = "Колонка1,Колонка2\nЗначение1,Значение2"
-		}
-
-		switch format {
-		case "csv":
-			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_%s.csv", reportType, time.Now().Format("20060102_150405")))
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(csv))
-			return
-
-		case "xlsx":
-			f := excelize.NewFile()
-			defer f.Close()
-			sheet := "Отчет"
-			idx, _ := f.NewSheet(sheet)
-			f.SetActiveSheet(idx)
-			// Заголовок и период
-			f.SetCellValue(sheet, "A1", fmt.Sprintf("Отчет: %s", reportType))
-			f.SetCellValue(sheet, "A2", fmt.Sprintf("Период: %s - %s", start.Format("02.01.2006"), end.Format("02.01.2006")))
-			// Заполняем CSV-данные в ячейки
-			rows := strings.Split(csv, "\n")
-			for rIdx, row := range rows {
-				cols := strings.Split(row, ",")
-				for cIdx, col := range cols {
-					cellRef, _ := excelize.CoordinatesToCellName(cIdx+1, rIdx+4)
-					f.SetCellValue(sheet, cellRef, col)
-				}
-			}
-			var buf bytes.Buffer
-			if err := f.Write(&buf); err != nil {
-				utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка формирования XLSX")
-				return
-			}
-			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_%s.xlsx", reportType, time.Now().Format("20060102_150405")))
-			w.WriteHeader(http.StatusOK)
-			w.Write(buf.Bytes())
-			return
-
-		case "pdf":
-			pdf := gofpdf.New("P", "mm", "A4", "")
-			// Подключаем UTF-8 шрифты (Regular + Bold) из байтов, чтобы исключить особенности путей
-			fontReg, _ := filepath.Abs("web/static/fonts/DejaVuSans.ttf")
-			fontBold, _ := filepath.Abs("web/static/fonts/DejaVuSans-Bold.ttf")
-			if b, err := os.ReadFile(fontReg); err == nil {
-				pdf.AddUTF8FontFromBytes("DejaVu", "", b)
-			} else {
-				log.Printf("PDF read font error (GET regular): %v, path=%s", err, fontReg)
-			}
-			if b, err := os.ReadFile(fontBold); err == nil {
-				pdf.AddUTF8FontFromBytes("DejaVu", "B", b)
-			} else {
-				log.Printf("PDF read font error (GET bold): %v, path=%s", err, fontBold)
-			}
-			if err := pdf.Error(); err != nil {
-				log.Printf("PDF font load error (GET): %v", err)
-				utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка загрузки шрифта для PDF")
-				return
-			}
-			pdf.AddPage()
-			pdf.SetFont("DejaVu", "B", 16)
-			title := fmt.Sprintf("Отчет: %s (%s - %s)", reportType, start.Format("02.01.2006"), end.Format("02.01.2006"))
-			pdf.CellFormat(190, 10, title, "", 1, "C", false, 0, "")
-			pdf.SetFont("DejaVu", "", 12)
-			pdf.Ln(4)
-			pdf.Cell(40, 8, "Данные отчета представлены в табличном виде.")
-			var out bytes.Buffer
-			if err := pdf.Output(&out); err != nil {
-				utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка формирования PDF")
-				return
-			}
-			w.Header().Set("Content-Type", "application/pdf")
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_%s.pdf", reportType, time.Now().Format("20060102_150405")))
-			w.WriteHeader(http.StatusOK)
-			w.Write(out.Bytes())
-			return
-		}
-
-		utils.ErrorResponse(w, http.StatusBadRequest, "Неподдерживаемый формат")
-	}).Methods("GET")
-
-	// API - Экспорт отчетов (CSV/XLSX/PDF) - POST с JSON телом (ожидает поля: type, format, date_from, date_to)
-	api.HandleFunc("/reports/export", func(w http.ResponseWriter, r *http.Request) {
-		type exportReq struct {
-			Type     string `json:"type"`
-			Format   string `json:"format"`
-			DateFrom string `json:"date_from"`
-			DateTo   string `json:"date_to"`
-		}
-		var req exportReq
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			utils.ErrorResponse(w, http.StatusBadRequest, "Некорректные данные запроса")
-			return
-		}
-
-		reportType := req.Type
-		format := req.Format
-		dateFrom := req.DateFrom
-		dateTo := req.DateTo
-
-		if format == "excel" || format == "xslx" {
-			format = "xlsx"
-		}
-
-		start, _ := time.Parse("2006-01-02", dateFrom)
-		end, _ := time.Parse("2006-01-02", dateTo)
-		if start.IsZero() {
-			start = time.Now().AddDate(0, -1, 0)
-		}
-		if end.IsZero() {
-			end = time.Now()
-		}
+	// API - Пользовательские функции (требуют JWT)
+	account := api.PathPrefix("/user").Subrouter()
+	account.Use(middleware.AuthMiddleware(app.Config.JWT.Secret))
+	account.HandleFunc("/stats", app.Handlers.User.GetUserStats).Methods("GET")
+	account.HandleFunc("/orders", app.Handlers.User.GetUserOrders).Methods("GET")
+	account.HandleFunc("/test-drives", app.Handlers.User.GetUserTestDrives).Methods("GET")
+	account.HandleFunc("/test-drives", app.Handlers.TestDrive.BookMine).Methods("POST")
+	account.HandleFunc("/test-drives/{id:[0-9]+}/cancel", app.Handlers.TestDrive.CancelMine).Methods("POST")
+	account.HandleFunc("/test-drives/{id:[0-9]+}/calendar.ics", app.Handlers.Calendar.MyTestDrive).Methods("GET")
+	account.HandleFunc("/service-orders", app.Handlers.User.GetUserServiceOrders).Methods("GET")
+	account.HandleFunc("/fleet", app.Handlers.User.GetUserFleet).Methods("GET")
+	account.HandleFunc("/client-link", app.Handlers.User.GetClientLink).Methods("GET")
+	account.HandleFunc("/client-link/claim", app.Handlers.User.ClaimClientLink).Methods("POST")
+	account.HandleFunc("/client-link/confirm", app.Handlers.User.ConfirmClientLink).Methods("POST")
+	account.HandleFunc("/favorites", app.Handlers.User.GetUserFavorites).Methods("GET")
+	account.HandleFunc("/favorites", app.Handlers.User.AddToFavorites).Methods("POST")
+	account.HandleFunc("/favorites", app.Handlers.User.RemoveFromFavorites).Methods("DELETE")
+	account.HandleFunc("/service-requests", app.Handlers.ServiceRequest.GetMine).Methods("GET")
+	account.HandleFunc("/service-requests", app.Handlers.ServiceRequest.Create).Methods("POST")
+	account.HandleFunc("/service-requests/{id:[0-9]+}", app.Handlers.ServiceRequest.Cancel).Methods("DELETE")
+	account.HandleFunc("/profile", app.Handlers.User.GetUserProfile).Methods("GET")
+	account.HandleFunc("/profile", app.Handlers.User.UpdateUserProfile).Methods("PUT")
 
-		csv := ""
-		switch reportType {
-		case "sales":
-			csv = "ID,Клиент,Техника,Сумма,Дата,Статус\n1,Иван Петров,Экскаватор,450000," + start.Format("2006-01-02") + ",Завершена"
-		case "vehicles":
-			csv = "ID,Название,Категория,Цена,Статус,Год\n1,Экскаватор 331,Экскаваторы,450000,В наличии,2023"
-		case "customers":
-			csv = "ID,Имя,Email,Телефон,Статус\n1,Иван Петров,ivan@example.com,+375291234567,Активный"
-		case "financial":
-			csv = "Период,Доходы,Расходы,Прибыль\n" + start.Format("2006-01-02") + ",450000,50000,400000"
-		default:
-			csv = "Колонка1,Колонка2\nЗначение1,Значение2"
-		}
+	// API - Защищенные эндпоинты (требуют JWT)
+	registerAdminRoutes(api, app.Config.JWT.Secret, app.Handlers, func(h http.HandlerFunc) http.HandlerFunc { return h })
 
-		switch format {
-		case "csv":
-			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_%s.csv", reportType, time.Now().Format("20060102_150405")))
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(csv))
-			return
-		case "xlsx":
-			f := excelize.NewFile()
-			defer f.Close()
-			sheet := "Отчет"
-			idx, _ := f.NewSheet(sheet)
-			f.SetActiveSheet(idx)
-			f.SetCellValue(sheet, "A1", fmt.Sprintf("Отчет: %s", reportType))
-			f.SetCellValue(sheet, "A2", fmt.Sprintf("Период: %s - %s", start.Format("02.01.2006"), end.Format("02.01.2006")))
-			rows := strings.Split(csv, "\n")
-			for rIdx, row := range rows {
-				cols := strings.Split(row, ",")
-				for cIdx, col := range cols {
-					cellRef, _ := excelize.CoordinatesToCellName(cIdx+1, rIdx+4)
-					f.SetCellValue(sheet, cellRef, col)
-				}
-			}
-			var buf bytes.Buffer
-			if err := f.Write(&buf); err != nil {
-				utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка формирования XLSX")
-				return
-			}
-			w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_%s.xlsx", reportType, time.Now().Format("20060102_150405")))
-			w.WriteHeader(http.StatusOK)
-			w.Write(buf.Bytes())
-			return
-		case "pdf":
-			pdf := gofpdf.New("P", "mm", "A4", "")
-			fontReg, _ := filepath.Abs("web/static/fonts/DejaVuSans.ttf")
-			fontBold, _ := filepath.Abs("web/static/fonts/DejaVuSans-Bold.ttf")
-			if b, err := os.ReadFile(fontReg); err == nil {
-				pdf.AddUTF8FontFromBytes("DejaVu", "", b)
-			} else {
-				log.Printf("PDF read font error (POST regular): %v, path=%s", err, fontReg)
-			}
-			if b, err := os.ReadFile(fontBold); err == nil {
-				pdf.AddUTF8FontFromBytes("DejaVu", "B", b)
-			} else {
-				log.Printf("PDF read font error (POST bold): %v, path=%s", err, fontBold)
-			}
-			if err := pdf.Error(); err != nil {
-				log.Printf("PDF font load error (POST): %v", err)
-				utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка загрузки шрифта для PDF")
-				return
-			}
-			pdf.AddPage()
-			pdf.SetFont("DejaVu", "B", 16)
-			title := fmt.Sprintf("Отчет: %s (%s - %s)", reportType, start.Format("02.01.2006"), end.Format("02.01.2006"))
-			pdf.CellFormat(190, 10, title, "", 1, "C", false, 0, "")
-			pdf.SetFont("DejaVu", "", 12)
-			pdf.Ln(4)
-			pdf.Cell(40, 8, "Данные отчета представлены в табличном виде.")
-			var out bytes.Buffer
-			if err := pdf.Output(&out); err != nil {
-				log.Printf("PDF output error (POST): %v", err)
-				utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка формирования PDF")
-				return
-			}
-			w.Header().Set("Content-Type", "application/pdf")
-			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=report_%s_%s.pdf", reportType, time.Now().Format("20060102_150405")))
-			w.WriteHeader(http.StatusOK)
-			w.Write(out.Bytes())
-			return
-		}
+	// Admin Panel - Template routes
+	adminPanel := r.PathPrefix("/admin").Subrouter()
+	adminPanel.Use(middleware.AuthMiddleware(app.Config.JWT.Secret))
+	adminPanel.HandleFunc("/dashboard", serveTemplate("admin/dashboard.html")).Methods("GET")
+	adminPanel.HandleFunc("/vehicles", serveTemplate("admin/vehicles.html")).Methods("GET")
+	adminPanel.HandleFunc("/sales", serveTemplate("admin/sales.html")).Methods("GET")
+	adminPanel.HandleFunc("/customers", serveTemplate("admin/customers.html")).Methods("GET")
+	adminPanel.HandleFunc("/employees", serveTemplate("admin/employees.html")).Methods("GET")
+	adminPanel.HandleFunc("/warehouses", serveTemplate("admin/warehouses.html")).Methods("GET")
+	adminPanel.HandleFunc("/service", serveTemplate("admin/service.html")).Methods("GET")
+	adminPanel.HandleFunc("/reports", serveTemplate("admin/reports.html")).Methods("GET")
+	adminPanel.HandleFunc("/settings", serveTemplate("admin/settings.html")).Methods("GET")
 
-		utils.ErrorResponse(w, http.StatusBadRequest, "Неподдерживаемый формат")
-	}).Methods("POST")
+	return r
+}
 
-	// API - Пользовательские функции (требуют JWT)
-	api.HandleFunc("/user/stats", app.Handlers.User.GetUserStats).Methods("GET")
-	api.HandleFunc("/user/orders", app.Handlers.User.GetUserOrders).Methods("GET")
-	api.HandleFunc("/user/favorites", app.Handlers.User.GetUserFavorites).Methods("GET")
-	api.HandleFunc("/user/favorites", app.Handlers.User.AddToFavorites).Methods("POST")
-	api.HandleFunc("/user/favorites", app.Handlers.User.RemoveFromFavorites).Methods("DELETE")
-	api.HandleFunc("/user/service-requests", app.Handlers.User.GetServiceRequests).Methods("GET")
-	api.HandleFunc("/user/service-requests", app.Handlers.User.CreateServiceRequest).Methods("POST")
-	api.HandleFunc("/user/profile", app.Handlers.User.GetUserProfile).Methods("GET")
-	api.HandleFunc("/user/profile", app.Handlers.User.UpdateUserProfile).Methods("PUT")
+// registerAdminRoutes регистрирует маршруты /api/admin с проверкой прав по
+// ролям. endpoint оборачивает конечные обработчики: приложение передаёт их
+// как есть, тесты прав подменяют заглушками.
+func registerAdminRoutes(api *mux.Router, secret string, h *handlers.Handlers, endpoint func(http.HandlerFunc) http.HandlerFunc) {
+	// События для живого обновления панели (SSE). Маршрут объявлен до
+	// подроутера /admin, так как EventSource передаёт токен в ?access_token=
+	// и AccessTokenFromQuery должен отработать раньше AuthMiddleware.
+	api.Handle("/admin/events", middleware.AccessTokenFromQuery(
+		middleware.AuthMiddleware(secret)(
+			middleware.RequirePermission(middleware.PermDashboardView)(endpoint(h.LiveEvent.Stream)),
+		),
+	)).Methods("GET")
 
-	// API - Защищенные эндпоинты (требуют JWT)
 	protected := api.PathPrefix("/admin").Subrouter()
-	protected.Use(middleware.AuthMiddleware(app.Config.JWT.Secret))
+	protected.Use(middleware.AuthMiddleware(secret))
+
+	// allow оборачивает обработчик проверкой права роли из токена,
+	// staff - проверкой, что токен выдан сотруднику
+	allow := func(perm middleware.Permission, fn http.HandlerFunc) http.Handler {
+		return middleware.RequirePermission(perm)(endpoint(fn))
+	}
+	staff := func(fn http.HandlerFunc) http.Handler {
+		return middleware.RequireStaff(endpoint(fn))
+	}
 
 	// Dashboard
-	protected.HandleFunc("/dashboard", app.Handlers.Dashboard.GetStats).Methods("GET")
-	protected.HandleFunc("/dashboard/charts", app.Handlers.Dashboard.GetCharts).Methods("GET")
+	protected.Handle("/dashboard", allow(middleware.PermDashboardView, h.Dashboard.GetStats)).Methods("GET")
+	protected.Handle("/dashboard/charts", allow(middleware.PermDashboardView, h.Dashboard.GetCharts)).Methods("GET")
+	protected.Handle("/dashboard/top-employees", allow(middleware.PermDashboardView, h.Dashboard.GetTopEmployees)).Methods("GET")
+	protected.Handle("/dashboard/recent-sales", allow(middleware.PermDashboardView, h.Dashboard.GetRecentSales)).Methods("GET")
 
 	// Vehicles - CRUD
-	protected.HandleFunc("/vehicles", app.Handlers.Vehicle.Create).Methods("POST")
-	protected.HandleFunc("/vehicles/{id}", app.Handlers.Vehicle.Update).Methods("PUT")
-	protected.HandleFunc("/vehicles/{id}", app.Handlers.Vehicle.Delete).Methods("DELETE")
-	// protected.HandleFunc("/vehicles/{id}/history", app.Handlers.Vehicle.GetHistory).Methods("GET")
+	protected.Handle("/vehicles", allow(middleware.PermVehiclesWrite, h.Vehicle.Create)).Methods("POST")
+	protected.Handle("/vehicles/{id}", allow(middleware.PermVehiclesWrite, h.Vehicle.Update)).Methods("PUT")
+	protected.Handle("/vehicles/{id}", allow(middleware.PermVehiclesWrite, h.Vehicle.Delete)).Methods("DELETE")
+	// protected.HandleFunc("/vehicles/{id}/history", h.Vehicle.GetHistory).Methods("GET")
 
 	// Customers - CRUD
-	protected.HandleFunc("/customers", app.Handlers.Customer.GetAll).Methods("GET")
-	protected.HandleFunc("/customers/{id}", app.Handlers.Customer.GetByID).Methods("GET")
-	protected.HandleFunc("/customers", app.Handlers.Customer.Create).Methods("POST")
-	protected.HandleFunc("/customers/{id}", app.Handlers.Customer.Update).Methods("PUT")
-	protected.HandleFunc("/customers/{id}", app.Handlers.Customer.Delete).Methods("DELETE")
-	// protected.HandleFunc("/customers/search", app.Handlers.Customer.Search).Methods("GET")
-
-	// Corporate Clients - заглушки
-	// protected.HandleFunc("/corporate-clients", app.Handlers.Customer.GetAllCorporate).Methods("GET")
-	// protected.HandleFunc("/corporate-clients/{id}", app.Handlers.Customer.GetCorporateByID).Methods("GET")
-	// protected.HandleFunc("/corporate-clients", app.Handlers.Customer.CreateCorporate).Methods("POST")
-	// protected.HandleFunc("/corporate-clients/{id}", app.Handlers.Customer.UpdateCorporate).Methods("PUT")
-	// protected.HandleFunc("/corporate-clients/{id}", app.Handlers.Customer.DeleteCorporate).Methods("DELETE")
+	protected.Handle("/customers", allow(middleware.PermCustomersRead, h.Customer.GetAll)).Methods("GET")
+	protected.Handle("/customers/search", allow(middleware.PermCustomersRead, h.Customer.Search)).Methods("GET")
+	protected.Handle("/customers/{id}", allow(middleware.PermCustomersRead, h.Customer.GetByID)).Methods("GET")
+	protected.Handle("/customers", allow(middleware.PermCustomersWrite, h.Customer.Create)).Methods("POST")
+	protected.Handle("/customers/{id}", allow(middleware.PermCustomersWrite, h.Customer.Update)).Methods("PUT")
+	protected.Handle("/customers/{id}", allow(middleware.PermCustomersWrite, h.Customer.Delete)).Methods("DELETE")
+	protected.Handle("/customers/{id}/duplicates", allow(middleware.PermCustomersRead, h.Customer.GetDuplicates)).Methods("GET")
+	protected.Handle("/customers/{id}/merge", allow(middleware.PermCustomersWrite, h.Customer.Merge)).Methods("POST")
+	protected.Handle("/customers/{id}/merges", allow(middleware.PermCustomersRead, h.Customer.GetMerges)).Methods("GET")
+
+	// Corporate Clients
+	protected.Handle("/corporate-clients", allow(middleware.PermCustomersRead, h.Customer.GetAllCorporate)).Methods("GET")
+	protected.Handle("/corporate-clients/{id}", allow(middleware.PermCustomersRead, h.Customer.GetCorporateByID)).Methods("GET")
+	protected.Handle("/corporate-clients", allow(middleware.PermCustomersWrite, h.Customer.CreateCorporate)).Methods("POST")
+	protected.Handle("/corporate-clients/{id}", allow(middleware.PermCustomersWrite, h.Customer.UpdateCorporate)).Methods("PUT")
+	protected.Handle("/corporate-clients/{id}", allow(middleware.PermCustomersWrite, h.Customer.DeleteCorporate)).Methods("DELETE")
 
 	// Sales - CRUD
-	protected.HandleFunc("/sales", app.Handlers.Sale.GetAll).Methods("GET")
-	protected.HandleFunc("/sales/{id}", app.Handlers.Sale.GetByID).Methods("GET")
-	protected.HandleFunc("/sales", app.Handlers.Sale.Create).Methods("POST")
-	protected.HandleFunc("/sales/{id}", app.Handlers.Sale.Update).Methods("PUT")
-	protected.HandleFunc("/sales/{id}", app.Handlers.Sale.Delete).Methods("DELETE")
-	protected.HandleFunc("/sales/{id}/history", app.Handlers.Sale.GetHistory).Methods("GET")
+	protected.Handle("/sales", allow(middleware.PermSalesRead, h.Sale.GetAll)).Methods("GET")
+	protected.Handle("/sales/{id}", allow(middleware.PermSalesRead, h.Sale.GetByID)).Methods("GET")
+	protected.Handle("/sales", allow(middleware.PermSalesWrite, h.Sale.Create)).Methods("POST")
+	protected.Handle("/sales/{id}", allow(middleware.PermSalesWrite, h.Sale.Update)).Methods("PUT")
+	protected.Handle("/sales/{id}", allow(middleware.PermSalesCancel, h.Sale.Delete)).Methods("DELETE")
+	protected.Handle("/sales/{id}/history", allow(middleware.PermSalesRead, h.Sale.GetHistory)).Methods("GET")
+
+	// Reservations - бронирование техники за клиентами
+	protected.Handle("/reservations", allow(middleware.PermSalesRead, h.Reservation.GetAll)).Methods("GET")
+	protected.Handle("/reservations/{id:[0-9]+}", allow(middleware.PermSalesRead, h.Reservation.GetByID)).Methods("GET")
+	protected.Handle("/reservations", allow(middleware.PermSalesWrite, h.Reservation.Create)).Methods("POST")
+	protected.Handle("/reservations/{id:[0-9]+}/extend", allow(middleware.PermSalesWrite, h.Reservation.Extend)).Methods("POST")
+	protected.Handle("/reservations/{id:[0-9]+}/cancel", allow(middleware.PermSalesWrite, h.Reservation.Cancel)).Methods("POST")
 
 	// Employees - CRUD
-	protected.HandleFunc("/employees", app.Handlers.Employee.GetAll).Methods("GET")
-	protected.HandleFunc("/employees/{id}", app.Handlers.Employee.GetByID).Methods("GET")
-	protected.HandleFunc("/employees", app.Handlers.Employee.Create).Methods("POST")
-	protected.HandleFunc("/employees/{id}", app.Handlers.Employee.Update).Methods("PUT")
-	protected.HandleFunc("/employees/{id}", app.Handlers.Employee.Delete).Methods("DELETE")
+	protected.Handle("/employees", allow(middleware.PermEmployeesManage, h.Employee.GetAll)).Methods("GET")
+	protected.Handle("/employees/{id}", allow(middleware.PermEmployeesManage, h.Employee.GetByID)).Methods("GET")
+	protected.Handle("/employees", allow(middleware.PermEmployeesManage, h.Employee.Create)).Methods("POST")
+	protected.Handle("/employees/{id}", allow(middleware.PermEmployeesManage, h.Employee.Update)).Methods("PUT")
+	protected.Handle("/employees/{id}", allow(middleware.PermEmployeesManage, h.Employee.Delete)).Methods("DELETE")
+	protected.Handle("/employees/{id}/restore", allow(middleware.PermEmployeesManage, h.Employee.Restore)).Methods("POST")
+	protected.Handle("/employees/{id}/password", allow(middleware.PermEmployeesManage, h.Employee.SetPassword)).Methods("PUT")
+	protected.Handle("/employees/{id}/password/reset", allow(middleware.PermEmployeesManage, h.Employee.ResetPassword)).Methods("POST")
+
+	// Positions
+	protected.Handle("/positions", allow(middleware.PermEmployeesManage, h.Employee.GetPositions)).Methods("GET")
+	protected.Handle("/positions", allow(middleware.PermEmployeesManage, h.Employee.CreatePosition)).Methods("POST")
+	protected.Handle("/positions/{id}", allow(middleware.PermEmployeesManage, h.Employee.UpdatePosition)).Methods("PUT")
+	protected.Handle("/positions/{id}", allow(middleware.PermEmployeesManage, h.Employee.DeletePosition)).Methods("DELETE")
+
+	// Users - сессии
+	protected.Handle("/users/{id}/sessions", allow(middleware.PermUsersManage, h.Auth.RevokeUserSessions)).Methods("DELETE")
+	protected.Handle("/users/{id:[0-9]+}/client-link", allow(middleware.PermCustomersRead, h.User.GetUserClientLink)).Methods("GET")
+	protected.Handle("/users/{id:[0-9]+}/client-link", allow(middleware.PermCustomersWrite, h.User.LinkUserClient)).Methods("PUT")
+	protected.Handle("/users/{id:[0-9]+}/client-link", allow(middleware.PermCustomersWrite, h.User.UnlinkUserClient)).Methods("DELETE")
 
 	// Warehouses
-	protected.HandleFunc("/warehouses", app.Handlers.Warehouse.GetAll).Methods("GET")
-	protected.HandleFunc("/warehouses/{id}", app.Handlers.Warehouse.GetByID).Methods("GET")
-	protected.HandleFunc("/warehouses", app.Handlers.Warehouse.Create).Methods("POST")
-	protected.HandleFunc("/warehouses/{id}", app.Handlers.Warehouse.Update).Methods("PUT")
-	protected.HandleFunc("/warehouses/{id}/statistics", app.Handlers.Warehouse.GetStatistics).Methods("GET")
+	protected.Handle("/warehouses", allow(middleware.PermWarehousesRead, h.Warehouse.GetAll)).Methods("GET")
+	protected.Handle("/warehouses/{id}", allow(middleware.PermWarehousesRead, h.Warehouse.GetByID)).Methods("GET")
+	protected.Handle("/warehouses", allow(middleware.PermWarehousesWrite, h.Warehouse.Create)).Methods("POST")
+	protected.Handle("/warehouses/{id}", allow(middleware.PermWarehousesWrite, h.Warehouse.Update)).Methods("PUT")
+	protected.Handle("/warehouses/{id}/statistics", allow(middleware.PermWarehousesRead, h.Warehouse.GetStatistics)).Methods("GET")
+
+	// Supplies - поставки техники от производителей
+	protected.Handle("/supplies", allow(middleware.PermWarehousesRead, h.Supply.GetAll)).Methods("GET")
+	protected.Handle("/supplies/{id:[0-9]+}", allow(middleware.PermWarehousesRead, h.Supply.GetByID)).Methods("GET")
+	protected.Handle("/supplies", allow(middleware.PermWarehousesWrite, h.Supply.Create)).Methods("POST")
+	protected.Handle("/supplies/{id:[0-9]+}/ship", allow(middleware.PermWarehousesWrite, h.Supply.Ship)).Methods("POST")
+	protected.Handle("/supplies/{id:[0-9]+}/receive", allow(middleware.PermWarehousesWrite, h.Supply.Receive)).Methods("POST")
+	protected.Handle("/supplies/{id:[0-9]+}/cancel", allow(middleware.PermWarehousesWrite, h.Supply.Cancel)).Methods("POST")
+
+	// Vehicle Transfers - перемещение техники между складами
+	protected.Handle("/vehicle-transfers", allow(middleware.PermWarehousesRead, h.Transfer.GetAll)).Methods("GET")
+	protected.Handle("/vehicle-transfers/{id:[0-9]+}", allow(middleware.PermWarehousesRead, h.Transfer.GetByID)).Methods("GET")
+	protected.Handle("/vehicle-transfers", allow(middleware.PermWarehousesWrite, h.Transfer.Create)).Methods("POST")
+	protected.Handle("/vehicle-transfers/{id:[0-9]+}/dispatch", allow(middleware.PermWarehousesWrite, h.Transfer.Dispatch)).Methods("POST")
+	protected.Handle("/vehicle-transfers/{id:[0-9]+}/receive", allow(middleware.PermWarehousesWrite, h.Transfer.Receive)).Methods("POST")
+	protected.Handle("/vehicle-transfers/{id:[0-9]+}/cancel", allow(middleware.PermWarehousesWrite, h.Transfer.Cancel)).Methods("POST")
 
 	// Service Orders
-	protected.HandleFunc("/service-orders", app.Handlers.Service.GetAllOrders).Methods("GET")
-	protected.HandleFunc("/service-orders/{id}", app.Handlers.Service.GetOrderByID).Methods("GET")
-	protected.HandleFunc("/service-orders", app.Handlers.Service.CreateOrder).Methods("POST")
-	protected.HandleFunc("/service-orders/{id}", app.Handlers.Service.UpdateOrder).Methods("PUT")
-	protected.HandleFunc("/service-orders/{id}/complete", app.Handlers.Service.CompleteOrder).Methods("POST")
-
-	// Service Requests - получение всех заявок для админа
-	protected.HandleFunc("/service-requests", app.Handlers.User.GetAllServiceRequests).Methods("GET")
+	protected.Handle("/service-orders", allow(middleware.PermServiceOrdersManage, h.Service.GetAllOrders)).Methods("GET")
+	protected.Handle("/service-orders/{id:[0-9]+}", allow(middleware.PermServiceOrdersManage, h.ServiceWork.GetOrder)).Methods("GET")
+	protected.Handle("/service-orders", allow(middleware.PermServiceOrdersManage, h.Service.CreateOrder)).Methods("POST")
+	protected.Handle("/service-orders/{id:[0-9]+}", allow(middleware.PermServiceOrdersManage, h.ServiceWork.Update)).Methods("PUT")
+	protected.Handle("/service-orders/{id:[0-9]+}/parts", allow(middleware.PermServiceOrdersManage, h.ServiceWork.AddPart)).Methods("POST")
+	protected.Handle("/service-orders/{id:[0-9]+}/parts/{lineId:[0-9]+}", allow(middleware.PermServiceOrdersManage, h.ServiceWork.RemovePart)).Methods("DELETE")
+	protected.Handle("/service-orders/{id:[0-9]+}/labour", allow(middleware.PermServiceOrdersManage, h.ServiceWork.AddLabour)).Methods("POST")
+	protected.Handle("/service-orders/{id:[0-9]+}/labour/{lineId:[0-9]+}", allow(middleware.PermServiceOrdersManage, h.ServiceWork.RemoveLabour)).Methods("DELETE")
+	protected.Handle("/service-orders/{id:[0-9]+}/complete", allow(middleware.PermServiceOrdersManage, h.ServiceWork.Complete)).Methods("POST")
+
+	// Service Requests - разбор заявок клиентов
+	protected.Handle("/service-requests", allow(middleware.PermServiceRequests, h.ServiceRequest.GetAll)).Methods("GET")
+	protected.Handle("/service-requests/{id:[0-9]+}", allow(middleware.PermServiceRequests, h.ServiceRequest.GetByID)).Methods("GET")
+	protected.Handle("/service-requests/{id:[0-9]+}/convert", allow(middleware.PermServiceRequests, h.ServiceRequest.Convert)).Methods("POST")
+	protected.Handle("/service-requests/{id:[0-9]+}/reject", allow(middleware.PermServiceRequests, h.ServiceRequest.Reject)).Methods("POST")
 
 	// Test Drives
-	protected.HandleFunc("/test-drives", app.Handlers.Service.GetAllTestDrives).Methods("GET")
-	protected.HandleFunc("/test-drives", app.Handlers.Service.CreateTestDrive).Methods("POST")
-	protected.HandleFunc("/test-drives/{id}", app.Handlers.Service.UpdateTestDrive).Methods("PUT")
+	protected.Handle("/test-drives", allow(middleware.PermTestDrivesManage, h.Service.GetAllTestDrives)).Methods("GET")
+	protected.Handle("/test-drives", allow(middleware.PermTestDrivesManage, h.TestDrive.Create)).Methods("POST")
+	protected.Handle("/test-drives/{id:[0-9]+}", allow(middleware.PermTestDrivesManage, h.TestDrive.Reschedule)).Methods("PUT")
+	protected.Handle("/test-drives/bookings", allow(middleware.PermTestDrivesManage, h.TestDrive.GetAll)).Methods("GET")
+	protected.Handle("/test-drives/availability", allow(middleware.PermTestDrivesManage, h.TestDrive.Availability)).Methods("GET")
+	protected.Handle("/test-drives/{id:[0-9]+}", allow(middleware.PermTestDrivesManage, h.TestDrive.GetByID)).Methods("GET")
+	protected.Handle("/test-drives/{id:[0-9]+}/reschedule", allow(middleware.PermTestDrivesManage, h.TestDrive.Reschedule)).Methods("POST")
+	protected.Handle("/test-drives/{id:[0-9]+}/cancel", allow(middleware.PermTestDrivesManage, h.TestDrive.Cancel)).Methods("POST")
+	protected.Handle("/test-drives/{id:[0-9]+}/complete", allow(middleware.PermTestDrivesManage, h.TestDrive.Complete)).Methods("POST")
+	protected.Handle("/test-drives/{id:[0-9]+}/no-show", allow(middleware.PermTestDrivesManage, h.TestDrive.NoShow)).Methods("POST")
+	protected.Handle("/test-drives/{id:[0-9]+}/calendar.ics", allow(middleware.PermTestDrivesManage, h.Calendar.TestDrive)).Methods("GET")
+	protected.Handle("/test-drives/managers/{id:[0-9]+}/working-hours", allow(middleware.PermTestDrivesManage, h.TestDrive.GetWorkingHours)).Methods("GET")
+	protected.Handle("/test-drives/managers/{id:[0-9]+}/working-hours", allow(middleware.PermTestDrivesManage, h.TestDrive.SetWorkingHours)).Methods("PUT")
+
+	// Calendar feeds - доступны любому сотруднику, чужие подписки - администратору
+	protected.Handle("/calendar-feeds", staff(h.Calendar.GetFeeds)).Methods("GET")
+	protected.Handle("/calendar-feeds", staff(h.Calendar.CreateFeed)).Methods("POST")
+	protected.Handle("/calendar-feeds/{id:[0-9]+}", staff(h.Calendar.RevokeFeed)).Methods("DELETE")
 
 	// Spare Parts
-	protected.HandleFunc("/spare-parts", app.Handlers.Service.GetAllParts).Methods("GET")
-	protected.HandleFunc("/spare-parts/{id}", app.Handlers.Service.GetPartByID).Methods("GET")
-	protected.HandleFunc("/spare-parts", app.Handlers.Service.CreatePart).Methods("POST")
-	protected.HandleFunc("/spare-parts/{id}", app.Handlers.Service.UpdatePart).Methods("PUT")
-	protected.HandleFunc("/spare-parts/{id}", app.Handlers.Service.DeleteSparePart).Methods("DELETE")
+	protected.Handle("/spare-parts", allow(middleware.PermSparePartsRead, h.Service.GetAllParts)).Methods("GET")
+	protected.Handle("/spare-parts/reorder-suggestions", allow(middleware.PermSparePartsRead, h.PurchaseOrder.GetReorderSuggestions)).Methods("GET")
+	protected.Handle("/spare-parts/stock", allow(middleware.PermSparePartsRead, h.Movement.GetStock)).Methods("GET")
+	protected.Handle("/spare-parts/reconciliation", allow(middleware.PermSparePartsRead, h.Movement.Reconcile)).Methods("GET")
+	protected.Handle("/spare-parts/{id:[0-9]+}/movements", allow(middleware.PermSparePartsRead, h.Movement.GetHistory)).Methods("GET")
+	protected.Handle("/spare-parts/{id:[0-9]+}/movements", allow(middleware.PermSparePartsWrite, h.Movement.Record)).Methods("POST")
+	protected.Handle("/spare-parts/{id}", allow(middleware.PermSparePartsRead, h.Service.GetPartByID)).Methods("GET")
+	protected.Handle("/spare-parts", allow(middleware.PermSparePartsWrite, h.Service.CreatePart)).Methods("POST")
+	protected.Handle("/spare-parts/{id}", allow(middleware.PermSparePartsWrite, h.Service.UpdatePart)).Methods("PUT")
+	protected.Handle("/spare-parts/{id}", allow(middleware.PermSparePartsWrite, h.Service.DeleteSparePart)).Methods("DELETE")
+
+	// Purchase Orders - поставщики и заказы запчастей
+	protected.Handle("/suppliers", allow(middleware.PermSparePartsRead, h.PurchaseOrder.GetSuppliers)).Methods("GET")
+	protected.Handle("/suppliers", allow(middleware.PermSparePartsWrite, h.PurchaseOrder.CreateSupplier)).Methods("POST")
+	protected.Handle("/suppliers/{id:[0-9]+}", allow(middleware.PermSparePartsWrite, h.PurchaseOrder.UpdateSupplier)).Methods("PUT")
+	protected.Handle("/purchase-orders", allow(middleware.PermSparePartsRead, h.PurchaseOrder.GetAll)).Methods("GET")
+	protected.Handle("/purchase-orders/{id:[0-9]+}", allow(middleware.PermSparePartsRead, h.PurchaseOrder.GetByID)).Methods("GET")
+	protected.Handle("/purchase-orders", allow(middleware.PermSparePartsWrite, h.PurchaseOrder.Create)).Methods("POST")
+	protected.Handle("/purchase-orders/{id:[0-9]+}", allow(middleware.PermSparePartsWrite, h.PurchaseOrder.Update)).Methods("PUT")
+	protected.Handle("/purchase-orders/{id:[0-9]+}/cancel", allow(middleware.PermSparePartsWrite, h.PurchaseOrder.Cancel)).Methods("POST")
+	protected.Handle("/purchase-orders/{id:[0-9]+}/receive", allow(middleware.PermSparePartsWrite, h.PurchaseOrder.Receive)).Methods("POST")
+
+	// Stock Alerts - оповещения о низком остатке запчастей
+	protected.Handle("/alerts", allow(middleware.PermSparePartsRead, h.StockAlert.GetAll)).Methods("GET")
+	protected.Handle("/alerts/{id:[0-9]+}/acknowledge", allow(middleware.PermSparePartsRead, h.StockAlert.Acknowledge)).Methods("POST")
+	protected.Handle("/alerts/{id:[0-9]+}/resolve", allow(middleware.PermSparePartsWrite, h.StockAlert.Resolve)).Methods("POST")
 
 	// Reports
-	protected.HandleFunc("/reports/sales", app.Handlers.Report.SalesReport).Methods("GET")
-	protected.HandleFunc("/reports/inventory", app.Handlers.Report.InventoryReport).Methods("GET")
-	protected.HandleFunc("/reports/export/sales", app.Handlers.Report.ExportSalesReport).Methods("GET")
-	protected.HandleFunc("/reports/export/inventory", app.Handlers.Report.ExportInventoryReport).Methods("GET")
-
-	// Admin Panel - Template routes
-	adminPanel := r.PathPrefix("/admin").Subrouter()
-	adminPanel.Use(middleware.AuthMiddleware(app.Config.JWT.Secret))
-	adminPanel.HandleFunc("/dashboard", serveTemplate("admin/dashboard.html")).Methods("GET")
-	adminPanel.HandleFunc("/vehicles", serveTemplate("admin/vehicles.html")).Methods("GET")
-	adminPanel.HandleFunc("/sales", serveTemplate("admin/sales.html")).Methods("GET")
-	adminPanel.HandleFunc("/customers", serveTemplate("admin/customers.html")).Methods("GET")
-	adminPanel.HandleFunc("/employees", serveTemplate("admin/employees.html")).Methods("GET")
-	adminPanel.HandleFunc("/warehouses", serveTemplate("admin/warehouses.html")).Methods("GET")
-	adminPanel.HandleFunc("/service", serveTemplate("admin/service.html")).Methods("GET")
-	adminPanel.HandleFunc("/reports", serveTemplate("admin/reports.html")).Methods("GET")
-	adminPanel.HandleFunc("/settings", serveTemplate("admin/settings.html")).Methods("GET")
-
-	return r
+	protected.Handle("/reports/sales", allow(middleware.PermReportsView, h.Report.SalesReport)).Methods("GET")
+	protected.Handle("/reports/inventory", allow(middleware.PermReportsView, h.Report.InventoryReport)).Methods("GET")
+	protected.Handle("/reports/export", allow(middleware.PermReportsView, h.Report.ExportReport)).Methods("GET", "POST")
+	protected.Handle("/reports/export/sales", allow(middleware.PermReportsView, h.Report.ExportSalesReport)).Methods("GET")
+	protected.Handle("/reports/export/inventory", allow(middleware.PermReportsView, h.Report.ExportInventoryReport)).Methods("GET")
 }
 
 func serveTemplate(templatePath string) http.HandlerFunc {
@@ -816,57 +724,6 @@ func serveTemplate(templatePath string) http.HandlerFunc {
 	}
 }
 
-// getRealStats получает реальную статистику из базы данных
-func getRealStats(db *sql.DB) (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
-
-	// Общее количество техники
-	var totalVehicles int
-	err := db.QueryRow("SELECT COUNT(*) FROM vehicles").Scan(&totalVehicles)
-	if err != nil {
-		return nil, fmt.Errorf("error getting total vehicles: %w", err)
-	}
-	stats["total_vehicles"] = totalVehicles
-
-	// Количество доступной техники
-	var availableVehicles int
-	err = db.QueryRow("SELECT COUNT(*) FROM vehicles WHERE status = 'В наличии'").Scan(&availableVehicles)
-	if err != nil {
-		return nil, fmt.Errorf("error getting available vehicles: %w", err)
-	}
-	stats["available_vehicles"] = availableVehicles
-
-	// Общее количество продаж
-	var totalSales int
-	err = db.QueryRow("SELECT COUNT(*) FROM sales").Scan(&totalSales)
-	if err != nil {
-		return nil, fmt.Errorf("error getting total sales: %w", err)
-	}
-	stats["total_sales"] = totalSales
-
-	// Общая выручка
-	var totalRevenue sql.NullFloat64
-	err = db.QueryRow("SELECT COALESCE(SUM(final_price), 0) FROM sales").Scan(&totalRevenue)
-	if err != nil {
-		return nil, fmt.Errorf("error getting total revenue: %w", err)
-	}
-	if totalRevenue.Valid {
-		stats["total_revenue"] = int(totalRevenue.Float64)
-	} else {
-		stats["total_revenue"] = 0
-	}
-
-	// Общее количество клиентов
-	var totalCustomers int
-	err = db.QueryRow("SELECT COUNT(*) FROM customers").Scan(&totalCustomers)
-	if err != nil {
-		return nil, fmt.Errorf("error getting total customers: %w", err)
-	}
-	stats["total_customers"] = totalCustomers
-
-	return stats, nil
-}
-
 // getRealVehicles получает реальные данные техники из базы данных
 func getRealVehicles(db *sql.DB) ([]map[string]interface{}, error) {
 	// Возвращаем пустой массив, так как мы используем новое API
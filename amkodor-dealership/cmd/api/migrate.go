@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+
+	"amkodor-dealership/internal/database"
+)
+
+const migrateUsage = "usage: api migrate up | down [steps] | status | baseline <version>"
+
+// runMigrate выполняет подкоманду `api migrate ...`
+func runMigrate(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("Применена миграция %03d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Схема БД актуальна")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid steps: %s", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("Откачена миграция %03d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "ожидает"
+			if s.Applied {
+				state = "применена " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Modified {
+				state += " (файл изменён после применения)"
+			}
+			fmt.Printf("%03d_%-30s %s\n", s.Version, s.Name, state)
+		}
+
+	case "baseline":
+		if len(args) < 2 {
+			return fmt.Errorf(migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version: %s", args[1])
+		}
+		marked, err := migrator.Baseline(ctx, version)
+		if err != nil {
+			return err
+		}
+		log.Printf("Отмечено как применённые: %d", marked)
+
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	return nil
+}
+
+// autoMigrate применяет миграции при старте сервера
+func autoMigrate(db *sql.DB) error {
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		log.Printf("Применена миграция %03d_%s", m.Version, m.Name)
+	}
+	return err
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"amkodor-dealership/internal/handlers"
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+const testJWTSecret = "rbac-test-secret"
+
+var allRoles = []string{
+	models.RoleAdmin,
+	models.RoleSalesManager,
+	models.RoleServiceMaster,
+	models.RoleWarehouseKeeper,
+	models.RoleCustomer,
+}
+
+// adminRouteCase - запрос к маршруту /api/admin и роли, которым он разрешён
+type adminRouteCase struct {
+	method  string
+	path    string
+	allowed []string
+}
+
+var (
+	staffRoles   = []string{models.RoleAdmin, models.RoleSalesManager, models.RoleServiceMaster, models.RoleWarehouseKeeper}
+	adminOnly    = []string{models.RoleAdmin}
+	salesRoles   = []string{models.RoleAdmin, models.RoleSalesManager}
+	serviceRoles = []string{models.RoleAdmin, models.RoleServiceMaster}
+	stockRoles   = []string{models.RoleAdmin, models.RoleWarehouseKeeper}
+	clientRead   = []string{models.RoleAdmin, models.RoleSalesManager, models.RoleServiceMaster}
+	vehicleRead  = []string{models.RoleAdmin, models.RoleSalesManager, models.RoleWarehouseKeeper}
+	partsRead    = []string{models.RoleAdmin, models.RoleServiceMaster, models.RoleWarehouseKeeper}
+)
+
+// adminRouteCases - ожидаемая матрица доступа по группам маршрутов.
+// Задана явно, а не через HasPermission, чтобы тест ловил ошибки в
+// выборе права для маршрута.
+var adminRouteCases = []adminRouteCase{
+	{"GET", "/api/admin/events", staffRoles},
+
+	{"GET", "/api/admin/dashboard", staffRoles},
+	{"GET", "/api/admin/dashboard/charts", staffRoles},
+
+	{"POST", "/api/admin/vehicles", stockRoles},
+	{"PUT", "/api/admin/vehicles/1", stockRoles},
+	{"DELETE", "/api/admin/vehicles/1", stockRoles},
+
+	{"GET", "/api/admin/customers", clientRead},
+	{"GET", "/api/admin/customers/1/duplicates", clientRead},
+	{"POST", "/api/admin/customers", salesRoles},
+	{"PUT", "/api/admin/customers/1", salesRoles},
+	{"POST", "/api/admin/customers/1/merge", salesRoles},
+
+	{"GET", "/api/admin/corporate-clients", clientRead},
+	{"POST", "/api/admin/corporate-clients", salesRoles},
+	{"DELETE", "/api/admin/corporate-clients/1", salesRoles},
+
+	{"GET", "/api/admin/sales", salesRoles},
+	{"POST", "/api/admin/sales", salesRoles},
+	{"PUT", "/api/admin/sales/1", salesRoles},
+	{"DELETE", "/api/admin/sales/1", adminOnly},
+
+	{"GET", "/api/admin/reservations", salesRoles},
+	{"POST", "/api/admin/reservations/1/extend", salesRoles},
+
+	{"GET", "/api/admin/employees", adminOnly},
+	{"POST", "/api/admin/employees/1/password/reset", adminOnly},
+
+	{"GET", "/api/admin/positions", adminOnly},
+	{"DELETE", "/api/admin/positions/1", adminOnly},
+
+	{"DELETE", "/api/admin/users/1/sessions", adminOnly},
+	{"GET", "/api/admin/users/1/client-link", clientRead},
+	{"PUT", "/api/admin/users/1/client-link", salesRoles},
+
+	{"GET", "/api/admin/warehouses", vehicleRead},
+	{"POST", "/api/admin/warehouses", adminOnly},
+
+	{"GET", "/api/admin/supplies", vehicleRead},
+	{"POST", "/api/admin/supplies/1/receive", adminOnly},
+
+	{"GET", "/api/admin/vehicle-transfers", vehicleRead},
+	{"POST", "/api/admin/vehicle-transfers/1/dispatch", adminOnly},
+
+	{"GET", "/api/admin/service-orders", serviceRoles},
+	{"PUT", "/api/admin/service-orders/1", serviceRoles},
+	{"POST", "/api/admin/service-orders/1/parts", serviceRoles},
+	{"POST", "/api/admin/service-orders/1/complete", serviceRoles},
+
+	{"GET", "/api/admin/service-requests", serviceRoles},
+	{"POST", "/api/admin/service-requests/1/convert", serviceRoles},
+
+	{"GET", "/api/admin/test-drives", salesRoles},
+	{"GET", "/api/admin/test-drives/availability", salesRoles},
+	{"PUT", "/api/admin/test-drives/managers/1/working-hours", salesRoles},
+
+	{"GET", "/api/admin/calendar-feeds", staffRoles},
+	{"POST", "/api/admin/calendar-feeds", staffRoles},
+	{"DELETE", "/api/admin/calendar-feeds/1", staffRoles},
+
+	{"GET", "/api/admin/spare-parts", partsRead},
+	{"GET", "/api/admin/spare-parts/reconciliation", partsRead},
+	{"POST", "/api/admin/spare-parts", stockRoles},
+	{"POST", "/api/admin/spare-parts/1/movements", stockRoles},
+	{"DELETE", "/api/admin/spare-parts/1", stockRoles},
+
+	{"GET", "/api/admin/suppliers", partsRead},
+	{"POST", "/api/admin/suppliers", stockRoles},
+
+	{"GET", "/api/admin/purchase-orders", partsRead},
+	{"POST", "/api/admin/purchase-orders/1/receive", stockRoles},
+
+	{"GET", "/api/admin/alerts", partsRead},
+	{"POST", "/api/admin/alerts/1/acknowledge", partsRead},
+	{"POST", "/api/admin/alerts/1/resolve", stockRoles},
+
+	{"GET", "/api/admin/reports/sales", salesRoles},
+	{"POST", "/api/admin/reports/export", salesRoles},
+}
+
+// newAdminTestRouter собирает маршруты /api/admin с заглушками вместо
+// обработчиков: заглушка отвечает 200, остальное - настоящие middleware
+func newAdminTestRouter() *mux.Router {
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api").Subrouter()
+	stub := func(http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+	registerAdminRoutes(api, testJWTSecret, &handlers.Handlers{}, stub)
+	return r
+}
+
+// testToken выпускает токен сотрудника для служебных ролей и токен
+// учётной записи users для покупателя
+func testToken(t *testing.T, role string) string {
+	t.Helper()
+
+	var (
+		token string
+		err   error
+	)
+	if role == models.RoleCustomer {
+		token, err = utils.GenerateJWT(1, "user@example.com", role, testJWTSecret, time.Minute)
+	} else {
+		staff := &models.StaffIdentity{EmployeeID: 1, PositionID: 1, Role: role}
+		token, err = utils.GenerateStaffJWT(nil, role+"@example.com", staff, testJWTSecret, time.Minute)
+	}
+	if err != nil {
+		t.Fatalf("generate token for %s: %v", role, err)
+	}
+	return token
+}
+
+func TestAdminRoutesRBAC(t *testing.T) {
+	router := newAdminTestRouter()
+
+	tokens := make(map[string]string, len(allRoles))
+	for _, role := range allRoles {
+		tokens[role] = testToken(t, role)
+	}
+
+	for _, tc := range adminRouteCases {
+		for _, role := range allRoles {
+			want := http.StatusForbidden
+			for _, allowed := range tc.allowed {
+				if allowed == role {
+					want = http.StatusOK
+				}
+			}
+
+			t.Run(tc.method+" "+tc.path+" as "+role, func(t *testing.T) {
+				req := httptest.NewRequest(tc.method, tc.path, nil)
+				req.Header.Set("Authorization", "Bearer "+tokens[role])
+				rec := httptest.NewRecorder()
+
+				router.ServeHTTP(rec, req)
+
+				if rec.Code != want {
+					t.Errorf("got %d, want %d", rec.Code, want)
+				}
+			})
+		}
+	}
+}
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	router := newAdminTestRouter()
+
+	for _, tc := range adminRouteCases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without token: got %d, want %d", tc.method, tc.path, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+// TestAdminRouteGroupsCovered не даёт добавить группу маршрутов /api/admin
+// без строк в adminRouteCases
+func TestAdminRouteGroupsCovered(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, tc := range adminRouteCases {
+		covered[adminRouteGroup(tc.path)] = true
+	}
+
+	err := newAdminTestRouter().Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		if _, err := route.GetMethods(); err != nil {
+			return nil // подроутер, а не конечный маршрут
+		}
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		if group := adminRouteGroup(tpl); !covered[group] {
+			t.Errorf("route group %q (%s) has no RBAC test cases", group, tpl)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+}
+
+// adminRouteGroup возвращает первый сегмент пути после /api/admin/
+func adminRouteGroup(path string) string {
+	group := strings.TrimPrefix(path, "/api/admin/")
+	if i := strings.Index(group, "/"); i >= 0 {
+		group = group[:i]
+	}
+	return group
+}
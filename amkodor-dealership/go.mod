@@ -11,6 +11,7 @@ require (
 	github.com/rs/cors v1.11.1
 	github.com/xuri/excelize/v2 v2.9.1
 	golang.org/x/crypto v0.42.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -1,59 +1,344 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultJWTSecrets - секреты из примеров конфигурации, с которыми
+// нельзя запускаться в production
+var defaultJWTSecrets = []string{
+	"amkodor-secret-key-change-in-production",
+	"amkodor-secret-key-change-in-production-please",
+	"amkodor-production-secret-key-change-me",
+	"your-secret-key-change-in-production",
+}
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	JWT      JWTConfig      `yaml:"jwt"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Logging  LoggingConfig  `yaml:"logging"`
+	Upload   UploadConfig   `yaml:"upload"`
+	Export   ExportConfig   `yaml:"export"`
+	Mail     MailConfig     `yaml:"mail"`
 }
 
 type ServerConfig struct {
-	Port string
-	Host string
-	Env  string
+	Port string `yaml:"port"`
+	Host string `yaml:"host"`
+	Env  string `yaml:"env"`
+	// PublicURL - адрес сайта для ссылок в письмах клиентам
+	PublicURL string `yaml:"public_url"`
+	// Таймауты в секундах
+	ReadTimeout  int `yaml:"read_timeout"`
+	WriteTimeout int `yaml:"write_timeout"`
+	IdleTimeout  int `yaml:"idle_timeout"`
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	DBName   string `yaml:"dbname"`
+	SSLMode  string `yaml:"sslmode"`
+	// Пул соединений, время жизни соединения в секундах
+	MaxOpenConns    int `yaml:"max_open_conns"`
+	MaxIdleConns    int `yaml:"max_idle_conns"`
+	ConnMaxLifetime int `yaml:"conn_max_lifetime"`
+	// AutoMigrate - применять встроенные миграции при старте сервера
+	AutoMigrate bool `yaml:"auto_migrate"`
 }
 
 type JWTConfig struct {
-	Secret      string
-	ExpireHours int
+	Secret              string `yaml:"secret"`
+	AccessExpireMinutes int    `yaml:"access_expire_minutes"`
+	RefreshExpireHours  int    `yaml:"refresh_expire_hours"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins"`
+	AllowedMethods   []string `yaml:"allowed_methods"`
+	AllowedHeaders   []string `yaml:"allowed_headers"`
+	AllowCredentials bool     `yaml:"allow_credentials"`
+	// MaxAge в секундах
+	MaxAge int `yaml:"max_age"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+	Output string `yaml:"output"`
+	File   string `yaml:"file"`
+}
+
+type UploadConfig struct {
+	// MaxSize в байтах
+	MaxSize      int64    `yaml:"max_size"`
+	AllowedTypes []string `yaml:"allowed_types"`
+	Path         string   `yaml:"path"`
+}
+
+type ExportConfig struct {
+	Path       string `yaml:"path"`
+	TempPath   string `yaml:"temp_path"`
+	MaxAgeDays int    `yaml:"max_age_days"`
 }
 
+// MailConfig - SMTP для служебных писем. Пустой Host отключает отправку,
+// письма тогда только пишутся в лог.
+type MailConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// LoadConfig собирает конфигурацию в три слоя: значения по умолчанию,
+// YAML-файл окружения и переменные окружения. Файл выбирается переменной
+// CONFIG_PATH, а без неё - по ENVIRONMENT: configs/config.yaml для
+// production и configs/config.<env>.yaml для остальных окружений
+// (development -> config.dev.yaml).
 func LoadConfig() (*Config, error) {
-	cfg := &Config{
+	cfg := defaultConfig()
+
+	env := getEnv("ENVIRONMENT", EnvDevelopment)
+	path, explicit := configPath(env)
+	if err := cfg.loadFile(path); err != nil {
+		// Отсутствие файла по умолчанию не ошибка: хватает переменных окружения
+		if explicit || !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	cfg.applyEnv()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func defaultConfig() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Host: getEnv("SERVER_HOST", "localhost"),
-			Env:  getEnv("ENVIRONMENT", "development"),
+			Port:         "8080",
+			Host:         "localhost",
+			Env:          EnvDevelopment,
+			PublicURL:    "http://localhost:8080",
+			ReadTimeout:  15,
+			WriteTimeout: 15,
+			IdleTimeout:  60,
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "amkodor_db"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:            "localhost",
+			Port:            "5432",
+			User:            "postgres",
+			Password:        "postgres",
+			DBName:          "amkodor_db",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 300,
 		},
 		JWT: JWTConfig{
-			Secret:      getEnv("JWT_SECRET", "amkodor-secret-key-change-in-production"),
-			ExpireHours: getEnvAsInt("JWT_EXPIRE_HOURS", 24),
+			Secret:              "amkodor-secret-key-change-in-production",
+			AccessExpireMinutes: 15,
+			RefreshExpireHours:  168,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"*"},
+			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Authorization", "Content-Type"},
+			AllowCredentials: true,
+			MaxAge:           300,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "text",
+			Output: "stdout",
+		},
+		Upload: UploadConfig{
+			MaxSize:      10 << 20,
+			AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"},
+			Path:         "./uploads/",
+		},
+		Export: ExportConfig{
+			Path:       "./exports/",
+			TempPath:   "./exports/temp/",
+			MaxAgeDays: 7,
+		},
+		Mail: MailConfig{
+			Port: "587",
 		},
 	}
+}
 
-	return cfg, nil
+func configPath(env string) (string, bool) {
+	if path := os.Getenv("CONFIG_PATH"); path != "" {
+		return path, true
+	}
+
+	switch env {
+	case EnvProduction:
+		return filepath.Join("configs", "config.yaml"), false
+	case EnvDevelopment:
+		return filepath.Join("configs", "config.dev.yaml"), false
+	default:
+		return filepath.Join("configs", "config."+env+".yaml"), false
+	}
+}
+
+// loadFile накладывает значения из YAML-файла поверх текущих.
+// Отсутствующие в файле ключи сохраняют значения по умолчанию.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("error parsing config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv переопределяет значения заданными переменными окружения
+func (c *Config) applyEnv() {
+	setString(&c.Server.Env, "ENVIRONMENT")
+	setString(&c.Server.Port, "SERVER_PORT")
+	setString(&c.Server.Host, "SERVER_HOST")
+	setString(&c.Server.PublicURL, "SERVER_PUBLIC_URL")
+	setInt(&c.Server.ReadTimeout, "SERVER_READ_TIMEOUT")
+	setInt(&c.Server.WriteTimeout, "SERVER_WRITE_TIMEOUT")
+	setInt(&c.Server.IdleTimeout, "SERVER_IDLE_TIMEOUT")
+
+	setString(&c.Database.Host, "DB_HOST")
+	setString(&c.Database.Port, "DB_PORT")
+	setString(&c.Database.User, "DB_USER")
+	setString(&c.Database.Password, "DB_PASSWORD")
+	setString(&c.Database.DBName, "DB_NAME")
+	setString(&c.Database.SSLMode, "DB_SSLMODE")
+	setInt(&c.Database.MaxOpenConns, "DB_MAX_OPEN_CONNS")
+	setInt(&c.Database.MaxIdleConns, "DB_MAX_IDLE_CONNS")
+	setInt(&c.Database.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME")
+	setBool(&c.Database.AutoMigrate, "DB_AUTO_MIGRATE")
+
+	setString(&c.JWT.Secret, "JWT_SECRET")
+	setInt(&c.JWT.AccessExpireMinutes, "JWT_ACCESS_EXPIRE_MINUTES")
+	setInt(&c.JWT.RefreshExpireHours, "JWT_REFRESH_EXPIRE_HOURS")
+
+	setList(&c.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	setList(&c.CORS.AllowedMethods, "CORS_ALLOWED_METHODS")
+	setList(&c.CORS.AllowedHeaders, "CORS_ALLOWED_HEADERS")
+
+	setString(&c.Logging.Level, "LOG_LEVEL")
+	setString(&c.Logging.File, "LOG_FILE")
+
+	setInt64(&c.Upload.MaxSize, "MAX_UPLOAD_SIZE")
+	setString(&c.Upload.Path, "UPLOAD_PATH")
+
+	setString(&c.Export.Path, "EXPORT_PATH")
+	setString(&c.Export.TempPath, "EXPORT_TEMP_PATH")
+
+	setString(&c.Mail.Host, "SMTP_HOST")
+	setString(&c.Mail.Port, "SMTP_PORT")
+	setString(&c.Mail.Username, "SMTP_USERNAME")
+	setString(&c.Mail.Password, "SMTP_PASSWORD")
+	setString(&c.Mail.From, "SMTP_FROM")
+}
+
+// Validate проверяет согласованность конфигурации и возвращает все
+// найденные ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port: invalid port %q", c.Server.Port))
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		errs = append(errs, errors.New("server: timeouts must be positive"))
+	}
+
+	if c.Database.Host == "" || c.Database.DBName == "" || c.Database.User == "" {
+		errs = append(errs, errors.New("database: host, user and dbname are required"))
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		errs = append(errs, errors.New("database.max_open_conns must be positive"))
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database.max_idle_conns must be between 0 and max_open_conns"))
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database.conn_max_lifetime must not be negative"))
+	}
+
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("jwt.secret is required"))
+	}
+	if c.JWT.AccessExpireMinutes <= 0 || c.JWT.RefreshExpireHours <= 0 {
+		errs = append(errs, errors.New("jwt: token lifetimes must be positive"))
+	}
+	if c.IsProduction() {
+		for _, secret := range defaultJWTSecrets {
+			if c.JWT.Secret == secret {
+				errs = append(errs, errors.New("jwt.secret: default secret is not allowed in production, set JWT_SECRET"))
+				break
+			}
+		}
+		if len(c.JWT.Secret) < 32 {
+			errs = append(errs, errors.New("jwt.secret must be at least 32 characters in production"))
+		}
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("cors.allowed_origins must not be empty"))
+	}
+
+	if c.Upload.MaxSize <= 0 {
+		errs = append(errs, errors.New("upload.max_size must be positive"))
+	}
+	if c.Upload.Path == "" {
+		errs = append(errs, errors.New("upload.path is required"))
+	}
+
+	if c.Mail.Host != "" && c.Mail.From == "" {
+		errs = append(errs, errors.New("mail.from is required when mail.host is set"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// IsProduction сообщает, запущено ли приложение в production
+func (c *Config) IsProduction() bool {
+	return c.Server.Env == EnvProduction
+}
+
+// Timeouts возвращает таймауты http.Server
+func (c *ServerConfig) Timeouts() (read, write, idle time.Duration) {
+	return time.Duration(c.ReadTimeout) * time.Second,
+		time.Duration(c.WriteTimeout) * time.Second,
+		time.Duration(c.IdleTimeout) * time.Second
 }
 
 func getEnv(key, defaultValue string) string {
@@ -63,13 +348,50 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
+func setString(dst *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+func setInt(dst *int, key string) {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
-			return intValue
+			*dst = intValue
 		}
 	}
-	return defaultValue
+}
+
+func setInt64(dst *int64, key string) {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+			*dst = intValue
+		}
+	}
+}
+
+func setBool(dst *bool, key string) {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			*dst = boolValue
+		}
+	}
+}
+
+// setList читает список через запятую
+func setList(dst *[]string, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
 }
 
 func (c *DatabaseConfig) ConnectionString() string {
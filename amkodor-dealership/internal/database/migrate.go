@@ -0,0 +1,336 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Файлы миграций: NNN_name.sql - применение, NNN_name.down.sql - откат.
+// Миграции выполняются в транзакции, поэтому не должны содержать BEGIN/COMMIT.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey - ключ pg_advisory_lock, сериализующий миграции
+// между экземплярами приложения
+const migrationLockKey int64 = 7245190301
+
+// Migration - одна версия схемы
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus - состояние миграции в БД
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified - файл изменился после применения (контрольная сумма не совпадает)
+	Modified bool
+}
+
+// Migrator применяет встроенные в бинарник миграции
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator загружает встроенные миграции
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(fileName, ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, ".sql")
+		isDown := strings.HasSuffix(base, ".down")
+		base = strings.TrimSuffix(base, ".down")
+
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name: %s", fileName)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", fileName, err)
+		}
+
+		content, err := fs.ReadFile(fsys, "migrations/"+fileName)
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version}
+			byVersion[version] = m
+		}
+
+		if isDown {
+			m.Down = string(content)
+			continue
+		}
+		if m.Up != "" {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		sum := sha256.Sum256(content)
+		m.Name = name
+		m.Up = string(content)
+		m.Checksum = hex.EncodeToString(sum[:])
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has a down script but no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up применяет все неприменённые миграции и возвращает их список
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.appliedState(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			checksum, ok := state[migration.Version]
+			if ok {
+				if checksum != migration.Checksum {
+					return fmt.Errorf("migration %03d_%s was modified after being applied", migration.Version, migration.Name)
+				}
+				continue
+			}
+
+			if err := m.apply(ctx, conn, migration.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					migration.Version, migration.Name, migration.Checksum)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %03d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down откатывает steps последних применённых миграций
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		state, err := m.appliedState(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := state[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %03d_%s has no down script", migration.Version, migration.Name)
+			}
+
+			if err := m.apply(ctx, conn, migration.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx,
+					`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("rollback of %03d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Baseline отмечает миграции до version включительно как применённые без
+// их выполнения. Нужен для БД, созданных до появления schema_migrations
+// (через psql или docker-entrypoint-initdb.d).
+func (m *Migrator) Baseline(ctx context.Context, version int) (int, error) {
+	marked := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		for _, migration := range m.migrations {
+			if migration.Version > version {
+				break
+			}
+			result, err := conn.ExecContext(ctx, `
+				INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)
+				ON CONFLICT (version) DO NOTHING`,
+				migration.Version, migration.Name, migration.Checksum)
+			if err != nil {
+				return fmt.Errorf("error marking migration %d: %w", migration.Version, err)
+			}
+			rows, _ := result.RowsAffected()
+			marked += int(rows)
+		}
+		return nil
+	})
+
+	return marked, err
+}
+
+// Status возвращает состояние всех известных миграций
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureTable(ctx, m.db); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	type appliedRow struct {
+		checksum  string
+		appliedAt time.Time
+	}
+	applied := map[int]appliedRow{}
+	for rows.Next() {
+		var version int
+		var row appliedRow
+		if err := rows.Scan(&version, &row.checksum, &row.appliedAt); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %w", err)
+		}
+		applied[version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrator) ensureTable(ctx context.Context, db execer) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) appliedState(ctx context.Context, conn *sql.Conn) (map[int]string, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	state := map[int]string{}
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("error scanning schema_migrations: %w", err)
+		}
+		state[version] = checksum
+	}
+
+	return state, rows.Err()
+}
+
+// apply выполняет скрипт и запись в schema_migrations одной транзакцией
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
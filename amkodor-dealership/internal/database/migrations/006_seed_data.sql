@@ -162,5 +162,3 @@ INSERT INTO ssis_configuration (config_name, config_value, description) VALUES
 INSERT INTO ssis_log (package_name, execution_date, rows_processed, status, username, execution_duration) VALUES
                                                                                                               ('SalesExport', CURRENT_TIMESTAMP - INTERVAL '1 day', 150, 'Success', 'admin', 45),
                                                                                                               ('InventoryExport', CURRENT_TIMESTAMP - INTERVAL '2 days', 200, 'Success', 'admin', 60);
-
-COMMIT;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- Refresh-токены для ротации сессий пользователей.
+-- Хранится только SHA-256 хеш токена. Все токены, полученные ротацией
+-- из одного входа в систему, имеют общий family_id: при повторном
+-- использовании уже обменянного токена отзывается всё семейство.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
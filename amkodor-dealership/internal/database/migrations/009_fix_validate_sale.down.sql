@@ -0,0 +1,33 @@
+-- Возврат исходной версии validate_sale из 005_create_triggers.sql
+CREATE OR REPLACE FUNCTION validate_sale()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_vehicle_status VARCHAR(50);
+BEGIN
+    -- Проверка статуса техники
+    SELECT status INTO v_vehicle_status
+    FROM vehicles
+    WHERE vehicle_id = NEW.vehicle_id;
+
+    IF v_vehicle_status NOT IN ('В наличии', 'Зарезервировано') THEN
+        RAISE EXCEPTION 'Невозможно продать технику со статусом: %', v_vehicle_status;
+    END IF;
+
+    -- Проверка что указан хотя бы один клиент
+    IF NEW.customer_id IS NULL AND NEW.corporate_client_id IS NULL THEN
+        RAISE EXCEPTION 'Необходимо указать клиента (физическое или юридическое лицо)';
+    END IF;
+
+    -- Проверка что не указаны оба типа клиентов
+    IF NEW.customer_id IS NOT NULL AND NEW.corporate_client_id IS NOT NULL THEN
+        RAISE EXCEPTION 'Нельзя указать одновременно физическое и юридическое лицо';
+    END IF;
+
+    -- Проверка корректности цен
+    IF NEW.final_price > NEW.base_price THEN
+        RAISE EXCEPTION 'Финальная цена не может быть больше базовой';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,22 @@
+-- Заявки клиентов на сервис.
+-- Заявка создаётся пользователем личного кабинета и проходит разбор
+-- сотрудником сервиса: либо отклоняется, либо превращается в сервисный
+-- заказ (service_orders). Дальнейший статус клиент видит по заказу.
+CREATE TABLE IF NOT EXISTS service_requests (
+    service_request_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    title VARCHAR(200) NOT NULL,
+    description TEXT,
+    service_type VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'in-progress', 'rejected', 'cancelled')),
+    service_order_id INTEGER UNIQUE REFERENCES service_orders(service_order_id) ON DELETE SET NULL,
+    processed_by INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    staff_comment TEXT,
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_requests_user ON service_requests(user_id);
+CREATE INDEX IF NOT EXISTS idx_service_requests_status ON service_requests(status);
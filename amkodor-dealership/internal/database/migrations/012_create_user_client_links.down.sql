@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS client_link_claims;
+DROP TABLE IF EXISTS user_client_links;
@@ -0,0 +1,35 @@
+-- Связь учётных записей личного кабинета (users) с клиентами CRM.
+-- Пользователь связан не более чем с одним клиентом. Физическое лицо
+-- принадлежит одному пользователю, а к юридическому лицу могут быть
+-- привязаны несколько сотрудников компании.
+CREATE TABLE IF NOT EXISTS user_client_links (
+    user_id INTEGER PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+    customer_id INTEGER UNIQUE REFERENCES customers(customer_id) ON DELETE CASCADE,
+    corporate_client_id INTEGER REFERENCES corporate_clients(corporate_client_id) ON DELETE CASCADE,
+    -- verification - клиент подтвердил телефон или email кодом, staff - привязал сотрудник
+    method VARCHAR(20) NOT NULL CHECK (method IN ('verification', 'staff')),
+    linked_by INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((customer_id IS NOT NULL AND corporate_client_id IS NULL) OR (customer_id IS NULL AND corporate_client_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_client_links_corporate ON user_client_links(corporate_client_id);
+
+-- Запросы на самостоятельную привязку. Код подтверждения отправляется
+-- на контакт из карточки клиента, хранится только его bcrypt-хеш.
+CREATE TABLE IF NOT EXISTS client_link_claims (
+    claim_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    customer_id INTEGER REFERENCES customers(customer_id) ON DELETE CASCADE,
+    corporate_client_id INTEGER REFERENCES corporate_clients(corporate_client_id) ON DELETE CASCADE,
+    channel VARCHAR(10) NOT NULL CHECK (channel IN ('phone', 'email')),
+    destination VARCHAR(200) NOT NULL,
+    code_hash VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((customer_id IS NOT NULL AND corporate_client_id IS NULL) OR (customer_id IS NULL AND corporate_client_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_client_link_claims_user ON client_link_claims(user_id);
@@ -0,0 +1,27 @@
+DROP VIEW IF EXISTS vw_all_clients;
+CREATE VIEW vw_all_clients AS
+SELECT
+    'CUSTOMER' AS client_type,
+    customer_id AS client_id,
+    last_name || ' ' || first_name AS client_name,
+    phone,
+    email,
+    discount_percent,
+    CASE WHEN is_vip THEN 'VIP' ELSE 'Обычный' END AS client_category,
+    created_at
+FROM customers
+UNION ALL
+SELECT
+    'CORPORATE' AS client_type,
+    corporate_client_id AS client_id,
+    company_name AS client_name,
+    phone,
+    email,
+    discount_percent,
+    'Корпоративный' AS client_category,
+    created_at
+FROM corporate_clients;
+
+DROP TABLE IF EXISTS corporate_client_contacts;
+DROP INDEX IF EXISTS idx_corporate_clients_contract_number;
+ALTER TABLE corporate_clients DROP COLUMN IF EXISTS bank_bic;
@@ -0,0 +1,74 @@
+-- Реквизиты и контактные лица корпоративных клиентов,
+-- итоги покупок в vw_all_clients.
+
+ALTER TABLE corporate_clients ADD COLUMN IF NOT EXISTS bank_bic VARCHAR(11);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_corporate_clients_contract_number
+    ON corporate_clients(contract_number) WHERE contract_number IS NOT NULL;
+
+-- Контактные лица компании. Основной контакт дублируется в
+-- corporate_clients.contact_person для представлений и отчетов.
+CREATE TABLE IF NOT EXISTS corporate_client_contacts (
+    contact_id SERIAL PRIMARY KEY,
+    corporate_client_id INTEGER NOT NULL REFERENCES corporate_clients(corporate_client_id) ON DELETE CASCADE,
+    full_name VARCHAR(200) NOT NULL,
+    position VARCHAR(100),
+    phone VARCHAR(50),
+    email VARCHAR(200),
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_client_contacts_client
+    ON corporate_client_contacts(corporate_client_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_corporate_client_contacts_primary
+    ON corporate_client_contacts(corporate_client_id) WHERE is_primary;
+
+INSERT INTO corporate_client_contacts (corporate_client_id, full_name, phone, email, is_primary)
+SELECT corporate_client_id, contact_person, phone, email, TRUE
+FROM corporate_clients
+WHERE COALESCE(contact_person, '') <> '';
+
+-- Новые колонки добавляются в конец, поэтому достаточно CREATE OR REPLACE
+CREATE OR REPLACE VIEW vw_all_clients AS
+SELECT
+    'CUSTOMER' AS client_type,
+    c.customer_id AS client_id,
+    c.last_name || ' ' || c.first_name AS client_name,
+    c.phone,
+    c.email,
+    c.discount_percent,
+    CASE WHEN c.is_vip THEN 'VIP' ELSE 'Обычный' END AS client_category,
+    c.created_at,
+    COALESCE(s.total_purchases, 0) AS total_purchases,
+    COALESCE(s.total_spent, 0) AS total_spent,
+    s.last_purchase_date
+FROM customers c
+         LEFT JOIN (
+    SELECT customer_id, COUNT(*) AS total_purchases, SUM(final_price) AS total_spent,
+           MAX(sale_date) AS last_purchase_date
+    FROM sales
+    WHERE status = 'Завершена' AND customer_id IS NOT NULL
+    GROUP BY customer_id
+) s ON s.customer_id = c.customer_id
+UNION ALL
+SELECT
+    'CORPORATE' AS client_type,
+    cc.corporate_client_id AS client_id,
+    cc.company_name AS client_name,
+    cc.phone,
+    cc.email,
+    cc.discount_percent,
+    'Корпоративный' AS client_category,
+    cc.created_at,
+    COALESCE(s.total_purchases, 0),
+    COALESCE(s.total_spent, 0),
+    s.last_purchase_date
+FROM corporate_clients cc
+         LEFT JOIN (
+    SELECT corporate_client_id, COUNT(*) AS total_purchases, SUM(final_price) AS total_spent,
+           MAX(sale_date) AS last_purchase_date
+    FROM sales
+    WHERE status = 'Завершена' AND corporate_client_id IS NOT NULL
+    GROUP BY corporate_client_id
+) s ON s.corporate_client_id = cc.corporate_client_id;
@@ -0,0 +1,92 @@
+-- Телефоны, приведённые к формату +375 XX XXX-XX-XX, не восстанавливаются
+
+DROP FUNCTION IF EXISTS sp_search_customers(VARCHAR, VARCHAR, VARCHAR, BOOLEAN, DECIMAL, VARCHAR);
+
+-- Исходные версии из 004_create_procedures.sql и 005_create_triggers.sql
+CREATE OR REPLACE FUNCTION sp_search_customers(
+    p_search_term VARCHAR(200) DEFAULT NULL,
+    p_phone VARCHAR(50) DEFAULT NULL,
+    p_email VARCHAR(200) DEFAULT NULL,
+    p_is_vip BOOLEAN DEFAULT NULL,
+    p_min_discount DECIMAL(5, 2) DEFAULT NULL
+)
+    RETURNS TABLE (
+                      customer_id INTEGER,
+                      full_name VARCHAR(300),
+                      phone VARCHAR(50),
+                      email VARCHAR(200),
+                      address TEXT,
+                      discount_percent DECIMAL(5, 2),
+                      is_vip BOOLEAN,
+                      customer_level VARCHAR(50),
+                      total_purchases BIGINT,
+                      total_spent DECIMAL(18, 2),
+                      created_at TIMESTAMP
+                  ) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            c.customer_id,
+            c.last_name || ' ' || c.first_name || COALESCE(' ' || c.middle_name, '') AS full_name,
+            c.phone,
+            c.email,
+            c.address,
+            c.discount_percent,
+            c.is_vip,
+            fn_get_customer_level(c.customer_id) AS customer_level,
+            (SELECT COUNT(*) FROM sales s WHERE s.customer_id = c.customer_id AND s.status = 'Завершена')::BIGINT AS total_purchases,
+            (SELECT COALESCE(SUM(s.final_price), 0) FROM sales s WHERE s.customer_id = c.customer_id AND s.status = 'Завершена') AS total_spent,
+            c.created_at
+        FROM customers c
+        WHERE
+            (p_search_term IS NULL OR
+             c.last_name ILIKE '%' || p_search_term || '%' OR
+             c.first_name ILIKE '%' || p_search_term || '%' OR
+             c.middle_name ILIKE '%' || p_search_term || '%')
+          AND (p_phone IS NULL OR c.phone ILIKE '%' || p_phone || '%')
+          AND (p_email IS NULL OR c.email ILIKE '%' || p_email || '%')
+          AND (p_is_vip IS NULL OR c.is_vip = p_is_vip)
+          AND (p_min_discount IS NULL OR c.discount_percent >= p_min_discount)
+        ORDER BY c.last_name, c.first_name;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+CREATE OR REPLACE FUNCTION check_vehicle_for_test_drive()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_vehicle_status VARCHAR(50);
+    v_overlapping_count INTEGER;
+BEGIN
+    -- Проверка статуса техники
+    SELECT status INTO v_vehicle_status
+    FROM vehicles
+    WHERE vehicle_id = NEW.vehicle_id;
+
+    IF v_vehicle_status NOT IN ('В наличии', 'Зарезервировано') THEN
+        RAISE EXCEPTION 'Техника недоступна для тест-драйва. Текущий статус: %', v_vehicle_status;
+    END IF;
+
+    -- Проверка на пересечение времени
+    SELECT COUNT(*) INTO v_overlapping_count
+    FROM test_drives
+    WHERE vehicle_id = NEW.vehicle_id
+      AND status = 'Запланирован'
+      AND test_drive_id != COALESCE(NEW.test_drive_id, 0)
+      AND (
+        (NEW.scheduled_date, NEW.scheduled_date + (NEW.duration || ' minutes')::INTERVAL)
+            OVERLAPS
+        (scheduled_date, scheduled_date + (duration || ' minutes')::INTERVAL)
+        );
+
+    IF v_overlapping_count > 0 THEN
+        RAISE EXCEPTION 'На это время уже запланирован другой тест-драйв';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS customer_merges;
+DROP INDEX IF EXISTS idx_customers_passport;
+DROP INDEX IF EXISTS idx_customers_phone_digits;
+DROP FUNCTION IF EXISTS fn_normalize_by_phone(TEXT);
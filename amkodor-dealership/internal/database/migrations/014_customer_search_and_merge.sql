@@ -0,0 +1,148 @@
+-- Нормализация телефонов клиентов, поиск по паспорту и журнал
+-- объединения дублей.
+
+-- Белорусский номер в формате +375 XX XXX-XX-XX. Принимает +375..., 80...
+-- и 9 цифр без кода страны; для прочих номеров возвращает NULL.
+CREATE OR REPLACE FUNCTION fn_normalize_by_phone(p_phone TEXT)
+    RETURNS TEXT AS $$
+DECLARE
+    v_digits TEXT := regexp_replace(COALESCE(p_phone, ''), '\D', '', 'g');
+BEGIN
+    IF v_digits ~ '^375\d{9}$' THEN
+        v_digits := substr(v_digits, 4);
+    ELSIF v_digits ~ '^80\d{9}$' THEN
+        v_digits := substr(v_digits, 3);
+    ELSIF v_digits !~ '^\d{9}$' THEN
+        RETURN NULL;
+    END IF;
+
+    RETURN '+375 ' || substr(v_digits, 1, 2) || ' ' || substr(v_digits, 3, 3) || '-' ||
+           substr(v_digits, 6, 2) || '-' || substr(v_digits, 8, 2);
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+UPDATE customers
+SET phone = fn_normalize_by_phone(phone)
+WHERE fn_normalize_by_phone(phone) IS NOT NULL
+  AND fn_normalize_by_phone(phone) <> phone;
+
+CREATE INDEX IF NOT EXISTS idx_customers_phone_digits
+    ON customers(regexp_replace(phone, '\D', '', 'g'));
+CREATE INDEX IF NOT EXISTS idx_customers_passport
+    ON customers(UPPER(passport_number)) WHERE passport_number IS NOT NULL;
+
+-- Журнал объединения дублей: снимок удалённой карточки и количество
+-- перенесённых на оставшегося клиента документов
+CREATE TABLE IF NOT EXISTS customer_merges (
+    merge_id SERIAL PRIMARY KEY,
+    survivor_id INTEGER REFERENCES customers(customer_id) ON DELETE SET NULL,
+    merged_customer_id INTEGER NOT NULL,
+    merged_data JSONB NOT NULL,
+    sales_moved INTEGER NOT NULL DEFAULT 0,
+    test_drives_moved INTEGER NOT NULL DEFAULT 0,
+    service_orders_moved INTEGER NOT NULL DEFAULT 0,
+    merged_by INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    merged_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_merges_survivor ON customer_merges(survivor_id);
+
+-- Проверка тест-драйва выполняется только при записи на тест-драйв или
+-- переносе. Иначе перенос прошедших тест-драйвов на другого клиента
+-- отклонялся, т.к. техника к этому моменту уже может быть продана.
+CREATE OR REPLACE FUNCTION check_vehicle_for_test_drive()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_vehicle_status VARCHAR(50);
+    v_overlapping_count INTEGER;
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND NEW.vehicle_id = OLD.vehicle_id
+        AND NEW.scheduled_date = OLD.scheduled_date
+        AND NEW.duration IS NOT DISTINCT FROM OLD.duration THEN
+        RETURN NEW;
+    END IF;
+
+    -- Проверка статуса техники
+    SELECT status INTO v_vehicle_status
+    FROM vehicles
+    WHERE vehicle_id = NEW.vehicle_id;
+
+    IF v_vehicle_status NOT IN ('В наличии', 'Зарезервировано') THEN
+        RAISE EXCEPTION 'Техника недоступна для тест-драйва. Текущий статус: %', v_vehicle_status;
+    END IF;
+
+    -- Проверка на пересечение времени
+    SELECT COUNT(*) INTO v_overlapping_count
+    FROM test_drives
+    WHERE vehicle_id = NEW.vehicle_id
+      AND status = 'Запланирован'
+      AND test_drive_id != COALESCE(NEW.test_drive_id, 0)
+      AND (
+        (NEW.scheduled_date, NEW.scheduled_date + (NEW.duration || ' minutes')::INTERVAL)
+            OVERLAPS
+        (scheduled_date, scheduled_date + (duration || ' minutes')::INTERVAL)
+        );
+
+    IF v_overlapping_count > 0 THEN
+        RAISE EXCEPTION 'На это время уже запланирован другой тест-драйв';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Поиск клиентов: телефон сравнивается по цифрам, добавлен номер паспорта.
+-- Меняется список параметров, поэтому функция пересоздаётся.
+DROP FUNCTION IF EXISTS sp_search_customers(VARCHAR, VARCHAR, VARCHAR, BOOLEAN, DECIMAL);
+
+CREATE OR REPLACE FUNCTION sp_search_customers(
+    p_search_term VARCHAR(200) DEFAULT NULL,
+    p_phone VARCHAR(50) DEFAULT NULL,
+    p_email VARCHAR(200) DEFAULT NULL,
+    p_is_vip BOOLEAN DEFAULT NULL,
+    p_min_discount DECIMAL(5, 2) DEFAULT NULL,
+    p_passport VARCHAR(50) DEFAULT NULL
+)
+    RETURNS TABLE (
+                      customer_id INTEGER,
+                      full_name VARCHAR(300),
+                      phone VARCHAR(50),
+                      email VARCHAR(200),
+                      address TEXT,
+                      discount_percent DECIMAL(5, 2),
+                      is_vip BOOLEAN,
+                      customer_level VARCHAR(50),
+                      total_purchases BIGINT,
+                      total_spent DECIMAL(18, 2),
+                      created_at TIMESTAMP
+                  ) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            c.customer_id,
+            (c.last_name || ' ' || c.first_name || COALESCE(' ' || c.middle_name, ''))::VARCHAR(300) AS full_name,
+            c.phone,
+            c.email,
+            c.address,
+            c.discount_percent,
+            c.is_vip,
+            fn_get_customer_level(c.customer_id) AS customer_level,
+            (SELECT COUNT(*) FROM sales s WHERE s.customer_id = c.customer_id AND s.status = 'Завершена')::BIGINT AS total_purchases,
+            (SELECT COALESCE(SUM(s.final_price), 0) FROM sales s WHERE s.customer_id = c.customer_id AND s.status = 'Завершена') AS total_spent,
+            c.created_at
+        FROM customers c
+        WHERE
+            (p_search_term IS NULL OR
+             c.last_name ILIKE '%' || p_search_term || '%' OR
+             c.first_name ILIKE '%' || p_search_term || '%' OR
+             c.middle_name ILIKE '%' || p_search_term || '%')
+          AND (p_phone IS NULL OR
+               regexp_replace(c.phone, '\D', '', 'g') LIKE '%' || regexp_replace(p_phone, '\D', '', 'g') || '%')
+          AND (p_email IS NULL OR c.email ILIKE '%' || p_email || '%')
+          AND (p_is_vip IS NULL OR c.is_vip = p_is_vip)
+          AND (p_min_discount IS NULL OR c.discount_percent >= p_min_discount)
+          AND (p_passport IS NULL OR UPPER(c.passport_number) = UPPER(regexp_replace(p_passport, '[\s-]', '', 'g')))
+        ORDER BY c.last_name, c.first_name;
+END;
+$$ LANGUAGE plpgsql STABLE;
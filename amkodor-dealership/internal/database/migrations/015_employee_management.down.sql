@@ -0,0 +1,25 @@
+-- Исходная версия vw_employees_full_info из 002_create_views.sql
+DROP VIEW IF EXISTS vw_employees_full_info;
+
+CREATE VIEW vw_employees_full_info AS
+SELECT
+    e.employee_id,
+    e.last_name || ' ' || e.first_name || COALESCE(' ' || e.middle_name, '') AS full_name,
+    e.first_name,
+    e.last_name,
+    e.middle_name,
+    p.position_name,
+    p.base_salary,
+    e.salary,
+    w.warehouse_name,
+    w.city AS warehouse_city,
+    e.email,
+    e.phone,
+    e.hire_date,
+    EXTRACT(YEAR FROM AGE(CURRENT_DATE, e.hire_date)) AS years_of_service,
+    e.is_active
+FROM employees e
+         INNER JOIN positions p ON e.position_id = p.position_id
+         LEFT JOIN warehouses w ON e.warehouse_id = w.warehouse_id;
+
+ALTER TABLE employees DROP COLUMN IF EXISTS dismissal_date;
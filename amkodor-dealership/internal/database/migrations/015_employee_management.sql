@@ -0,0 +1,33 @@
+-- Увольнение сотрудников вместо удаления: продажи, тест-драйвы и сервисные
+-- заказы ссылаются на employees с ON DELETE RESTRICT. Стаж уволенного
+-- сотрудника считается до даты увольнения.
+
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS dismissal_date DATE;
+
+-- Новые колонки добавляются в конец, поэтому достаточно CREATE OR REPLACE
+CREATE OR REPLACE VIEW vw_employees_full_info AS
+SELECT
+    e.employee_id,
+    e.last_name || ' ' || e.first_name || COALESCE(' ' || e.middle_name, '') AS full_name,
+    e.first_name,
+    e.last_name,
+    e.middle_name,
+    p.position_name,
+    p.base_salary,
+    e.salary,
+    w.warehouse_name,
+    w.city AS warehouse_city,
+    e.email,
+    e.phone,
+    e.hire_date,
+    EXTRACT(YEAR FROM AGE(COALESCE(e.dismissal_date, CURRENT_DATE), e.hire_date)) AS years_of_service,
+    e.is_active,
+    e.position_id,
+    e.warehouse_id,
+    e.dismissal_date,
+    (EXTRACT(YEAR FROM AGE(COALESCE(e.dismissal_date, CURRENT_DATE), e.hire_date)) * 12 +
+     EXTRACT(MONTH FROM AGE(COALESCE(e.dismissal_date, CURRENT_DATE), e.hire_date)))::INTEGER AS tenure_months,
+    e.created_at
+FROM employees e
+         INNER JOIN positions p ON e.position_id = p.position_id
+         LEFT JOIN warehouses w ON e.warehouse_id = w.warehouse_id;
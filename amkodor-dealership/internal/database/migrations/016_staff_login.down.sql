@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_employee;
+DELETE FROM refresh_tokens WHERE user_id IS NULL;
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS chk_refresh_tokens_owner;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS employee_id;
+ALTER TABLE refresh_tokens ALTER COLUMN user_id SET NOT NULL;
+
+ALTER TABLE users DROP COLUMN IF EXISTS employee_id;
+ALTER TABLE positions DROP COLUMN IF EXISTS access_role;
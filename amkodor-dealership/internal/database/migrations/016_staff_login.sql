@@ -0,0 +1,30 @@
+-- Вход сотрудников в административную часть.
+-- Роль доступа определяется должностью; должность без роли не даёт входа.
+ALTER TABLE positions ADD COLUMN IF NOT EXISTS access_role VARCHAR(50)
+    CHECK (access_role IN ('admin', 'sales_manager', 'service_master', 'warehouse_keeper'));
+
+UPDATE positions SET access_role = CASE
+    WHEN position_name IN ('Администратор', 'Директор филиала') THEN 'admin'
+    WHEN position_name ILIKE '%менеджер по продажам%' THEN 'sales_manager'
+    WHEN position_name IN ('Механик', 'Мастер сервисного центра') THEN 'service_master'
+    WHEN position_name = 'Кладовщик' THEN 'warehouse_keeper'
+END
+WHERE access_role IS NULL;
+
+-- Учётная запись users может быть связана с сотрудником: тогда её токен
+-- тоже несёт сотрудника, должность и склад
+ALTER TABLE users ADD COLUMN IF NOT EXISTS employee_id INTEGER UNIQUE
+    REFERENCES employees(employee_id) ON DELETE SET NULL;
+
+UPDATE users u SET employee_id = e.employee_id
+FROM employees e
+WHERE LOWER(e.email) = LOWER(u.email) AND u.role <> 'user' AND u.employee_id IS NULL;
+
+-- Refresh-токены выдаются либо пользователю, либо сотруднику
+ALTER TABLE refresh_tokens ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS employee_id INTEGER
+    REFERENCES employees(employee_id) ON DELETE CASCADE;
+ALTER TABLE refresh_tokens ADD CONSTRAINT chk_refresh_tokens_owner
+    CHECK (num_nonnulls(user_id, employee_id) = 1);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_employee ON refresh_tokens(employee_id);
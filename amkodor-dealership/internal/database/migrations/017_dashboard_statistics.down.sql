@@ -0,0 +1,35 @@
+DROP VIEW IF EXISTS vw_sales_statistics_by_category_warehouse;
+
+-- Исходные версии представлений из 002_create_views.sql
+DROP VIEW IF EXISTS vw_sales_statistics_by_manager;
+
+CREATE VIEW vw_sales_statistics_by_manager AS
+SELECT
+    e.employee_id,
+    e.last_name || ' ' || e.first_name AS manager_name,
+    w.warehouse_name,
+    COUNT(s.sale_id) AS total_sales,
+    COALESCE(SUM(s.final_price), 0) AS total_revenue,
+    COALESCE(AVG(s.final_price), 0) AS average_sale_price,
+    MIN(s.sale_date) AS first_sale_date,
+    MAX(s.sale_date) AS last_sale_date
+FROM employees e
+         LEFT JOIN sales s ON e.employee_id = s.employee_id AND s.status = 'Завершена'
+         LEFT JOIN warehouses w ON e.warehouse_id = w.warehouse_id
+         INNER JOIN positions p ON e.position_id = p.position_id
+WHERE p.position_name ILIKE '%менеджер%' OR p.position_name ILIKE '%продаж%'
+GROUP BY e.employee_id, e.last_name, e.first_name, w.warehouse_name;
+
+DROP VIEW IF EXISTS vw_dashboard_statistics;
+
+CREATE VIEW vw_dashboard_statistics AS
+SELECT
+    (SELECT COUNT(*) FROM vehicles WHERE status = 'В наличии') AS available_vehicles,
+    (SELECT COUNT(*) FROM sales WHERE sale_date >= CURRENT_DATE - INTERVAL '30 days' AND status = 'Завершена') AS sales_last_month,
+    (SELECT COALESCE(SUM(final_price), 0) FROM sales WHERE sale_date >= CURRENT_DATE - INTERVAL '30 days' AND status = 'Завершена') AS revenue_last_month,
+    (SELECT COUNT(*) FROM customers) AS total_customers,
+    (SELECT COUNT(*) FROM corporate_clients) AS total_corporate_clients,
+    (SELECT COUNT(*) FROM test_drives WHERE scheduled_date >= CURRENT_TIMESTAMP AND status = 'Запланирован') AS upcoming_test_drives,
+    (SELECT COUNT(*) FROM service_orders WHERE status = 'В работе') AS active_service_orders;
+
+DROP FUNCTION IF EXISTS fn_dashboard_statistics(INTEGER);
@@ -0,0 +1,84 @@
+-- Статистика дашборда с фильтром по складу.
+-- Техника, продажи и тест-драйвы относятся к складу техники, сервисные
+-- заказы и сотрудники - к складу сотрудника. Клиенты общие для всех складов.
+CREATE OR REPLACE FUNCTION fn_dashboard_statistics(p_warehouse_id INTEGER)
+RETURNS TABLE (
+    available_vehicles BIGINT,
+    sales_last_month BIGINT,
+    revenue_last_month NUMERIC,
+    total_customers BIGINT,
+    total_corporate_clients BIGINT,
+    upcoming_test_drives BIGINT,
+    active_service_orders BIGINT,
+    total_vehicles BIGINT,
+    total_sales BIGINT,
+    total_revenue NUMERIC,
+    total_employees BIGINT
+) AS $$
+    WITH wv AS (
+        SELECT v.vehicle_id, v.status
+        FROM vehicles v
+        WHERE p_warehouse_id IS NULL OR v.warehouse_id = p_warehouse_id
+    ),
+    ws AS (
+        SELECT s.sale_date, s.final_price
+        FROM sales s
+        INNER JOIN wv ON wv.vehicle_id = s.vehicle_id
+        WHERE s.status = 'Завершена'
+    )
+    SELECT
+        (SELECT COUNT(*) FROM wv WHERE wv.status = 'В наличии'),
+        (SELECT COUNT(*) FROM ws WHERE ws.sale_date >= CURRENT_DATE - INTERVAL '30 days'),
+        (SELECT COALESCE(SUM(ws.final_price), 0) FROM ws WHERE ws.sale_date >= CURRENT_DATE - INTERVAL '30 days'),
+        (SELECT COUNT(*) FROM customers),
+        (SELECT COUNT(*) FROM corporate_clients),
+        (SELECT COUNT(*) FROM test_drives td INNER JOIN wv ON wv.vehicle_id = td.vehicle_id
+         WHERE td.scheduled_date >= CURRENT_TIMESTAMP AND td.status = 'Запланирован'),
+        (SELECT COUNT(*) FROM service_orders so INNER JOIN employees e ON e.employee_id = so.employee_id
+         WHERE so.status = 'В работе' AND (p_warehouse_id IS NULL OR e.warehouse_id = p_warehouse_id)),
+        (SELECT COUNT(*) FROM wv),
+        (SELECT COUNT(*) FROM ws),
+        (SELECT COALESCE(SUM(ws.final_price), 0) FROM ws),
+        (SELECT COUNT(*) FROM employees e
+         WHERE e.is_active AND (p_warehouse_id IS NULL OR e.warehouse_id = p_warehouse_id));
+$$ LANGUAGE sql STABLE;
+
+-- Новые колонки добавляются в конец, поэтому достаточно CREATE OR REPLACE
+CREATE OR REPLACE VIEW vw_dashboard_statistics AS
+SELECT * FROM fn_dashboard_statistics(NULL);
+
+CREATE OR REPLACE VIEW vw_sales_statistics_by_manager AS
+SELECT
+    e.employee_id,
+    e.last_name || ' ' || e.first_name AS manager_name,
+    w.warehouse_name,
+    COUNT(s.sale_id) AS total_sales,
+    COALESCE(SUM(s.final_price), 0) AS total_revenue,
+    COALESCE(AVG(s.final_price), 0) AS average_sale_price,
+    MIN(s.sale_date) AS first_sale_date,
+    MAX(s.sale_date) AS last_sale_date,
+    e.warehouse_id
+FROM employees e
+         LEFT JOIN sales s ON e.employee_id = s.employee_id AND s.status = 'Завершена'
+         LEFT JOIN warehouses w ON e.warehouse_id = w.warehouse_id
+         INNER JOIN positions p ON e.position_id = p.position_id
+WHERE p.position_name ILIKE '%менеджер%' OR p.position_name ILIKE '%продаж%'
+GROUP BY e.employee_id, e.last_name, e.first_name, w.warehouse_name, e.warehouse_id;
+
+-- Продажи по категориям в разрезе склада техники
+CREATE OR REPLACE VIEW vw_sales_statistics_by_category_warehouse AS
+SELECT
+    vc.category_id,
+    vc.category_name,
+    v.warehouse_id,
+    COUNT(s.sale_id) AS total_sales,
+    COALESCE(SUM(s.final_price), 0) AS total_revenue,
+    COALESCE(AVG(s.final_price), 0) AS average_sale_price,
+    MIN(s.sale_date) AS first_sale_date,
+    MAX(s.sale_date) AS last_sale_date
+FROM vehicle_categories vc
+         INNER JOIN vehicle_types vt ON vc.category_id = vt.category_id
+         INNER JOIN vehicle_models vm ON vt.type_id = vm.type_id
+         INNER JOIN vehicles v ON vm.model_id = v.model_id
+         LEFT JOIN sales s ON v.vehicle_id = s.vehicle_id AND s.status = 'Завершена'
+GROUP BY vc.category_id, vc.category_name, v.warehouse_id;
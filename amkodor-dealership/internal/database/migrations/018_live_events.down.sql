@@ -0,0 +1,104 @@
+DROP TRIGGER IF EXISTS trg_notify_service_request_created ON service_requests;
+DROP FUNCTION IF EXISTS notify_service_request_created();
+
+-- Исходные версии функций из 005_create_triggers.sql
+CREATE OR REPLACE FUNCTION log_sales_changes()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'INSERT') THEN
+        INSERT INTO sales_history (
+            sale_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.sale_id, 'INSERT', NULL,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN NEW;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO sales_history (
+            sale_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.sale_id, 'UPDATE',
+                     row_to_json(OLD)::jsonb,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN NEW;
+    ELSIF (TG_OP = 'DELETE') THEN
+        INSERT INTO sales_history (
+            sale_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     OLD.sale_id, 'DELETE',
+                     row_to_json(OLD)::jsonb,
+                     NULL,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION log_vehicles_changes()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'INSERT') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.vehicle_id, 'INSERT', NULL,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN NEW;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.vehicle_id, 'UPDATE',
+                     row_to_json(OLD)::jsonb,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN NEW;
+    ELSIF (TG_OP = 'DELETE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     OLD.vehicle_id, 'DELETE',
+                     row_to_json(OLD)::jsonb,
+                     NULL,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notify_low_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.quantity_in_stock <= NEW.min_quantity THEN
+        -- Здесь можно добавить отправку уведомления
+        RAISE NOTICE 'Низкий остаток запчастей: % (Артикул: %). Остаток: %, Минимум: %',
+            NEW.part_name, NEW.part_number, NEW.quantity_in_stock, NEW.min_quantity;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS fn_publish_live_event(VARCHAR, INTEGER, JSONB);
+DROP TABLE IF EXISTS live_events;
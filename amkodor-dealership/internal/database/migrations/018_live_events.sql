@@ -0,0 +1,191 @@
+-- События для живого обновления административной панели.
+-- Триггеры из 005_create_triggers.sql записывают событие в live_events и
+-- отправляют его идентификатор в канал NOTIFY live_events. Сервер слушает
+-- канал, читает событие по идентификатору и рассылает его подписчикам SSE.
+-- Таблица хранит события ограниченное время, чтобы переподключившийся
+-- клиент получил пропущенное по Last-Event-ID.
+CREATE TABLE IF NOT EXISTS live_events (
+    event_id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(50) NOT NULL,
+    warehouse_id INTEGER REFERENCES warehouses(warehouse_id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_live_events_created ON live_events(created_at);
+
+-- Сохраняет событие и уведомляет слушателей. NOTIFY доставляется только
+-- после фиксации транзакции, откат отменяет и запись, и уведомление.
+CREATE OR REPLACE FUNCTION fn_publish_live_event(p_event_type VARCHAR, p_warehouse_id INTEGER, p_payload JSONB)
+    RETURNS VOID AS $$
+DECLARE
+    v_event_id BIGINT;
+BEGIN
+    INSERT INTO live_events (event_type, warehouse_id, payload)
+    VALUES (p_event_type, p_warehouse_id, p_payload)
+    RETURNING event_id INTO v_event_id;
+
+    PERFORM pg_notify('live_events', v_event_id::TEXT);
+END;
+$$ LANGUAGE plpgsql;
+
+-- Продажи: sale.created при создании, sale.cancelled при переводе в статус 'Отменена'
+CREATE OR REPLACE FUNCTION log_sales_changes()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_warehouse_id INTEGER;
+BEGIN
+    IF (TG_OP = 'INSERT') THEN
+        INSERT INTO sales_history (
+            sale_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.sale_id, 'INSERT', NULL,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+
+        SELECT warehouse_id INTO v_warehouse_id FROM vehicles WHERE vehicle_id = NEW.vehicle_id;
+        PERFORM fn_publish_live_event('sale.created', v_warehouse_id, jsonb_build_object(
+            'sale_id', NEW.sale_id,
+            'vehicle_id', NEW.vehicle_id,
+            'employee_id', NEW.employee_id,
+            'contract_number', NEW.contract_number,
+            'final_price', NEW.final_price,
+            'status', NEW.status
+        ));
+        RETURN NEW;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO sales_history (
+            sale_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.sale_id, 'UPDATE',
+                     row_to_json(OLD)::jsonb,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+
+        IF NEW.status = 'Отменена' AND OLD.status IS DISTINCT FROM 'Отменена' THEN
+            SELECT warehouse_id INTO v_warehouse_id FROM vehicles WHERE vehicle_id = NEW.vehicle_id;
+            PERFORM fn_publish_live_event('sale.cancelled', v_warehouse_id, jsonb_build_object(
+                'sale_id', NEW.sale_id,
+                'vehicle_id', NEW.vehicle_id,
+                'employee_id', NEW.employee_id,
+                'contract_number', NEW.contract_number,
+                'final_price', NEW.final_price,
+                'previous_status', OLD.status
+            ));
+        END IF;
+        RETURN NEW;
+    ELSIF (TG_OP = 'DELETE') THEN
+        INSERT INTO sales_history (
+            sale_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     OLD.sale_id, 'DELETE',
+                     row_to_json(OLD)::jsonb,
+                     NULL,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Техника: vehicle.status_changed при смене статуса
+CREATE OR REPLACE FUNCTION log_vehicles_changes()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'INSERT') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.vehicle_id, 'INSERT', NULL,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN NEW;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.vehicle_id, 'UPDATE',
+                     row_to_json(OLD)::jsonb,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+
+        IF NEW.status IS DISTINCT FROM OLD.status THEN
+            PERFORM fn_publish_live_event('vehicle.status_changed', NEW.warehouse_id, jsonb_build_object(
+                'vehicle_id', NEW.vehicle_id,
+                'model_id', NEW.model_id,
+                'vin', NEW.vin,
+                'old_status', OLD.status,
+                'new_status', NEW.status
+            ));
+        END IF;
+        RETURN NEW;
+    ELSIF (TG_OP = 'DELETE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     OLD.vehicle_id, 'DELETE',
+                     row_to_json(OLD)::jsonb,
+                     NULL,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Запчасти: spare_part.low_stock при переходе остатка через минимум
+CREATE OR REPLACE FUNCTION notify_low_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.quantity_in_stock <= NEW.min_quantity THEN
+        RAISE NOTICE 'Низкий остаток запчастей: % (Артикул: %). Остаток: %, Минимум: %',
+            NEW.part_name, NEW.part_number, NEW.quantity_in_stock, NEW.min_quantity;
+
+        PERFORM fn_publish_live_event('spare_part.low_stock', NEW.warehouse_id, jsonb_build_object(
+            'spare_part_id', NEW.spare_part_id,
+            'part_number', NEW.part_number,
+            'part_name', NEW.part_name,
+            'quantity_in_stock', NEW.quantity_in_stock,
+            'min_quantity', NEW.min_quantity
+        ));
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Заявки клиентов: service_request.created при создании заявки
+CREATE OR REPLACE FUNCTION notify_service_request_created()
+    RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM fn_publish_live_event('service_request.created', NULL, jsonb_build_object(
+        'service_request_id', NEW.service_request_id,
+        'user_id', NEW.user_id,
+        'title', NEW.title,
+        'service_type', NEW.service_type
+    ));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_notify_service_request_created ON service_requests;
+CREATE TRIGGER trg_notify_service_request_created
+    AFTER INSERT ON service_requests
+    FOR EACH ROW EXECUTE FUNCTION notify_service_request_created();
@@ -0,0 +1,30 @@
+-- Оповещения о низком остатке запчастей.
+-- notify_low_spare_parts_stock публикует событие spare_part.low_stock через
+-- pg_notify (018_live_events.sql), сервер превращает его в запись stock_alerts.
+-- Для запчасти может быть только одно незакрытое оповещение: повторные
+-- события увеличивают occurrences, а не создают новые записи.
+CREATE TABLE IF NOT EXISTS stock_alerts (
+    stock_alert_id SERIAL PRIMARY KEY,
+    spare_part_id INTEGER NOT NULL REFERENCES spare_parts(spare_part_id) ON DELETE CASCADE,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(warehouse_id) ON DELETE CASCADE,
+    -- Остаток и минимум на момент последнего события
+    quantity_in_stock INTEGER NOT NULL,
+    min_quantity INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'acknowledged', 'resolved')),
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    last_raised_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    acknowledged_at TIMESTAMP,
+    resolved_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP,
+    -- Кому и когда отправлено письмо об оповещении
+    notified_email VARCHAR(200),
+    notified_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_stock_alerts_active_part
+    ON stock_alerts(spare_part_id) WHERE status <> 'resolved';
+CREATE INDEX IF NOT EXISTS idx_stock_alerts_status ON stock_alerts(status);
+CREATE INDEX IF NOT EXISTS idx_stock_alerts_warehouse ON stock_alerts(warehouse_id);
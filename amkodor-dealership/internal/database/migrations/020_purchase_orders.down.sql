@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS spare_part_movements;
+DROP FUNCTION IF EXISTS apply_spare_part_movement();
+DROP TABLE IF EXISTS purchase_order_lines;
+DROP TABLE IF EXISTS purchase_orders;
+DROP FUNCTION IF EXISTS generate_purchase_order_number();
+DROP TABLE IF EXISTS suppliers;
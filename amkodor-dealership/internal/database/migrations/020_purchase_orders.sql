@@ -0,0 +1,97 @@
+-- Закупка запчастей у поставщиков.
+-- Заказ создаётся со статусом 'Заказано' и принимается на склад частями или
+-- целиком. Каждый приём записывается движением в spare_part_movements, а
+-- остаток spare_parts.quantity_in_stock меняется только через это движение.
+
+CREATE TABLE IF NOT EXISTS suppliers (
+    supplier_id SERIAL PRIMARY KEY,
+    supplier_name VARCHAR(200) NOT NULL,
+    tax_id VARCHAR(20),
+    contact_person VARCHAR(200),
+    phone VARCHAR(50),
+    email VARCHAR(200),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS purchase_orders (
+    purchase_order_id SERIAL PRIMARY KEY,
+    order_number VARCHAR(50) UNIQUE,
+    supplier_id INTEGER NOT NULL REFERENCES suppliers(supplier_id) ON DELETE RESTRICT,
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(warehouse_id) ON DELETE RESTRICT,
+    status VARCHAR(50) NOT NULL DEFAULT 'Заказано'
+        CHECK (status IN ('Заказано', 'Частично получено', 'Получено', 'Отменено')),
+    order_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    expected_date DATE,
+    received_date DATE,
+    created_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (expected_date IS NULL OR expected_date >= order_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_status ON purchase_orders(status);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_warehouse ON purchase_orders(warehouse_id);
+
+CREATE TABLE IF NOT EXISTS purchase_order_lines (
+    purchase_order_line_id SERIAL PRIMARY KEY,
+    purchase_order_id INTEGER NOT NULL REFERENCES purchase_orders(purchase_order_id) ON DELETE CASCADE,
+    spare_part_id INTEGER NOT NULL REFERENCES spare_parts(spare_part_id) ON DELETE RESTRICT,
+    quantity_ordered INTEGER NOT NULL CHECK (quantity_ordered > 0),
+    quantity_received INTEGER NOT NULL DEFAULT 0,
+    unit_price DECIMAL(18, 2) NOT NULL CHECK (unit_price >= 0),
+    UNIQUE (purchase_order_id, spare_part_id),
+    CHECK (quantity_received >= 0 AND quantity_received <= quantity_ordered)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_order_lines_part ON purchase_order_lines(spare_part_id);
+
+-- Номер заказа по образцу номера договора продажи
+CREATE OR REPLACE FUNCTION generate_purchase_order_number()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.order_number IS NULL THEN
+        NEW.order_number := 'PO-' || TO_CHAR(CURRENT_DATE, 'YYYYMM') || '-'
+                                || LPAD(NEW.purchase_order_id::TEXT, 6, '0');
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_generate_purchase_order_number ON purchase_orders;
+CREATE TRIGGER trg_generate_purchase_order_number
+    BEFORE INSERT ON purchase_orders
+    FOR EACH ROW EXECUTE FUNCTION generate_purchase_order_number();
+
+-- Журнал движений запчастей. quantity - изменение остатка со знаком.
+CREATE TABLE IF NOT EXISTS spare_part_movements (
+    movement_id BIGSERIAL PRIMARY KEY,
+    spare_part_id INTEGER NOT NULL REFERENCES spare_parts(spare_part_id) ON DELETE RESTRICT,
+    movement_type VARCHAR(20) NOT NULL
+        CHECK (movement_type IN ('receipt', 'consumption', 'adjustment', 'transfer', 'write_off')),
+    quantity INTEGER NOT NULL CHECK (quantity <> 0),
+    purchase_order_line_id INTEGER REFERENCES purchase_order_lines(purchase_order_line_id) ON DELETE RESTRICT,
+    employee_id INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_spare_part_movements_part ON spare_part_movements(spare_part_id, created_at);
+
+-- Движение применяется к остатку запчасти. Уход остатка в минус отклоняется
+-- проверкой quantity_in_stock >= 0 в spare_parts.
+CREATE OR REPLACE FUNCTION apply_spare_part_movement()
+    RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE spare_parts
+    SET quantity_in_stock = quantity_in_stock + NEW.quantity
+    WHERE spare_part_id = NEW.spare_part_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_apply_spare_part_movement ON spare_part_movements;
+CREATE TRIGGER trg_apply_spare_part_movement
+    AFTER INSERT ON spare_part_movements
+    FOR EACH ROW EXECUTE FUNCTION apply_spare_part_movement();
@@ -0,0 +1,85 @@
+DROP VIEW IF EXISTS vw_spare_parts_stock;
+
+DROP TRIGGER IF EXISTS trg_record_spare_part_opening_stock ON spare_parts;
+DROP FUNCTION IF EXISTS record_spare_part_opening_stock();
+DROP TRIGGER IF EXISTS trg_guard_spare_parts_stock ON spare_parts;
+DROP FUNCTION IF EXISTS guard_spare_parts_stock();
+DROP TRIGGER IF EXISTS trg_prevent_spare_part_movement_change ON spare_part_movements;
+DROP FUNCTION IF EXISTS prevent_spare_part_movement_change();
+
+CREATE OR REPLACE FUNCTION apply_spare_part_movement()
+    RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE spare_parts
+    SET quantity_in_stock = quantity_in_stock + NEW.quantity
+    WHERE spare_part_id = NEW.spare_part_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'INSERT') THEN
+        UPDATE spare_parts
+        SET quantity_in_stock = quantity_in_stock - NEW.quantity
+        WHERE spare_part_id = NEW.spare_part_id;
+        RETURN NEW;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        UPDATE spare_parts
+        SET quantity_in_stock = quantity_in_stock + OLD.quantity - NEW.quantity
+        WHERE spare_part_id = NEW.spare_part_id;
+        RETURN NEW;
+    ELSIF (TG_OP = 'DELETE') THEN
+        UPDATE spare_parts
+        SET quantity_in_stock = quantity_in_stock + OLD.quantity
+        WHERE spare_part_id = OLD.spare_part_id;
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION sp_add_spare_parts_to_service(
+    p_service_order_id INTEGER,
+    p_spare_part_id INTEGER,
+    p_quantity INTEGER
+)
+    RETURNS INTEGER AS $$
+DECLARE
+    v_service_order_part_id INTEGER;
+    v_unit_price DECIMAL(18, 2);
+    v_available_quantity INTEGER;
+BEGIN
+    SELECT price, quantity_in_stock
+    INTO v_unit_price, v_available_quantity
+    FROM spare_parts
+    WHERE spare_part_id = p_spare_part_id;
+
+    IF v_available_quantity < p_quantity THEN
+        RAISE EXCEPTION 'Недостаточно запчастей на складе';
+    END IF;
+
+    INSERT INTO service_order_parts (
+        service_order_id, spare_part_id, quantity, unit_price
+    ) VALUES (
+                 p_service_order_id, p_spare_part_id, p_quantity, v_unit_price
+             ) RETURNING service_order_part_id INTO v_service_order_part_id;
+
+    UPDATE spare_parts
+    SET quantity_in_stock = quantity_in_stock - p_quantity
+    WHERE spare_part_id = p_spare_part_id;
+
+    RETURN v_service_order_part_id;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE spare_part_movements DROP CONSTRAINT IF EXISTS spare_part_movements_employee_id_fkey;
+ALTER TABLE spare_part_movements
+    ADD CONSTRAINT spare_part_movements_employee_id_fkey
+        FOREIGN KEY (employee_id) REFERENCES employees(employee_id) ON DELETE SET NULL;
+
+DROP INDEX IF EXISTS idx_spare_part_movements_service_order;
+ALTER TABLE spare_part_movements
+    DROP COLUMN IF EXISTS related_movement_id,
+    DROP COLUMN IF EXISTS service_order_id;
@@ -0,0 +1,184 @@
+-- Журнал движений запчастей становится единственным способом изменить остаток.
+-- spare_parts.quantity_in_stock хранит проекцию журнала: прямое изменение
+-- количества отклоняется, расход в сервисных заказах и начальный остаток
+-- новых запчастей записываются движениями.
+
+ALTER TABLE spare_part_movements
+    ADD COLUMN IF NOT EXISTS service_order_id INTEGER,
+    ADD COLUMN IF NOT EXISTS related_movement_id BIGINT REFERENCES spare_part_movements(movement_id);
+
+-- Журнал неизменяем, поэтому ссылка на сотрудника не обнуляется при удалении
+ALTER TABLE spare_part_movements DROP CONSTRAINT IF EXISTS spare_part_movements_employee_id_fkey;
+ALTER TABLE spare_part_movements
+    ADD CONSTRAINT spare_part_movements_employee_id_fkey
+        FOREIGN KEY (employee_id) REFERENCES employees(employee_id);
+
+CREATE INDEX IF NOT EXISTS idx_spare_part_movements_service_order ON spare_part_movements(service_order_id);
+
+CREATE OR REPLACE FUNCTION prevent_spare_part_movement_change()
+    RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'Журнал движений запчастей не изменяется: запишите корректирующее движение';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_prevent_spare_part_movement_change ON spare_part_movements;
+CREATE TRIGGER trg_prevent_spare_part_movement_change
+    BEFORE UPDATE OR DELETE ON spare_part_movements
+    FOR EACH ROW EXECUTE FUNCTION prevent_spare_part_movement_change();
+
+-- Движение применяется к остатку. Начальный остаток (amkodor.stock_opening)
+-- уже учтён в spare_parts и только фиксируется в журнале.
+CREATE OR REPLACE FUNCTION apply_spare_part_movement()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('amkodor.stock_opening', TRUE) = 'on' THEN
+        RETURN NEW;
+    END IF;
+
+    PERFORM set_config('amkodor.stock_ledger', 'on', TRUE);
+    UPDATE spare_parts
+    SET quantity_in_stock = quantity_in_stock + NEW.quantity
+    WHERE spare_part_id = NEW.spare_part_id;
+    PERFORM set_config('amkodor.stock_ledger', 'off', TRUE);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Остаток меняется только из apply_spare_part_movement
+CREATE OR REPLACE FUNCTION guard_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF current_setting('amkodor.stock_ledger', TRUE) IS DISTINCT FROM 'on' THEN
+        RAISE EXCEPTION 'Остаток запчасти "%" меняется только движением по журналу', NEW.part_name;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_guard_spare_parts_stock ON spare_parts;
+CREATE TRIGGER trg_guard_spare_parts_stock
+    BEFORE UPDATE OF quantity_in_stock ON spare_parts
+    FOR EACH ROW
+    WHEN (NEW.quantity_in_stock IS DISTINCT FROM OLD.quantity_in_stock)
+EXECUTE FUNCTION guard_spare_parts_stock();
+
+-- Количество, с которым запчасть заведена, записывается начальным остатком
+CREATE OR REPLACE FUNCTION record_spare_part_opening_stock()
+    RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM set_config('amkodor.stock_opening', 'on', TRUE);
+    INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, reason)
+    VALUES (NEW.spare_part_id, 'adjustment', NEW.quantity_in_stock, 'Начальный остаток');
+    PERFORM set_config('amkodor.stock_opening', 'off', TRUE);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_record_spare_part_opening_stock ON spare_parts;
+CREATE TRIGGER trg_record_spare_part_opening_stock
+    AFTER INSERT ON spare_parts
+    FOR EACH ROW
+    WHEN (NEW.quantity_in_stock <> 0)
+EXECUTE FUNCTION record_spare_part_opening_stock();
+
+-- Расход в сервисном заказе: списание при добавлении, возврат при удалении
+-- и разница при изменении количества или запчасти
+CREATE OR REPLACE FUNCTION update_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_order_id INTEGER;
+    v_employee_id INTEGER;
+    v_reason TEXT;
+BEGIN
+    v_order_id := CASE WHEN TG_OP = 'DELETE' THEN OLD.service_order_id ELSE NEW.service_order_id END;
+    SELECT employee_id INTO v_employee_id FROM service_orders WHERE service_order_id = v_order_id;
+    v_reason := 'Сервисный заказ №' || v_order_id;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF TG_OP = 'DELETE' OR OLD.spare_part_id <> NEW.spare_part_id OR OLD.quantity <> NEW.quantity THEN
+            INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, service_order_id, employee_id, reason)
+            VALUES (OLD.spare_part_id, 'consumption', OLD.quantity, v_order_id, v_employee_id,
+                    'Возврат: ' || v_reason);
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF TG_OP = 'INSERT' OR OLD.spare_part_id <> NEW.spare_part_id OR OLD.quantity <> NEW.quantity THEN
+            INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, service_order_id, employee_id, reason)
+            VALUES (NEW.spare_part_id, 'consumption', -NEW.quantity, v_order_id, v_employee_id, v_reason);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Остаток списывает trg_update_spare_parts_stock, процедура больше не меняет его сама
+CREATE OR REPLACE FUNCTION sp_add_spare_parts_to_service(
+    p_service_order_id INTEGER,
+    p_spare_part_id INTEGER,
+    p_quantity INTEGER
+)
+    RETURNS INTEGER AS $$
+DECLARE
+    v_service_order_part_id INTEGER;
+    v_unit_price DECIMAL(18, 2);
+    v_available_quantity INTEGER;
+BEGIN
+    SELECT price, quantity_in_stock
+    INTO v_unit_price, v_available_quantity
+    FROM spare_parts
+    WHERE spare_part_id = p_spare_part_id;
+
+    IF v_available_quantity < p_quantity THEN
+        RAISE EXCEPTION 'Недостаточно запчастей на складе';
+    END IF;
+
+    INSERT INTO service_order_parts (
+        service_order_id, spare_part_id, quantity, unit_price
+    ) VALUES (
+                 p_service_order_id, p_spare_part_id, p_quantity, v_unit_price
+             ) RETURNING service_order_part_id INTO v_service_order_part_id;
+
+    RETURN v_service_order_part_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Начальный остаток по уже существующим запчастям: разница между хранимым
+-- количеством и тем, что уже есть в журнале (приёмы по заказам поставщикам)
+SELECT set_config('amkodor.stock_opening', 'on', FALSE);
+INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, reason)
+SELECT sp.spare_part_id, 'adjustment', sp.quantity_in_stock - COALESCE(SUM(m.quantity), 0), 'Начальный остаток'
+FROM spare_parts sp
+LEFT JOIN spare_part_movements m ON m.spare_part_id = sp.spare_part_id
+GROUP BY sp.spare_part_id, sp.quantity_in_stock
+HAVING sp.quantity_in_stock - COALESCE(SUM(m.quantity), 0) <> 0;
+SELECT set_config('amkodor.stock_opening', 'off', FALSE);
+
+-- Остаток по журналу рядом с хранимым; drift <> 0 означает расхождение
+CREATE OR REPLACE VIEW vw_spare_parts_stock AS
+SELECT
+    sp.spare_part_id,
+    sp.part_number,
+    sp.part_name,
+    sp.warehouse_id,
+    w.warehouse_name,
+    sp.quantity_in_stock AS stored_quantity,
+    COALESCE(l.ledger_quantity, 0) AS ledger_quantity,
+    sp.quantity_in_stock - COALESCE(l.ledger_quantity, 0) AS drift,
+    sp.min_quantity,
+    l.movements_count,
+    l.last_movement_at
+FROM spare_parts sp
+INNER JOIN warehouses w ON w.warehouse_id = sp.warehouse_id
+LEFT JOIN (
+    SELECT spare_part_id,
+           SUM(quantity) AS ledger_quantity,
+           COUNT(*) AS movements_count,
+           MAX(created_at) AS last_movement_at
+    FROM spare_part_movements
+    GROUP BY spare_part_id
+) l ON l.spare_part_id = sp.spare_part_id;
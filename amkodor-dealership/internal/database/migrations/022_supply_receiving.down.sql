@@ -0,0 +1,27 @@
+DROP VIEW IF EXISTS vw_supplies_full_info;
+CREATE VIEW vw_supplies_full_info AS
+SELECT
+    su.supply_id,
+    su.supply_date,
+    su.expected_arrival_date,
+    su.actual_arrival_date,
+    su.status,
+    su.total_cost,
+    su.invoice_number,
+    m.manufacturer_name,
+    m.country,
+    w.warehouse_name,
+    w.city AS warehouse_city,
+    (SELECT COUNT(*) FROM supply_items si WHERE si.supply_id = su.supply_id) AS items_count,
+    (SELECT COALESCE(SUM(si.quantity), 0) FROM supply_items si WHERE si.supply_id = su.supply_id) AS total_quantity
+FROM supplies su
+         INNER JOIN manufacturers m ON su.manufacturer_id = m.manufacturer_id
+         INNER JOIN warehouses w ON su.warehouse_id = w.warehouse_id;
+
+DROP INDEX IF EXISTS idx_supplies_status;
+ALTER TABLE supplies
+    DROP COLUMN IF EXISTS received_by,
+    DROP COLUMN IF EXISTS created_by;
+
+DROP INDEX IF EXISTS idx_vehicles_supply_item;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS supply_item_id;
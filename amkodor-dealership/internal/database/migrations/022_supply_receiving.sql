@@ -0,0 +1,41 @@
+-- Приём поставок техники от производителей. Единицы техники заводятся по
+-- строкам поставки со статусом 'В пути' и переходят в 'В наличии' при приёме.
+
+ALTER TABLE vehicles
+    ADD COLUMN IF NOT EXISTS supply_item_id INTEGER REFERENCES supply_items(supply_item_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_vehicles_supply_item ON vehicles(supply_item_id);
+
+ALTER TABLE supplies
+    ADD COLUMN IF NOT EXISTS created_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS received_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_supplies_status ON supplies(status);
+
+-- К представлению добавлены идентификаторы, примечание и число уже заведённых единиц
+CREATE OR REPLACE VIEW vw_supplies_full_info AS
+SELECT
+    su.supply_id,
+    su.supply_date,
+    su.expected_arrival_date,
+    su.actual_arrival_date,
+    su.status,
+    su.total_cost,
+    su.invoice_number,
+    m.manufacturer_name,
+    m.country,
+    w.warehouse_name,
+    w.city AS warehouse_city,
+    (SELECT COUNT(*) FROM supply_items si WHERE si.supply_id = su.supply_id) AS items_count,
+    (SELECT COALESCE(SUM(si.quantity), 0) FROM supply_items si WHERE si.supply_id = su.supply_id) AS total_quantity,
+    su.manufacturer_id,
+    su.warehouse_id,
+    su.notes,
+    (SELECT COUNT(*)
+     FROM vehicles v
+     INNER JOIN supply_items si ON si.supply_item_id = v.supply_item_id
+     WHERE si.supply_id = su.supply_id) AS registered_quantity,
+    su.created_at
+FROM supplies su
+         INNER JOIN manufacturers m ON su.manufacturer_id = m.manufacturer_id
+         INNER JOIN warehouses w ON su.warehouse_id = w.warehouse_id;
@@ -0,0 +1,62 @@
+DROP TRIGGER IF EXISTS trg_guard_vehicle_in_transfer ON vehicles;
+DROP FUNCTION IF EXISTS guard_vehicle_in_transfer();
+
+-- Версия из 018_live_events без ссылки на перемещение
+CREATE OR REPLACE FUNCTION log_vehicles_changes()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF (TG_OP = 'INSERT') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.vehicle_id, 'INSERT', NULL,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN NEW;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.vehicle_id, 'UPDATE',
+                     row_to_json(OLD)::jsonb,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+
+        IF NEW.status IS DISTINCT FROM OLD.status THEN
+            PERFORM fn_publish_live_event('vehicle.status_changed', NEW.warehouse_id, jsonb_build_object(
+                'vehicle_id', NEW.vehicle_id,
+                'model_id', NEW.model_id,
+                'vin', NEW.vin,
+                'old_status', OLD.status,
+                'new_status', NEW.status
+            ));
+        END IF;
+        RETURN NEW;
+    ELSIF (TG_OP = 'DELETE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     OLD.vehicle_id, 'DELETE',
+                     row_to_json(OLD)::jsonb,
+                     NULL,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE vehicles_history DROP COLUMN IF EXISTS transfer_id;
+
+DROP TABLE IF EXISTS vehicle_transfer_items;
+DROP TABLE IF EXISTS vehicle_transfers;
+DROP FUNCTION IF EXISTS generate_vehicle_transfer_number();
@@ -0,0 +1,151 @@
+-- Перемещение техники между складами.
+-- Документ создаётся со статусом 'Запланировано'; при отправке техника
+-- переходит в 'В пути', при приёме - на склад назначения с прежним статусом.
+-- Изменения техники по перемещению попадают в vehicles_history со ссылкой на документ.
+
+CREATE TABLE IF NOT EXISTS vehicle_transfers (
+    transfer_id SERIAL PRIMARY KEY,
+    transfer_number VARCHAR(50) UNIQUE,
+    from_warehouse_id INTEGER NOT NULL REFERENCES warehouses(warehouse_id) ON DELETE RESTRICT,
+    to_warehouse_id INTEGER NOT NULL REFERENCES warehouses(warehouse_id) ON DELETE RESTRICT,
+    status VARCHAR(50) NOT NULL DEFAULT 'Запланировано'
+        CHECK (status IN ('Запланировано', 'В пути', 'Получено', 'Отменено')),
+    planned_departure_date DATE,
+    planned_arrival_date DATE,
+    actual_departure_date DATE,
+    actual_arrival_date DATE,
+    driver_name VARCHAR(200),
+    transport_cost DECIMAL(18, 2) NOT NULL DEFAULT 0 CHECK (transport_cost >= 0),
+    notes TEXT,
+    created_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    dispatched_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    received_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_warehouse_id <> to_warehouse_id),
+    CHECK (planned_arrival_date IS NULL OR planned_departure_date IS NULL
+        OR planned_arrival_date >= planned_departure_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_transfers_status ON vehicle_transfers(status);
+CREATE INDEX IF NOT EXISTS idx_vehicle_transfers_from ON vehicle_transfers(from_warehouse_id);
+CREATE INDEX IF NOT EXISTS idx_vehicle_transfers_to ON vehicle_transfers(to_warehouse_id);
+
+-- previous_status - статус техники до отправки, восстанавливается при приёме или отмене
+CREATE TABLE IF NOT EXISTS vehicle_transfer_items (
+    transfer_id INTEGER NOT NULL REFERENCES vehicle_transfers(transfer_id) ON DELETE CASCADE,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(vehicle_id) ON DELETE RESTRICT,
+    previous_status VARCHAR(50),
+    PRIMARY KEY (transfer_id, vehicle_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_vehicle_transfer_items_vehicle ON vehicle_transfer_items(vehicle_id);
+
+-- Номер перемещения по образцу номера заказа поставщику
+CREATE OR REPLACE FUNCTION generate_vehicle_transfer_number()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.transfer_number IS NULL THEN
+        NEW.transfer_number := 'TR-' || TO_CHAR(CURRENT_DATE, 'YYYYMM') || '-'
+                                   || LPAD(NEW.transfer_id::TEXT, 6, '0');
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_generate_vehicle_transfer_number ON vehicle_transfers;
+CREATE TRIGGER trg_generate_vehicle_transfer_number
+    BEFORE INSERT ON vehicle_transfers
+    FOR EACH ROW EXECUTE FUNCTION generate_vehicle_transfer_number();
+
+-- Техника в пути меняет склад и статус только по документу перемещения:
+-- приложение выставляет amkodor.vehicle_transfer_id на время транзакции
+CREATE OR REPLACE FUNCTION guard_vehicle_in_transfer()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_transfer_number VARCHAR(50);
+BEGIN
+    IF COALESCE(current_setting('amkodor.vehicle_transfer_id', TRUE), '') <> '' THEN
+        RETURN NEW;
+    END IF;
+
+    SELECT t.transfer_number INTO v_transfer_number
+    FROM vehicle_transfer_items i
+    INNER JOIN vehicle_transfers t ON t.transfer_id = i.transfer_id
+    WHERE i.vehicle_id = NEW.vehicle_id AND t.status = 'В пути';
+
+    IF v_transfer_number IS NOT NULL THEN
+        RAISE EXCEPTION 'Техника % в пути по перемещению %', NEW.vehicle_id, v_transfer_number
+            USING ERRCODE = 'object_in_use';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_guard_vehicle_in_transfer ON vehicles;
+CREATE TRIGGER trg_guard_vehicle_in_transfer
+    BEFORE UPDATE OF warehouse_id, status ON vehicles
+    FOR EACH ROW
+    WHEN (NEW.warehouse_id IS DISTINCT FROM OLD.warehouse_id OR NEW.status IS DISTINCT FROM OLD.status)
+EXECUTE FUNCTION guard_vehicle_in_transfer();
+
+ALTER TABLE vehicles_history
+    ADD COLUMN IF NOT EXISTS transfer_id INTEGER REFERENCES vehicle_transfers(transfer_id) ON DELETE SET NULL;
+
+-- История техники: к изменению добавляется документ перемещения, если оно
+-- выполнено по нему; остальное как в 018_live_events
+CREATE OR REPLACE FUNCTION log_vehicles_changes()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_transfer_id INTEGER := NULLIF(current_setting('amkodor.vehicle_transfer_id', TRUE), '')::INTEGER;
+BEGIN
+    IF (TG_OP = 'INSERT') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     NEW.vehicle_id, 'INSERT', NULL,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN NEW;
+    ELSIF (TG_OP = 'UPDATE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name, transfer_id
+        ) VALUES (
+                     NEW.vehicle_id, 'UPDATE',
+                     row_to_json(OLD)::jsonb,
+                     row_to_json(NEW)::jsonb,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true),
+                     v_transfer_id
+                 );
+
+        IF NEW.status IS DISTINCT FROM OLD.status THEN
+            PERFORM fn_publish_live_event('vehicle.status_changed', NEW.warehouse_id, jsonb_build_object(
+                'vehicle_id', NEW.vehicle_id,
+                'model_id', NEW.model_id,
+                'vin', NEW.vin,
+                'old_status', OLD.status,
+                'new_status', NEW.status
+            ));
+        END IF;
+        RETURN NEW;
+    ELSIF (TG_OP = 'DELETE') THEN
+        INSERT INTO vehicles_history (
+            vehicle_id, operation_type, old_value, new_value,
+            username, hostname, application_name
+        ) VALUES (
+                     OLD.vehicle_id, 'DELETE',
+                     row_to_json(OLD)::jsonb,
+                     NULL,
+                     CURRENT_USER, inet_client_addr()::VARCHAR,
+                     current_setting('application_name', true)
+                 );
+        RETURN OLD;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,109 @@
+-- Бронирование техники за клиентом до указанного срока.
+-- На время активной брони техника имеет статус 'Зарезервировано' и продаётся
+-- только клиенту брони. Просроченные брони снимает фоновый обработчик
+-- приложения, возвращая технику в 'В наличии'.
+
+CREATE TABLE IF NOT EXISTS vehicle_reservations (
+    reservation_id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(vehicle_id) ON DELETE CASCADE,
+    customer_id INTEGER REFERENCES customers(customer_id) ON DELETE CASCADE,
+    corporate_client_id INTEGER REFERENCES corporate_clients(corporate_client_id) ON DELETE CASCADE,
+    employee_id INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    deposit_amount DECIMAL(18, 2) NOT NULL DEFAULT 0 CHECK (deposit_amount >= 0),
+    expires_at TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'Активна'
+        CHECK (status IN ('Активна', 'Выкуплена', 'Истекла', 'Отменена')),
+    sale_id INTEGER REFERENCES sales(sale_id) ON DELETE SET NULL,
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP,
+    CHECK ((customer_id IS NOT NULL AND corporate_client_id IS NULL)
+        OR (customer_id IS NULL AND corporate_client_id IS NOT NULL)),
+    CHECK (expires_at > created_at)
+);
+
+-- Одна активная бронь на единицу техники
+CREATE UNIQUE INDEX IF NOT EXISTS uq_vehicle_reservations_active
+    ON vehicle_reservations(vehicle_id) WHERE status = 'Активна';
+CREATE INDEX IF NOT EXISTS idx_vehicle_reservations_expiry
+    ON vehicle_reservations(expires_at) WHERE status = 'Активна';
+CREATE INDEX IF NOT EXISTS idx_vehicle_reservations_customer ON vehicle_reservations(customer_id);
+CREATE INDEX IF NOT EXISTS idx_vehicle_reservations_corporate ON vehicle_reservations(corporate_client_id);
+
+-- Валидация продажи из 009_fix_validate_sale, дополненная проверкой брони:
+-- технику с действующей бронью может купить только клиент брони
+CREATE OR REPLACE FUNCTION validate_sale()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_vehicle_status VARCHAR(50);
+    v_reserved_customer INTEGER;
+    v_reserved_corporate INTEGER;
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.vehicle_id <> OLD.vehicle_id THEN
+        -- Проверка статуса техники
+        SELECT status INTO v_vehicle_status
+        FROM vehicles
+        WHERE vehicle_id = NEW.vehicle_id;
+
+        IF v_vehicle_status NOT IN ('В наличии', 'Зарезервировано') THEN
+            RAISE EXCEPTION 'Невозможно продать технику со статусом: %', v_vehicle_status;
+        END IF;
+
+        -- Проверка брони
+        SELECT customer_id, corporate_client_id INTO v_reserved_customer, v_reserved_corporate
+        FROM vehicle_reservations
+        WHERE vehicle_id = NEW.vehicle_id AND status = 'Активна' AND expires_at > CURRENT_TIMESTAMP;
+
+        IF FOUND AND (NEW.customer_id IS DISTINCT FROM v_reserved_customer
+            OR NEW.corporate_client_id IS DISTINCT FROM v_reserved_corporate) THEN
+            RAISE EXCEPTION 'Техника % зарезервирована другим клиентом', NEW.vehicle_id
+                USING ERRCODE = 'object_in_use';
+        END IF;
+    END IF;
+
+    -- Проверка что указан хотя бы один клиент
+    IF NEW.customer_id IS NULL AND NEW.corporate_client_id IS NULL THEN
+        RAISE EXCEPTION 'Необходимо указать клиента (физическое или юридическое лицо)';
+    END IF;
+
+    -- Проверка что не указаны оба типа клиентов
+    IF NEW.customer_id IS NOT NULL AND NEW.corporate_client_id IS NOT NULL THEN
+        RAISE EXCEPTION 'Нельзя указать одновременно физическое и юридическое лицо';
+    END IF;
+
+    -- Проверка корректности цен
+    IF NEW.final_price > NEW.base_price THEN
+        RAISE EXCEPTION 'Финальная цена не может быть больше базовой';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Продажа закрывает активную бронь: клиенту брони - как выкупленную,
+-- просроченную бронь другого клиента - как истёкшую
+CREATE OR REPLACE FUNCTION close_vehicle_reservation_on_sale()
+    RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE vehicle_reservations
+    SET status = CASE
+                     WHEN customer_id IS NOT DISTINCT FROM NEW.customer_id
+                         AND corporate_client_id IS NOT DISTINCT FROM NEW.corporate_client_id
+                         THEN 'Выкуплена'
+                     ELSE 'Истекла'
+                 END,
+        sale_id = CASE
+                      WHEN customer_id IS NOT DISTINCT FROM NEW.customer_id
+                          AND corporate_client_id IS NOT DISTINCT FROM NEW.corporate_client_id
+                          THEN NEW.sale_id
+                  END,
+        closed_at = CURRENT_TIMESTAMP
+    WHERE vehicle_id = NEW.vehicle_id AND status = 'Активна';
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_close_vehicle_reservation_on_sale ON sales;
+CREATE TRIGGER trg_close_vehicle_reservation_on_sale
+    AFTER INSERT ON sales
+    FOR EACH ROW EXECUTE FUNCTION close_vehicle_reservation_on_sale();
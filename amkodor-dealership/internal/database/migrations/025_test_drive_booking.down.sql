@@ -0,0 +1,49 @@
+-- Версия из 005_create_triggers
+CREATE OR REPLACE FUNCTION check_vehicle_for_test_drive()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_vehicle_status VARCHAR(50);
+    v_overlapping_count INTEGER;
+BEGIN
+    -- Проверка статуса техники
+    SELECT status INTO v_vehicle_status
+    FROM vehicles
+    WHERE vehicle_id = NEW.vehicle_id;
+
+    IF v_vehicle_status NOT IN ('В наличии', 'Зарезервировано') THEN
+        RAISE EXCEPTION 'Техника недоступна для тест-драйва. Текущий статус: %', v_vehicle_status;
+    END IF;
+
+    -- Проверка на пересечение времени
+    SELECT COUNT(*) INTO v_overlapping_count
+    FROM test_drives
+    WHERE vehicle_id = NEW.vehicle_id
+      AND status = 'Запланирован'
+      AND test_drive_id != COALESCE(NEW.test_drive_id, 0)
+      AND (
+        (NEW.scheduled_date, NEW.scheduled_date + (NEW.duration || ' minutes')::INTERVAL)
+            OVERLAPS
+        (scheduled_date, scheduled_date + (duration || ' minutes')::INTERVAL)
+        );
+
+    IF v_overlapping_count > 0 THEN
+        RAISE EXCEPTION 'На это время уже запланирован другой тест-драйв';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS employee_working_hours;
+
+DROP INDEX IF EXISTS idx_test_drives_employee_scheduled;
+DROP INDEX IF EXISTS idx_test_drives_vehicle_scheduled;
+
+ALTER TABLE test_drives
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS cancel_reason,
+    DROP COLUMN IF EXISTS booked_by_user_id;
+
+-- Прошедшие тест-драйвы не проходят исходную проверку, поэтому без валидации
+ALTER TABLE test_drives
+    ADD CONSTRAINT test_drives_scheduled_date_check CHECK (scheduled_date > CURRENT_TIMESTAMP) NOT VALID;
@@ -0,0 +1,98 @@
+-- Запись на тест-драйв по свободным слотам.
+-- Время тест-драйвов хранится по Минску (Europe/Minsk). Рабочее время
+-- менеджеров задаётся по дням недели; у менеджера без расписания рабочее
+-- время по умолчанию задаёт приложение.
+
+-- CHECK (scheduled_date > CURRENT_TIMESTAMP) перепроверялся при каждом UPDATE,
+-- из-за чего прошедший тест-драйв нельзя было завершить или отметить неявку.
+-- Проверка будущего времени перенесена в триггер для новых и перенесённых записей.
+ALTER TABLE test_drives DROP CONSTRAINT IF EXISTS test_drives_scheduled_date_check;
+
+ALTER TABLE test_drives
+    ADD COLUMN IF NOT EXISTS booked_by_user_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS cancel_reason TEXT,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_test_drives_vehicle_scheduled
+    ON test_drives(vehicle_id, scheduled_date) WHERE status = 'Запланирован';
+CREATE INDEX IF NOT EXISTS idx_test_drives_employee_scheduled
+    ON test_drives(employee_id, scheduled_date) WHERE status = 'Запланирован';
+
+-- Рабочее время менеджера по дням недели (1 - понедельник, 7 - воскресенье)
+CREATE TABLE IF NOT EXISTS employee_working_hours (
+    employee_id INTEGER NOT NULL REFERENCES employees(employee_id) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 1 AND 7),
+    start_time TIME NOT NULL,
+    end_time TIME NOT NULL,
+    PRIMARY KEY (employee_id, weekday),
+    CHECK (start_time < end_time)
+);
+
+-- Проверка техники и пересечений из 005_create_triggers. Выполняется только
+-- для запланированных тест-драйвов при записи и переносе: отмена, неявка и
+-- завершение проходят без проверок. Пересечения по технике и по менеджеру
+-- сериализуются advisory-блокировками, чтобы две параллельные записи не
+-- заняли один слот.
+CREATE OR REPLACE FUNCTION check_vehicle_for_test_drive()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_vehicle_status VARCHAR(50);
+BEGIN
+    IF NEW.status <> 'Запланирован' THEN
+        RETURN NEW;
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.status = 'Запланирован'
+        AND NEW.vehicle_id = OLD.vehicle_id AND NEW.employee_id = OLD.employee_id
+        AND NEW.scheduled_date = OLD.scheduled_date
+        AND COALESCE(NEW.duration, 60) = COALESCE(OLD.duration, 60) THEN
+        RETURN NEW;
+    END IF;
+
+    IF NEW.scheduled_date <= (CURRENT_TIMESTAMP AT TIME ZONE 'Europe/Minsk') THEN
+        RAISE EXCEPTION 'Тест-драйв можно назначить только на будущее время'
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'test_drives_scheduled_in_future';
+    END IF;
+
+    -- Проверка статуса техники
+    SELECT status INTO v_vehicle_status
+    FROM vehicles
+    WHERE vehicle_id = NEW.vehicle_id;
+
+    IF v_vehicle_status NOT IN ('В наличии', 'Зарезервировано') THEN
+        RAISE EXCEPTION 'Техника недоступна для тест-драйва. Текущий статус: %', v_vehicle_status
+            USING ERRCODE = 'object_in_use';
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('test_drive_vehicle'), NEW.vehicle_id);
+    PERFORM pg_advisory_xact_lock(hashtext('test_drive_employee'), NEW.employee_id);
+
+    -- Проверка на пересечение времени
+    IF EXISTS (
+        SELECT 1 FROM test_drives
+        WHERE vehicle_id = NEW.vehicle_id
+          AND status = 'Запланирован'
+          AND test_drive_id != COALESCE(NEW.test_drive_id, 0)
+          AND (NEW.scheduled_date, NEW.scheduled_date + (COALESCE(NEW.duration, 60) || ' minutes')::INTERVAL)
+              OVERLAPS
+              (scheduled_date, scheduled_date + (COALESCE(duration, 60) || ' minutes')::INTERVAL)
+    ) THEN
+        RAISE EXCEPTION 'На это время уже запланирован другой тест-драйв'
+            USING ERRCODE = 'exclusion_violation', CONSTRAINT = 'test_drives_vehicle_overlap';
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM test_drives
+        WHERE employee_id = NEW.employee_id
+          AND status = 'Запланирован'
+          AND test_drive_id != COALESCE(NEW.test_drive_id, 0)
+          AND (NEW.scheduled_date, NEW.scheduled_date + (COALESCE(NEW.duration, 60) || ' minutes')::INTERVAL)
+              OVERLAPS
+              (scheduled_date, scheduled_date + (COALESCE(duration, 60) || ' minutes')::INTERVAL)
+    ) THEN
+        RAISE EXCEPTION 'У менеджера на это время уже есть тест-драйв'
+            USING ERRCODE = 'exclusion_violation', CONSTRAINT = 'test_drives_employee_overlap';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
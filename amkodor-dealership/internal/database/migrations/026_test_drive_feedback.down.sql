@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS vw_model_test_drive_ratings;
+DROP TABLE IF EXISTS test_drive_feedback_links;
@@ -0,0 +1,24 @@
+-- Отзывы о тест-драйвах по одноразовой ссылке.
+-- При завершении тест-драйва создаётся ссылка с токеном; в базе хранится
+-- только SHA-256 хеш токена. Оценку по ссылке можно оставить один раз.
+
+CREATE TABLE IF NOT EXISTS test_drive_feedback_links (
+    test_drive_id INTEGER PRIMARY KEY REFERENCES test_drives(test_drive_id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    submitted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Оценки моделей по отзывам о завершённых тест-драйвах, как в
+-- fn_get_average_test_drive_rating
+CREATE OR REPLACE VIEW vw_model_test_drive_ratings AS
+SELECT
+    v.model_id,
+    ROUND(AVG(td.feedback_rating), 2) AS average_rating,
+    COUNT(*) AS ratings_count
+FROM test_drives td
+         INNER JOIN vehicles v ON td.vehicle_id = v.vehicle_id
+WHERE td.status = 'Завершен'
+  AND td.feedback_rating IS NOT NULL
+GROUP BY v.model_id;
@@ -0,0 +1,46 @@
+-- Исходная версия vw_test_drives_full_info из 002_create_views.sql
+DROP VIEW IF EXISTS vw_test_drives_full_info;
+
+CREATE VIEW vw_test_drives_full_info AS
+SELECT
+    td.test_drive_id,
+    td.scheduled_date,
+    td.duration,
+    td.status,
+    vm.model_name,
+    vt.type_name,
+    v.color,
+    v.manufacture_year,
+    CASE
+        WHEN td.customer_id IS NOT NULL THEN c.last_name || ' ' || c.first_name
+        ELSE cc.company_name
+        END AS client_name,
+    CASE
+        WHEN td.customer_id IS NOT NULL THEN c.phone
+        ELSE cc.phone
+        END AS client_phone,
+    e.last_name || ' ' || e.first_name AS manager_name,
+    e.phone AS manager_phone,
+    td.feedback_rating,
+    td.feedback_comment,
+    w.warehouse_name,
+    w.city AS warehouse_city
+FROM test_drives td
+         INNER JOIN vehicles v ON td.vehicle_id = v.vehicle_id
+         INNER JOIN vehicle_models vm ON v.model_id = vm.model_id
+         INNER JOIN vehicle_types vt ON vm.type_id = vt.type_id
+         INNER JOIN warehouses w ON v.warehouse_id = w.warehouse_id
+         INNER JOIN employees e ON td.employee_id = e.employee_id
+         LEFT JOIN customers c ON td.customer_id = c.customer_id
+         LEFT JOIN corporate_clients cc ON td.corporate_client_id = cc.corporate_client_id;
+
+DROP TRIGGER IF EXISTS trg_service_order_ical_sequence ON service_orders;
+DROP FUNCTION IF EXISTS bump_service_order_ical_sequence();
+DROP TRIGGER IF EXISTS trg_test_drive_ical_sequence ON test_drives;
+DROP FUNCTION IF EXISTS bump_test_drive_ical_sequence();
+
+ALTER TABLE service_orders DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE service_orders DROP COLUMN IF EXISTS ical_sequence;
+ALTER TABLE test_drives DROP COLUMN IF EXISTS ical_sequence;
+
+DROP TABLE IF EXISTS calendar_feeds;
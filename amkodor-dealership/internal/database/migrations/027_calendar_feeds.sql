@@ -0,0 +1,112 @@
+-- Календарные подписки (iCalendar) сотрудников на тест-драйвы и сервисные
+-- заказы. Лента открывается по ссылке с токеном без входа в систему; в базе
+-- хранится только SHA-256 хеш токена. Лента строится по сотруднику или по
+-- складу.
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    feed_id SERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    employee_id INTEGER REFERENCES employees(employee_id) ON DELETE CASCADE,
+    warehouse_id INTEGER REFERENCES warehouses(warehouse_id) ON DELETE CASCADE,
+    created_by INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_accessed_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    CHECK ((employee_id IS NOT NULL AND warehouse_id IS NULL)
+        OR (employee_id IS NULL AND warehouse_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_feeds_created_by ON calendar_feeds(created_by);
+
+-- Номер версии события (SEQUENCE) растёт при каждом изменении времени,
+-- исполнителя или статуса записи, чтобы календари обновляли уже
+-- загруженные события
+ALTER TABLE test_drives
+    ADD COLUMN IF NOT EXISTS ical_sequence INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE service_orders
+    ADD COLUMN IF NOT EXISTS ical_sequence INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+CREATE OR REPLACE FUNCTION bump_test_drive_ical_sequence()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.scheduled_date IS DISTINCT FROM OLD.scheduled_date
+        OR NEW.duration IS DISTINCT FROM OLD.duration
+        OR NEW.employee_id IS DISTINCT FROM OLD.employee_id
+        OR NEW.vehicle_id IS DISTINCT FROM OLD.vehicle_id
+        OR NEW.status IS DISTINCT FROM OLD.status THEN
+        NEW.ical_sequence := OLD.ical_sequence + 1;
+        NEW.updated_at := CURRENT_TIMESTAMP;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_test_drive_ical_sequence ON test_drives;
+CREATE TRIGGER trg_test_drive_ical_sequence
+    BEFORE UPDATE ON test_drives
+    FOR EACH ROW EXECUTE FUNCTION bump_test_drive_ical_sequence();
+
+CREATE OR REPLACE FUNCTION bump_service_order_ical_sequence()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.order_date IS DISTINCT FROM OLD.order_date
+        OR NEW.completion_date IS DISTINCT FROM OLD.completion_date
+        OR NEW.employee_id IS DISTINCT FROM OLD.employee_id
+        OR NEW.service_type IS DISTINCT FROM OLD.service_type
+        OR NEW.description IS DISTINCT FROM OLD.description
+        OR NEW.status IS DISTINCT FROM OLD.status THEN
+        NEW.ical_sequence := OLD.ical_sequence + 1;
+    END IF;
+    NEW.updated_at := CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_service_order_ical_sequence ON service_orders;
+CREATE TRIGGER trg_service_order_ical_sequence
+    BEFORE UPDATE ON service_orders
+    FOR EACH ROW EXECUTE FUNCTION bump_service_order_ical_sequence();
+
+-- Идентификаторы, склад и версия события для календарных лент. Новые колонки
+-- добавлены в конец, прежние не изменились.
+CREATE OR REPLACE VIEW vw_test_drives_full_info AS
+SELECT
+    td.test_drive_id,
+    td.scheduled_date,
+    td.duration,
+    td.status,
+    vm.model_name,
+    vt.type_name,
+    v.color,
+    v.manufacture_year,
+    CASE
+        WHEN td.customer_id IS NOT NULL THEN c.last_name || ' ' || c.first_name
+        ELSE cc.company_name
+        END AS client_name,
+    CASE
+        WHEN td.customer_id IS NOT NULL THEN c.phone
+        ELSE cc.phone
+        END AS client_phone,
+    e.last_name || ' ' || e.first_name AS manager_name,
+    e.phone AS manager_phone,
+    td.feedback_rating,
+    td.feedback_comment,
+    w.warehouse_name,
+    w.city AS warehouse_city,
+    td.vehicle_id,
+    td.employee_id,
+    v.warehouse_id,
+    w.address AS warehouse_address,
+    v.vin,
+    td.cancel_reason,
+    td.ical_sequence
+FROM test_drives td
+         INNER JOIN vehicles v ON td.vehicle_id = v.vehicle_id
+         INNER JOIN vehicle_models vm ON v.model_id = vm.model_id
+         INNER JOIN vehicle_types vt ON vm.type_id = vt.type_id
+         INNER JOIN warehouses w ON v.warehouse_id = w.warehouse_id
+         INNER JOIN employees e ON td.employee_id = e.employee_id
+         LEFT JOIN customers c ON td.customer_id = c.customer_id
+         LEFT JOIN corporate_clients cc ON td.corporate_client_id = cc.corporate_client_id;
@@ -0,0 +1,101 @@
+-- Версия из 004_create_procedures.sql: стоимость запчастей прибавляется к
+-- стоимости заказа при завершении
+CREATE OR REPLACE FUNCTION sp_complete_service_order(
+    p_service_order_id INTEGER
+)
+    RETURNS VOID AS $$
+DECLARE
+    v_parts_cost DECIMAL(18, 2);
+    v_service_cost DECIMAL(18, 2);
+    v_total_cost DECIMAL(18, 2);
+BEGIN
+    SELECT COALESCE(SUM(quantity * unit_price), 0)
+    INTO v_parts_cost
+    FROM service_order_parts
+    WHERE service_order_id = p_service_order_id;
+
+    SELECT cost INTO v_service_cost
+    FROM service_orders
+    WHERE service_order_id = p_service_order_id;
+
+    v_total_cost := v_service_cost + v_parts_cost;
+
+    UPDATE service_orders
+    SET
+        cost = v_total_cost,
+        status = 'Завершен',
+        completion_date = CURRENT_DATE
+    WHERE service_order_id = p_service_order_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Версия из 021_spare_part_ledger.sql
+CREATE OR REPLACE FUNCTION sp_add_spare_parts_to_service(
+    p_service_order_id INTEGER,
+    p_spare_part_id INTEGER,
+    p_quantity INTEGER
+)
+    RETURNS INTEGER AS $$
+DECLARE
+    v_service_order_part_id INTEGER;
+    v_unit_price DECIMAL(18, 2);
+    v_available_quantity INTEGER;
+BEGIN
+    SELECT price, quantity_in_stock
+    INTO v_unit_price, v_available_quantity
+    FROM spare_parts
+    WHERE spare_part_id = p_spare_part_id;
+
+    IF v_available_quantity < p_quantity THEN
+        RAISE EXCEPTION 'Недостаточно запчастей на складе';
+    END IF;
+
+    INSERT INTO service_order_parts (
+        service_order_id, spare_part_id, quantity, unit_price
+    ) VALUES (
+                 p_service_order_id, p_spare_part_id, p_quantity, v_unit_price
+             ) RETURNING service_order_part_id INTO v_service_order_part_id;
+
+    RETURN v_service_order_part_id;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_service_order_guard ON service_orders;
+DROP FUNCTION IF EXISTS guard_service_order();
+DROP TRIGGER IF EXISTS trg_service_order_initial_labour ON service_orders;
+DROP FUNCTION IF EXISTS add_service_order_initial_labour();
+
+DROP TRIGGER IF EXISTS trg_service_order_parts_cost ON service_order_parts;
+DROP TRIGGER IF EXISTS trg_service_order_parts_open ON service_order_parts;
+
+-- Версия из 005_create_triggers.sql
+CREATE OR REPLACE FUNCTION check_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_available_quantity INTEGER;
+    v_part_name VARCHAR(200);
+BEGIN
+    SELECT quantity_in_stock, part_name
+    INTO v_available_quantity, v_part_name
+    FROM spare_parts
+    WHERE spare_part_id = NEW.spare_part_id;
+
+    IF v_available_quantity < NEW.quantity THEN
+        RAISE EXCEPTION 'Недостаточно запчастей "%". В наличии: %, требуется: %',
+            v_part_name, v_available_quantity, NEW.quantity;
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_check_spare_parts_stock ON service_order_parts;
+CREATE TRIGGER trg_check_spare_parts_stock
+    BEFORE INSERT ON service_order_parts
+    FOR EACH ROW EXECUTE FUNCTION check_spare_parts_stock();
+
+DROP TABLE IF EXISTS service_order_labour;
+DROP FUNCTION IF EXISTS recalc_service_order_cost();
+DROP FUNCTION IF EXISTS check_service_order_open();
+DROP FUNCTION IF EXISTS service_order_total(INTEGER);
+DROP INDEX IF EXISTS idx_service_order_parts_order;
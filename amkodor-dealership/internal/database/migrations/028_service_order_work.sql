@@ -0,0 +1,266 @@
+-- Работы по сервисному заказу. Стоимость заказа (service_orders.cost) -
+-- сумма запчастей и работ, пока заказ открыт она пересчитывается
+-- триггерами. Завершённый заказ закрыт для изменений.
+CREATE TABLE IF NOT EXISTS service_order_labour (
+    labour_id SERIAL PRIMARY KEY,
+    service_order_id INTEGER NOT NULL REFERENCES service_orders(service_order_id) ON DELETE CASCADE,
+    description VARCHAR(200) NOT NULL,
+    hours DECIMAL(8, 2) NOT NULL CHECK (hours > 0),
+    rate DECIMAL(18, 2) NOT NULL CHECK (rate >= 0),
+    employee_id INTEGER REFERENCES employees(employee_id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_order_labour_order ON service_order_labour(service_order_id);
+CREATE INDEX IF NOT EXISTS idx_service_order_parts_order ON service_order_parts(service_order_id);
+
+-- Прежняя стоимость заказа становится работой: у открытых заказов это вся
+-- стоимость, у завершённых sp_complete_service_order уже прибавил к ней запчасти
+INSERT INTO service_order_labour (service_order_id, description, hours, rate, employee_id, created_at)
+SELECT so.service_order_id, so.service_type, 1, l.amount, so.employee_id, so.created_at
+FROM service_orders so
+CROSS JOIN LATERAL (
+    SELECT CASE
+               WHEN so.status = 'Завершен' THEN so.cost - COALESCE(SUM(sop.quantity * sop.unit_price), 0)
+               ELSE so.cost
+               END AS amount
+    FROM service_order_parts sop
+    WHERE sop.service_order_id = so.service_order_id
+) l
+WHERE l.amount > 0
+  AND NOT EXISTS (SELECT 1 FROM service_order_labour sol WHERE sol.service_order_id = so.service_order_id);
+
+-- Стоимость заказа: запчасти и работы
+CREATE OR REPLACE FUNCTION service_order_total(p_service_order_id INTEGER)
+    RETURNS DECIMAL(18, 2) AS $$
+SELECT
+    COALESCE((SELECT SUM(quantity * unit_price) FROM service_order_parts
+              WHERE service_order_id = p_service_order_id), 0)
+    + COALESCE((SELECT SUM(ROUND(hours * rate, 2)) FROM service_order_labour
+                WHERE service_order_id = p_service_order_id), 0);
+$$ LANGUAGE sql STABLE;
+
+-- Открытые заказы с запчастями до этой миграции получают полную стоимость
+UPDATE service_orders
+SET cost = service_order_total(service_order_id)
+WHERE status IN ('В работе', 'Приостановлен')
+  AND cost IS DISTINCT FROM service_order_total(service_order_id);
+
+-- Проверка остатка с блокировкой строки запчасти, чтобы параллельные заказы
+-- не списали один остаток дважды. При изменении строки проверяется только
+-- дополнительное количество. Код check_violation отличает нехватку от
+-- прочих ошибок.
+CREATE OR REPLACE FUNCTION check_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_available_quantity INTEGER;
+    v_part_name VARCHAR(200);
+    v_required INTEGER;
+BEGIN
+    SELECT quantity_in_stock, part_name
+    INTO v_available_quantity, v_part_name
+    FROM spare_parts
+    WHERE spare_part_id = NEW.spare_part_id
+    FOR UPDATE;
+
+    v_required := NEW.quantity;
+    IF TG_OP = 'UPDATE' AND OLD.spare_part_id = NEW.spare_part_id THEN
+        v_required := NEW.quantity - OLD.quantity;
+    END IF;
+
+    IF v_required > 0 AND v_available_quantity < v_required THEN
+        RAISE EXCEPTION 'Недостаточно запчастей "%". В наличии: %, требуется: %',
+            v_part_name, v_available_quantity, v_required
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'service_order_parts_stock';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_check_spare_parts_stock ON service_order_parts;
+CREATE TRIGGER trg_check_spare_parts_stock
+    BEFORE INSERT OR UPDATE ON service_order_parts
+    FOR EACH ROW EXECUTE FUNCTION check_spare_parts_stock();
+
+-- Запчасти и работы меняются только в открытом заказе. Если заказа уже нет,
+-- строки удаляются каскадом вместе с ним.
+CREATE OR REPLACE FUNCTION check_service_order_open()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_order_id INTEGER;
+    v_status VARCHAR(50);
+BEGIN
+    FOREACH v_order_id IN ARRAY CASE TG_OP
+        WHEN 'INSERT' THEN ARRAY[NEW.service_order_id]
+        WHEN 'DELETE' THEN ARRAY[OLD.service_order_id]
+        ELSE ARRAY[OLD.service_order_id, NEW.service_order_id]
+        END
+    LOOP
+        SELECT status INTO v_status
+        FROM service_orders
+        WHERE service_order_id = v_order_id
+        FOR UPDATE;
+
+        IF FOUND AND v_status NOT IN ('В работе', 'Приостановлен') THEN
+            RAISE EXCEPTION 'Сервисный заказ №% в статусе "%" не изменяется', v_order_id, v_status
+                USING ERRCODE = 'object_not_in_prerequisite_state', CONSTRAINT = 'service_order_closed';
+        END IF;
+    END LOOP;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_service_order_parts_open ON service_order_parts;
+CREATE TRIGGER trg_service_order_parts_open
+    BEFORE INSERT OR UPDATE OR DELETE ON service_order_parts
+    FOR EACH ROW EXECUTE FUNCTION check_service_order_open();
+
+DROP TRIGGER IF EXISTS trg_service_order_labour_open ON service_order_labour;
+CREATE TRIGGER trg_service_order_labour_open
+    BEFORE INSERT OR UPDATE OR DELETE ON service_order_labour
+    FOR EACH ROW EXECUTE FUNCTION check_service_order_open();
+
+-- Пересчёт стоимости заказа после изменения запчастей или работ
+CREATE OR REPLACE FUNCTION recalc_service_order_cost()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE service_orders
+        SET cost = service_order_total(OLD.service_order_id)
+        WHERE service_order_id = OLD.service_order_id;
+    END IF;
+    IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.service_order_id <> OLD.service_order_id) THEN
+        UPDATE service_orders
+        SET cost = service_order_total(NEW.service_order_id)
+        WHERE service_order_id = NEW.service_order_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_service_order_parts_cost ON service_order_parts;
+CREATE TRIGGER trg_service_order_parts_cost
+    AFTER INSERT OR UPDATE OR DELETE ON service_order_parts
+    FOR EACH ROW EXECUTE FUNCTION recalc_service_order_cost();
+
+DROP TRIGGER IF EXISTS trg_service_order_labour_cost ON service_order_labour;
+CREATE TRIGGER trg_service_order_labour_cost
+    AFTER INSERT OR UPDATE OR DELETE ON service_order_labour
+    FOR EACH ROW EXECUTE FUNCTION recalc_service_order_cost();
+
+-- Стоимость, указанная при создании открытого заказа, записывается первой
+-- работой, чтобы пересчёт её не потерял
+CREATE OR REPLACE FUNCTION add_service_order_initial_labour()
+    RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO service_order_labour (service_order_id, description, hours, rate, employee_id)
+    VALUES (NEW.service_order_id, NEW.service_type, 1, NEW.cost, NEW.employee_id);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_service_order_initial_labour ON service_orders;
+CREATE TRIGGER trg_service_order_initial_labour
+    AFTER INSERT ON service_orders
+    FOR EACH ROW
+    WHEN (NEW.cost > 0 AND NEW.status IN ('В работе', 'Приостановлен'))
+EXECUTE FUNCTION add_service_order_initial_labour();
+
+-- Завершённый заказ не удаляется и не меняется, кроме клиента (объединение
+-- дубликатов клиентов). У открытого заказа стоимость всегда равна сумме
+-- запчастей и работ. Переход в 'Завершен' проставляет дату завершения.
+CREATE OR REPLACE FUNCTION guard_service_order()
+    RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.status = 'Завершен' THEN
+        IF TG_OP = 'DELETE'
+            OR NEW.status IS DISTINCT FROM OLD.status
+            OR NEW.completion_date IS DISTINCT FROM OLD.completion_date
+            OR NEW.cost IS DISTINCT FROM OLD.cost
+            OR NEW.vehicle_id IS DISTINCT FROM OLD.vehicle_id
+            OR NEW.employee_id IS DISTINCT FROM OLD.employee_id
+            OR NEW.order_date IS DISTINCT FROM OLD.order_date
+            OR NEW.service_type IS DISTINCT FROM OLD.service_type
+            OR NEW.description IS DISTINCT FROM OLD.description THEN
+            RAISE EXCEPTION 'Сервисный заказ №% завершён и не изменяется', OLD.service_order_id
+                USING ERRCODE = 'object_not_in_prerequisite_state', CONSTRAINT = 'service_order_closed';
+        END IF;
+    ELSIF TG_OP = 'UPDATE' THEN
+        NEW.cost := service_order_total(NEW.service_order_id);
+        IF NEW.status = 'Завершен' AND NEW.completion_date IS NULL THEN
+            NEW.completion_date := CURRENT_DATE;
+        END IF;
+    END IF;
+
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_service_order_guard ON service_orders;
+CREATE TRIGGER trg_service_order_guard
+    BEFORE UPDATE OR DELETE ON service_orders
+    FOR EACH ROW EXECUTE FUNCTION guard_service_order();
+
+-- Запчасть добавляется по текущей цене; остаток проверяет
+-- trg_check_spare_parts_stock, открытость заказа - trg_service_order_parts_open
+CREATE OR REPLACE FUNCTION sp_add_spare_parts_to_service(
+    p_service_order_id INTEGER,
+    p_spare_part_id INTEGER,
+    p_quantity INTEGER
+)
+    RETURNS INTEGER AS $$
+DECLARE
+    v_service_order_part_id INTEGER;
+    v_unit_price DECIMAL(18, 2);
+BEGIN
+    SELECT price INTO v_unit_price
+    FROM spare_parts
+    WHERE spare_part_id = p_spare_part_id;
+
+    INSERT INTO service_order_parts (
+        service_order_id, spare_part_id, quantity, unit_price
+    ) VALUES (
+                 p_service_order_id, p_spare_part_id, p_quantity, v_unit_price
+             ) RETURNING service_order_part_id INTO v_service_order_part_id;
+
+    RETURN v_service_order_part_id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Завершение открытого заказа. Стоимость уже пересчитана триггерами, дата
+-- завершения - сегодня.
+CREATE OR REPLACE FUNCTION sp_complete_service_order(
+    p_service_order_id INTEGER
+)
+    RETURNS VOID AS $$
+DECLARE
+    v_status VARCHAR(50);
+BEGIN
+    SELECT status INTO v_status
+    FROM service_orders
+    WHERE service_order_id = p_service_order_id
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Сервисный заказ №% не найден', p_service_order_id
+            USING ERRCODE = 'no_data_found';
+    END IF;
+    IF v_status NOT IN ('В работе', 'Приостановлен') THEN
+        RAISE EXCEPTION 'Сервисный заказ №% в статусе "%" не завершается', p_service_order_id, v_status
+            USING ERRCODE = 'object_not_in_prerequisite_state', CONSTRAINT = 'service_order_closed';
+    END IF;
+
+    UPDATE service_orders
+    SET status = 'Завершен',
+        completion_date = CURRENT_DATE
+    WHERE service_order_id = p_service_order_id;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,132 @@
+-- Исходные версии из 002_create_views.sql и 004_create_procedures.sql
+CREATE OR REPLACE VIEW vw_vehicles_full_info AS
+SELECT
+    v.vehicle_id,
+    v.vin,
+    v.serial_number,
+    vm.model_name,
+    vt.type_name,
+    vc.category_name,
+    m.manufacturer_name,
+    v.manufacture_year,
+    v.color,
+    v.price,
+    v.discount,
+    ROUND(v.price * (1 - v.discount / 100), 2) AS final_price,
+    v.status,
+    w.warehouse_name,
+    w.city AS warehouse_city,
+    v.arrival_date,
+    v.created_at,
+    vm.description,
+    vm.specifications
+FROM vehicles v
+         INNER JOIN vehicle_models vm ON v.model_id = vm.model_id
+         INNER JOIN vehicle_types vt ON vm.type_id = vt.type_id
+         INNER JOIN vehicle_categories vc ON vt.category_id = vc.category_id
+         INNER JOIN manufacturers m ON vm.manufacturer_id = m.manufacturer_id
+         INNER JOIN warehouses w ON v.warehouse_id = w.warehouse_id;
+
+CREATE OR REPLACE VIEW vw_available_vehicles AS
+SELECT
+    v.vehicle_id,
+    vm.model_name,
+    vt.type_name,
+    vc.category_name,
+    m.manufacturer_name,
+    v.manufacture_year,
+    v.color,
+    v.price,
+    v.discount,
+    ROUND(v.price * (1 - v.discount / 100), 2) AS final_price,
+    w.warehouse_name,
+    w.city,
+    w.phone AS warehouse_phone,
+    vm.description,
+    vm.specifications,
+    CURRENT_DATE - v.arrival_date AS days_in_stock
+FROM vehicles v
+         INNER JOIN vehicle_models vm ON v.model_id = vm.model_id
+         INNER JOIN vehicle_types vt ON vm.type_id = vt.type_id
+         INNER JOIN vehicle_categories vc ON vt.category_id = vc.category_id
+         INNER JOIN manufacturers m ON vm.manufacturer_id = m.manufacturer_id
+         INNER JOIN warehouses w ON v.warehouse_id = w.warehouse_id
+WHERE v.status = 'В наличии' AND w.is_active = TRUE;
+
+CREATE OR REPLACE FUNCTION sp_search_vehicles(
+    p_model_name VARCHAR(100) DEFAULT NULL,
+    p_category_name VARCHAR(100) DEFAULT NULL,
+    p_type_name VARCHAR(100) DEFAULT NULL,
+    p_manufacturer_name VARCHAR(200) DEFAULT NULL,
+    p_min_price DECIMAL(18, 2) DEFAULT NULL,
+    p_max_price DECIMAL(18, 2) DEFAULT NULL,
+    p_min_year INTEGER DEFAULT NULL,
+    p_max_year INTEGER DEFAULT NULL,
+    p_status VARCHAR(50) DEFAULT NULL,
+    p_warehouse_id INTEGER DEFAULT NULL,
+    p_city VARCHAR(100) DEFAULT NULL
+)
+    RETURNS TABLE (
+                      vehicle_id INTEGER,
+                      vin VARCHAR(50),
+                      serial_number VARCHAR(100),
+                      model_name VARCHAR(100),
+                      type_name VARCHAR(100),
+                      category_name VARCHAR(100),
+                      manufacturer_name VARCHAR(200),
+                      manufacture_year INTEGER,
+                      color VARCHAR(50),
+                      price DECIMAL(18, 2),
+                      discount DECIMAL(5, 2),
+                      final_price DECIMAL(18, 2),
+                      status VARCHAR(50),
+                      warehouse_name VARCHAR(200),
+                      city VARCHAR(100),
+                      warehouse_phone VARCHAR(50),
+                      description TEXT,
+                      specifications JSONB
+                  ) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            v.vehicle_id,
+            v.vin,
+            v.serial_number,
+            vm.model_name,
+            vt.type_name,
+            vc.category_name,
+            m.manufacturer_name,
+            v.manufacture_year,
+            v.color,
+            v.price,
+            v.discount,
+            fn_calculate_final_price(v.price, v.discount) AS final_price,
+            v.status,
+            w.warehouse_name,
+            w.city,
+            w.phone AS warehouse_phone,
+            vm.description,
+            vm.specifications
+        FROM vehicles v
+                 INNER JOIN vehicle_models vm ON v.model_id = vm.model_id
+                 INNER JOIN vehicle_types vt ON vm.type_id = vt.type_id
+                 INNER JOIN vehicle_categories vc ON vt.category_id = vc.category_id
+                 INNER JOIN manufacturers m ON vm.manufacturer_id = m.manufacturer_id
+                 INNER JOIN warehouses w ON v.warehouse_id = w.warehouse_id
+        WHERE
+            (p_model_name IS NULL OR vm.model_name ILIKE '%' || p_model_name || '%')
+          AND (p_category_name IS NULL OR vc.category_name ILIKE '%' || p_category_name || '%')
+          AND (p_type_name IS NULL OR vt.type_name ILIKE '%' || p_type_name || '%')
+          AND (p_manufacturer_name IS NULL OR m.manufacturer_name ILIKE '%' || p_manufacturer_name || '%')
+          AND (p_min_price IS NULL OR v.price >= p_min_price)
+          AND (p_max_price IS NULL OR v.price <= p_max_price)
+          AND (p_min_year IS NULL OR v.manufacture_year >= p_min_year)
+          AND (p_max_year IS NULL OR v.manufacture_year <= p_max_year)
+          AND (p_status IS NULL OR v.status = p_status)
+          AND (p_warehouse_id IS NULL OR v.warehouse_id = p_warehouse_id)
+          AND (p_city IS NULL OR w.city ILIKE '%' || p_city || '%')
+        ORDER BY v.created_at DESC;
+END;
+$$ LANGUAGE plpgsql STABLE;
+
+ALTER TABLE vehicles DROP COLUMN IF EXISTS specifications;
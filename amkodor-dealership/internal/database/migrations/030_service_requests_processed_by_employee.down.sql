@@ -0,0 +1,8 @@
+ALTER TABLE service_requests DROP CONSTRAINT IF EXISTS service_requests_processed_by_fkey;
+
+UPDATE service_requests sr
+SET processed_by = (SELECT u.user_id FROM users u WHERE u.employee_id = sr.processed_by)
+WHERE sr.processed_by IS NOT NULL;
+
+ALTER TABLE service_requests ADD CONSTRAINT service_requests_processed_by_fkey
+    FOREIGN KEY (processed_by) REFERENCES users(user_id) ON DELETE SET NULL;
@@ -0,0 +1,11 @@
+-- Заявку разбирает сотрудник, поэтому processed_by ссылается на employees.
+-- Раньше здесь был users(user_id), и сотрудник без связанной учётной
+-- записи users (вход по employees, 016_staff_login.sql) записывался как NULL.
+ALTER TABLE service_requests DROP CONSTRAINT IF EXISTS service_requests_processed_by_fkey;
+
+UPDATE service_requests sr
+SET processed_by = (SELECT u.employee_id FROM users u WHERE u.user_id = sr.processed_by)
+WHERE sr.processed_by IS NOT NULL;
+
+ALTER TABLE service_requests ADD CONSTRAINT service_requests_processed_by_fkey
+    FOREIGN KEY (processed_by) REFERENCES employees(employee_id) ON DELETE SET NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE user_client_links DROP CONSTRAINT IF EXISTS user_client_links_linked_by_fkey;
+
+UPDATE user_client_links l
+SET linked_by = (SELECT u.user_id FROM users u WHERE u.employee_id = l.linked_by)
+WHERE l.linked_by IS NOT NULL;
+
+ALTER TABLE user_client_links ADD CONSTRAINT user_client_links_linked_by_fkey
+    FOREIGN KEY (linked_by) REFERENCES users(user_id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+-- Привязку клиента делает сотрудник, поэтому linked_by ссылается на
+-- employees, как и service_requests.processed_by (030).
+ALTER TABLE user_client_links DROP CONSTRAINT IF EXISTS user_client_links_linked_by_fkey;
+
+UPDATE user_client_links l
+SET linked_by = (SELECT u.employee_id FROM users u WHERE u.user_id = l.linked_by)
+WHERE l.linked_by IS NOT NULL;
+
+ALTER TABLE user_client_links ADD CONSTRAINT user_client_links_linked_by_fkey
+    FOREIGN KEY (linked_by) REFERENCES employees(employee_id) ON DELETE SET NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE customer_merges DROP CONSTRAINT IF EXISTS customer_merges_merged_by_fkey;
+
+UPDATE customer_merges m
+SET merged_by = (SELECT u.user_id FROM users u WHERE u.employee_id = m.merged_by)
+WHERE m.merged_by IS NOT NULL;
+
+ALTER TABLE customer_merges ADD CONSTRAINT customer_merges_merged_by_fkey
+    FOREIGN KEY (merged_by) REFERENCES users(user_id) ON DELETE SET NULL;
@@ -0,0 +1,10 @@
+-- Объединение клиентов выполняет сотрудник, поэтому merged_by ссылается на
+-- employees, как processed_by (030) и linked_by (031).
+ALTER TABLE customer_merges DROP CONSTRAINT IF EXISTS customer_merges_merged_by_fkey;
+
+UPDATE customer_merges m
+SET merged_by = (SELECT u.employee_id FROM users u WHERE u.user_id = m.merged_by)
+WHERE m.merged_by IS NOT NULL;
+
+ALTER TABLE customer_merges ADD CONSTRAINT customer_merges_merged_by_fkey
+    FOREIGN KEY (merged_by) REFERENCES employees(employee_id) ON DELETE SET NULL;
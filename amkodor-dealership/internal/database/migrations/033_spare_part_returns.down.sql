@@ -0,0 +1,39 @@
+ALTER TABLE spare_part_movements DISABLE TRIGGER trg_prevent_spare_part_movement_change;
+UPDATE spare_part_movements SET movement_type = 'consumption' WHERE movement_type = 'return';
+ALTER TABLE spare_part_movements ENABLE TRIGGER trg_prevent_spare_part_movement_change;
+
+ALTER TABLE spare_part_movements DROP CONSTRAINT IF EXISTS spare_part_movements_movement_type_check;
+ALTER TABLE spare_part_movements ADD CONSTRAINT spare_part_movements_movement_type_check
+    CHECK (movement_type IN ('receipt', 'consumption', 'adjustment', 'transfer', 'write_off'));
+
+-- Версия из 021_spare_part_ledger.sql
+CREATE OR REPLACE FUNCTION update_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_order_id INTEGER;
+    v_employee_id INTEGER;
+    v_reason TEXT;
+BEGIN
+    v_order_id := CASE WHEN TG_OP = 'DELETE' THEN OLD.service_order_id ELSE NEW.service_order_id END;
+    SELECT employee_id INTO v_employee_id FROM service_orders WHERE service_order_id = v_order_id;
+    v_reason := 'Сервисный заказ №' || v_order_id;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF TG_OP = 'DELETE' OR OLD.spare_part_id <> NEW.spare_part_id OR OLD.quantity <> NEW.quantity THEN
+            INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, service_order_id, employee_id, reason)
+            VALUES (OLD.spare_part_id, 'consumption', OLD.quantity, v_order_id, v_employee_id,
+                    'Возврат: ' || v_reason);
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF TG_OP = 'INSERT' OR OLD.spare_part_id <> NEW.spare_part_id OR OLD.quantity <> NEW.quantity THEN
+            INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, service_order_id, employee_id, reason)
+            VALUES (NEW.spare_part_id, 'consumption', -NEW.quantity, v_order_id, v_employee_id, v_reason);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
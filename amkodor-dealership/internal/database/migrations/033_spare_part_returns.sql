@@ -0,0 +1,47 @@
+-- Возврат запчасти из сервисного заказа на склад записывается отдельным
+-- типом движения return. Раньше он шёл как consumption с положительным
+-- количеством и попадал в отчёты о расходе.
+ALTER TABLE spare_part_movements DROP CONSTRAINT IF EXISTS spare_part_movements_movement_type_check;
+ALTER TABLE spare_part_movements ADD CONSTRAINT spare_part_movements_movement_type_check
+    CHECK (movement_type IN ('receipt', 'consumption', 'return', 'adjustment', 'transfer', 'write_off'));
+
+-- Уже записанные возвраты переводятся в новый тип. Журнал неизменяем,
+-- поэтому защитный триггер отключается только на время переразметки.
+ALTER TABLE spare_part_movements DISABLE TRIGGER trg_prevent_spare_part_movement_change;
+UPDATE spare_part_movements
+SET movement_type = 'return'
+WHERE movement_type = 'consumption' AND quantity > 0 AND service_order_id IS NOT NULL;
+ALTER TABLE spare_part_movements ENABLE TRIGGER trg_prevent_spare_part_movement_change;
+
+-- Расход в сервисном заказе: списание при добавлении, возврат при удалении
+-- и разница при изменении количества или запчасти
+CREATE OR REPLACE FUNCTION update_spare_parts_stock()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_order_id INTEGER;
+    v_employee_id INTEGER;
+    v_reason TEXT;
+BEGIN
+    v_order_id := CASE WHEN TG_OP = 'DELETE' THEN OLD.service_order_id ELSE NEW.service_order_id END;
+    SELECT employee_id INTO v_employee_id FROM service_orders WHERE service_order_id = v_order_id;
+    v_reason := 'Сервисный заказ №' || v_order_id;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF TG_OP = 'DELETE' OR OLD.spare_part_id <> NEW.spare_part_id OR OLD.quantity <> NEW.quantity THEN
+            INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, service_order_id, employee_id, reason)
+            VALUES (OLD.spare_part_id, 'return', OLD.quantity, v_order_id, v_employee_id,
+                    'Возврат: ' || v_reason);
+        END IF;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF TG_OP = 'INSERT' OR OLD.spare_part_id <> NEW.spare_part_id OR OLD.quantity <> NEW.quantity THEN
+            INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, service_order_id, employee_id, reason)
+            VALUES (NEW.spare_part_id, 'consumption', -NEW.quantity, v_order_id, v_employee_id, v_reason);
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,4 @@
+ALTER TABLE vehicle_reservations
+    ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at::TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at::TIMESTAMP,
+    ALTER COLUMN closed_at TYPE TIMESTAMP USING closed_at::TIMESTAMP;
@@ -0,0 +1,9 @@
+-- Сроки брони хранятся с часовым поясом. В TIMESTAMP приложение писало
+-- местное время своего процесса, и срок был верным, только пока пояс
+-- приложения совпадал с поясом базы. Существующие значения
+-- интерпретируются в поясе сеанса - так же, как их сравнивал
+-- CURRENT_TIMESTAMP.
+ALTER TABLE vehicle_reservations
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at::TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::TIMESTAMPTZ,
+    ALTER COLUMN closed_at TYPE TIMESTAMPTZ USING closed_at::TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+ALTER TABLE refresh_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at::TIMESTAMP,
+    ALTER COLUMN used_at TYPE TIMESTAMP USING used_at::TIMESTAMP,
+    ALTER COLUMN revoked_at TYPE TIMESTAMP USING revoked_at::TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at::TIMESTAMP;
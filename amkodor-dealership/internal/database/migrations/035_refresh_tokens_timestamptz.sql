@@ -0,0 +1,9 @@
+-- Сроки refresh-токенов хранятся с часовым поясом. В TIMESTAMP уходило
+-- время с поясом приложения, а lib/pq читал его обратно как UTC, и при
+-- поясе приложения, отличном от UTC, токен жил дольше или истекал раньше.
+-- Существующие значения интерпретируются в поясе сеанса.
+ALTER TABLE refresh_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at::TIMESTAMPTZ,
+    ALTER COLUMN used_at TYPE TIMESTAMPTZ USING used_at::TIMESTAMPTZ,
+    ALTER COLUMN revoked_at TYPE TIMESTAMPTZ USING revoked_at::TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::TIMESTAMPTZ;
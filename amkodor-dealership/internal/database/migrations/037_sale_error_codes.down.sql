@@ -0,0 +1,119 @@
+-- Версии из 024_vehicle_reservations и 036_sale_reserved_vehicle
+CREATE OR REPLACE FUNCTION validate_sale()
+    RETURNS TRIGGER AS $$
+DECLARE
+    v_vehicle_status VARCHAR(50);
+    v_reserved_customer INTEGER;
+    v_reserved_corporate INTEGER;
+BEGIN
+    IF TG_OP = 'INSERT' OR NEW.vehicle_id <> OLD.vehicle_id THEN
+        -- Проверка статуса техники
+        SELECT status INTO v_vehicle_status
+        FROM vehicles
+        WHERE vehicle_id = NEW.vehicle_id;
+
+        IF v_vehicle_status NOT IN ('В наличии', 'Зарезервировано') THEN
+            RAISE EXCEPTION 'Невозможно продать технику со статусом: %', v_vehicle_status;
+        END IF;
+
+        -- Проверка брони
+        SELECT customer_id, corporate_client_id INTO v_reserved_customer, v_reserved_corporate
+        FROM vehicle_reservations
+        WHERE vehicle_id = NEW.vehicle_id AND status = 'Активна' AND expires_at > CURRENT_TIMESTAMP;
+
+        IF FOUND AND (NEW.customer_id IS DISTINCT FROM v_reserved_customer
+            OR NEW.corporate_client_id IS DISTINCT FROM v_reserved_corporate) THEN
+            RAISE EXCEPTION 'Техника % зарезервирована другим клиентом', NEW.vehicle_id
+                USING ERRCODE = 'object_in_use';
+        END IF;
+    END IF;
+
+    -- Проверка что указан хотя бы один клиент
+    IF NEW.customer_id IS NULL AND NEW.corporate_client_id IS NULL THEN
+        RAISE EXCEPTION 'Необходимо указать клиента (физическое или юридическое лицо)';
+    END IF;
+
+    -- Проверка что не указаны оба типа клиентов
+    IF NEW.customer_id IS NOT NULL AND NEW.corporate_client_id IS NOT NULL THEN
+        RAISE EXCEPTION 'Нельзя указать одновременно физическое и юридическое лицо';
+    END IF;
+
+    -- Проверка корректности цен
+    IF NEW.final_price > NEW.base_price THEN
+        RAISE EXCEPTION 'Финальная цена не может быть больше базовой';
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION sp_create_sale(
+    p_vehicle_id INTEGER,
+    p_customer_id INTEGER DEFAULT NULL,
+    p_corporate_client_id INTEGER DEFAULT NULL,
+    p_employee_id INTEGER DEFAULT NULL,
+    p_payment_type VARCHAR(50) DEFAULT 'Наличные',
+    p_additional_discount DECIMAL(5, 2) DEFAULT 0,
+    p_contract_number VARCHAR(50) DEFAULT NULL,
+    p_notes TEXT DEFAULT NULL
+)
+    RETURNS INTEGER AS $$
+DECLARE
+    v_sale_id INTEGER;
+    v_base_price DECIMAL(18, 2);
+    v_vehicle_discount DECIMAL(5, 2);
+    v_client_discount DECIMAL(5, 2) := 0;
+    v_total_discount DECIMAL(5, 2);
+    v_discount_amount DECIMAL(18, 2);
+    v_final_price DECIMAL(18, 2);
+BEGIN
+    -- Продаётся техника в наличии или забронированная: покупателя брони
+    -- проверяет validate_sale. fn_is_vehicle_available здесь не подходит -
+    -- для неё доступна только техника 'В наличии'.
+    IF NOT EXISTS (SELECT 1 FROM vehicles
+                   WHERE vehicle_id = p_vehicle_id
+                     AND status IN ('В наличии', 'Зарезервировано')) THEN
+        RAISE EXCEPTION 'Техника недоступна для продажи';
+    END IF;
+
+    -- Получение базовой цены и скидки техники
+    SELECT price, discount
+    INTO v_base_price, v_vehicle_discount
+    FROM vehicles
+    WHERE vehicle_id = p_vehicle_id;
+
+    -- Получение скидки клиента
+    IF p_customer_id IS NOT NULL THEN
+        SELECT discount_percent INTO v_client_discount
+        FROM customers WHERE customer_id = p_customer_id;
+    ELSIF p_corporate_client_id IS NOT NULL THEN
+        SELECT discount_percent INTO v_client_discount
+        FROM corporate_clients WHERE corporate_client_id = p_corporate_client_id;
+    END IF;
+
+    -- Расчет общей скидки
+    v_total_discount := v_vehicle_discount + v_client_discount + p_additional_discount;
+    IF v_total_discount > 100 THEN v_total_discount := 100; END IF;
+
+    v_discount_amount := fn_calculate_discount_amount(v_base_price, v_total_discount);
+    v_final_price := fn_calculate_final_price(v_base_price, v_total_discount);
+
+    -- Создание продажи
+    INSERT INTO sales (
+        vehicle_id, customer_id, corporate_client_id, employee_id,
+        base_price, discount_amount, final_price, payment_type,
+        contract_number, notes
+    ) VALUES (
+                 p_vehicle_id, p_customer_id, p_corporate_client_id, p_employee_id,
+                 v_base_price, v_discount_amount, v_final_price, p_payment_type,
+                 p_contract_number, p_notes
+             ) RETURNING sale_id INTO v_sale_id;
+
+    -- Обновление статуса техники
+    UPDATE vehicles
+    SET status = 'Продано', updated_at = CURRENT_TIMESTAMP
+    WHERE vehicle_id = p_vehicle_id;
+
+    RETURN v_sale_id;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,4 @@
+ALTER TABLE client_link_claims
+    ALTER COLUMN expires_at TYPE TIMESTAMP USING expires_at::TIMESTAMP,
+    ALTER COLUMN confirmed_at TYPE TIMESTAMP USING confirmed_at::TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at::TIMESTAMP;
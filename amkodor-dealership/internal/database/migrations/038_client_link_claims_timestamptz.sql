@@ -0,0 +1,8 @@
+-- Срок кода подтверждения привязки хранится с часовым поясом: в TIMESTAMP
+-- приложение писало время своего пояса, а lib/pq читал его обратно как UTC,
+-- и код жил дольше или истекал раньше, если пояс приложения не UTC.
+-- Существующие значения интерпретируются в поясе сеанса.
+ALTER TABLE client_link_claims
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ USING expires_at::TIMESTAMPTZ,
+    ALTER COLUMN confirmed_at TYPE TIMESTAMPTZ USING confirmed_at::TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at::TIMESTAMPTZ;
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 
 	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
 	"amkodor-dealership/internal/service"
 	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
 )
 
 type AuthHandler struct {
@@ -23,28 +29,96 @@ func NewAuthHandler(service *service.AuthService) *AuthHandler {
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Некорректные данные")
+		utils.RespondError(w, http.StatusBadRequest, "Неверный формат данных")
 		return
 	}
 
 	if req.Email == "" || req.Password == "" {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Email и пароль обязательны")
+		utils.RespondError(w, http.StatusBadRequest, "Email и пароль обязательны")
 		return
 	}
 
-	// Реальная авторизация через сервис
-	user, token, err := h.service.Login(req.Email, req.Password)
+	session, err := h.service.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusUnauthorized, "Неверные учетные данные")
+		utils.RespondError(w, http.StatusUnauthorized, "Неверный email или пароль")
 		return
 	}
 
-	utils.RespondSuccess(w, map[string]interface{}{
-		"token":   token,
-		"message": "Login successful",
-		"name":    user.Name,
-		"role":    user.Role,
-	})
+	resp := sessionResponse(session)
+	resp["message"] = "Login successful"
+	utils.RespondSuccess(w, resp)
+}
+
+// StaffLogin авторизация сотрудника по учётным данным из employees.
+// Токен несёт сотрудника, его должность и склад.
+func (h *AuthHandler) StaffLogin(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный формат данных")
+		return
+	}
+
+	if req.Email == "" || req.Password == "" {
+		utils.RespondError(w, http.StatusBadRequest, "Email и пароль обязательны")
+		return
+	}
+
+	session, err := h.service.StaffLogin(r.Context(), req.Email, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials):
+			utils.RespondError(w, http.StatusUnauthorized, "Неверный email или пароль")
+		case errors.Is(err, service.ErrNoStaffAccess):
+			utils.RespondError(w, http.StatusForbidden, "Должность сотрудника не даёт доступа к системе")
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, "Ошибка входа")
+		}
+		return
+	}
+
+	resp := sessionResponse(session)
+	resp["message"] = "Login successful"
+	utils.RespondSuccess(w, resp)
+}
+
+// Refresh выдает новую пару токенов в обмен на refresh-токен
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		utils.RespondError(w, http.StatusBadRequest, "Refresh-токен обязателен")
+		return
+	}
+
+	session, err := h.service.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRefreshTokenReused):
+			log.Printf("Повторное использование refresh-токена, сессии семейства отозваны")
+			utils.RespondError(w, http.StatusUnauthorized, "Сессия отозвана, войдите заново")
+		case errors.Is(err, repository.ErrRefreshTokenInvalid):
+			utils.RespondError(w, http.StatusUnauthorized, "Недействительный refresh-токен")
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, "Ошибка обновления токена")
+		}
+		return
+	}
+
+	utils.RespondSuccess(w, sessionResponse(session))
+}
+
+// sessionResponse - тело ответа входа и обновления токенов
+func sessionResponse(session *models.Session) map[string]interface{} {
+	resp := map[string]interface{}{
+		"token":         session.Tokens.AccessToken,
+		"refresh_token": session.Tokens.RefreshToken,
+		"expires_in":    session.Tokens.ExpiresIn,
+		"name":          session.Name,
+		"role":          session.Role,
+	}
+	if session.Staff != nil {
+		resp["employee"] = session.Staff
+	}
+	return resp
 }
 
 // Register регистрация нового пользователя
@@ -69,12 +143,40 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Logout выход из системы
+// Logout выход из системы: отзывает семейство refresh-токена
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// В случае JWT токенов, logout обычно обрабатывается на клиенте
-	// удалением токена из localStorage
-	utils.SuccessResponse(w, http.StatusOK, map[string]string{
-		"message": "Успешный выход",
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		utils.RespondError(w, http.StatusBadRequest, "Refresh-токен обязателен")
+		return
+	}
+
+	if err := h.service.Logout(r.Context(), req.RefreshToken); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка выхода из системы")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Успешный выход")
+}
+
+// RevokeUserSessions отзывает все сессии пользователя (для администратора)
+func (h *AuthHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	revoked, err := h.service.RevokeAllSessions(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка отзыва сессий")
+		return
+	}
+
+	utils.RespondSuccess(w, map[string]interface{}{
+		"user_id": userID,
+		"revoked": revoked,
 	})
 }
 
@@ -94,4 +196,4 @@ func (h *AuthHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	utils.SuccessResponse(w, http.StatusOK, user)
-}
\ No newline at end of file
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/middleware"
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+	"amkodor-dealership/pkg/ical"
+
+	"github.com/gorilla/mux"
+)
+
+// CalendarHandler - календарные подписки сотрудников и файлы .ics тест-драйвов
+type CalendarHandler struct {
+	service *service.CalendarService
+}
+
+func NewCalendarHandler(service *service.CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// Feed отдаёт календарную ленту по токену подписки. Доступно без входа:
+// календарные приложения не передают заголовок Authorization.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	cal, err := h.service.Feed(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		respondCalendarError(w, err, "Ошибка формирования календаря")
+		return
+	}
+
+	writeCalendar(w, cal, "inline", "amkodor.ics")
+}
+
+// GetFeeds возвращает подписки сотрудника; администратору - все подписки
+func (h *CalendarHandler) GetFeeds(w http.ResponseWriter, r *http.Request) {
+	staff, manageAll, ok := currentStaff(w, r)
+	if !ok {
+		return
+	}
+
+	feeds, err := h.service.GetFeeds(r.Context(), staff, manageAll)
+	if err != nil {
+		respondCalendarError(w, err, "Ошибка получения подписок")
+		return
+	}
+
+	utils.RespondSuccess(w, feeds)
+}
+
+// CreateFeed создаёт подписку на календарь. Тело необязательно: по
+// умолчанию создаётся подписка на календарь текущего сотрудника.
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	staff, manageAll, ok := currentStaff(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCalendarFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	feed, err := h.service.CreateFeed(r.Context(), staff, manageAll, &req)
+	if err != nil {
+		respondCalendarError(w, err, "Ошибка создания подписки")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: feed})
+}
+
+// RevokeFeed отзывает подписку
+func (h *CalendarHandler) RevokeFeed(w http.ResponseWriter, r *http.Request) {
+	staff, manageAll, ok := currentStaff(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	if err := h.service.RevokeFeed(r.Context(), staff, manageAll, id); err != nil {
+		respondCalendarError(w, err, "Ошибка отзыва подписки")
+		return
+	}
+
+	utils.RespondSuccess(w, map[string]string{"message": "Подписка отозвана"})
+}
+
+// TestDrive отдаёт тест-драйв файлом .ics
+func (h *CalendarHandler) TestDrive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	cal, err := h.service.TestDriveEvent(r.Context(), id)
+	if err != nil {
+		respondCalendarError(w, err, "Ошибка формирования календаря")
+		return
+	}
+
+	writeCalendar(w, cal, "attachment", fmt.Sprintf("test-drive-%d.ics", id))
+}
+
+// MyTestDrive отдаёт файлом .ics тест-драйв клиента текущего пользователя
+func (h *CalendarHandler) MyTestDrive(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	cal, err := h.service.TestDriveEventMine(r.Context(), userID, id)
+	if err != nil {
+		respondCalendarError(w, err, "Ошибка формирования календаря")
+		return
+	}
+
+	writeCalendar(w, cal, "attachment", fmt.Sprintf("test-drive-%d.ics", id))
+}
+
+// currentStaff возвращает сотрудника из токена и признак права управлять
+// подписками всех сотрудников
+func currentStaff(w http.ResponseWriter, r *http.Request) (*models.StaffIdentity, bool, bool) {
+	staff, ok := middleware.GetStaffFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusForbidden, "Действие доступно только сотрудникам")
+		return nil, false, false
+	}
+	return staff, middleware.HasPermission(staff.Role, middleware.PermEmployeesManage), true
+}
+
+func writeCalendar(w http.ResponseWriter, cal *ical.Calendar, disposition, filename string) {
+	var buf bytes.Buffer
+	if err := cal.Write(&buf); err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка формирования календаря")
+		return
+	}
+
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", disposition+"; filename="+filename)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func respondCalendarError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCalendarFeed):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrCalendarFeedReference):
+		utils.RespondError(w, http.StatusBadRequest, "Сотрудник или склад не найден")
+	case errors.Is(err, service.ErrCalendarFeedForbidden):
+		utils.RespondError(w, http.StatusForbidden, "Можно подписаться только на свой календарь и свой склад")
+	case errors.Is(err, repository.ErrClientLinkNotFound):
+		utils.RespondError(w, http.StatusForbidden, "Учётная запись не привязана к клиенту")
+	case errors.Is(err, repository.ErrCalendarFeedNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Подписка не найдена")
+	case errors.Is(err, repository.ErrTestDriveNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Тест-драйв не найден")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -1,8 +1,18 @@
 package handlers
 
 import (
-	"amkodor-dealership/internal/service"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
 )
 
 type CustomerHandler struct {
@@ -13,32 +23,341 @@ func NewCustomerHandler(service *service.CustomerService) *CustomerHandler {
 	return &CustomerHandler{service: service}
 }
 
+// GetAll возвращает страницу клиентов.
+// ?q= ищет по ФИО, телефону, email и номеру паспорта.
 func (h *CustomerHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Customer handler not implemented"))
+	page, perPage := pageParams(r)
+
+	customers, pagination, err := h.service.GetPage(r.URL.Query().Get("q"), page, perPage)
+	if err != nil {
+		log.Printf("GetAll: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения клиентов")
+		return
+	}
+
+	utils.RespondPaginated(w, customers, pagination)
+}
+
+// Search ищет клиентов по отдельным полям:
+// ?name=&phone=&email=&passport=&vip=true&min_discount=5
+func (h *CustomerHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	params := models.CustomerSearchParams{
+		Name:     query.Get("name"),
+		Phone:    query.Get("phone"),
+		Email:    query.Get("email"),
+		Passport: query.Get("passport"),
+	}
+
+	if v := query.Get("vip"); v != "" {
+		vip, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Некорректный параметр vip")
+			return
+		}
+		params.IsVIP = &vip
+	}
+	if v := query.Get("min_discount"); v != "" {
+		discount, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Некорректный параметр min_discount")
+			return
+		}
+		params.MinDiscount = &discount
+	}
+
+	customers, err := h.service.Search(params)
+	if err != nil {
+		log.Printf("Search: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка поиска клиентов")
+		return
+	}
+
+	utils.RespondSuccess(w, customers)
 }
 
+// GetByID возвращает карточку клиента
 func (h *CustomerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Customer handler not implemented"))
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	customer, err := h.service.GetByID(id)
+	if err != nil {
+		respondCustomerError(w, err, "Ошибка получения клиента")
+		return
+	}
+
+	utils.RespondSuccess(w, customer)
 }
 
+// Create заводит клиента. При найденных дублях отвечает 409 со списком
+// похожих карточек; повторный запрос с allow_duplicate создаёт клиента.
 func (h *CustomerHandler) Create(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Customer handler not implemented"))
+	var req models.CustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	customer, duplicates, err := h.service.Create(&req)
+	if errors.Is(err, service.ErrPossibleDuplicate) {
+		utils.RespondJSON(w, http.StatusConflict, utils.Response{
+			Success: false,
+			Error:   "Найдены похожие клиенты",
+			Data:    duplicates,
+		})
+		return
+	}
+	if err != nil {
+		respondCustomerError(w, err, "Ошибка создания клиента")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: customer})
 }
 
+// Update изменяет данные клиента
 func (h *CustomerHandler) Update(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Customer handler not implemented"))
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.CustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	customer, err := h.service.Update(id, &req)
+	if err != nil {
+		respondCustomerError(w, err, "Ошибка обновления клиента")
+		return
+	}
+
+	utils.RespondSuccess(w, customer)
 }
 
+// Delete удаляет клиента
 func (h *CustomerHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Customer handler not implemented"))
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	if err := h.service.Delete(id); err != nil {
+		respondCustomerError(w, err, "Ошибка удаления клиента")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Клиент удален")
+}
+
+// GetDuplicates возвращает карточки, похожие на клиента
+func (h *CustomerHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	duplicates, err := h.service.FindDuplicates(id)
+	if err != nil {
+		respondCustomerError(w, err, "Ошибка поиска дублей")
+		return
+	}
+
+	utils.RespondSuccess(w, duplicates)
+}
+
+// Merge объединяет дубль с клиентом из URL
+func (h *CustomerHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CustomerMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	merge, err := h.service.Merge(id, &req, &employeeID)
+	if err != nil {
+		respondCustomerError(w, err, "Ошибка объединения клиентов")
+		return
+	}
+
+	utils.RespondSuccess(w, merge)
+}
+
+// GetMerges возвращает журнал объединений клиента
+func (h *CustomerHandler) GetMerges(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	merges, err := h.service.GetMerges(id)
+	if err != nil {
+		respondCustomerError(w, err, "Ошибка получения журнала объединений")
+		return
+	}
+
+	utils.RespondSuccess(w, merges)
+}
+
+// GetAllCorporate возвращает страницу корпоративных клиентов.
+// ?q= ищет по названию, УНП и номеру договора.
+func (h *CustomerHandler) GetAllCorporate(w http.ResponseWriter, r *http.Request) {
+	page, perPage := pageParams(r)
+
+	clients, pagination, err := h.service.GetAllCorporate(r.URL.Query().Get("q"), page, perPage)
+	if err != nil {
+		log.Printf("GetAllCorporate: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения корпоративных клиентов")
+		return
+	}
+
+	utils.RespondPaginated(w, clients, pagination)
+}
+
+// GetCorporateByID возвращает карточку корпоративного клиента
+func (h *CustomerHandler) GetCorporateByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	client, err := h.service.GetCorporateByID(id)
+	if err != nil {
+		respondCorporateClientError(w, err, "Ошибка получения корпоративного клиента")
+		return
+	}
+
+	utils.RespondSuccess(w, client)
+}
+
+// CreateCorporate заводит корпоративного клиента
+func (h *CustomerHandler) CreateCorporate(w http.ResponseWriter, r *http.Request) {
+	var req models.CorporateClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	client, err := h.service.CreateCorporate(&req)
+	if err != nil {
+		respondCorporateClientError(w, err, "Ошибка создания корпоративного клиента")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: client})
+}
+
+// UpdateCorporate изменяет корпоративного клиента
+func (h *CustomerHandler) UpdateCorporate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.CorporateClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	client, err := h.service.UpdateCorporate(id, &req)
+	if err != nil {
+		respondCorporateClientError(w, err, "Ошибка обновления корпоративного клиента")
+		return
+	}
+
+	utils.RespondSuccess(w, client)
+}
+
+// DeleteCorporate удаляет корпоративного клиента
+func (h *CustomerHandler) DeleteCorporate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	if err := h.service.DeleteCorporate(id); err != nil {
+		respondCorporateClientError(w, err, "Ошибка удаления корпоративного клиента")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Корпоративный клиент удален")
+}
+
+func respondCustomerError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCustomer):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrCustomerNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Клиент не найден")
+	case errors.Is(err, repository.ErrCustomerInUse):
+		utils.RespondError(w, http.StatusConflict, "У клиента есть продажи, тест-драйвы или сервисные заказы")
+	case errors.Is(err, repository.ErrCustomerMergeConflict):
+		utils.RespondError(w, http.StatusConflict, "Оба клиента привязаны к учётным записям личного кабинета")
+	default:
+		log.Printf("customer: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// pageParams читает ?page= и ?per_page= (по умолчанию 50, не больше 200)
+func pageParams(r *http.Request) (int, int) {
+	query := r.URL.Query()
+
+	page := 1
+	if p := parseIntParam(query.Get("page")); p != nil && *p > 0 {
+		page = *p
+	}
+
+	perPage := 50
+	if pp := parseIntParam(query.Get("per_page")); pp != nil && *pp > 0 {
+		perPage = *pp
+	}
+	if perPage > 200 {
+		perPage = 200
+	}
+
+	return page, perPage
+}
+
+func respondCorporateClientError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCorporateClient):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrCorporateClientNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Корпоративный клиент не найден")
+	case errors.Is(err, repository.ErrDuplicateTaxID):
+		utils.RespondError(w, http.StatusConflict, "Клиент с таким УНП уже существует")
+	case errors.Is(err, repository.ErrDuplicateContractNumber):
+		utils.RespondError(w, http.StatusConflict, "Договор с таким номером уже зарегистрирован")
+	case errors.Is(err, repository.ErrCorporateClientInUse):
+		utils.RespondError(w, http.StatusConflict, "У клиента есть продажи, тест-драйвы или сервисные заказы")
+	default:
+		log.Printf("corporate client: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
 }
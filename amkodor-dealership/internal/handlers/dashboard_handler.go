@@ -1,26 +1,83 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+
 	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
 )
 
 type DashboardHandler struct {
-	service *service.WarehouseService
+	service *service.DashboardService
 }
 
-func NewDashboardHandler(service *service.WarehouseService) *DashboardHandler {
+func NewDashboardHandler(service *service.DashboardService) *DashboardHandler {
 	return &DashboardHandler{service: service}
 }
 
+// GetStats возвращает сводку дашборда, ?warehouse_id= ограничивает её складом
 func (h *DashboardHandler) GetStats(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Dashboard handler not implemented"))
+	stats, err := h.service.GetStatistics(r.Context(), parseIntParam(r.URL.Query().Get("warehouse_id")))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения статистики")
+		return
+	}
+
+	utils.RespondSuccess(w, stats)
 }
 
+// GetCharts возвращает график продаж (?period=day|week|month, ?from=, ?to=)
+// и продажи по категориям
 func (h *DashboardHandler) GetCharts(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Dashboard handler not implemented"))
-}
\ No newline at end of file
+	query := r.URL.Query()
+	charts, err := h.service.GetCharts(r.Context(), query.Get("period"), query.Get("from"), query.Get("to"),
+		parseIntParam(query.Get("warehouse_id")))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidChartQuery) {
+			utils.RespondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения графиков")
+		return
+	}
+
+	utils.RespondSuccess(w, charts)
+}
+
+// GetTopEmployees возвращает лучших менеджеров по выручке
+func (h *DashboardHandler) GetTopEmployees(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	employees, err := h.service.GetTopEmployees(r.Context(), dashboardLimit(query.Get("limit"), 5),
+		parseIntParam(query.Get("warehouse_id")))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения рейтинга сотрудников")
+		return
+	}
+
+	utils.RespondSuccess(w, employees)
+}
+
+// GetRecentSales возвращает последние продажи
+func (h *DashboardHandler) GetRecentSales(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	sales, err := h.service.GetRecentSales(r.Context(), dashboardLimit(query.Get("limit"), 10),
+		parseIntParam(query.Get("warehouse_id")))
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения последних продаж")
+		return
+	}
+
+	utils.RespondSuccess(w, sales)
+}
+
+func dashboardLimit(param string, def int) int {
+	limit := def
+	if l := parseIntParam(param); l != nil && *l > 0 {
+		limit = *l
+	}
+	if limit > 50 {
+		limit = 50
+	}
+	return limit
+}
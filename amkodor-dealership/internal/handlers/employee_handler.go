@@ -1,8 +1,18 @@
 package handlers
 
 import (
-	"amkodor-dealership/internal/service"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
 )
 
 type EmployeeHandler struct {
@@ -13,32 +23,252 @@ func NewEmployeeHandler(service *service.EmployeeService) *EmployeeHandler {
 	return &EmployeeHandler{service: service}
 }
 
+// GetAll возвращает страницу сотрудников.
+// ?q= ищет по ФИО, email и телефону; ?status=active|inactive, по умолчанию все.
 func (h *EmployeeHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Employee handler not implemented"))
+	page, perPage := pageParams(r)
+
+	var active *bool
+	switch r.URL.Query().Get("status") {
+	case "active":
+		v := true
+		active = &v
+	case "inactive":
+		v := false
+		active = &v
+	case "", "all":
+	default:
+		utils.RespondError(w, http.StatusBadRequest, "Некорректный статус")
+		return
+	}
+
+	employees, pagination, err := h.service.GetPage(r.URL.Query().Get("q"), active, page, perPage)
+	if err != nil {
+		log.Printf("GetAll: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения сотрудников")
+		return
+	}
+
+	utils.RespondPaginated(w, employees, pagination)
 }
 
+// GetByID возвращает карточку сотрудника
 func (h *EmployeeHandler) GetByID(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Employee handler not implemented"))
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	employee, err := h.service.GetByID(id)
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка получения сотрудника")
+		return
+	}
+
+	utils.RespondSuccess(w, employee)
 }
 
+// Create принимает сотрудника на работу
 func (h *EmployeeHandler) Create(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Employee handler not implemented"))
+	var req models.EmployeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	employee, err := h.service.Create(&req)
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка создания сотрудника")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: employee})
 }
 
+// Update изменяет данные сотрудника
 func (h *EmployeeHandler) Update(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Employee handler not implemented"))
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.EmployeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	employee, err := h.service.Update(id, &req)
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка обновления сотрудника")
+		return
+	}
+
+	utils.RespondSuccess(w, employee)
 }
 
+// Delete увольняет сотрудника. Запись не удаляется: на неё ссылаются
+// продажи, тест-драйвы и сервисные заказы.
 func (h *EmployeeHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	// Заглушка
-	w.WriteHeader(http.StatusNotImplemented)
-	w.Write([]byte("Employee handler not implemented"))
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	if err := h.service.Deactivate(id); err != nil {
+		respondEmployeeError(w, err, "Ошибка увольнения сотрудника")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Сотрудник уволен")
+}
+
+// Restore возвращает сотрудника в штат
+func (h *EmployeeHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	employee, err := h.service.Restore(id)
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка восстановления сотрудника")
+		return
+	}
+
+	utils.RespondSuccess(w, employee)
+}
+
+// SetPassword задаёт сотруднику пароль
+func (h *EmployeeHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.SetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	if err := h.service.SetPassword(id, req.Password); err != nil {
+		respondEmployeeError(w, err, "Ошибка смены пароля")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Пароль изменен")
+}
+
+// ResetPassword генерирует сотруднику временный пароль
+func (h *EmployeeHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	reset, err := h.service.ResetPassword(id)
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка сброса пароля")
+		return
+	}
+
+	utils.RespondSuccess(w, reset)
+}
+
+// GetPositions возвращает справочник должностей
+func (h *EmployeeHandler) GetPositions(w http.ResponseWriter, r *http.Request) {
+	positions, err := h.service.GetPositions()
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка получения должностей")
+		return
+	}
+
+	utils.RespondSuccess(w, positions)
+}
+
+// CreatePosition добавляет должность
+func (h *EmployeeHandler) CreatePosition(w http.ResponseWriter, r *http.Request) {
+	var req models.PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	position, err := h.service.CreatePosition(&req)
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка создания должности")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: position})
+}
+
+// UpdatePosition изменяет должность
+func (h *EmployeeHandler) UpdatePosition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	position, err := h.service.UpdatePosition(id, &req)
+	if err != nil {
+		respondEmployeeError(w, err, "Ошибка обновления должности")
+		return
+	}
+
+	utils.RespondSuccess(w, position)
+}
+
+// DeletePosition удаляет должность
+func (h *EmployeeHandler) DeletePosition(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	if err := h.service.DeletePosition(id); err != nil {
+		respondEmployeeError(w, err, "Ошибка удаления должности")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Должность удалена")
+}
+
+func respondEmployeeError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidEmployee), errors.Is(err, service.ErrInvalidPosition):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrWeakPassword):
+		utils.RespondError(w, http.StatusBadRequest, "Пароль должен содержать не менее 8 символов, заглавные и строчные буквы и цифры")
+	case errors.Is(err, repository.ErrEmployeeNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Сотрудник не найден")
+	case errors.Is(err, repository.ErrPositionNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Должность не найдена")
+	case errors.Is(err, repository.ErrWarehouseNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Склад не найден")
+	case errors.Is(err, repository.ErrDuplicateEmployeeEmail):
+		utils.RespondError(w, http.StatusConflict, "Сотрудник с таким email уже существует")
+	case errors.Is(err, repository.ErrDuplicatePosition):
+		utils.RespondError(w, http.StatusConflict, "Такая должность уже существует")
+	case errors.Is(err, repository.ErrPositionInUse):
+		utils.RespondError(w, http.StatusConflict, "На должность назначены сотрудники")
+	default:
+		log.Printf("employee: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
 }
@@ -1,40 +1,64 @@
 package handlers
 
 import (
+	"amkodor-dealership/internal/config"
 	"amkodor-dealership/internal/service"
+	"amkodor-dealership/pkg/report"
 	"database/sql"
 )
 
 // Структура для группировки всех handlers
 type Handlers struct {
-	Vehicle   *VehicleHandler
-	Customer  *CustomerHandler
-	Sale      *SaleHandler
-	Employee  *EmployeeHandler
-	Auth      *AuthHandler
-	Dashboard *DashboardHandler
-	Report    *ReportHandler
-	Admin     *AdminHandler
-	Warehouse *WarehouseHandler
-	Service   *ServiceHandler
-	Favorite  *FavoriteHandler
-	User      *UserHandler
+	Vehicle        *VehicleHandler
+	Customer       *CustomerHandler
+	Sale           *SaleHandler
+	Employee       *EmployeeHandler
+	Auth           *AuthHandler
+	Dashboard      *DashboardHandler
+	Report         *ReportHandler
+	Admin          *AdminHandler
+	Warehouse      *WarehouseHandler
+	Service        *ServiceHandler
+	Favorite       *FavoriteHandler
+	User           *UserHandler
+	ServiceRequest *ServiceRequestHandler
+	LiveEvent      *LiveEventHandler
+	StockAlert     *StockAlertHandler
+	PurchaseOrder  *PurchaseOrderHandler
+	Movement       *SparePartMovementHandler
+	Supply         *SupplyHandler
+	Transfer       *VehicleTransferHandler
+	Reservation    *ReservationHandler
+	TestDrive      *TestDriveHandler
+	Calendar       *CalendarHandler
+	ServiceWork    *ServiceOrderWorkHandler
 }
 
 // NewHandlers создает новый экземпляр Handlers
-func NewHandlers(db *sql.DB, services *service.Services) *Handlers {
+func NewHandlers(db *sql.DB, services *service.Services, uploadCfg config.UploadConfig) *Handlers {
 	return &Handlers{
-		Vehicle:   NewVehicleHandler(services.Vehicle),
-		Customer:  NewCustomerHandler(services.Customer),
-		Sale:      NewSaleHandler(services.Sale),
-		Employee:  NewEmployeeHandler(services.Employee),
-		Auth:      NewAuthHandler(services.Auth),
-		Dashboard: NewDashboardHandler(services.Warehouse),
-		Report:    NewReportHandler(services.Report),
-		Admin:     NewAdminHandler(services.Warehouse),
-		Warehouse: NewWarehouseHandler(services.Warehouse),
-		Service:   NewServiceHandler(services.ServiceOrderRepo),
-		Favorite:  NewFavoriteHandler(services.Favorite),
-		User:      NewUserHandler(),
+		Vehicle:        NewVehicleHandler(services.Vehicle, uploadCfg),
+		Customer:       NewCustomerHandler(services.Customer),
+		Sale:           NewSaleHandler(services.Sale),
+		Employee:       NewEmployeeHandler(services.Employee),
+		Auth:           NewAuthHandler(services.Auth),
+		Dashboard:      NewDashboardHandler(services.Dashboard),
+		Report:         NewReportHandler(services.Report, report.DefaultRegistry("web/static/fonts")),
+		Admin:          NewAdminHandler(services.Warehouse),
+		Warehouse:      NewWarehouseHandler(services.Warehouse),
+		Service:        NewServiceHandler(services.ServiceOrderRepo),
+		Favorite:       NewFavoriteHandler(services.Favorite),
+		User:           NewUserHandler(services.Account),
+		ServiceRequest: NewServiceRequestHandler(services.ServiceRequest),
+		LiveEvent:      NewLiveEventHandler(services.LiveEvent),
+		StockAlert:     NewStockAlertHandler(services.StockAlert),
+		PurchaseOrder:  NewPurchaseOrderHandler(services.PurchaseOrder),
+		Movement:       NewSparePartMovementHandler(services.Movement),
+		Supply:         NewSupplyHandler(services.Supply),
+		Transfer:       NewVehicleTransferHandler(services.Transfer),
+		Reservation:    NewReservationHandler(services.Reservation),
+		TestDrive:      NewTestDriveHandler(services.TestDrive),
+		Calendar:       NewCalendarHandler(services.Calendar),
+		ServiceWork:    NewServiceOrderWorkHandler(services.ServiceWork),
 	}
 }
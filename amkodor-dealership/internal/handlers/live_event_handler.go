@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"amkodor-dealership/internal/middleware"
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+)
+
+// liveEventHeartbeat - интервал комментария-пинга, не дающего прокси закрыть поток
+const liveEventHeartbeat = 25 * time.Second
+
+// liveEventPermissions - право, нужное роли, чтобы получать событие типа
+var liveEventPermissions = map[string]middleware.Permission{
+	models.LiveEventSaleCreated:           middleware.PermSalesRead,
+	models.LiveEventSaleCancelled:         middleware.PermSalesRead,
+	models.LiveEventVehicleStatusChanged:  middleware.PermWarehousesRead,
+	models.LiveEventSparePartLowStock:     middleware.PermSparePartsRead,
+	models.LiveEventServiceRequestCreated: middleware.PermServiceRequests,
+}
+
+type LiveEventHandler struct {
+	service *service.LiveEventService
+}
+
+func NewLiveEventHandler(service *service.LiveEventService) *LiveEventHandler {
+	return &LiveEventHandler{service: service}
+}
+
+// Stream отдаёт события в формате text/event-stream. Сотрудник получает только
+// события, доступные его роли; ?warehouse_id= оставляет события одного склада
+// и события без склада. При переподключении пропущенные события досылаются
+// по заголовку Last-Event-ID (или ?last_event_id=).
+func (h *LiveEventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	role, _ := middleware.GetRoleFromContext(r.Context())
+	warehouseID := parseIntParam(r.URL.Query().Get("warehouse_id"))
+
+	// Подписка оформляется до чтения пропущенных событий, чтобы не потерять
+	// события, пришедшие между чтением и подпиской
+	events, unsubscribe := h.service.Subscribe()
+	defer unsubscribe()
+
+	var missed []models.LiveEvent
+	gap := false
+	if lastID := lastEventID(r); lastID > 0 {
+		var err error
+		missed, err = h.service.Replay(r.Context(), lastID)
+		if errors.Is(err, service.ErrLiveEventsGap) {
+			gap = true
+		} else if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения событий")
+			return
+		}
+	}
+
+	// Поток живёт дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		utils.RespondError(w, http.StatusInternalServerError, "Потоковая передача не поддерживается")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+
+	// reset - клиент пропустил слишком много событий и должен перечитать дашборд
+	if gap {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+
+	sent := make(map[int64]bool, len(missed))
+	for _, event := range missed {
+		sent[event.ID] = true
+		if liveEventVisible(event, role, warehouseID) {
+			writeLiveEvent(w, event)
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(liveEventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			// Канал закрыт для отстающего подписчика: клиент переподключится
+			// и дочитает пропущенное по Last-Event-ID
+			if !ok {
+				return
+			}
+			if sent[event.ID] || !liveEventVisible(event, role, warehouseID) {
+				continue
+			}
+			writeLiveEvent(w, event)
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func liveEventVisible(event models.LiveEvent, role string, warehouseID *int) bool {
+	perm, ok := liveEventPermissions[event.Type]
+	if !ok || !middleware.HasPermission(role, perm) {
+		return false
+	}
+	return warehouseID == nil || event.WarehouseID == nil || *event.WarehouseID == *warehouseID
+}
+
+func writeLiveEvent(w http.ResponseWriter, event models.LiveEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
+
+func lastEventID(r *http.Request) int64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// PurchaseOrderHandler - поставщики, заказы запчастей и рекомендации по дозаказу
+type PurchaseOrderHandler struct {
+	service *service.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(service *service.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{service: service}
+}
+
+// GetSuppliers возвращает поставщиков. ?active=true скрывает неактивных.
+func (h *PurchaseOrderHandler) GetSuppliers(w http.ResponseWriter, r *http.Request) {
+	activeOnly := r.URL.Query().Get("active") == "true"
+	suppliers, err := h.service.GetSuppliers(r.Context(), activeOnly)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка получения поставщиков")
+		return
+	}
+
+	utils.RespondSuccess(w, suppliers)
+}
+
+// CreateSupplier добавляет поставщика
+func (h *PurchaseOrderHandler) CreateSupplier(w http.ResponseWriter, r *http.Request) {
+	var req models.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	supplier, err := h.service.CreateSupplier(r.Context(), &req)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка создания поставщика")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: supplier})
+}
+
+// UpdateSupplier изменяет данные поставщика
+func (h *PurchaseOrderHandler) UpdateSupplier(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.SupplierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	supplier, err := h.service.UpdateSupplier(r.Context(), id, &req)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка изменения поставщика")
+		return
+	}
+
+	utils.RespondSuccess(w, supplier)
+}
+
+// GetAll возвращает заказы. Фильтры: ?status=, ?warehouse_id=, ?supplier_id=.
+func (h *PurchaseOrderHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	orders, err := h.service.GetAll(r.Context(), query.Get("status"),
+		parseIntParam(query.Get("warehouse_id")), parseIntParam(query.Get("supplier_id")))
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка получения заказов")
+		return
+	}
+
+	utils.RespondSuccess(w, orders)
+}
+
+// GetByID возвращает заказ со строками
+func (h *PurchaseOrderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	order, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка получения заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// Create оформляет заказ от имени текущего сотрудника
+func (h *PurchaseOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	order, err := h.service.Create(r.Context(), employeeID, &req)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка создания заказа")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: order})
+}
+
+// Update изменяет заказ, пока по нему ничего не получено
+func (h *PurchaseOrderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.PurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	order, err := h.service.Update(r.Context(), id, &req)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка изменения заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// Cancel отменяет заказ
+func (h *PurchaseOrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	order, err := h.service.Cancel(r.Context(), id)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка отмены заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// Receive принимает запчасти по заказу. Без тела запроса принимается
+// весь недополученный остаток.
+func (h *PurchaseOrderHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ReceivePurchaseOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	order, err := h.service.Receive(r.Context(), id, employeeID, &req)
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка приёма заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// GetReorderSuggestions возвращает рекомендации по дозаказу. ?warehouse_id=,
+// ?window_days= - окно расчёта расхода, ?cover_days= - на сколько дней заказывать.
+func (h *PurchaseOrderHandler) GetReorderSuggestions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	suggestions, err := h.service.GetReorderSuggestions(r.Context(), parseIntParam(query.Get("warehouse_id")),
+		parseIntParam(query.Get("window_days")), parseIntParam(query.Get("cover_days")))
+	if err != nil {
+		respondPurchaseOrderError(w, err, "Ошибка расчёта дозаказа")
+		return
+	}
+
+	utils.RespondSuccess(w, suggestions)
+}
+
+func respondPurchaseOrderError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSupplier), errors.Is(err, service.ErrInvalidPurchaseOrder):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrSupplierNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Поставщик не найден")
+	case errors.Is(err, repository.ErrPurchaseOrderNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Заказ не найден")
+	case errors.Is(err, repository.ErrPurchaseOrderReference):
+		utils.RespondError(w, http.StatusBadRequest, "Поставщик или склад не найдены")
+	case errors.Is(err, repository.ErrPurchaseOrderPart):
+		utils.RespondError(w, http.StatusBadRequest, "Запчасть не найдена на складе заказа")
+	case errors.Is(err, repository.ErrPurchaseOrderQuantity):
+		utils.RespondError(w, http.StatusBadRequest, "Количество превышает недополученный остаток по строке заказа")
+	case errors.Is(err, repository.ErrPurchaseOrderState):
+		utils.RespondError(w, http.StatusConflict, "Заказ уже получен, отменён или по нему начат приём")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,20 +13,18 @@ import (
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/service"
 	"amkodor-dealership/internal/utils"
-
-	"path/filepath"
-
-	"github.com/jung-kurt/gofpdf"
-	"github.com/xuri/excelize/v2"
+	"amkodor-dealership/pkg/report"
 )
 
 type ReportHandler struct {
-	service *service.ReportService
+	service   *service.ReportService
+	renderers *report.Registry
 }
 
-func NewReportHandler(service *service.ReportService) *ReportHandler {
+func NewReportHandler(service *service.ReportService, renderers *report.Registry) *ReportHandler {
 	return &ReportHandler{
-		service: service,
+		service:   service,
+		renderers: renderers,
 	}
 }
 
@@ -131,156 +130,130 @@ func (h *ReportHandler) InventoryReport(w http.ResponseWriter, r *http.Request)
 	utils.SuccessResponse(w, http.StatusOK, report)
 }
 
-// ExportSalesReport экспорт отчёта по продажам
+// ExportSalesReport экспорт отчёта по продажам (по умолчанию XLSX)
 func (h *ReportHandler) ExportSalesReport(w http.ResponseWriter, r *http.Request) {
-	// Заглушка для ExportSalesReport
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	w.Header().Set("Content-Disposition", "attachment; filename=sales_report.xlsx")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Sales report export not implemented"))
+	h.exportTyped(w, r, "sales")
 }
 
-// ExportInventoryReport экспорт отчёта по инвентарю
+// ExportInventoryReport экспорт отчёта по инвентарю (по умолчанию XLSX)
 func (h *ReportHandler) ExportInventoryReport(w http.ResponseWriter, r *http.Request) {
-	// Заглушка для ExportInventoryReport
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	w.Header().Set("Content-Disposition", "attachment; filename=inventory_report.xlsx")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Inventory report export not implemented"))
+	h.exportTyped(w, r, "inventory")
 }
 
-// ExportReport - единая точка экспорта: csv, xlsx, pdf
-func (h *ReportHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
+func (h *ReportHandler) exportTyped(w http.ResponseWriter, r *http.Request, reportType string) {
 	q := r.URL.Query()
-	reportType := q.Get("type") // sales, vehicles, customers, financial
-	format := q.Get("format")   // csv | xlsx | pdf (может прийти excel/xslx)
-	if format == "excel" || format == "xslx" {
+	format := q.Get("format")
+	if format == "" {
 		format = "xlsx"
 	}
-	startStr := q.Get("start_date")
-	endStr := q.Get("end_date")
+	h.export(w, r, exportParams{
+		Type:      reportType,
+		Format:    format,
+		StartDate: q.Get("start_date"),
+		EndDate:   q.Get("end_date"),
+	})
+}
 
-	startDate, _ := time.Parse("2006-01-02", startStr)
-	endDate, _ := time.Parse("2006-01-02", endStr)
-	if startDate.IsZero() {
-		startDate = time.Now().AddDate(0, -1, 0)
+// exportParams - параметры экспорта. POST принимает и date_from/date_to,
+// которые отправляет страница отчетов.
+type exportParams struct {
+	Type      string `json:"type"`
+	Format    string `json:"format"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	DateFrom  string `json:"date_from"`
+	DateTo    string `json:"date_to"`
+}
+
+// ExportReport - единая точка экспорта: type = sales | vehicles | customers |
+// financial | inventory | test_drives, format = csv | xlsx | pdf. GET принимает параметры
+// в query, POST - в JSON.
+func (h *ReportHandler) ExportReport(w http.ResponseWriter, r *http.Request) {
+	var params exportParams
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			utils.RespondError(w, http.StatusBadRequest, "Некорректные данные запроса")
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		params = exportParams{
+			Type:      q.Get("type"),
+			Format:    q.Get("format"),
+			StartDate: q.Get("start_date"),
+			EndDate:   q.Get("end_date"),
+		}
 	}
-	if endDate.IsZero() {
-		endDate = time.Now()
+
+	if params.StartDate == "" {
+		params.StartDate = params.DateFrom
+	}
+	if params.EndDate == "" {
+		params.EndDate = params.DateTo
 	}
 
-	filenameBase := map[string]string{
-		"sales":     "sales_report",
-		"vehicles":  "vehicles_report",
-		"customers": "customers_report",
-		"financial": "financial_report",
-	}[reportType]
-	if filenameBase == "" {
-		filenameBase = "report"
+	h.export(w, r, params)
+}
+
+func (h *ReportHandler) export(w http.ResponseWriter, r *http.Request, params exportParams) {
+	renderer, err := h.renderers.Get(params.Format)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неподдерживаемый формат: "+params.Format)
+		return
 	}
 
-	switch format {
-	case "csv":
-		buf := &bytes.Buffer{}
-		wtr := csv.NewWriter(buf)
-		// Примеры данных (можно заменить реальными)
-		switch reportType {
-		case "sales":
-			wtr.Write([]string{"ID", "Клиент", "Техника", "Сумма", "Дата", "Статус"})
-			wtr.Write([]string{"1", "Иван Петров", "Экскаватор", "450000", startDate.Format("2006-01-02"), "Завершена"})
-		case "vehicles":
-			wtr.Write([]string{"ID", "Название", "Категория", "Цена", "Статус", "Год"})
-			wtr.Write([]string{"1", "Экскаватор 331.04", "Экскаваторы", "450000", "В наличии", "2023"})
-		case "customers":
-			wtr.Write([]string{"ID", "Название", "Тип", "Email", "Телефон"})
-			wtr.Write([]string{"1", "Иван Петров", "ФЛ", "ivan@example.com", "+375291234567"})
-		case "financial":
-			wtr.Write([]string{"Период", "Доходы", "Расходы", "Прибыль", "Продажи"})
-			wtr.Write([]string{startDate.Format("2006-01-02"), "450000", "50000", "400000", "1"})
-		default:
-			wtr.Write([]string{"Отчет", "Период"})
-			wtr.Write([]string{"Общий", fmt.Sprintf("%s - %s", startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))})
-		}
-		wtr.Flush()
-		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s_%s.csv", filenameBase, startDate.Format("20060102"), endDate.Format("20060102")))
-		w.WriteHeader(http.StatusOK)
-		w.Write(buf.Bytes())
+	startDate, endDate, err := parseReportPeriod(params.StartDate, params.EndDate)
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
 		return
+	}
 
-	case "xlsx":
-		f := excelize.NewFile()
-		defer f.Close()
-		sheet := "Отчет"
-		idx, _ := f.NewSheet(sheet)
-		f.SetActiveSheet(idx)
-		f.SetCellValue(sheet, "A1", fmt.Sprintf("Отчет: %s", reportType))
-		f.SetCellValue(sheet, "A2", fmt.Sprintf("Период: %s - %s", startDate.Format("02.01.2006"), endDate.Format("02.01.2006")))
-		// Заголовки и строки по типу
-		switch reportType {
-		case "sales":
-			headers := []string{"ID", "Клиент", "Техника", "Сумма", "Дата", "Статус"}
-			for i, hname := range headers {
-				f.SetCellValue(sheet, fmt.Sprintf("%c4", 'A'+i), hname)
-			}
-			f.SetCellValue(sheet, "A5", 1)
-			f.SetCellValue(sheet, "B5", "Иван Петров")
-			f.SetCellValue(sheet, "C5", "Экскаватор")
-			f.SetCellValue(sheet, "D5", 450000)
-			f.SetCellValue(sheet, "E5", startDate.Format("2006-01-02"))
-			f.SetCellValue(sheet, "F5", "Завершена")
-		default:
-			f.SetCellValue(sheet, "A4", "Данные")
-			f.SetCellValue(sheet, "A5", "Пример строки")
-		}
-		buf, err := f.WriteToBuffer()
-		if err != nil {
-			utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка формирования XLSX")
+	table, err := h.service.BuildReport(r.Context(), params.Type, startDate, endDate)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownReportType) {
+			utils.RespondError(w, http.StatusBadRequest, "Неизвестный тип отчета: "+params.Type)
 			return
 		}
-		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s_%s.xlsx", filenameBase, startDate.Format("20060102"), endDate.Format("20060102")))
-		w.WriteHeader(http.StatusOK)
-		w.Write(buf.Bytes())
+		log.Printf("ExportReport: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка формирования отчета")
 		return
+	}
 
-	case "pdf":
-		pdf := gofpdf.New("P", "mm", "A4", "")
-		// Подключаем UTF-8 шрифты для корректной кириллицы (из файлов)
-		fontReg, _ := filepath.Abs("web/static/fonts/DejaVuSans.ttf")
-		fontBold, _ := filepath.Abs("web/static/fonts/DejaVuSans-Bold.ttf")
-		pdf.AddUTF8Font("DejaVu", "", fontReg)
-		pdf.AddUTF8Font("DejaVu", "B", fontBold)
-		if err := pdf.Error(); err != nil {
-			utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка загрузки шрифта для PDF")
-			return
+	// Рендерим в буфер, чтобы при ошибке ещё можно было вернуть JSON
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, table); err != nil {
+		log.Printf("ExportReport: render %s: %v", params.Format, err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка формирования файла отчета")
+		return
+	}
+
+	w.Header().Set("Content-Type", renderer.ContentType())
+	w.Header().Set("Content-Disposition", "attachment; filename="+table.Filename(renderer.Extension()))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// parseReportPeriod разбирает даты YYYY-MM-DD; по умолчанию - последний месяц
+func parseReportPeriod(startStr, endStr string) (time.Time, time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	startDate, endDate := today.AddDate(0, -1, 0), today
+
+	var err error
+	if startStr != "" {
+		if startDate, err = time.Parse("2006-01-02", startStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Некорректная дата начала: %s", startStr)
 		}
-		pdf.AddPage()
-		pdf.SetFont("DejaVu", "B", 16)
-		pdf.Cell(190, 10, fmt.Sprintf("Отчет: %s", reportType))
-		pdf.Ln(8)
-		pdf.SetFont("DejaVu", "", 12)
-		pdf.Cell(190, 8, fmt.Sprintf("Период: %s - %s", startDate.Format("02.01.2006"), endDate.Format("02.01.2006")))
-		pdf.Ln(12)
-		// Простая таблица-заглушка
-		pdf.CellFormat(30, 8, "Колонка 1", "1", 0, "L", false, 0, "")
-		pdf.CellFormat(60, 8, "Колонка 2", "1", 0, "L", false, 0, "")
-		pdf.CellFormat(30, 8, "Колонка 3", "1", 1, "L", false, 0, "")
-		pdf.CellFormat(30, 8, "Значение 1", "1", 0, "L", false, 0, "")
-		pdf.CellFormat(60, 8, "Значение 2", "1", 0, "L", false, 0, "")
-		pdf.CellFormat(30, 8, "Значение 3", "1", 1, "L", false, 0, "")
-		var out bytes.Buffer
-		if err := pdf.Output(&out); err != nil {
-			utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка формирования PDF")
-			return
+	}
+	if endStr != "" {
+		if endDate, err = time.Parse("2006-01-02", endStr); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Некорректная дата окончания: %s", endStr)
 		}
-		w.Header().Set("Content-Type", "application/pdf")
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s_%s_%s.pdf", filenameBase, startDate.Format("20060102"), endDate.Format("20060102")))
-		w.WriteHeader(http.StatusOK)
-		w.Write(out.Bytes())
-		return
-	default:
-		utils.ErrorResponse(w, http.StatusBadRequest, "Неверный формат отчёта")
-		return
 	}
+	if startDate.After(endDate) {
+		return time.Time{}, time.Time{}, errors.New("Дата начала позже даты окончания")
+	}
+
+	return startDate, endDate, nil
 }
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ReservationHandler - бронирование техники за клиентами
+type ReservationHandler struct {
+	service *service.ReservationService
+}
+
+func NewReservationHandler(service *service.ReservationService) *ReservationHandler {
+	return &ReservationHandler{service: service}
+}
+
+// GetAll возвращает брони. Фильтры: ?status=, ?vehicle_id=, ?customer_id=, ?corporate_client_id=.
+func (h *ReservationHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	reservations, err := h.service.GetAll(r.Context(), query.Get("status"),
+		parseIntParam(query.Get("vehicle_id")), parseIntParam(query.Get("customer_id")),
+		parseIntParam(query.Get("corporate_client_id")))
+	if err != nil {
+		respondReservationError(w, err, "Ошибка получения броней")
+		return
+	}
+
+	utils.RespondSuccess(w, reservations)
+}
+
+// GetByID возвращает бронь
+func (h *ReservationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	reservation, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		respondReservationError(w, err, "Ошибка получения брони")
+		return
+	}
+
+	utils.RespondSuccess(w, reservation)
+}
+
+// Create бронирует технику. Менеджером брони становится сотрудник из токена.
+func (h *ReservationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.VehicleReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	reservation, err := h.service.Create(r.Context(), employeeID, &req)
+	if err != nil {
+		respondReservationError(w, err, "Ошибка бронирования техники")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: reservation})
+}
+
+// Extend продлевает бронь
+func (h *ReservationHandler) Extend(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.ExtendReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	reservation, err := h.service.Extend(r.Context(), id, &req)
+	if err != nil {
+		respondReservationError(w, err, "Ошибка продления брони")
+		return
+	}
+
+	utils.RespondSuccess(w, reservation)
+}
+
+// Cancel снимает бронь
+func (h *ReservationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	reservation, err := h.service.Cancel(r.Context(), id)
+	if err != nil {
+		respondReservationError(w, err, "Ошибка снятия брони")
+		return
+	}
+
+	utils.RespondSuccess(w, reservation)
+}
+
+func respondReservationError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidReservation):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrReservationNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Бронь не найдена")
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Техника не найдена")
+	case errors.Is(err, repository.ErrReservationClient):
+		utils.RespondError(w, http.StatusBadRequest, "Клиент не найден")
+	case errors.Is(err, repository.ErrReservationVehicle):
+		utils.RespondError(w, http.StatusConflict, "Техника недоступна для бронирования")
+	case errors.Is(err, repository.ErrReservationState):
+		utils.RespondError(w, http.StatusConflict, "Бронь уже выкуплена, истекла или отменена")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
 	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
 	"amkodor-dealership/internal/service"
 	"amkodor-dealership/internal/utils"
 
@@ -68,13 +71,17 @@ func (h *SaleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	utils.RespondSuccess(w, sale)
 }
 
-// Create создает новую продажу
+// Create создает новую продажу. Менеджером продажи становится сотрудник из токена.
 func (h *SaleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
 	var req struct {
 		VehicleID          int     `json:"vehicle_id"`
 		CustomerID         *int    `json:"customer_id"`
 		CorporateClientID  *int    `json:"corporate_client_id"`
-		EmployeeID         int     `json:"employee_id"`
 		PaymentType        string  `json:"payment_type"`
 		AdditionalDiscount float64 `json:"additional_discount"`
 		ContractNumber     string  `json:"contract_number"`
@@ -87,8 +94,8 @@ func (h *SaleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Валидация
-	if req.VehicleID == 0 || req.EmployeeID == 0 {
-		utils.RespondError(w, http.StatusBadRequest, "Необходимо указать технику и менеджера")
+	if req.VehicleID == 0 {
+		utils.RespondError(w, http.StatusBadRequest, "Необходимо указать технику")
 		return
 	}
 
@@ -105,15 +112,36 @@ func (h *SaleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		req.VehicleID,
 		req.CustomerID,
 		req.CorporateClientID,
-		req.EmployeeID,
+		employeeID,
 		req.PaymentType,
 		req.AdditionalDiscount,
 		req.ContractNumber,
 		req.Notes,
 	)
 
+	switch {
+	case errors.Is(err, service.ErrVehicleInTransit):
+		utils.RespondError(w, http.StatusConflict, "Техника в пути и не может быть продана")
+		return
+	case errors.Is(err, service.ErrVehicleReserved):
+		utils.RespondError(w, http.StatusConflict, "Техника забронирована за другим клиентом")
+		return
+	case errors.Is(err, service.ErrVehicleUnavailable):
+		utils.RespondError(w, http.StatusConflict, "Техника недоступна для продажи")
+		return
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Техника не найдена")
+		return
+	case errors.Is(err, repository.ErrSaleReference):
+		utils.RespondError(w, http.StatusBadRequest, "Клиент или сотрудник не найден")
+		return
+	case errors.Is(err, repository.ErrInvalidSale):
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные продажи: проверьте клиента и скидку")
+		return
+	}
 	if err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, err.Error())
+		log.Printf("Create sale: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка создания продажи")
 		return
 	}
 
@@ -141,14 +169,21 @@ func (h *SaleHandler) Update(w http.ResponseWriter, r *http.Request) {
 	sale.SaleID = id
 
 	if err := h.service.Update(&sale); err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, "Ошибка обновления продажи")
+		switch {
+		case errors.Is(err, service.ErrUseSaleCancellation):
+			utils.RespondError(w, http.StatusBadRequest, "Для отмены продажи используйте отмену, а не обновление")
+		case errors.Is(err, repository.ErrSaleNotFound):
+			utils.RespondError(w, http.StatusNotFound, "Продажа не найдена или отменена")
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, "Ошибка обновления продажи")
+		}
 		return
 	}
 
 	utils.RespondMessage(w, http.StatusOK, "Продажа успешно обновлена")
 }
 
-// Delete удаляет продажу
+// Delete отменяет продажу и возвращает технику в наличие
 func (h *SaleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
@@ -158,11 +193,18 @@ func (h *SaleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.Delete(id); err != nil {
-		utils.RespondError(w, http.StatusInternalServerError, "Ошибка удаления продажи")
+		switch {
+		case errors.Is(err, repository.ErrSaleNotFound):
+			utils.RespondError(w, http.StatusNotFound, "Продажа не найдена")
+		case errors.Is(err, repository.ErrSaleAlreadyCancelled):
+			utils.RespondError(w, http.StatusConflict, "Продажа уже отменена")
+		default:
+			utils.RespondError(w, http.StatusInternalServerError, "Ошибка отмены продажи")
+		}
 		return
 	}
 
-	utils.RespondMessage(w, http.StatusOK, "Продажа успешно удалена")
+	utils.RespondMessage(w, http.StatusOK, "Продажа отменена")
 }
 
 // GetHistory возвращает историю изменений продажи
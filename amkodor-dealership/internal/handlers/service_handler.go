@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"amkodor-dealership/internal/middleware"
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
 	"amkodor-dealership/internal/utils"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+
+	"github.com/gorilla/mux"
 )
 
 type ServiceHandler struct {
@@ -43,6 +47,9 @@ func (h *ServiceHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if req.Status == "" {
 		req.Status = "В работе"
 	}
+	if req.EmployeeID == 0 {
+		req.EmployeeID, _ = middleware.GetEmployeeIDFromContext(r.Context())
+	}
 
 	order, err := h.serviceOrderRepo.CreateServiceOrder(req)
 	if err != nil {
@@ -74,12 +81,6 @@ func (h *ServiceHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	utils.RespondSuccess(w, orders)
 }
 
-func (h *ServiceHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success":true,"data":null}`))
-}
-
 func (h *ServiceHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateServiceOrderRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -101,18 +102,6 @@ func (h *ServiceHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	utils.RespondSuccess(w, order)
 }
 
-func (h *ServiceHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success":true,"message":"Service order updated"}`))
-}
-
-func (h *ServiceHandler) CompleteOrder(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"success":true,"message":"Service order completed"}`))
-}
-
 func (h *ServiceHandler) GetAllTestDrives(w http.ResponseWriter, r *http.Request) {
 	testDrives, err := h.serviceOrderRepo.GetAllTestDrives()
 	if err != nil {
@@ -180,6 +169,9 @@ func (h *ServiceHandler) CreateTestDrive(w http.ResponseWriter, r *http.Request)
 	if req.Duration == 0 {
 		req.Duration = 60
 	}
+	if req.EmployeeID == 0 {
+		req.EmployeeID, _ = middleware.GetEmployeeIDFromContext(r.Context())
+	}
 
 	testDrive, err := h.serviceOrderRepo.CreateTestDrive(req)
 	if err != nil {
@@ -192,22 +184,22 @@ func (h *ServiceHandler) CreateTestDrive(w http.ResponseWriter, r *http.Request)
 
 // DeleteSparePart удаляет запчасть
 func (h *ServiceHandler) DeleteSparePart(w http.ResponseWriter, r *http.Request) {
-	// Извлекаем ID из URL
-	vars := r.URL.Query()
-	idStr := vars.Get("id")
-	if idStr == "" {
-		utils.RespondError(w, http.StatusBadRequest, "Missing spare part ID")
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		utils.RespondError(w, http.StatusBadRequest, "Invalid spare part ID")
 		return
 	}
 
 	err = h.serviceOrderRepo.DeleteSparePart(id)
-	if err != nil {
+	switch {
+	case errors.Is(err, repository.ErrSparePartNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Запчасть не найдена")
+		return
+	case errors.Is(err, repository.ErrSparePartInUse):
+		utils.RespondError(w, http.StatusConflict,
+			"Запчасть есть в журнале движений или в сервисных заказах и не может быть удалена. Остаток можно списать движением write_off")
+		return
+	case err != nil:
 		utils.RespondError(w, http.StatusInternalServerError, "Failed to delete spare part")
 		return
 	}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ServiceOrderWorkHandler - запчасти, работы и завершение сервисных заказов
+type ServiceOrderWorkHandler struct {
+	service *service.ServiceOrderWorkService
+}
+
+func NewServiceOrderWorkHandler(service *service.ServiceOrderWorkService) *ServiceOrderWorkHandler {
+	return &ServiceOrderWorkHandler{service: service}
+}
+
+// GetOrder возвращает карточку заказа с запчастями, работами и стоимостью
+func (h *ServiceOrderWorkHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	order, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		respondServiceOrderWorkError(w, err, "Ошибка получения сервисного заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// Update меняет мастера, тип работ, описание или статус открытого заказа.
+// Отмена возвращает запчасти заказа на склад. Завершённый или отменённый
+// заказ - 409.
+func (h *ServiceOrderWorkHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.UpdateServiceOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	order, err := h.service.Update(r.Context(), id, &req)
+	if err != nil {
+		respondServiceOrderWorkError(w, err, "Ошибка обновления сервисного заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// AddPart списывает запчасть со склада в заказ. Нехватка на складе - 409.
+func (h *ServiceOrderWorkHandler) AddPart(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.ServiceOrderPartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	order, err := h.service.AddPart(r.Context(), id, &req)
+	if err != nil {
+		respondServiceOrderWorkError(w, err, "Ошибка добавления запчасти")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: order})
+}
+
+// RemovePart возвращает запчасть из заказа на склад
+func (h *ServiceOrderWorkHandler) RemovePart(w http.ResponseWriter, r *http.Request) {
+	id, lineID, ok := orderLineIDs(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.RemovePart(r.Context(), id, lineID)
+	if err != nil {
+		respondServiceOrderWorkError(w, err, "Ошибка удаления запчасти")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// AddLabour добавляет работу в заказ
+func (h *ServiceOrderWorkHandler) AddLabour(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ServiceOrderLabourRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	order, err := h.service.AddLabour(r.Context(), id, employeeID, &req)
+	if err != nil {
+		respondServiceOrderWorkError(w, err, "Ошибка добавления работы")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: order})
+}
+
+// RemoveLabour удаляет работу из заказа
+func (h *ServiceOrderWorkHandler) RemoveLabour(w http.ResponseWriter, r *http.Request) {
+	id, lineID, ok := orderLineIDs(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.service.RemoveLabour(r.Context(), id, lineID)
+	if err != nil {
+		respondServiceOrderWorkError(w, err, "Ошибка удаления работы")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// Complete завершает заказ: дата завершения - сегодня, дальше заказ не меняется
+func (h *ServiceOrderWorkHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	order, err := h.service.Complete(r.Context(), id)
+	if err != nil {
+		respondServiceOrderWorkError(w, err, "Ошибка завершения сервисного заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, order)
+}
+
+// orderLineIDs разбирает ID заказа и строки заказа из пути
+func orderLineIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return 0, 0, false
+	}
+	lineID, err := strconv.Atoi(vars["lineId"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID строки заказа")
+		return 0, 0, false
+	}
+	return id, lineID, true
+}
+
+func respondServiceOrderWorkError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidServiceOrderWork):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrServiceOrderReference):
+		utils.RespondError(w, http.StatusBadRequest, "Сотрудник не найден")
+	case errors.Is(err, repository.ErrServiceOrderNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Сервисный заказ не найден")
+	case errors.Is(err, repository.ErrServiceOrderLineNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Строка не найдена в заказе")
+	case errors.Is(err, repository.ErrSparePartNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Запчасть не найдена")
+	case errors.Is(err, repository.ErrInsufficientStock):
+		utils.RespondError(w, http.StatusConflict, "Недостаточно запчастей на складе")
+	case errors.Is(err, repository.ErrServiceOrderClosed):
+		utils.RespondError(w, http.StatusConflict, "Заказ завершён или отменён и не изменяется")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
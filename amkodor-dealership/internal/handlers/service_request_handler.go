@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ServiceRequestHandler - заявки клиентов на сервис и их разбор сотрудниками
+type ServiceRequestHandler struct {
+	service *service.ServiceRequestService
+}
+
+func NewServiceRequestHandler(service *service.ServiceRequestService) *ServiceRequestHandler {
+	return &ServiceRequestHandler{service: service}
+}
+
+// GetMine возвращает заявки текущего пользователя
+func (h *ServiceRequestHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	requests, err := h.service.GetByUser(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения заявок")
+		return
+	}
+
+	utils.RespondSuccess(w, requests)
+}
+
+// Create создаёт заявку от имени текущего пользователя
+func (h *ServiceRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateServiceRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	sr, err := h.service.Create(r.Context(), userID, &req)
+	if err != nil {
+		respondServiceRequestError(w, err, "Ошибка создания заявки")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: sr})
+}
+
+// Cancel отменяет заявку текущего пользователя
+func (h *ServiceRequestHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	if err := h.service.Cancel(r.Context(), id, userID); err != nil {
+		respondServiceRequestError(w, err, "Ошибка отмены заявки")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Заявка отменена")
+}
+
+// GetAll возвращает заявки всех клиентов, ?status= фильтрует по статусу
+func (h *ServiceRequestHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	requests, err := h.service.GetAll(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		respondServiceRequestError(w, err, "Ошибка получения заявок")
+		return
+	}
+
+	utils.RespondSuccess(w, requests)
+}
+
+// GetByID возвращает заявку по ID
+func (h *ServiceRequestHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	sr, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		respondServiceRequestError(w, err, "Ошибка получения заявки")
+		return
+	}
+
+	utils.RespondSuccess(w, sr)
+}
+
+// Convert создаёт по заявке сервисный заказ
+func (h *ServiceRequestHandler) Convert(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ConvertServiceRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+	// Мастер по умолчанию - сотрудник, принявший заявку
+	if req.EmployeeID == 0 {
+		req.EmployeeID = employeeID
+	}
+
+	sr, err := h.service.Convert(r.Context(), id, employeeID, &req)
+	if err != nil {
+		respondServiceRequestError(w, err, "Ошибка создания сервисного заказа")
+		return
+	}
+
+	utils.RespondSuccess(w, sr)
+}
+
+// Reject отклоняет заявку с комментарием
+func (h *ServiceRequestHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RejectServiceRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	sr, err := h.service.Reject(r.Context(), id, employeeID, req.Comment)
+	if err != nil {
+		respondServiceRequestError(w, err, "Ошибка отклонения заявки")
+		return
+	}
+
+	utils.RespondSuccess(w, sr)
+}
+
+func respondServiceRequestError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidServiceRequest):
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные заявки")
+	case errors.Is(err, repository.ErrServiceRequestNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Заявка не найдена")
+	case errors.Is(err, repository.ErrServiceRequestNotPending):
+		utils.RespondError(w, http.StatusConflict, "Заявка уже обработана")
+	case errors.Is(err, repository.ErrServiceOrderReference):
+		utils.RespondError(w, http.StatusBadRequest, "Техника, мастер или клиент не найдены")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
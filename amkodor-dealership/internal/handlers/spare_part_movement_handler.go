@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// SparePartMovementHandler - журнал движений запчастей
+type SparePartMovementHandler struct {
+	service *service.SparePartMovementService
+}
+
+func NewSparePartMovementHandler(service *service.SparePartMovementService) *SparePartMovementHandler {
+	return &SparePartMovementHandler{service: service}
+}
+
+// GetHistory возвращает историю движений запчасти. ?movement_type= ограничивает типом.
+func (h *SparePartMovementHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	page, perPage := pageParams(r)
+
+	movements, pagination, err := h.service.GetHistory(r.Context(), id, r.URL.Query().Get("movement_type"), page, perPage)
+	if err != nil {
+		respondMovementError(w, err, "Ошибка получения движений запчасти")
+		return
+	}
+
+	utils.RespondPaginated(w, movements, pagination)
+}
+
+// Record записывает корректировку, списание или перемещение запчасти
+func (h *SparePartMovementHandler) Record(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SparePartMovementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	movementID, err := h.service.Record(r.Context(), id, employeeID, &req)
+	if err != nil {
+		respondMovementError(w, err, "Ошибка записи движения запчасти")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: map[string]int64{"id": movementID}})
+}
+
+// GetStock возвращает остатки запчастей по журналу. ?warehouse_id= ограничивает складом.
+func (h *SparePartMovementHandler) GetStock(w http.ResponseWriter, r *http.Request) {
+	stock, err := h.service.GetStock(r.Context(), parseIntParam(r.URL.Query().Get("warehouse_id")))
+	if err != nil {
+		respondMovementError(w, err, "Ошибка получения остатков")
+		return
+	}
+
+	utils.RespondSuccess(w, stock)
+}
+
+// Reconcile возвращает расхождения хранимых остатков с журналом
+func (h *SparePartMovementHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	drift, err := h.service.Reconcile(r.Context(), parseIntParam(r.URL.Query().Get("warehouse_id")))
+	if err != nil {
+		respondMovementError(w, err, "Ошибка сверки остатков")
+		return
+	}
+
+	utils.RespondSuccess(w, drift)
+}
+
+func respondMovementError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMovement):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrSparePartNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Запчасть не найдена")
+	case errors.Is(err, repository.ErrTransferWarehouse):
+		utils.RespondError(w, http.StatusBadRequest, "Перемещение возможно только на запчасть другого склада")
+	case errors.Is(err, repository.ErrInsufficientStock):
+		utils.RespondError(w, http.StatusConflict, "Недостаточно запчастей на складе")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// StockAlertHandler - оповещения о низком остатке запчастей
+type StockAlertHandler struct {
+	service *service.StockAlertService
+}
+
+func NewStockAlertHandler(service *service.StockAlertService) *StockAlertHandler {
+	return &StockAlertHandler{service: service}
+}
+
+// GetAll возвращает оповещения. ?status= active | open | acknowledged | resolved | all,
+// ?warehouse_id= ограничивает складом.
+func (h *StockAlertHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	alerts, err := h.service.GetAll(r.Context(), query.Get("status"), parseIntParam(query.Get("warehouse_id")))
+	if err != nil {
+		respondStockAlertError(w, err, "Ошибка получения оповещений")
+		return
+	}
+
+	utils.RespondSuccess(w, alerts)
+}
+
+// Acknowledge принимает оповещение в работу
+func (h *StockAlertHandler) Acknowledge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	alert, err := h.service.Acknowledge(r.Context(), id, employeeID)
+	if err != nil {
+		respondStockAlertError(w, err, "Ошибка принятия оповещения")
+		return
+	}
+
+	utils.RespondSuccess(w, alert)
+}
+
+// Resolve закрывает оповещение
+func (h *StockAlertHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	alert, err := h.service.Resolve(r.Context(), id, employeeID)
+	if err != nil {
+		respondStockAlertError(w, err, "Ошибка закрытия оповещения")
+		return
+	}
+
+	utils.RespondSuccess(w, alert)
+}
+
+func respondStockAlertError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidStockAlertStatus):
+		utils.RespondError(w, http.StatusBadRequest, "Неверный статус оповещения")
+	case errors.Is(err, repository.ErrStockAlertNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Оповещение не найдено")
+	case errors.Is(err, repository.ErrStockAlertState):
+		utils.RespondError(w, http.StatusConflict, "Оповещение уже принято или закрыто")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// SupplyHandler - поставки техники от производителей
+type SupplyHandler struct {
+	service *service.SupplyService
+}
+
+func NewSupplyHandler(service *service.SupplyService) *SupplyHandler {
+	return &SupplyHandler{service: service}
+}
+
+// GetAll возвращает поставки. Фильтры: ?status=, ?warehouse_id=, ?manufacturer_id=.
+func (h *SupplyHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	supplies, err := h.service.GetAll(r.Context(), query.Get("status"),
+		parseIntParam(query.Get("warehouse_id")), parseIntParam(query.Get("manufacturer_id")))
+	if err != nil {
+		respondSupplyError(w, err, "Ошибка получения поставок")
+		return
+	}
+
+	utils.RespondSuccess(w, supplies)
+}
+
+// GetByID возвращает поставку со строками и заведённой техникой
+func (h *SupplyHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	supply, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		respondSupplyError(w, err, "Ошибка получения поставки")
+		return
+	}
+
+	utils.RespondSuccess(w, supply)
+}
+
+// Create оформляет заказ техники у производителя
+func (h *SupplyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SupplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	supply, err := h.service.Create(r.Context(), employeeID, &req)
+	if err != nil {
+		respondSupplyError(w, err, "Ошибка создания поставки")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: supply})
+}
+
+// Ship отмечает отгрузку поставки. В теле можно передать номер накладной
+// и единицы техники, известные на момент отгрузки.
+func (h *SupplyHandler) Ship(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.SupplyUnitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	supply, err := h.service.Ship(r.Context(), id, &req)
+	if err != nil {
+		respondSupplyError(w, err, "Ошибка отгрузки поставки")
+		return
+	}
+
+	utils.RespondSuccess(w, supply)
+}
+
+// Receive принимает поставку на склад. В теле передаются единицы техники,
+// не заведённые при отгрузке.
+func (h *SupplyHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SupplyUnitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	supply, err := h.service.Receive(r.Context(), id, employeeID, &req)
+	if err != nil {
+		respondSupplyError(w, err, "Ошибка приёма поставки")
+		return
+	}
+
+	utils.RespondSuccess(w, supply)
+}
+
+// Cancel отменяет поставку
+func (h *SupplyHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	supply, err := h.service.Cancel(r.Context(), id)
+	if err != nil {
+		respondSupplyError(w, err, "Ошибка отмены поставки")
+		return
+	}
+
+	utils.RespondSuccess(w, supply)
+}
+
+func respondSupplyError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSupply):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrSupplyNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Поставка не найдена")
+	case errors.Is(err, repository.ErrSupplyReference), errors.Is(err, repository.ErrWarehouseNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Производитель или склад не найдены")
+	case errors.Is(err, repository.ErrSupplyModel):
+		utils.RespondError(w, http.StatusBadRequest, "Модель не выпускается производителем поставки")
+	case errors.Is(err, repository.ErrSupplyUnits):
+		utils.RespondError(w, http.StatusBadRequest, "Единицы техники не соответствуют строкам поставки")
+	case errors.Is(err, repository.ErrSupplyState):
+		utils.RespondError(w, http.StatusConflict, "Действие недоступно в текущем статусе поставки")
+	case errors.Is(err, repository.ErrSupplyIncomplete), errors.Is(err, repository.ErrWarehouseCapacity),
+		errors.Is(err, repository.ErrDuplicateVIN):
+		utils.RespondError(w, http.StatusConflict, err.Error())
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// TestDriveHandler - запись на тест-драйвы сотрудниками и клиентами
+type TestDriveHandler struct {
+	service *service.TestDriveService
+}
+
+func NewTestDriveHandler(service *service.TestDriveService) *TestDriveHandler {
+	return &TestDriveHandler{service: service}
+}
+
+// GetAll возвращает тест-драйвы. Фильтры: ?status=, ?vehicle_id=, ?employee_id=, ?date=YYYY-MM-DD.
+func (h *TestDriveHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	drives, err := h.service.GetAll(r.Context(), query.Get("status"),
+		parseIntParam(query.Get("vehicle_id")), parseIntParam(query.Get("employee_id")), query.Get("date"))
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка получения тест-драйвов")
+		return
+	}
+
+	utils.RespondSuccess(w, drives)
+}
+
+// GetByID возвращает тест-драйв
+func (h *TestDriveHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	td, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка получения тест-драйва")
+		return
+	}
+
+	utils.RespondSuccess(w, td)
+}
+
+// Availability возвращает свободные слоты.
+// Параметры: ?vehicle_id=, ?employee_id= (хотя бы один), ?date=YYYY-MM-DD, ?duration= в минутах.
+func (h *TestDriveHandler) Availability(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	h.respondAvailability(w, r, parseIntParam(query.Get("vehicle_id")), parseIntParam(query.Get("employee_id")))
+}
+
+// VehicleSlots возвращает свободные слоты тест-драйва техники для каталога.
+// Параметры: ?date=YYYY-MM-DD, ?duration= в минутах.
+func (h *TestDriveHandler) VehicleSlots(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	h.respondAvailability(w, r, &id, nil)
+}
+
+func (h *TestDriveHandler) respondAvailability(w http.ResponseWriter, r *http.Request, vehicleID, employeeID *int) {
+	query := r.URL.Query()
+	duration := 0
+	if v := parseIntParam(query.Get("duration")); v != nil {
+		duration = *v
+	}
+
+	availability, err := h.service.Availability(r.Context(), vehicleID, employeeID, query.Get("date"), duration)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка получения свободного времени")
+		return
+	}
+
+	utils.RespondSuccess(w, availability)
+}
+
+// Create записывает клиента на тест-драйв
+func (h *TestDriveHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.BookTestDriveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	td, err := h.service.Book(r.Context(), &req)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка записи на тест-драйв")
+		return
+	}
+	td.CalendarURL = fmt.Sprintf("/api/admin/test-drives/%d/calendar.ics", td.ID)
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: td})
+}
+
+// Reschedule переносит тест-драйв
+func (h *TestDriveHandler) Reschedule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.RescheduleTestDriveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	td, err := h.service.Reschedule(r.Context(), id, &req)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка переноса тест-драйва")
+		return
+	}
+	td.CalendarURL = fmt.Sprintf("/api/admin/test-drives/%d/calendar.ics", td.ID)
+
+	utils.RespondSuccess(w, td)
+}
+
+// Cancel отменяет тест-драйв. Тело с причиной необязательно.
+func (h *TestDriveHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.CancelTestDriveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	td, err := h.service.Cancel(r.Context(), id, req.Reason)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка отмены тест-драйва")
+		return
+	}
+
+	utils.RespondSuccess(w, td)
+}
+
+// Complete отмечает, что тест-драйв состоялся
+func (h *TestDriveHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	td, err := h.service.Complete(r.Context(), id)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка завершения тест-драйва")
+		return
+	}
+
+	utils.RespondSuccess(w, td)
+}
+
+// NoShow отмечает неявку клиента
+func (h *TestDriveHandler) NoShow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	td, err := h.service.NoShow(r.Context(), id)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка отметки неявки")
+		return
+	}
+
+	utils.RespondSuccess(w, td)
+}
+
+// BookMine записывает на тест-драйв клиента текущего пользователя
+func (h *TestDriveHandler) BookMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SelfBookTestDriveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	td, err := h.service.SelfBook(r.Context(), userID, &req)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка записи на тест-драйв")
+		return
+	}
+	td.CalendarURL = fmt.Sprintf("/api/user/test-drives/%d/calendar.ics", td.ID)
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: td})
+}
+
+// CancelMine отменяет тест-драйв клиента текущего пользователя
+func (h *TestDriveHandler) CancelMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var req models.CancelTestDriveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	td, err := h.service.CancelMine(r.Context(), userID, id, req.Reason)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка отмены тест-драйва")
+		return
+	}
+
+	utils.RespondSuccess(w, td)
+}
+
+// GetWorkingHours возвращает рабочее время менеджера
+func (h *TestDriveHandler) GetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	hours, err := h.service.GetWorkingHours(r.Context(), id)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка получения рабочего времени")
+		return
+	}
+
+	utils.RespondSuccess(w, hours)
+}
+
+// SetWorkingHours заменяет рабочее время менеджера
+func (h *TestDriveHandler) SetWorkingHours(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	var hours []models.WorkingHours
+	if err := json.NewDecoder(r.Body).Decode(&hours); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	saved, err := h.service.SetWorkingHours(r.Context(), id, hours)
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка сохранения рабочего времени")
+		return
+	}
+
+	utils.RespondSuccess(w, saved)
+}
+
+// GetFeedback возвращает тест-драйв по ссылке на отзыв. Доступно без входа:
+// доступ даёт токен из ссылки.
+func (h *TestDriveHandler) GetFeedback(w http.ResponseWriter, r *http.Request) {
+	form, err := h.service.GetFeedbackForm(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		respondTestDriveError(w, err, "Ошибка получения тест-драйва")
+		return
+	}
+
+	utils.RespondSuccess(w, form)
+}
+
+// SubmitFeedback сохраняет оценку и комментарий клиента по ссылке на отзыв
+func (h *TestDriveHandler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	var req models.TestDriveFeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	if err := h.service.SubmitFeedback(r.Context(), mux.Vars(r)["token"], &req); err != nil {
+		respondTestDriveError(w, err, "Ошибка сохранения отзыва")
+		return
+	}
+
+	utils.RespondSuccess(w, map[string]string{"message": "Спасибо за отзыв"})
+}
+
+func respondTestDriveError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTestDrive):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrTestDriveInPast):
+		utils.RespondError(w, http.StatusBadRequest, "Тест-драйв можно назначить только на будущее время")
+	case errors.Is(err, service.ErrTestDriveOutsideHours):
+		utils.RespondError(w, http.StatusBadRequest, "Время вне рабочего времени менеджера")
+	case errors.Is(err, service.ErrTestDriveManager):
+		utils.RespondError(w, http.StatusBadRequest, "Сотрудник не проводит тест-драйвы")
+	case errors.Is(err, repository.ErrTestDriveReference):
+		utils.RespondError(w, http.StatusBadRequest, "Клиент или менеджер не найден")
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Техника не найдена")
+	case errors.Is(err, repository.ErrClientLinkNotFound):
+		utils.RespondError(w, http.StatusForbidden, "Учётная запись не привязана к клиенту")
+	case errors.Is(err, repository.ErrTestDriveNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Тест-драйв не найден")
+	case errors.Is(err, repository.ErrTestDriveVehicleBusy):
+		utils.RespondError(w, http.StatusConflict, "На это время техника уже записана на тест-драйв")
+	case errors.Is(err, repository.ErrTestDriveManagerBusy):
+		utils.RespondError(w, http.StatusConflict, "У менеджера на это время уже есть тест-драйв")
+	case errors.Is(err, service.ErrNoFreeManager):
+		utils.RespondError(w, http.StatusConflict, "На это время нет свободного менеджера")
+	case errors.Is(err, repository.ErrTestDriveVehicle):
+		utils.RespondError(w, http.StatusConflict, "Техника недоступна для тест-драйва")
+	case errors.Is(err, repository.ErrTestDriveState):
+		utils.RespondError(w, http.StatusConflict, "Тест-драйв уже завершён или отменён")
+	case errors.Is(err, service.ErrTestDriveNotStarted):
+		utils.RespondError(w, http.StatusConflict, "Тест-драйв ещё не начался")
+	case errors.Is(err, service.ErrInvalidFeedback):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrFeedbackLinkNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Ссылка на отзыв не найдена")
+	case errors.Is(err, repository.ErrFeedbackSubmitted):
+		utils.RespondError(w, http.StatusConflict, "Отзыв по этой ссылке уже оставлен")
+	case errors.Is(err, repository.ErrFeedbackLinkExpired):
+		utils.RespondError(w, http.StatusGone, "Срок действия ссылки на отзыв истёк")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
@@ -2,80 +2,277 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"sync"
-	"time"
+	"strconv"
 
+	"amkodor-dealership/internal/middleware"
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
 	"amkodor-dealership/internal/utils"
-)
 
-// Глобальное хранилище для сервисных заявок (временное решение)
-var (
-	serviceRequestsStore = make(map[int]map[string]interface{})
-	serviceRequestsMutex sync.RWMutex
-	nextServiceRequestID = 1
+	"github.com/gorilla/mux"
 )
 
 type UserHandler struct {
-	// Здесь можно добавить зависимости от сервисов
+	account *service.AccountService
+}
+
+func NewUserHandler(account *service.AccountService) *UserHandler {
+	return &UserHandler{account: account}
+}
+
+// currentUserID возвращает ID пользователя из токена или отвечает 401
+func currentUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusUnauthorized, "Требуется авторизация")
+	}
+	return userID, ok
 }
 
-func NewUserHandler() *UserHandler {
-	return &UserHandler{}
+// currentEmployeeID возвращает ID сотрудника из токена или отвечает 403
+func currentEmployeeID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	employeeID, ok := middleware.GetEmployeeIDFromContext(r.Context())
+	if !ok {
+		utils.RespondError(w, http.StatusForbidden, "Действие доступно только сотрудникам")
+	}
+	return employeeID, ok
 }
 
 // GetUserStats получение статистики пользователя
 func (h *UserHandler) GetUserStats(w http.ResponseWriter, r *http.Request) {
-	// Заглушка для демонстрации
-	stats := map[string]interface{}{
-		"totalOrders":   3,
-		"favoriteItems": 5,
-		"serviceVisits": 2,
-		"totalSpent":    450000,
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.account.GetStats(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения статистики")
+		return
 	}
 
 	utils.RespondSuccess(w, stats)
 }
 
-// GetUserOrders получение заказов пользователя
+// GetUserOrders получение покупок привязанного клиента
 func (h *UserHandler) GetUserOrders(w http.ResponseWriter, r *http.Request) {
-	// Заглушка для демонстрации
-	orders := []map[string]interface{}{
-		{
-			"id":     1,
-			"number": "ORD-2024-001",
-			"date":   "2024-01-15",
-			"status": "processing",
-			"items": []map[string]interface{}{
-				{
-					"name":  "Экскаватор АМКОДОР 3316",
-					"specs": "2023 год, Дизель, 160 л.с.",
-					"price": 150000,
-					"image": "🚜",
-				},
-			},
-			"total": 150000,
-		},
-		{
-			"id":     2,
-			"number": "ORD-2024-002",
-			"date":   "2024-01-10",
-			"status": "delivered",
-			"items": []map[string]interface{}{
-				{
-					"name":  "Бульдозер АМКОДОР 3317",
-					"specs": "2023 год, Дизель, 180 л.с.",
-					"price": 200000,
-					"image": "🚜",
-				},
-			},
-			"total": 200000,
-		},
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	orders, err := h.account.GetOrders(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения заказов")
+		return
+	}
+
+	utils.RespondSuccess(w, orders)
+}
+
+// GetUserTestDrives получение тест-драйвов привязанного клиента
+func (h *UserHandler) GetUserTestDrives(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	drives, err := h.account.GetTestDrives(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения тест-драйвов")
+		return
+	}
+
+	utils.RespondSuccess(w, drives)
+}
+
+// GetUserServiceOrders получение сервисных заказов привязанного клиента
+func (h *UserHandler) GetUserServiceOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	orders, err := h.account.GetServiceOrders(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения сервисных заказов")
+		return
 	}
 
 	utils.RespondSuccess(w, orders)
 }
 
+// GetUserFleet получение техники клиента (для юр. лица - парк компании)
+func (h *UserHandler) GetUserFleet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	fleet, err := h.account.GetFleet(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения техники")
+		return
+	}
+
+	utils.RespondSuccess(w, fleet)
+}
+
+// GetClientLink возвращает привязку к клиенту; data = null, если её нет
+func (h *UserHandler) GetClientLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	link, err := h.account.GetLink(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения привязки")
+		return
+	}
+
+	utils.RespondSuccess(w, link)
+}
+
+// ClaimClientLink ищет клиента по телефону или email и отправляет код подтверждения
+func (h *UserHandler) ClaimClientLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ClientLinkClaimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	claim, err := h.account.StartClaim(r.Context(), userID, &req)
+	if err != nil {
+		respondClientLinkError(w, err, "Ошибка создания запроса на привязку")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: claim})
+}
+
+// ConfirmClientLink подтверждает привязку кодом
+func (h *UserHandler) ConfirmClientLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ClientLinkConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	link, err := h.account.ConfirmClaim(r.Context(), userID, &req)
+	if err != nil {
+		respondClientLinkError(w, err, "Ошибка подтверждения привязки")
+		return
+	}
+
+	utils.RespondSuccess(w, link)
+}
+
+// GetUserClientLink возвращает привязку пользователя (для сотрудников)
+func (h *UserHandler) GetUserClientLink(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	link, err := h.account.GetLink(r.Context(), userID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения привязки")
+		return
+	}
+
+	utils.RespondSuccess(w, link)
+}
+
+// LinkUserClient привязывает пользователя к клиенту по решению сотрудника
+func (h *UserHandler) LinkUserClient(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.LinkClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	link, err := h.account.LinkByStaff(r.Context(), userID, employeeID, &req)
+	if err != nil {
+		respondClientLinkError(w, err, "Ошибка привязки клиента")
+		return
+	}
+
+	utils.RespondSuccess(w, link)
+}
+
+// UnlinkUserClient снимает привязку пользователя к клиенту
+func (h *UserHandler) UnlinkUserClient(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	if err := h.account.Unlink(r.Context(), userID); err != nil {
+		respondClientLinkError(w, err, "Ошибка удаления привязки")
+		return
+	}
+
+	utils.RespondMessage(w, http.StatusOK, "Привязка удалена")
+}
+
+func respondClientLinkError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidClientLink):
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные привязки")
+	case errors.Is(err, service.ErrClientNotMatched):
+		utils.RespondError(w, http.StatusNotFound, "Клиент с такими контактами не найден")
+	case errors.Is(err, service.ErrClientMatchAmbiguous):
+		utils.RespondError(w, http.StatusConflict, "Контакт указан у нескольких клиентов, обратитесь к менеджеру")
+	case errors.Is(err, service.ErrUserAlreadyLinked):
+		utils.RespondError(w, http.StatusConflict, "Учётная запись уже привязана к клиенту")
+	case errors.Is(err, repository.ErrClientAlreadyLinked):
+		utils.RespondError(w, http.StatusConflict, "Клиент уже привязан к другой учётной записи")
+	case errors.Is(err, service.ErrClaimExpired):
+		utils.RespondError(w, http.StatusGone, "Срок действия кода истёк, запросите новый")
+	case errors.Is(err, service.ErrClaimCodeMismatch):
+		utils.RespondError(w, http.StatusBadRequest, "Неверный код подтверждения")
+	case errors.Is(err, service.ErrClaimAttemptsExceeded):
+		utils.RespondError(w, http.StatusTooManyRequests, "Превышено число попыток, запросите новый код")
+	case errors.Is(err, repository.ErrClientClaimNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Запрос на привязку не найден")
+	case errors.Is(err, repository.ErrClientLinkNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Привязка не найдена")
+	case errors.Is(err, repository.ErrLinkedClientMissing):
+		utils.RespondError(w, http.StatusNotFound, "Клиент не найден")
+	case errors.Is(err, repository.ErrLinkedUserMissing):
+		utils.RespondError(w, http.StatusNotFound, "Пользователь не найден")
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
+
 // GetUserFavorites получение избранного пользователя
 func (h *UserHandler) GetUserFavorites(w http.ResponseWriter, r *http.Request) {
 	// Заглушка для демонстрации
@@ -138,119 +335,6 @@ func (h *UserHandler) RemoveFromFavorites(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// GetServiceRequests получение заявок на сервис
-func (h *UserHandler) GetServiceRequests(w http.ResponseWriter, r *http.Request) {
-	// Базовые тестовые данные
-	baseRequests := []map[string]interface{}{
-		{
-			"id":          1,
-			"title":       "Техническое обслуживание экскаватора",
-			"description": "Плановое ТО экскаватора АМКОДОР 3316",
-			"date":        "2025-01-15",
-			"status":      "pending",
-		},
-		{
-			"id":          2,
-			"title":       "Ремонт гидравлики",
-			"description": "Замена гидроцилиндра подъема стрелы",
-			"date":        "2025-01-10",
-			"status":      "in-progress",
-		},
-	}
-
-	// Получаем созданные пользователями заявки
-	serviceRequestsMutex.RLock()
-	userRequests := make([]map[string]interface{}, 0, len(serviceRequestsStore))
-	for _, request := range serviceRequestsStore {
-		userRequests = append(userRequests, request)
-	}
-	serviceRequestsMutex.RUnlock()
-
-	// Объединяем базовые и пользовательские заявки
-	allRequests := append(baseRequests, userRequests...)
-
-	utils.RespondSuccess(w, allRequests)
-}
-
-// CreateServiceRequest создание заявки на сервис
-func (h *UserHandler) CreateServiceRequest(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		ServiceType string `json:"service_type"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
-		return
-	}
-
-	// Сохраняем заявку в глобальное хранилище
-	serviceRequestsMutex.Lock()
-	id := nextServiceRequestID
-	nextServiceRequestID++
-
-	serviceRequestsStore[id] = map[string]interface{}{
-		"id":           id,
-		"title":        req.Title,
-		"description":  req.Description,
-		"service_type": req.ServiceType,
-		"date":         time.Now().Format("2006-01-02"),
-		"status":       "pending",
-	}
-	serviceRequestsMutex.Unlock()
-
-	utils.RespondSuccess(w, map[string]interface{}{
-		"id":      id,
-		"message": "Заявка на сервис создана",
-	})
-}
-
-// GetAllServiceRequests получение всех сервисных заявок для админа
-func (h *UserHandler) GetAllServiceRequests(w http.ResponseWriter, r *http.Request) {
-	// Базовые тестовые данные
-	baseRequests := []map[string]interface{}{
-		{
-			"id":          1,
-			"title":       "Техническое обслуживание экскаватора",
-			"description": "Плановое ТО экскаватора АМКОДОР 3316",
-			"date":        "2025-01-15",
-			"status":      "pending",
-			"customer":    "Иван Иванов",
-			"phone":       "+375 29 123-45-67",
-		},
-		{
-			"id":          2,
-			"title":       "Ремонт гидравлики",
-			"description": "Замена гидроцилиндра подъема стрелы",
-			"date":        "2025-01-10",
-			"status":      "in-progress",
-			"customer":    "Петр Петров",
-			"phone":       "+375 29 234-56-78",
-		},
-	}
-
-	// Получаем созданные пользователями заявки
-	serviceRequestsMutex.RLock()
-	userRequests := make([]map[string]interface{}, 0, len(serviceRequestsStore))
-	for _, request := range serviceRequestsStore {
-		// Добавляем информацию о клиенте для админа
-		adminRequest := make(map[string]interface{})
-		for k, v := range request {
-			adminRequest[k] = v
-		}
-		adminRequest["customer"] = "Клиент"
-		adminRequest["phone"] = "Не указан"
-		userRequests = append(userRequests, adminRequest)
-	}
-	serviceRequestsMutex.RUnlock()
-
-	// Объединяем базовые и пользовательские заявки
-	allRequests := append(baseRequests, userRequests...)
-
-	utils.RespondSuccess(w, allRequests)
-}
-
 // GetUserProfile получение профиля пользователя
 func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	// Заглушка для демонстрации
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -9,115 +10,55 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 
+	"amkodor-dealership/internal/config"
 	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
 	"amkodor-dealership/internal/service"
 	"amkodor-dealership/internal/utils"
 )
 
-// Глобальное хранилище для техники (временное решение)
-var (
-	vehiclesStore = make(map[int]models.CreateVehicleRequest)
-	vehiclesMutex sync.RWMutex
-	nextVehicleID = 1
-)
-
 type VehicleHandler struct {
 	service *service.VehicleService
+	upload  config.UploadConfig
 }
 
-func NewVehicleHandler(service *service.VehicleService) *VehicleHandler {
+func NewVehicleHandler(service *service.VehicleService, upload config.UploadConfig) *VehicleHandler {
 	return &VehicleHandler{
 		service: service,
+		upload:  upload,
 	}
 }
 
-// GetAll получение всех автомобилей
+// GetAll получение страницы каталога техники
 func (h *VehicleHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	log.Println("GetAll: получен запрос на получение всех автомобилей")
-
-	// Базовые тестовые данные
-	baseVehicles := []map[string]interface{}{
-		{
-			"id":       1,
-			"name":     "Экскаватор АМКОДОР 3316",
-			"category": "Экскаваторы",
-			"price":    150000,
-			"status":   "В наличии",
-			"year":     2025,
-			"engine":   "Дизель",
-			"power":    "160 л.с.",
-			"weight":   "16 т",
-			"image":    "🚜",
-		},
-		{
-			"id":       2,
-			"name":     "Бульдозер АМКОДОР 3317",
-			"category": "Бульдозеры",
-			"price":    200000,
-			"status":   "В наличии",
-			"year":     2025,
-			"engine":   "Дизель",
-			"power":    "180 л.с.",
-			"weight":   "17 т",
-			"image":    "🚜",
-		},
-		{
-			"id":       3,
-			"name":     "Погрузчик АМКОДОР 3318",
-			"category": "Погрузчики",
-			"price":    120000,
-			"status":   "В наличии",
-			"year":     2025,
-			"engine":   "Дизель",
-			"power":    "140 л.с.",
-			"weight":   "14 т",
-			"image":    "🚜",
-		},
-		{
-			"id":       4,
-			"name":     "Кран АМКОДОР 3319",
-			"category": "Краны",
-			"price":    300000,
-			"status":   "В наличии",
-			"year":     2025,
-			"engine":   "Дизель",
-			"power":    "200 л.с.",
-			"weight":   "25 т",
-			"image":    "🚜",
-		},
-	}
+	query := r.URL.Query()
 
-	// Получаем добавленную админом технику
-	vehiclesMutex.RLock()
-	addedVehicles := make([]map[string]interface{}, 0, len(vehiclesStore))
-	for id, vehicle := range vehiclesStore {
-		addedVehicles = append(addedVehicles, map[string]interface{}{
-			"id":       id,
-			"name":     vehicle.Name,
-			"category": vehicle.Category,
-			"price":    vehicle.Price,
-			"status":   vehicle.Status,
-			"year":     vehicle.Year,
-			"engine":   vehicle.Engine,
-			"power":    vehicle.Power,
-			"weight":   vehicle.Weight,
-			"image":    vehicle.Image,
-		})
+	page := 1
+	if p := parseIntParam(query.Get("page")); p != nil && *p > 0 {
+		page = *p
 	}
-	vehiclesMutex.RUnlock()
 
-	// Объединяем базовые и добавленные данные
-	allVehicles := append(baseVehicles, addedVehicles...)
+	perPage := 50
+	if pp := parseIntParam(query.Get("per_page")); pp != nil && *pp > 0 {
+		perPage = *pp
+	}
+	if perPage > 200 {
+		perPage = 200
+	}
 
-	log.Printf("GetAll: возвращаем %d автомобилей (%d базовых + %d добавленных)",
-		len(allVehicles), len(baseVehicles), len(addedVehicles))
+	vehicles, pagination, err := h.service.GetCatalog(r.Context(), query.Get("status"), page, perPage)
+	if err != nil {
+		log.Printf("GetAll: ошибка получения каталога: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка получения каталога")
+		return
+	}
 
-	utils.RespondSuccess(w, allVehicles)
+	utils.RespondPaginated(w, vehicles, pagination)
 }
 
 // GetByID получение автомобиля по ID
@@ -125,39 +66,32 @@ func (h *VehicleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Некорректный ID")
+		utils.RespondError(w, http.StatusBadRequest, "Некорректный ID")
 		return
 	}
 
-	vehicle, err := h.service.GetByID(id)
+	vehicle, err := h.service.GetCatalogVehicle(r.Context(), id)
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusNotFound, "Автомобиль не найден")
+		utils.RespondError(w, http.StatusNotFound, "Автомобиль не найден")
 		return
 	}
 
-	utils.SuccessResponse(w, http.StatusOK, vehicle)
+	utils.RespondSuccess(w, vehicle)
 }
 
 // Create создание нового автомобиля
 func (h *VehicleHandler) Create(w http.ResponseWriter, r *http.Request) {
-	log.Println("Create: получен запрос на создание техники")
-
 	var req models.CreateVehicleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
 		return
 	}
 
-	log.Printf("Create: получены данные: %+v", req)
-
-	// Сохраняем в глобальное хранилище
-	vehiclesMutex.Lock()
-	id := nextVehicleID
-	nextVehicleID++
-	vehiclesStore[id] = req
-	vehiclesMutex.Unlock()
-
-	log.Printf("Create: техника сохранена с ID %d", id)
+	id, err := h.service.CreateFromRequest(r.Context(), &req)
+	if err != nil {
+		respondVehicleError(w, err, "Ошибка создания техники")
+		return
+	}
 
 	utils.RespondSuccess(w, map[string]interface{}{
 		"id":      id,
@@ -171,31 +105,22 @@ func (h *VehicleHandler) Update(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Некорректный ID")
-		return
-	}
-
-	var vehicle models.Vehicle
-	if err := json.NewDecoder(r.Body).Decode(&vehicle); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Некорректные данные")
+		utils.RespondError(w, http.StatusBadRequest, "Некорректный ID")
 		return
 	}
 
-	vehicle.VehicleID = id
-
-	if err := utils.ValidateStruct(&vehicle); err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Ошибка валидации")
+	var req models.CreateVehicleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
 		return
 	}
 
-	if err := h.service.Update(&vehicle); err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка обновления автомобиля")
+	if err := h.service.UpdateFromRequest(r.Context(), id, &req); err != nil {
+		respondVehicleError(w, err, "Ошибка обновления автомобиля")
 		return
 	}
 
-	utils.SuccessResponse(w, http.StatusOK, map[string]string{
-		"message": "Автомобиль успешно обновлён",
-	})
+	utils.RespondMessage(w, http.StatusOK, "Автомобиль успешно обновлён")
 }
 
 // Delete удаление автомобиля
@@ -203,18 +128,42 @@ func (h *VehicleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		utils.ErrorResponse(w, http.StatusBadRequest, "Некорректный ID")
+		utils.RespondError(w, http.StatusBadRequest, "Некорректный ID")
 		return
 	}
 
 	if err := h.service.Delete(id); err != nil {
-		utils.ErrorResponse(w, http.StatusInternalServerError, "Ошибка удаления автомобиля")
+		utils.RespondError(w, http.StatusInternalServerError, "Ошибка удаления автомобиля")
 		return
 	}
 
-	utils.SuccessResponse(w, http.StatusOK, map[string]string{
-		"message": "Автомобиль успешно удалён",
-	})
+	utils.RespondMessage(w, http.StatusOK, "Автомобиль успешно удалён")
+}
+
+// respondVehicleError сопоставляет ошибки сервиса техники с HTTP-статусами
+func respondVehicleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidVehicleRequest):
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные техники")
+	case errors.Is(err, service.ErrVehicleStatusWorkflow):
+		utils.RespondError(w, http.StatusBadRequest,
+			"В каталоге ставится только статус \"В наличии\" или \"В ремонте\": остальные - продажей, бронью, поставкой или перемещением")
+	case errors.Is(err, repository.ErrVehicleStatusLocked):
+		utils.RespondError(w, http.StatusConflict, "Техника продана или забронирована: статус меняется отменой продажи или брони")
+	case errors.Is(err, repository.ErrUnknownVehicleCategory):
+		utils.RespondError(w, http.StatusBadRequest, "Неизвестная категория техники")
+	case errors.Is(err, repository.ErrVehicleModelNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Модель техники не найдена")
+	case errors.Is(err, repository.ErrWarehouseNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Склад не найден")
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Автомобиль не найден")
+	case errors.Is(err, repository.ErrVehicleInTransfer):
+		utils.RespondError(w, http.StatusConflict, "Техника в пути: склад и статус меняются перемещением")
+	default:
+		log.Printf("Ошибка сохранения техники: %v", err)
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
 }
 
 // Search поиск автомобилей по критериям
@@ -283,27 +232,23 @@ func parseFloatParam(param string) *float64 {
 func (h *VehicleHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	log.Println("UploadImage: получен запрос на загрузку изображения")
 
-	// Добавляем CORS заголовки
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-	// Обрабатываем preflight запросы
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
 	// Проверяем метод
 	if r.Method != http.MethodPost {
 		utils.RespondError(w, http.StatusMethodNotAllowed, "Метод не поддерживается")
 		return
 	}
 
-	// Парсим multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB
+	// Ограничиваем размер тела и парсим multipart form
+	r.Body = http.MaxBytesReader(w, r.Body, h.upload.MaxSize)
+	err := r.ParseMultipartForm(h.upload.MaxSize)
 	if err != nil {
 		log.Printf("UploadImage: ошибка парсинга формы: %v", err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("Размер файла превышает %d байт", h.upload.MaxSize))
+			return
+		}
 		utils.RespondError(w, http.StatusBadRequest, "Ошибка парсинга формы")
 		return
 	}
@@ -321,8 +266,8 @@ func (h *VehicleHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 
 	// Проверяем тип файла
 	contentType := handler.Header.Get("Content-Type")
-	if len(contentType) < 5 || contentType[:5] != "image" {
-		utils.RespondError(w, http.StatusBadRequest, "Файл должен быть изображением")
+	if !strings.HasPrefix(contentType, "image/") || !h.isAllowedType(contentType) {
+		utils.RespondError(w, http.StatusBadRequest, "Недопустимый тип файла: "+contentType)
 		return
 	}
 
@@ -331,7 +276,7 @@ func (h *VehicleHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
 
 	// Создаем папку для загрузок, если её нет
-	uploadDir := "web/static/uploads"
+	uploadDir := h.upload.Path
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
 		utils.RespondError(w, http.StatusInternalServerError, "Ошибка создания папки: "+err.Error())
 		return
@@ -354,9 +299,19 @@ func (h *VehicleHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Возвращаем URL изображения
-	imageURL := fmt.Sprintf("/static/uploads/%s", filename)
+	imageURL := fmt.Sprintf("/uploads/%s", filename)
 	utils.RespondSuccess(w, map[string]string{
 		"url":      imageURL,
 		"filename": filename,
 	})
 }
+
+// isAllowedType проверяет MIME-тип по списку upload.allowed_types
+func (h *VehicleHandler) isAllowedType(contentType string) bool {
+	for _, allowed := range h.upload.AllowedTypes {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/service"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// VehicleTransferHandler - перемещение техники между складами
+type VehicleTransferHandler struct {
+	service *service.VehicleTransferService
+}
+
+func NewVehicleTransferHandler(service *service.VehicleTransferService) *VehicleTransferHandler {
+	return &VehicleTransferHandler{service: service}
+}
+
+// GetAll возвращает перемещения. Фильтры: ?status=, ?warehouse_id= (склад
+// отправки или назначения), ?vehicle_id=.
+func (h *VehicleTransferHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	transfers, err := h.service.GetAll(r.Context(), query.Get("status"),
+		parseIntParam(query.Get("warehouse_id")), parseIntParam(query.Get("vehicle_id")))
+	if err != nil {
+		respondVehicleTransferError(w, err, "Ошибка получения перемещений")
+		return
+	}
+
+	utils.RespondSuccess(w, transfers)
+}
+
+// GetByID возвращает перемещение с техникой
+func (h *VehicleTransferHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	transfer, err := h.service.GetByID(r.Context(), id)
+	if err != nil {
+		respondVehicleTransferError(w, err, "Ошибка получения перемещения")
+		return
+	}
+
+	utils.RespondSuccess(w, transfer)
+}
+
+// Create оформляет перемещение техники
+func (h *VehicleTransferHandler) Create(w http.ResponseWriter, r *http.Request) {
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.VehicleTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Некорректные данные")
+		return
+	}
+
+	transfer, err := h.service.Create(r.Context(), employeeID, &req)
+	if err != nil {
+		respondVehicleTransferError(w, err, "Ошибка создания перемещения")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusCreated, utils.Response{Success: true, Data: transfer})
+}
+
+// Dispatch отправляет технику со склада
+func (h *VehicleTransferHandler) Dispatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.Dispatch(r.Context(), id, employeeID)
+	if err != nil {
+		respondVehicleTransferError(w, err, "Ошибка отправки техники")
+		return
+	}
+
+	utils.RespondSuccess(w, transfer)
+}
+
+// Receive принимает технику на складе назначения
+func (h *VehicleTransferHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+	employeeID, ok := currentEmployeeID(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := h.service.Receive(r.Context(), id, employeeID)
+	if err != nil {
+		respondVehicleTransferError(w, err, "Ошибка приёма техники")
+		return
+	}
+
+	utils.RespondSuccess(w, transfer)
+}
+
+// Cancel отменяет перемещение
+func (h *VehicleTransferHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "Неверный ID")
+		return
+	}
+
+	transfer, err := h.service.Cancel(r.Context(), id)
+	if err != nil {
+		respondVehicleTransferError(w, err, "Ошибка отмены перемещения")
+		return
+	}
+
+	utils.RespondSuccess(w, transfer)
+}
+
+func respondVehicleTransferError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidVehicleTransfer):
+		utils.RespondError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, repository.ErrVehicleTransferNotFound):
+		utils.RespondError(w, http.StatusNotFound, "Перемещение не найдено")
+	case errors.Is(err, repository.ErrWarehouseNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Склад не найден")
+	case errors.Is(err, repository.ErrVehicleNotFound):
+		utils.RespondError(w, http.StatusBadRequest, "Техника не найдена")
+	case errors.Is(err, repository.ErrVehicleTransferState):
+		utils.RespondError(w, http.StatusConflict, "Действие недоступно в текущем статусе перемещения")
+	case errors.Is(err, repository.ErrVehicleTransferVehicle), errors.Is(err, repository.ErrWarehouseCapacity):
+		utils.RespondError(w, http.StatusConflict, err.Error())
+	default:
+		utils.RespondError(w, http.StatusInternalServerError, fallback)
+	}
+}
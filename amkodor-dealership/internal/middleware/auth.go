@@ -7,12 +7,19 @@ import (
 	"net/http"
 	"strings"
 
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/utils"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey contextKey = "userID"
+	RoleKey   contextKey = "role"
+	StaffKey  contextKey = "staff"
+)
 
 // AuthMiddleware проверяет JWT токен
 func AuthMiddleware(secret string) func(http.Handler) http.Handler {
@@ -20,14 +27,14 @@ func AuthMiddleware(secret string) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				utils.RespondError(w, http.StatusUnauthorized, "Требуется авторизация")
 				return
 			}
 
 			// Формат: Bearer <token>
 			parts := strings.Split(authHeader, " ")
 			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				utils.RespondError(w, http.StatusUnauthorized, "Неверный формат заголовка авторизации")
 				return
 			}
 
@@ -42,23 +49,61 @@ func AuthMiddleware(secret string) func(http.Handler) http.Handler {
 			})
 
 			if err != nil || !token.Valid {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				utils.RespondError(w, http.StatusUnauthorized, "Недействительный токен")
 				return
 			}
 
 			// Извлечение claims
-			if claims, ok := token.Claims.(jwt.MapClaims); ok {
-				userID := int(claims["user_id"].(float64))
-				ctx := context.WithValue(r.Context(), UserIDKey, userID)
-				next.ServeHTTP(w, r.WithContext(ctx))
-			} else {
-				http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			claims, ok := token.Claims.(jwt.MapClaims)
+			if !ok {
+				utils.RespondError(w, http.StatusUnauthorized, "Недействительный токен")
+				return
+			}
+
+			// Токен сотрудника может не содержать user_id, если у сотрудника
+			// нет связанной учётной записи users
+			rawUserID, hasUser := claims["user_id"].(float64)
+			rawEmployeeID, hasEmployee := claims["employee_id"].(float64)
+			if !hasUser && !hasEmployee {
+				utils.RespondError(w, http.StatusUnauthorized, "Недействительный токен")
 				return
 			}
+
+			// Токены, выпущенные до введения ролей, не содержат role и
+			// не проходят ни одну проверку прав
+			role, _ := claims["role"].(string)
+
+			ctx := context.WithValue(r.Context(), RoleKey, role)
+			if hasUser {
+				ctx = context.WithValue(ctx, UserIDKey, int(rawUserID))
+			}
+			if hasEmployee {
+				staff := &models.StaffIdentity{EmployeeID: int(rawEmployeeID), Role: role}
+				if positionID, ok := claims["position_id"].(float64); ok {
+					staff.PositionID = int(positionID)
+				}
+				if warehouseID, ok := claims["warehouse_id"].(float64); ok {
+					id := int(warehouseID)
+					staff.WarehouseID = &id
+				}
+				ctx = context.WithValue(ctx, StaffKey, staff)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// AccessTokenFromQuery переносит ?access_token= в заголовок Authorization,
+// если заголовок не задан. Нужен для EventSource, который не умеет
+// передавать заголовки. Применяется перед AuthMiddleware.
+func AccessTokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
 // RecoveryMiddleware обрабатывает панику
 func RecoveryMiddleware(next http.Handler) http.Handler {
@@ -94,3 +139,25 @@ func GetUserIDFromContext(ctx context.Context) (int, bool) {
 	userID, ok := ctx.Value(UserIDKey).(int)
 	return userID, ok
 }
+
+// GetRoleFromContext извлекает роль пользователя из контекста
+func GetRoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(RoleKey).(string)
+	return role, ok
+}
+
+// GetStaffFromContext извлекает сотрудника из токена. В контексте заполнены
+// только идентификаторы сотрудника, должности и склада и роль.
+func GetStaffFromContext(ctx context.Context) (*models.StaffIdentity, bool) {
+	staff, ok := ctx.Value(StaffKey).(*models.StaffIdentity)
+	return staff, ok
+}
+
+// GetEmployeeIDFromContext извлекает ID сотрудника из токена
+func GetEmployeeIDFromContext(ctx context.Context) (int, bool) {
+	staff, ok := GetStaffFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return staff.EmployeeID, true
+}
@@ -17,6 +17,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap даёт http.ResponseController доступ к Flush и дедлайнам исходного writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Logger middleware для логирования HTTP запросов
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -36,10 +41,20 @@ func Logger(next http.Handler) http.Handler {
 		log.Printf(
 			"[%s] %s %s - Status: %d - Duration: %v",
 			r.Method,
-			r.RequestURI,
+			requestURIForLog(r),
 			r.RemoteAddr,
 			wrapped.statusCode,
 			duration,
 		)
 	})
 }
+
+// requestURIForLog скрывает access_token, переданный в строке запроса
+func requestURIForLog(r *http.Request) string {
+	query := r.URL.Query()
+	if query.Get("access_token") == "" {
+		return r.RequestURI
+	}
+	query.Set("access_token", "***")
+	return r.URL.Path + "?" + query.Encode()
+}
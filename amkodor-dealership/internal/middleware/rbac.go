@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"net/http"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/utils"
+)
+
+// Permission - право на действие в административной части API
+type Permission string
+
+const (
+	PermDashboardView       Permission = "dashboard:view"
+	PermVehiclesWrite       Permission = "vehicles:write"
+	PermCustomersRead       Permission = "customers:read"
+	PermCustomersWrite      Permission = "customers:write"
+	PermSalesRead           Permission = "sales:read"
+	PermSalesWrite          Permission = "sales:write"
+	PermSalesCancel         Permission = "sales:cancel"
+	PermEmployeesManage     Permission = "employees:manage"
+	PermUsersManage         Permission = "users:manage"
+	PermWarehousesRead      Permission = "warehouses:read"
+	PermWarehousesWrite     Permission = "warehouses:write"
+	PermServiceOrdersManage Permission = "service_orders:manage"
+	PermServiceRequests     Permission = "service_requests:manage"
+	PermTestDrivesManage    Permission = "test_drives:manage"
+	PermSparePartsRead      Permission = "spare_parts:read"
+	PermSparePartsWrite     Permission = "spare_parts:write"
+	PermReportsView         Permission = "reports:view"
+)
+
+// rolePermissions - матрица прав по ролям. Администратор имеет все права,
+// поэтому в матрице не перечисляется.
+var rolePermissions = map[string][]Permission{
+	models.RoleSalesManager: {
+		PermDashboardView,
+		PermCustomersRead,
+		PermCustomersWrite,
+		PermSalesRead,
+		PermSalesWrite,
+		PermWarehousesRead,
+		PermTestDrivesManage,
+		PermReportsView,
+	},
+	models.RoleServiceMaster: {
+		PermDashboardView,
+		PermCustomersRead,
+		PermServiceOrdersManage,
+		PermServiceRequests,
+		PermSparePartsRead,
+	},
+	models.RoleWarehouseKeeper: {
+		PermDashboardView,
+		PermVehiclesWrite,
+		PermWarehousesRead,
+		PermSparePartsRead,
+		PermSparePartsWrite,
+	},
+}
+
+// HasPermission проверяет, есть ли у роли указанное право
+func HasPermission(role string, perm Permission) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission пропускает запрос только при наличии у роли из токена
+// указанного права. Должен применяться после AuthMiddleware.
+func RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := GetRoleFromContext(r.Context())
+			if !HasPermission(role, perm) {
+				utils.RespondError(w, http.StatusForbidden, "Недостаточно прав")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireStaff пропускает только запросы с токеном сотрудника. Должен
+// применяться после AuthMiddleware.
+func RequireStaff(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetStaffFromContext(r.Context()); !ok {
+			utils.RespondError(w, http.StatusForbidden, "Действие доступно только сотрудникам")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Области календарной ленты
+const (
+	CalendarFeedEmployee  = "employee"
+	CalendarFeedWarehouse = "warehouse"
+)
+
+// CalendarFeed - подписка на календарь тест-драйвов и сервисных заказов
+// сотрудника или склада
+type CalendarFeed struct {
+	ID          int    `json:"id"`
+	Scope       string `json:"scope"`
+	EmployeeID  *int   `json:"employee_id,omitempty"`
+	WarehouseID *int   `json:"warehouse_id,omitempty"`
+	// Name - ФИО сотрудника или название склада
+	Name           string     `json:"name"`
+	CreatedBy      *int       `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	// URL - адрес подписки с токеном, возвращается один раз при создании
+	URL string `json:"url,omitempty"`
+}
+
+// CreateCalendarFeedRequest - создание подписки. Без employee_id и
+// warehouse_id лента строится по текущему сотруднику или его складу.
+type CreateCalendarFeedRequest struct {
+	Scope       string `json:"scope"`
+	EmployeeID  *int   `json:"employee_id"`
+	WarehouseID *int   `json:"warehouse_id"`
+}
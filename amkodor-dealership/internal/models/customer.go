@@ -1 +1,267 @@
 package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Типы клиентов (значения client_type в vw_all_clients)
+const (
+	ClientTypeCustomer  = "CUSTOMER"
+	ClientTypeCorporate = "CORPORATE"
+)
+
+// Способы привязки учётной записи к клиенту
+const (
+	ClientLinkVerification = "verification"
+	ClientLinkStaff        = "staff"
+)
+
+// ClientLink - привязка учётной записи личного кабинета к клиенту CRM
+type ClientLink struct {
+	UserID            int       `json:"user_id"`
+	ClientType        string    `json:"client_type"`
+	CustomerID        *int      `json:"customer_id,omitempty"`
+	CorporateClientID *int      `json:"corporate_client_id,omitempty"`
+	ClientName        string    `json:"client_name"`
+	Method            string    `json:"method"`
+	LinkedAt          time.Time `json:"linked_at"`
+}
+
+// ClientLinkClaimRequest - запрос пользователя на самостоятельную привязку.
+// Клиент ищется по телефону или email; УНП сужает поиск до юр. лиц.
+type ClientLinkClaimRequest struct {
+	Channel string `json:"channel"`
+	Value   string `json:"value"`
+	TaxID   string `json:"tax_id"`
+}
+
+// ClientLinkClaim - ожидающий подтверждения запрос на привязку
+type ClientLinkClaim struct {
+	ID                int        `json:"claim_id"`
+	UserID            int        `json:"-"`
+	CustomerID        *int       `json:"-"`
+	CorporateClientID *int       `json:"-"`
+	Channel           string     `json:"channel"`
+	Destination       string     `json:"destination"`
+	CodeHash          string     `json:"-"`
+	Attempts          int        `json:"-"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	ConfirmedAt       *time.Time `json:"-"`
+}
+
+type ClientLinkConfirmRequest struct {
+	ClaimID int    `json:"claim_id"`
+	Code    string `json:"code"`
+}
+
+// LinkClientRequest - привязка сотрудником; указывается ровно один клиент
+type LinkClientRequest struct {
+	CustomerID        *int `json:"customer_id"`
+	CorporateClientID *int `json:"corporate_client_id"`
+}
+
+// AccountOrder - покупка клиента в формате личного кабинета
+type AccountOrder struct {
+	ID          int                `json:"id"`
+	Number      string             `json:"number"`
+	Date        time.Time          `json:"date"`
+	Status      string             `json:"status"`
+	PaymentType string             `json:"payment_type"`
+	Items       []AccountOrderItem `json:"items"`
+	Total       float64            `json:"total"`
+}
+
+type AccountOrderItem struct {
+	Name  string  `json:"name"`
+	Specs string  `json:"specs"`
+	Price float64 `json:"price"`
+	Image string  `json:"image"`
+}
+
+// AccountTestDrive - тест-драйв клиента
+type AccountTestDrive struct {
+	ID             int       `json:"id"`
+	VehicleID      int       `json:"vehicle_id"`
+	VehicleName    string    `json:"vehicle_name"`
+	ScheduledDate  time.Time `json:"scheduled_date"`
+	Duration       int       `json:"duration"`
+	Status         string    `json:"status"`
+	ManagerName    string    `json:"manager_name"`
+	FeedbackRating *int      `json:"feedback_rating,omitempty"`
+}
+
+// AccountServiceOrder - сервисный заказ клиента
+type AccountServiceOrder struct {
+	ID             int        `json:"id"`
+	VehicleID      int        `json:"vehicle_id"`
+	VehicleName    string     `json:"vehicle_name"`
+	ServiceType    string     `json:"service_type"`
+	Description    string     `json:"description"`
+	OrderDate      time.Time  `json:"order_date"`
+	CompletionDate *time.Time `json:"completion_date,omitempty"`
+	Cost           float64    `json:"cost"`
+	Status         string     `json:"status"`
+	MasterName     string     `json:"master_name"`
+}
+
+// FleetVehicle - единица техники, купленная клиентом
+type FleetVehicle struct {
+	VehicleID         int        `json:"vehicle_id"`
+	ModelName         string     `json:"model_name"`
+	TypeName          string     `json:"type_name"`
+	VIN               string     `json:"vin"`
+	SerialNumber      string     `json:"serial_number"`
+	Year              int        `json:"year"`
+	SaleID            int        `json:"sale_id"`
+	PurchaseDate      time.Time  `json:"purchase_date"`
+	Price             float64    `json:"price"`
+	ServiceOrders     int        `json:"service_orders"`
+	OpenServiceOrders int        `json:"open_service_orders"`
+	LastServiceDate   *time.Time `json:"last_service_date,omitempty"`
+}
+
+// AccountStats - сводка личного кабинета
+type AccountStats struct {
+	Linked        bool    `json:"linked"`
+	TotalOrders   int     `json:"totalOrders"`
+	FavoriteItems int     `json:"favoriteItems"`
+	ServiceVisits int     `json:"serviceVisits"`
+	TestDrives    int     `json:"testDrives"`
+	TotalSpent    float64 `json:"totalSpent"`
+}
+
+// CorporateContact - контактное лицо корпоративного клиента
+type CorporateContact struct {
+	ID        int    `json:"id,omitempty"`
+	FullName  string `json:"full_name"`
+	Position  string `json:"position,omitempty"`
+	Phone     string `json:"phone,omitempty"`
+	Email     string `json:"email,omitempty"`
+	IsPrimary bool   `json:"is_primary"`
+}
+
+// CorporateClientRequest - данные для создания и изменения корпоративного клиента.
+// ContractDate передаётся в формате YYYY-MM-DD. Contacts заменяет список
+// контактных лиц целиком.
+type CorporateClientRequest struct {
+	CompanyName     string             `json:"company_name"`
+	TaxID           string             `json:"tax_id"`
+	LegalAddress    string             `json:"legal_address"`
+	Phone           string             `json:"phone"`
+	Email           string             `json:"email"`
+	BankAccount     string             `json:"bank_account"`
+	BankName        string             `json:"bank_name"`
+	BankBIC         string             `json:"bank_bic"`
+	DiscountPercent float64            `json:"discount_percent"`
+	ContractNumber  string             `json:"contract_number"`
+	ContractDate    string             `json:"contract_date"`
+	Contacts        []CorporateContact `json:"contacts"`
+}
+
+// CorporateClientCard - карточка корпоративного клиента с итогами покупок
+type CorporateClientCard struct {
+	ID               int                `json:"id"`
+	CompanyName      string             `json:"company_name"`
+	TaxID            string             `json:"tax_id"`
+	LegalAddress     string             `json:"legal_address"`
+	ContactPerson    string             `json:"contact_person"`
+	Phone            string             `json:"phone"`
+	Email            string             `json:"email"`
+	BankAccount      string             `json:"bank_account"`
+	BankName         string             `json:"bank_name"`
+	BankBIC          string             `json:"bank_bic"`
+	DiscountPercent  float64            `json:"discount_percent"`
+	ContractNumber   string             `json:"contract_number"`
+	ContractDate     *time.Time         `json:"contract_date"`
+	TotalPurchases   int                `json:"total_purchases"`
+	TotalSpent       float64            `json:"total_spent"`
+	LastPurchaseDate *time.Time         `json:"last_purchase_date"`
+	Contacts         []CorporateContact `json:"contacts"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// CustomerRequest - данные для создания и изменения клиента (физ. лица).
+// DateOfBirth передаётся в формате YYYY-MM-DD. AllowDuplicate подтверждает
+// создание клиента, несмотря на найденные похожие карточки.
+type CustomerRequest struct {
+	FirstName       string  `json:"first_name"`
+	LastName        string  `json:"last_name"`
+	MiddleName      string  `json:"middle_name"`
+	Phone           string  `json:"phone"`
+	Email           string  `json:"email"`
+	PassportNumber  string  `json:"passport_number"`
+	Address         string  `json:"address"`
+	DateOfBirth     string  `json:"date_of_birth"`
+	DiscountPercent float64 `json:"discount_percent"`
+	IsVIP           bool    `json:"is_vip"`
+	AllowDuplicate  bool    `json:"allow_duplicate"`
+}
+
+// CustomerCard - карточка клиента с итогами покупок
+type CustomerCard struct {
+	ID               int        `json:"id"`
+	FirstName        string     `json:"first_name"`
+	LastName         string     `json:"last_name"`
+	MiddleName       string     `json:"middle_name"`
+	FullName         string     `json:"full_name"`
+	Phone            string     `json:"phone"`
+	Email            string     `json:"email"`
+	PassportNumber   string     `json:"passport_number"`
+	Address          string     `json:"address"`
+	DateOfBirth      *time.Time `json:"date_of_birth"`
+	DiscountPercent  float64    `json:"discount_percent"`
+	IsVIP            bool       `json:"is_vip"`
+	CustomerLevel    string     `json:"customer_level,omitempty"`
+	TotalPurchases   int        `json:"total_purchases"`
+	TotalSpent       float64    `json:"total_spent"`
+	LastPurchaseDate *time.Time `json:"last_purchase_date"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// CustomerSearchParams - фильтры поиска клиентов (sp_search_customers)
+type CustomerSearchParams struct {
+	Name        string
+	Phone       string
+	Email       string
+	Passport    string
+	IsVIP       *bool
+	MinDiscount *float64
+}
+
+// Признаки, по которым карточки считаются возможными дублями
+const (
+	DuplicateByPhone    = "phone"
+	DuplicateByEmail    = "email"
+	DuplicateByPassport = "passport"
+	DuplicateByName     = "name_and_birth_date"
+)
+
+// CustomerDuplicate - карточка, похожая на проверяемого клиента
+type CustomerDuplicate struct {
+	ID       int      `json:"id"`
+	FullName string   `json:"full_name"`
+	Phone    string   `json:"phone"`
+	Email    string   `json:"email"`
+	Reasons  []string `json:"reasons"`
+}
+
+// CustomerMergeRequest - объединение дубля с карточкой из URL
+type CustomerMergeRequest struct {
+	DuplicateID int `json:"duplicate_id"`
+}
+
+// CustomerMerge - запись журнала объединения клиентов
+type CustomerMerge struct {
+	ID                 int             `json:"id"`
+	SurvivorID         *int            `json:"survivor_id"`
+	MergedCustomerID   int             `json:"merged_customer_id"`
+	MergedData         json.RawMessage `json:"merged_data"`
+	SalesMoved         int             `json:"sales_moved"`
+	TestDrivesMoved    int             `json:"test_drives_moved"`
+	ServiceOrdersMoved int             `json:"service_orders_moved"`
+	MergedBy           *int            `json:"merged_by"`
+	MergedAt           time.Time       `json:"merged_at"`
+}
@@ -1 +1,93 @@
 package models
+
+import "time"
+
+// Position - должность сотрудника
+type Position struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"position_name"`
+	BaseSalary    float64   `json:"base_salary"`
+	Description   string    `json:"description"`
+	AccessRole    string    `json:"access_role"`
+	EmployeeCount int       `json:"employee_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PositionRequest - данные должности. AccessRole - роль в административной
+// части (admin, sales_manager, service_master, warehouse_keeper); пустая
+// роль не даёт сотрудникам на этой должности входа в систему.
+type PositionRequest struct {
+	Name        string  `json:"position_name"`
+	BaseSalary  float64 `json:"base_salary"`
+	Description string  `json:"description"`
+	AccessRole  string  `json:"access_role"`
+}
+
+// EmployeeRequest - данные для создания и изменения сотрудника.
+// HireDate передаётся в формате YYYY-MM-DD, по умолчанию - текущая дата.
+// Password задаётся только при создании.
+type EmployeeRequest struct {
+	FirstName   string   `json:"first_name"`
+	LastName    string   `json:"last_name"`
+	MiddleName  string   `json:"middle_name"`
+	PositionID  int      `json:"position_id"`
+	WarehouseID *int     `json:"warehouse_id"`
+	Email       string   `json:"email"`
+	Phone       string   `json:"phone"`
+	Salary      *float64 `json:"salary"`
+	HireDate    string   `json:"hire_date"`
+	Password    string   `json:"password"`
+}
+
+// EmployeeCard - карточка сотрудника из vw_employees_full_info
+type EmployeeCard struct {
+	ID             int        `json:"id"`
+	FullName       string     `json:"full_name"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	MiddleName     string     `json:"middle_name"`
+	PositionID     int        `json:"position_id"`
+	PositionName   string     `json:"position_name"`
+	BaseSalary     float64    `json:"base_salary"`
+	Salary         *float64   `json:"salary"`
+	WarehouseID    *int       `json:"warehouse_id"`
+	WarehouseName  string     `json:"warehouse_name"`
+	WarehouseCity  string     `json:"warehouse_city"`
+	Email          string     `json:"email"`
+	Phone          string     `json:"phone"`
+	HireDate       time.Time  `json:"hire_date"`
+	DismissalDate  *time.Time `json:"dismissal_date"`
+	YearsOfService int        `json:"years_of_service"`
+	TenureMonths   int        `json:"tenure_months"`
+	IsActive       bool       `json:"is_active"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type SetPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// PasswordReset - временный пароль, показывается один раз
+type PasswordReset struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+// StaffAccount - учётные данные сотрудника для входа. UserID - связанная
+// учётная запись users, если она есть.
+type StaffAccount struct {
+	Identity     StaffIdentity
+	UserID       *int
+	Email        string
+	PasswordHash string
+	IsActive     bool
+}
+
+// StaffIdentity - сотрудник, от имени которого действует токен
+type StaffIdentity struct {
+	EmployeeID   int    `json:"employee_id"`
+	FullName     string `json:"full_name"`
+	PositionID   int    `json:"position_id"`
+	PositionName string `json:"position_name"`
+	WarehouseID  *int   `json:"warehouse_id"`
+	Role         string `json:"role"`
+}
@@ -0,0 +1,212 @@
+package models
+
+import "time"
+
+// Статусы оповещения о низком остатке запчасти
+const (
+	StockAlertOpen         = "open"
+	StockAlertAcknowledged = "acknowledged"
+	StockAlertResolved     = "resolved"
+)
+
+// StockAlert - оповещение о низком остатке запчасти. QuantityInStock и
+// MinQuantity зафиксированы последним событием, CurrentQuantity - текущий остаток.
+type StockAlert struct {
+	ID                 int        `json:"id"`
+	SparePartID        int        `json:"spare_part_id"`
+	PartNumber         string     `json:"part_number"`
+	PartName           string     `json:"part_name"`
+	WarehouseID        int        `json:"warehouse_id"`
+	WarehouseName      string     `json:"warehouse_name"`
+	QuantityInStock    int        `json:"quantity_in_stock"`
+	MinQuantity        int        `json:"min_quantity"`
+	CurrentQuantity    int        `json:"current_quantity"`
+	Status             string     `json:"status"`
+	Occurrences        int        `json:"occurrences"`
+	CreatedAt          time.Time  `json:"created_at"`
+	LastRaisedAt       time.Time  `json:"last_raised_at"`
+	AcknowledgedBy     *int       `json:"acknowledged_by,omitempty"`
+	AcknowledgedByName string     `json:"acknowledged_by_name,omitempty"`
+	AcknowledgedAt     *time.Time `json:"acknowledged_at,omitempty"`
+	ResolvedBy         *int       `json:"resolved_by,omitempty"`
+	ResolvedByName     string     `json:"resolved_by_name,omitempty"`
+	ResolvedAt         *time.Time `json:"resolved_at,omitempty"`
+	NotifiedEmail      string     `json:"notified_email,omitempty"`
+	NotifiedAt         *time.Time `json:"notified_at,omitempty"`
+}
+
+// WarehouseManagerContact - заведующий складом из warehouses.manager_name.
+// Email берётся у работающего сотрудника склада с тем же ФИО.
+type WarehouseManagerContact struct {
+	WarehouseName string
+	ManagerName   string
+	Email         string
+}
+
+// Статусы заказа запчастей у поставщика
+const (
+	PurchaseOrderOrdered   = "Заказано"
+	PurchaseOrderPartial   = "Частично получено"
+	PurchaseOrderReceived  = "Получено"
+	PurchaseOrderCancelled = "Отменено"
+)
+
+// Типы движений запчастей в spare_part_movements
+const (
+	MovementReceipt     = "receipt"
+	MovementConsumption = "consumption"
+	MovementReturn      = "return"
+	MovementAdjustment  = "adjustment"
+	MovementTransfer    = "transfer"
+	MovementWriteOff    = "write_off"
+)
+
+// Supplier - поставщик запчастей
+type Supplier struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	TaxID         string    `json:"tax_id"`
+	ContactPerson string    `json:"contact_person"`
+	Phone         string    `json:"phone"`
+	Email         string    `json:"email"`
+	IsActive      bool      `json:"is_active"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SupplierRequest - данные для создания и изменения поставщика.
+// IsActive по умолчанию true.
+type SupplierRequest struct {
+	Name          string `json:"name"`
+	TaxID         string `json:"tax_id"`
+	ContactPerson string `json:"contact_person"`
+	Phone         string `json:"phone"`
+	Email         string `json:"email"`
+	IsActive      *bool  `json:"is_active"`
+}
+
+// PurchaseOrder - заказ запчастей у поставщика. Lines заполняется только
+// при получении заказа по ID.
+type PurchaseOrder struct {
+	ID            int                 `json:"id"`
+	OrderNumber   string              `json:"order_number"`
+	SupplierID    int                 `json:"supplier_id"`
+	SupplierName  string              `json:"supplier_name"`
+	WarehouseID   int                 `json:"warehouse_id"`
+	WarehouseName string              `json:"warehouse_name"`
+	Status        string              `json:"status"`
+	OrderDate     time.Time           `json:"order_date"`
+	ExpectedDate  *time.Time          `json:"expected_date"`
+	ReceivedDate  *time.Time          `json:"received_date"`
+	CreatedBy     *int                `json:"created_by"`
+	CreatedByName string              `json:"created_by_name,omitempty"`
+	Notes         string              `json:"notes"`
+	TotalAmount   float64             `json:"total_amount"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	Lines         []PurchaseOrderLine `json:"lines,omitempty"`
+}
+
+// PurchaseOrderLine - строка заказа запчастей
+type PurchaseOrderLine struct {
+	ID                int     `json:"id"`
+	SparePartID       int     `json:"spare_part_id"`
+	PartNumber        string  `json:"part_number"`
+	PartName          string  `json:"part_name"`
+	QuantityOrdered   int     `json:"quantity_ordered"`
+	QuantityReceived  int     `json:"quantity_received"`
+	QuantityRemaining int     `json:"quantity_remaining"`
+	UnitPrice         float64 `json:"unit_price"`
+	Amount            float64 `json:"amount"`
+}
+
+// PurchaseOrderRequest - данные для создания и изменения заказа.
+// ExpectedDate передаётся в формате YYYY-MM-DD.
+type PurchaseOrderRequest struct {
+	SupplierID   int                        `json:"supplier_id"`
+	WarehouseID  int                        `json:"warehouse_id"`
+	ExpectedDate string                     `json:"expected_date"`
+	Notes        string                     `json:"notes"`
+	Lines        []PurchaseOrderLineRequest `json:"lines"`
+}
+
+// PurchaseOrderLineRequest - строка заказа. UnitPrice по умолчанию - цена запчасти.
+type PurchaseOrderLineRequest struct {
+	SparePartID int      `json:"spare_part_id"`
+	Quantity    int      `json:"quantity"`
+	UnitPrice   *float64 `json:"unit_price"`
+}
+
+// ReceivePurchaseOrderRequest - приём заказа на склад. Пустой список строк
+// означает приём всего недополученного остатка.
+type ReceivePurchaseOrderRequest struct {
+	Lines []ReceivePurchaseOrderLine `json:"lines"`
+}
+
+type ReceivePurchaseOrderLine struct {
+	LineID   int `json:"line_id"`
+	Quantity int `json:"quantity"`
+}
+
+// ReorderSuggestion - рекомендация по дозаказу запчасти. SuggestedQuantity
+// покрывает средний расход за период покрытия сверх минимального остатка
+// с учётом того, что уже заказано у поставщиков.
+type ReorderSuggestion struct {
+	SparePartID         int      `json:"spare_part_id"`
+	PartNumber          string   `json:"part_number"`
+	PartName            string   `json:"part_name"`
+	WarehouseID         int      `json:"warehouse_id"`
+	WarehouseName       string   `json:"warehouse_name"`
+	QuantityInStock     int      `json:"quantity_in_stock"`
+	MinQuantity         int      `json:"min_quantity"`
+	Consumed            int      `json:"consumed"`
+	AvgDailyConsumption float64  `json:"avg_daily_consumption"`
+	OnOrder             int      `json:"on_order"`
+	SuggestedQuantity   int      `json:"suggested_quantity"`
+	LastSupplierID      *int     `json:"last_supplier_id"`
+	LastSupplierName    string   `json:"last_supplier_name,omitempty"`
+	LastUnitPrice       *float64 `json:"last_unit_price"`
+}
+
+// SparePartMovement - запись журнала движений запчасти. Quantity - изменение
+// остатка со знаком, BalanceAfter - остаток по журналу после движения.
+type SparePartMovement struct {
+	ID                  int64     `json:"id"`
+	SparePartID         int       `json:"spare_part_id"`
+	Type                string    `json:"movement_type"`
+	Quantity            int       `json:"quantity"`
+	BalanceAfter        int       `json:"balance_after"`
+	PurchaseOrderLineID *int      `json:"purchase_order_line_id,omitempty"`
+	PurchaseOrderNumber string    `json:"purchase_order_number,omitempty"`
+	ServiceOrderID      *int      `json:"service_order_id,omitempty"`
+	RelatedMovementID   *int64    `json:"related_movement_id,omitempty"`
+	EmployeeID          *int      `json:"employee_id"`
+	EmployeeName        string    `json:"employee_name,omitempty"`
+	Reason              string    `json:"reason"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// SparePartMovementRequest - ручное движение запчасти. Для adjustment
+// Quantity указывается со знаком, для write_off и transfer - положительным.
+// TargetSparePartID - запчасть на другом складе, куда перемещается остаток.
+type SparePartMovementRequest struct {
+	Type              string `json:"movement_type"`
+	Quantity          int    `json:"quantity"`
+	Reason            string `json:"reason"`
+	TargetSparePartID int    `json:"target_spare_part_id"`
+}
+
+// SparePartStock - остаток запчасти по журналу рядом с хранимым из
+// vw_spare_parts_stock. Drift - расхождение хранимого остатка с журналом.
+type SparePartStock struct {
+	SparePartID    int        `json:"spare_part_id"`
+	PartNumber     string     `json:"part_number"`
+	PartName       string     `json:"part_name"`
+	WarehouseID    int        `json:"warehouse_id"`
+	WarehouseName  string     `json:"warehouse_name"`
+	StoredQuantity int        `json:"stored_quantity"`
+	LedgerQuantity int        `json:"ledger_quantity"`
+	Drift          int        `json:"drift"`
+	MinQuantity    int        `json:"min_quantity"`
+	MovementsCount int        `json:"movements_count"`
+	LastMovementAt *time.Time `json:"last_movement_at"`
+}
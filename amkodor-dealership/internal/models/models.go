@@ -31,9 +31,17 @@ type Vehicle struct {
 	FinalPrice       float64         `json:"final_price,omitempty"`
 	Description      sql.NullString  `json:"description,omitempty"`
 	Specifications   json.RawMessage `json:"specifications,omitempty"`
+	// ReservedUntil - срок действующей брони, заполняется для каталога
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+	// Rating - средняя оценка модели по отзывам о тест-драйвах, заполняется для каталога
+	Rating       *float64 `json:"rating,omitempty"`
+	RatingsCount int      `json:"ratings_count"`
 }
 
-// CreateVehicleRequest представляет запрос на создание техники
+// CreateVehicleRequest представляет запрос на создание техники.
+// Name сопоставляется с vehicle_models, Category - с vehicle_types или
+// vehicle_categories, Engine/Power/Weight/Image сохраняются в JSONB
+// specifications модели.
 type CreateVehicleRequest struct {
 	Name     string  `json:"name" validate:"required"`
 	Category string  `json:"category" validate:"required"`
@@ -44,6 +52,41 @@ type CreateVehicleRequest struct {
 	Power    string  `json:"power" validate:"required"`
 	Weight   string  `json:"weight" validate:"required"`
 	Image    string  `json:"image"`
+	// Необязательные поля: при отсутствии модель ищется по имени,
+	// склад выбирается основной, серийный номер генерируется
+	ModelID        int    `json:"model_id,omitempty"`
+	ManufacturerID int    `json:"manufacturer_id,omitempty"`
+	WarehouseID    int    `json:"warehouse_id,omitempty"`
+	VIN            string `json:"vin,omitempty"`
+	SerialNumber   string `json:"serial_number,omitempty"`
+	Color          string `json:"color,omitempty"`
+	// Скидка - указатель: 0 снимает скидку, а отсутствие поля при правке
+	// оставляет текущую
+	Discount *float64 `json:"discount,omitempty"`
+}
+
+// CatalogVehicle - карточка техники в каталоге
+type CatalogVehicle struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Category      string  `json:"category"`
+	Price         float64 `json:"price"`
+	Discount      float64 `json:"discount"`
+	FinalPrice    float64 `json:"final_price"`
+	Status        string  `json:"status"`
+	Year          int     `json:"year"`
+	Color         string  `json:"color,omitempty"`
+	Engine        string  `json:"engine"`
+	Power         string  `json:"power"`
+	Weight        string  `json:"weight"`
+	Image         string  `json:"image"`
+	WarehouseName string  `json:"warehouse_name"`
+	WarehouseCity string  `json:"warehouse_city"`
+	// ReservedUntil - до какого момента техника забронирована
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+	// Rating - средняя оценка модели по отзывам о тест-драйвах
+	Rating       *float64 `json:"rating,omitempty"`
+	RatingsCount int      `json:"ratings_count"`
 }
 
 // VehicleModel представляет модель техники
@@ -73,10 +116,11 @@ type Customer struct {
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	// Дополнительные поля
-	FullName       string  `json:"full_name,omitempty"`
-	CustomerLevel  string  `json:"customer_level,omitempty"`
-	TotalPurchases int     `json:"total_purchases,omitempty"`
-	TotalSpent     float64 `json:"total_spent,omitempty"`
+	FullName         string       `json:"full_name,omitempty"`
+	CustomerLevel    string       `json:"customer_level,omitempty"`
+	TotalPurchases   int          `json:"total_purchases,omitempty"`
+	TotalSpent       float64      `json:"total_spent,omitempty"`
+	LastPurchaseDate sql.NullTime `json:"last_purchase_date"`
 }
 
 // CorporateClient представляет корпоративного клиента
@@ -90,14 +134,17 @@ type CorporateClient struct {
 	Email             sql.NullString `json:"email"`
 	BankAccount       sql.NullString `json:"bank_account"`
 	BankName          sql.NullString `json:"bank_name"`
+	BankBIC           sql.NullString `json:"bank_bic"`
 	DiscountPercent   float64        `json:"discount_percent"`
 	ContractNumber    sql.NullString `json:"contract_number"`
 	ContractDate      sql.NullTime   `json:"contract_date"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
 	// Дополнительные поля
-	TotalPurchases int     `json:"total_purchases,omitempty"`
-	TotalSpent     float64 `json:"total_spent,omitempty"`
+	TotalPurchases   int                `json:"total_purchases,omitempty"`
+	TotalSpent       float64            `json:"total_spent,omitempty"`
+	LastPurchaseDate sql.NullTime       `json:"last_purchase_date"`
+	Contacts         []CorporateContact `json:"contacts,omitempty"`
 }
 
 // Sale представляет продажу
@@ -117,6 +164,8 @@ type Sale struct {
 	Notes             sql.NullString `json:"notes"`
 	CreatedAt         time.Time      `json:"created_at"`
 	UpdatedAt         time.Time      `json:"updated_at"`
+	// Дополнительная скидка менеджера в процентах, передается в sp_create_sale
+	AdditionalDiscount float64 `json:"additional_discount,omitempty"`
 	// Дополнительные поля из JOIN
 	VIN           string `json:"vin,omitempty"`
 	ModelName     string `json:"model_name,omitempty"`
@@ -146,12 +195,16 @@ type Employee struct {
 	Salary       sql.NullFloat64 `json:"salary"`
 	IsActive     bool            `json:"is_active"`
 	CreatedAt    time.Time       `json:"created_at"`
+	// Дата увольнения; уволенный сотрудник остаётся в базе неактивным
+	DismissalDate sql.NullTime `json:"dismissal_date"`
 	// Дополнительные поля
-	FullName       string `json:"full_name,omitempty"`
-	PositionName   string `json:"position_name,omitempty"`
-	WarehouseName  string `json:"warehouse_name,omitempty"`
-	WarehouseCity  string `json:"warehouse_city,omitempty"`
-	YearsOfService int    `json:"years_of_service,omitempty"`
+	FullName       string  `json:"full_name,omitempty"`
+	PositionName   string  `json:"position_name,omitempty"`
+	WarehouseName  string  `json:"warehouse_name,omitempty"`
+	WarehouseCity  string  `json:"warehouse_city,omitempty"`
+	YearsOfService int     `json:"years_of_service,omitempty"`
+	TenureMonths   int     `json:"tenure_months,omitempty"`
+	BaseSalary     float64 `json:"base_salary,omitempty"`
 }
 
 // Warehouse представляет склад/филиал
@@ -265,7 +318,7 @@ type SaleHistory struct {
 	ApplicationName sql.NullString  `json:"application_name"`
 }
 
-// Statistics структуры для дашборда
+// Statistics структуры для дашборда (vw_dashboard_statistics / fn_dashboard_statistics)
 type DashboardStatistics struct {
 	AvailableVehicles     int     `json:"available_vehicles"`
 	SalesLastMonth        int     `json:"sales_last_month"`
@@ -274,6 +327,10 @@ type DashboardStatistics struct {
 	TotalCorporateClients int     `json:"total_corporate_clients"`
 	UpcomingTestDrives    int     `json:"upcoming_test_drives"`
 	ActiveServiceOrders   int     `json:"active_service_orders"`
+	TotalVehicles         int     `json:"total_vehicles"`
+	TotalSales            int     `json:"total_sales"`
+	TotalRevenue          float64 `json:"total_revenue"`
+	TotalEmployees        int     `json:"total_employees"`
 }
 
 // Pagination для списков
@@ -333,26 +390,79 @@ type Service struct {
 	IsActive    bool    `json:"is_active"`
 }
 
-type DashboardStats struct {
-	TotalVehicles     int     `json:"total_vehicles"`
-	AvailableVehicles int     `json:"available_vehicles"`
-	TotalSales        int     `json:"total_sales"`
-	TotalRevenue      float64 `json:"total_revenue"`
-	TotalCustomers    int     `json:"total_customers"`
-	ActiveEmployees   int     `json:"active_employees"`
-}
+// Шаг группировки графика продаж
+const (
+	ChartPeriodDay   = "day"
+	ChartPeriodWeek  = "week"
+	ChartPeriodMonth = "month"
+)
 
+// ChartData - точка графика продаж: Value - выручка, Count - число продаж
+// за интервал, начинающийся с Date
 type ChartData struct {
 	Label string  `json:"label"`
 	Value float64 `json:"value"`
+	Count int     `json:"count"`
 	Date  string  `json:"date"`
 }
 
+// DashboardCharts - графики дашборда: продажи по интервалам и по категориям
+type DashboardCharts struct {
+	Period     string          `json:"period"`
+	Sales      []ChartData     `json:"sales"`
+	Categories []CategorySales `json:"categories"`
+}
+
+// CategorySales - строка vw_sales_statistics_by_category
+type CategorySales struct {
+	CategoryID       int     `json:"category_id"`
+	CategoryName     string  `json:"category_name"`
+	SalesCount       int     `json:"sales_count"`
+	Revenue          float64 `json:"revenue"`
+	AverageSalePrice float64 `json:"average_sale_price"`
+}
+
+// TopEmployee - строка vw_sales_statistics_by_manager
 type TopEmployee struct {
-	EmployeeID   int     `json:"employee_id"`
-	EmployeeName string  `json:"employee_name"`
-	SalesCount   int     `json:"sales_count"`
-	Revenue      float64 `json:"revenue"`
+	EmployeeID       int        `json:"employee_id"`
+	EmployeeName     string     `json:"employee_name"`
+	WarehouseName    string     `json:"warehouse_name"`
+	SalesCount       int        `json:"sales_count"`
+	Revenue          float64    `json:"revenue"`
+	AverageSalePrice float64    `json:"average_sale_price"`
+	LastSaleDate     *time.Time `json:"last_sale_date"`
+}
+
+// RecentSale - последняя продажа для дашборда (vw_sales_full_info)
+type RecentSale struct {
+	SaleID         int       `json:"sale_id"`
+	ContractNumber string    `json:"contract_number"`
+	SaleDate       time.Time `json:"sale_date"`
+	ModelName      string    `json:"model_name"`
+	ClientName     string    `json:"client_name"`
+	ManagerName    string    `json:"manager_name"`
+	WarehouseName  string    `json:"warehouse_name"`
+	FinalPrice     float64   `json:"final_price"`
+	Status         string    `json:"status"`
+}
+
+// Типы событий живого обновления (live_events.event_type)
+const (
+	LiveEventSaleCreated           = "sale.created"
+	LiveEventSaleCancelled         = "sale.cancelled"
+	LiveEventVehicleStatusChanged  = "vehicle.status_changed"
+	LiveEventSparePartLowStock     = "spare_part.low_stock"
+	LiveEventServiceRequestCreated = "service_request.created"
+)
+
+// LiveEvent - событие из live_events, рассылаемое по SSE. WarehouseID
+// не задан у событий, не относящихся к складу.
+type LiveEvent struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	WarehouseID *int            `json:"warehouse_id,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
 }
 
 type ReportFilters struct {
@@ -432,10 +542,54 @@ type User struct {
 	Phone        string    `json:"phone" db:"phone"`
 	PasswordHash string    `json:"-" db:"password_hash"`
 	Role         string    `json:"role" db:"role"`
+	EmployeeID   *int      `json:"employee_id,omitempty" db:"employee_id"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Роли пользователей (значения колонки users.role)
+const (
+	RoleAdmin           = "admin"
+	RoleSalesManager    = "sales_manager"
+	RoleServiceMaster   = "service_master"
+	RoleWarehouseKeeper = "warehouse_keeper"
+	RoleCustomer        = "user"
+)
+
+// RefreshToken представляет сохранённый refresh-токен (только хеш).
+// Токен принадлежит либо пользователю, либо сотруднику.
+type RefreshToken struct {
+	TokenID    int        `json:"token_id" db:"token_id"`
+	UserID     *int       `json:"user_id,omitempty" db:"user_id"`
+	EmployeeID *int       `json:"employee_id,omitempty" db:"employee_id"`
+	FamilyID   string     `json:"family_id" db:"family_id"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty" db:"used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TokenPair - пара access/refresh токенов, выдаваемая при входе и обновлении
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // время жизни access-токена в секундах
+}
+
+// Session - результат входа или обновления токенов. Staff заполнен, если
+// токен выдан сотруднику или пользователю, связанному с сотрудником.
+type Session struct {
+	Name   string
+	Role   string
+	Staff  *StaffIdentity
+	Tokens *TokenPair
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // Favorite представляет избранную технику пользователя
 type Favorite struct {
 	FavoriteID int       `json:"favorite_id" db:"favorite_id"`
@@ -459,6 +613,9 @@ type FavoriteWithVehicle struct {
 	Color        string  `json:"color" db:"color"`
 	SerialNumber string  `json:"serial_number" db:"serial_number"`
 	VIN          string  `json:"vin" db:"vin"`
+	// Бронь: срок действующей брони и забронирована ли техника за клиентом пользователя
+	ReservedUntil *time.Time `json:"reserved_until,omitempty"`
+	ReservedByMe  bool       `json:"reserved_by_me"`
 }
 
 // CreateServiceOrderRequest запрос на создание сервисного заказа
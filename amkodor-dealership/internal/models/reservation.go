@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Статусы брони техники
+const (
+	ReservationActive    = "Активна"
+	ReservationPurchased = "Выкуплена"
+	ReservationExpired   = "Истекла"
+	ReservationCancelled = "Отменена"
+)
+
+// VehicleReservation - бронь единицы техники за клиентом до ExpiresAt
+type VehicleReservation struct {
+	ID                int        `json:"id"`
+	VehicleID         int        `json:"vehicle_id"`
+	ModelName         string     `json:"model_name"`
+	VIN               string     `json:"vin"`
+	WarehouseName     string     `json:"warehouse_name"`
+	CustomerID        *int       `json:"customer_id,omitempty"`
+	CorporateClientID *int       `json:"corporate_client_id,omitempty"`
+	ClientName        string     `json:"client_name"`
+	EmployeeID        *int       `json:"employee_id"`
+	ManagerName       string     `json:"manager_name"`
+	DepositAmount     float64    `json:"deposit_amount"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	Status            string     `json:"status"`
+	SaleID            *int       `json:"sale_id,omitempty"`
+	Notes             string     `json:"notes"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ClosedAt          *time.Time `json:"closed_at,omitempty"`
+}
+
+// VehicleReservationRequest - бронирование техники. Указывается ровно один
+// клиент; ExpiresAt в формате RFC 3339, по умолчанию срок брони - 3 дня.
+type VehicleReservationRequest struct {
+	VehicleID         int     `json:"vehicle_id"`
+	CustomerID        *int    `json:"customer_id"`
+	CorporateClientID *int    `json:"corporate_client_id"`
+	DepositAmount     float64 `json:"deposit_amount"`
+	ExpiresAt         string  `json:"expires_at"`
+	Notes             string  `json:"notes"`
+}
+
+// ExtendReservationRequest - продление брони до нового срока в формате RFC 3339
+type ExtendReservationRequest struct {
+	ExpiresAt string `json:"expires_at"`
+}
@@ -1 +1,140 @@
 package models
+
+import "time"
+
+// Статусы заявки на сервис. completed не хранится в service_requests:
+// заявка считается выполненной, когда завершён созданный по ней заказ.
+const (
+	ServiceRequestPending    = "pending"
+	ServiceRequestInProgress = "in-progress"
+	ServiceRequestCompleted  = "completed"
+	ServiceRequestRejected   = "rejected"
+	ServiceRequestCancelled  = "cancelled"
+)
+
+// ServiceRequest - заявка клиента на сервис
+type ServiceRequest struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"user_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ServiceType string `json:"service_type"`
+	Status      string `json:"status"`
+	// Date - дата создания заявки
+	Date         time.Time  `json:"date"`
+	StaffComment string     `json:"staff_comment,omitempty"`
+	ProcessedAt  *time.Time `json:"processed_at,omitempty"`
+	// Сервисный заказ, созданный по заявке
+	ServiceOrderID     *int   `json:"service_order_id,omitempty"`
+	ServiceOrderStatus string `json:"service_order_status,omitempty"`
+	VehicleName        string `json:"vehicle_name,omitempty"`
+	MasterName         string `json:"master_name,omitempty"`
+	// Контакты заявителя (для сотрудников)
+	Customer string `json:"customer,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Phone    string `json:"phone,omitempty"`
+}
+
+type CreateServiceRequestRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ServiceType string `json:"service_type"`
+}
+
+// ConvertServiceRequestRequest - данные сервисного заказа, создаваемого по заявке.
+// Указывается ровно один из CustomerID и CorporateClientID.
+type ConvertServiceRequestRequest struct {
+	VehicleID         int     `json:"vehicle_id"`
+	EmployeeID        int     `json:"employee_id"`
+	CustomerID        *int    `json:"customer_id"`
+	CorporateClientID *int    `json:"corporate_client_id"`
+	ServiceType       string  `json:"service_type"`
+	Description       string  `json:"description"`
+	Cost              float64 `json:"cost"`
+	Comment           string  `json:"comment"`
+}
+
+type RejectServiceRequestRequest struct {
+	Comment string `json:"comment"`
+}
+
+// Статусы сервисного заказа. Запчасти и работы меняются только в открытом
+// заказе (в работе или приостановлен), завершённый заказ не изменяется.
+const (
+	ServiceOrderInProgress = "В работе"
+	ServiceOrderSuspended  = "Приостановлен"
+	ServiceOrderCompleted  = "Завершен"
+	ServiceOrderCancelled  = "Отменен"
+)
+
+// ServiceOrderDetail - карточка сервисного заказа с запчастями и работами.
+// Cost - сумма PartsCost и LabourCost, её пересчитывает база.
+type ServiceOrderDetail struct {
+	ID             int                  `json:"id"`
+	VehicleID      int                  `json:"vehicle_id"`
+	ModelName      string               `json:"model_name"`
+	VIN            string               `json:"vin"`
+	ClientName     string               `json:"client_name"`
+	ClientPhone    string               `json:"client_phone"`
+	EmployeeID     int                  `json:"employee_id"`
+	MasterName     string               `json:"master_name"`
+	OrderDate      time.Time            `json:"order_date"`
+	CompletionDate *time.Time           `json:"completion_date"`
+	ServiceType    string               `json:"service_type"`
+	Description    string               `json:"description"`
+	Status         string               `json:"status"`
+	PartsCost      float64              `json:"parts_cost"`
+	LabourCost     float64              `json:"labour_cost"`
+	Cost           float64              `json:"cost"`
+	Parts          []ServiceOrderPart   `json:"parts"`
+	Labour         []ServiceOrderLabour `json:"labour"`
+}
+
+// ServiceOrderPart - запчасть в сервисном заказе по цене на момент добавления
+type ServiceOrderPart struct {
+	ID          int       `json:"id"`
+	SparePartID int       `json:"spare_part_id"`
+	PartNumber  string    `json:"part_number"`
+	PartName    string    `json:"part_name"`
+	Quantity    int       `json:"quantity"`
+	UnitPrice   float64   `json:"unit_price"`
+	Amount      float64   `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ServiceOrderLabour - работа в сервисном заказе: часы по ставке
+type ServiceOrderLabour struct {
+	ID           int       `json:"id"`
+	Description  string    `json:"description"`
+	Hours        float64   `json:"hours"`
+	Rate         float64   `json:"rate"`
+	Amount       float64   `json:"amount"`
+	EmployeeID   *int      `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UpdateServiceOrderRequest - правка открытого заказа. Незаполненные поля
+// не меняются; стоимость считается по запчастям и работам, завершение -
+// через /complete.
+type UpdateServiceOrderRequest struct {
+	EmployeeID  int    `json:"employee_id"`
+	ServiceType string `json:"service_type"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+// ServiceOrderPartRequest - добавление запчасти в заказ со склада
+type ServiceOrderPartRequest struct {
+	SparePartID int `json:"spare_part_id"`
+	Quantity    int `json:"quantity"`
+}
+
+// ServiceOrderLabourRequest - добавление работы. EmployeeID - исполнитель,
+// по умолчанию текущий сотрудник.
+type ServiceOrderLabourRequest struct {
+	Description string  `json:"description"`
+	Hours       float64 `json:"hours"`
+	Rate        float64 `json:"rate"`
+	EmployeeID  *int    `json:"employee_id"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// Статусы поставки техники от производителя
+const (
+	SupplyOrdered   = "Заказано"
+	SupplyInTransit = "В пути"
+	SupplyReceived  = "Получено"
+	SupplyCancelled = "Отменено"
+)
+
+// Supply - поставка техники из vw_supplies_full_info. RegisteredQuantity -
+// сколько единиц техники уже заведено по строкам поставки. Items
+// заполняется только при получении поставки по ID.
+type Supply struct {
+	ID                  int          `json:"id"`
+	ManufacturerID      int          `json:"manufacturer_id"`
+	ManufacturerName    string       `json:"manufacturer_name"`
+	Country             string       `json:"country"`
+	WarehouseID         int          `json:"warehouse_id"`
+	WarehouseName       string       `json:"warehouse_name"`
+	WarehouseCity       string       `json:"warehouse_city"`
+	SupplyDate          time.Time    `json:"supply_date"`
+	ExpectedArrivalDate *time.Time   `json:"expected_arrival_date"`
+	ActualArrivalDate   *time.Time   `json:"actual_arrival_date"`
+	Status              string       `json:"status"`
+	TotalCost           float64      `json:"total_cost"`
+	InvoiceNumber       string       `json:"invoice_number"`
+	Notes               string       `json:"notes"`
+	ItemsCount          int          `json:"items_count"`
+	TotalQuantity       int          `json:"total_quantity"`
+	RegisteredQuantity  int          `json:"registered_quantity"`
+	CreatedAt           time.Time    `json:"created_at"`
+	Items               []SupplyItem `json:"items,omitempty"`
+}
+
+// SupplyItem - строка поставки с заведёнными по ней единицами техники
+type SupplyItem struct {
+	ID        int             `json:"id"`
+	ModelID   int             `json:"model_id"`
+	ModelName string          `json:"model_name"`
+	Quantity  int             `json:"quantity"`
+	UnitPrice float64         `json:"unit_price"`
+	Vehicles  []SupplyVehicle `json:"vehicles"`
+}
+
+// SupplyVehicle - единица техники, заведённая по строке поставки
+type SupplyVehicle struct {
+	VehicleID       int     `json:"vehicle_id"`
+	SerialNumber    string  `json:"serial_number"`
+	VIN             string  `json:"vin"`
+	ManufactureYear int     `json:"manufacture_year"`
+	Color           string  `json:"color"`
+	Price           float64 `json:"price"`
+	Status          string  `json:"status"`
+}
+
+// SupplyRequest - заказ техники у производителя. ExpectedArrivalDate
+// передаётся в формате YYYY-MM-DD.
+type SupplyRequest struct {
+	ManufacturerID      int                 `json:"manufacturer_id"`
+	WarehouseID         int                 `json:"warehouse_id"`
+	ExpectedArrivalDate string              `json:"expected_arrival_date"`
+	InvoiceNumber       string              `json:"invoice_number"`
+	Notes               string              `json:"notes"`
+	Items               []SupplyItemRequest `json:"items"`
+}
+
+type SupplyItemRequest struct {
+	ModelID   int     `json:"model_id"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+}
+
+// SupplyUnitsRequest - единицы техники, которые заводятся при отгрузке или
+// приёме поставки. InvoiceNumber, если указан, заменяет номер накладной.
+type SupplyUnitsRequest struct {
+	InvoiceNumber string       `json:"invoice_number"`
+	Units         []SupplyUnit `json:"units"`
+}
+
+// SupplyUnit - единица техники по строке поставки. Price - цена продажи,
+// по умолчанию равна закупочной цене строки.
+type SupplyUnit struct {
+	SupplyItemID    int      `json:"supply_item_id"`
+	SerialNumber    string   `json:"serial_number"`
+	VIN             string   `json:"vin"`
+	ManufactureYear int      `json:"manufacture_year"`
+	Color           string   `json:"color"`
+	Price           *float64 `json:"price"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// Статусы тест-драйва
+const (
+	TestDriveScheduled = "Запланирован"
+	TestDriveCompleted = "Завершен"
+	TestDriveCancelled = "Отменен"
+	TestDriveNoShow    = "Не явился"
+)
+
+// TestDriveBooking - запись на тест-драйв. Время указывается по Минску.
+type TestDriveBooking struct {
+	ID                int       `json:"id"`
+	VehicleID         int       `json:"vehicle_id"`
+	ModelName         string    `json:"model_name"`
+	VIN               string    `json:"vin"`
+	WarehouseID       int       `json:"warehouse_id"`
+	WarehouseName     string    `json:"warehouse_name"`
+	CustomerID        *int      `json:"customer_id,omitempty"`
+	CorporateClientID *int      `json:"corporate_client_id,omitempty"`
+	ClientName        string    `json:"client_name"`
+	ClientPhone       string    `json:"client_phone"`
+	ClientEmail       string    `json:"client_email"`
+	EmployeeID        int       `json:"employee_id"`
+	ManagerName       string    `json:"manager_name"`
+	ScheduledDate     time.Time `json:"scheduled_date"`
+	EndDate           time.Time `json:"end_date"`
+	Duration          int       `json:"duration"`
+	Status            string    `json:"status"`
+	CancelReason      string    `json:"cancel_reason,omitempty"`
+	FeedbackRating    *int      `json:"feedback_rating,omitempty"`
+	FeedbackComment   string    `json:"feedback_comment,omitempty"`
+	BookedByUserID    *int      `json:"booked_by_user_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// FeedbackURL - ссылка на отзыв, возвращается один раз при завершении
+	FeedbackURL string `json:"feedback_url,omitempty"`
+	// CalendarURL - файл .ics с тест-драйвом, возвращается при записи и переносе
+	CalendarURL string `json:"calendar_url,omitempty"`
+}
+
+// BookTestDriveRequest - запись на тест-драйв сотрудником. Указывается ровно
+// один клиент; без employee_id менеджер подбирается среди свободных.
+type BookTestDriveRequest struct {
+	VehicleID         int       `json:"vehicle_id"`
+	CustomerID        *int      `json:"customer_id"`
+	CorporateClientID *int      `json:"corporate_client_id"`
+	EmployeeID        *int      `json:"employee_id"`
+	ScheduledDate     time.Time `json:"scheduled_date"`
+	Duration          int       `json:"duration"`
+}
+
+// SelfBookTestDriveRequest - запись клиента на тест-драйв из каталога
+type SelfBookTestDriveRequest struct {
+	VehicleID     int       `json:"vehicle_id"`
+	EmployeeID    *int      `json:"employee_id"`
+	ScheduledDate time.Time `json:"scheduled_date"`
+	Duration      int       `json:"duration"`
+}
+
+// RescheduleTestDriveRequest - перенос тест-драйва. Пустые duration и
+// employee_id оставляют прежние значения.
+type RescheduleTestDriveRequest struct {
+	ScheduledDate time.Time `json:"scheduled_date"`
+	Duration      int       `json:"duration"`
+	EmployeeID    *int      `json:"employee_id"`
+}
+
+// CancelTestDriveRequest - отмена тест-драйва с необязательной причиной
+type CancelTestDriveRequest struct {
+	Reason string `json:"reason"`
+}
+
+// TestDriveManager - менеджер, проводящий тест-драйвы
+type TestDriveManager struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// TestDriveSlot - свободный слот и менеджеры, которые могут его провести
+type TestDriveSlot struct {
+	Start    time.Time          `json:"start"`
+	End      time.Time          `json:"end"`
+	Managers []TestDriveManager `json:"managers"`
+}
+
+// TestDriveAvailability - свободные слоты на дату по технике и/или менеджеру
+type TestDriveAvailability struct {
+	Date       string          `json:"date"`
+	TimeZone   string          `json:"time_zone"`
+	VehicleID  *int            `json:"vehicle_id,omitempty"`
+	EmployeeID *int            `json:"employee_id,omitempty"`
+	Duration   int             `json:"duration"`
+	Slots      []TestDriveSlot `json:"slots"`
+}
+
+// WorkingHours - рабочее время сотрудника в день недели (1 - понедельник,
+// 7 - воскресенье). Время в формате HH:MM по Минску.
+type WorkingHours struct {
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// TestDriveFeedbackForm - тест-драйв, о котором клиент оставляет отзыв по ссылке
+type TestDriveFeedbackForm struct {
+	ModelName     string    `json:"model_name"`
+	WarehouseName string    `json:"warehouse_name"`
+	ManagerName   string    `json:"manager_name"`
+	ScheduledDate time.Time `json:"scheduled_date"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	Submitted     bool      `json:"submitted"`
+	Rating        *int      `json:"rating,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+}
+
+// TestDriveFeedbackRequest - отзыв о тест-драйве: оценка от 1 до 5 и комментарий
+type TestDriveFeedbackRequest struct {
+	Rating  int    `json:"rating"`
+	Comment string `json:"comment"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Статусы перемещения техники между складами
+const (
+	TransferPlanned   = "Запланировано"
+	TransferInTransit = "В пути"
+	TransferReceived  = "Получено"
+	TransferCancelled = "Отменено"
+)
+
+// VehicleTransfer - документ перемещения техники между складами. Vehicles
+// заполняется только при получении перемещения по ID.
+type VehicleTransfer struct {
+	ID                   int                   `json:"id"`
+	TransferNumber       string                `json:"transfer_number"`
+	FromWarehouseID      int                   `json:"from_warehouse_id"`
+	FromWarehouseName    string                `json:"from_warehouse_name"`
+	ToWarehouseID        int                   `json:"to_warehouse_id"`
+	ToWarehouseName      string                `json:"to_warehouse_name"`
+	Status               string                `json:"status"`
+	PlannedDepartureDate *time.Time            `json:"planned_departure_date"`
+	PlannedArrivalDate   *time.Time            `json:"planned_arrival_date"`
+	ActualDepartureDate  *time.Time            `json:"actual_departure_date"`
+	ActualArrivalDate    *time.Time            `json:"actual_arrival_date"`
+	DriverName           string                `json:"driver_name"`
+	TransportCost        float64               `json:"transport_cost"`
+	Notes                string                `json:"notes"`
+	CreatedBy            *int                  `json:"created_by"`
+	CreatedByName        string                `json:"created_by_name"`
+	VehiclesCount        int                   `json:"vehicles_count"`
+	CreatedAt            time.Time             `json:"created_at"`
+	UpdatedAt            time.Time             `json:"updated_at"`
+	Vehicles             []VehicleTransferItem `json:"vehicles,omitempty"`
+}
+
+// VehicleTransferItem - единица техники в перемещении. PreviousStatus -
+// статус до отправки, который вернётся технике при приёме или отмене.
+type VehicleTransferItem struct {
+	VehicleID      int    `json:"vehicle_id"`
+	ModelName      string `json:"model_name"`
+	VIN            string `json:"vin"`
+	SerialNumber   string `json:"serial_number"`
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status"`
+}
+
+// VehicleTransferRequest - создание перемещения. Даты передаются в формате YYYY-MM-DD.
+type VehicleTransferRequest struct {
+	FromWarehouseID      int     `json:"from_warehouse_id"`
+	ToWarehouseID        int     `json:"to_warehouse_id"`
+	VehicleIDs           []int   `json:"vehicle_ids"`
+	PlannedDepartureDate string  `json:"planned_departure_date"`
+	PlannedArrivalDate   string  `json:"planned_arrival_date"`
+	DriverName           string  `json:"driver_name"`
+	TransportCost        float64 `json:"transport_cost"`
+	Notes                string  `json:"notes"`
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+)
+
+// AccountRepository - данные клиента CRM для личного кабинета.
+// Все выборки фильтруются по клиенту из привязки пользователя.
+type AccountRepository struct {
+	db *sql.DB
+}
+
+func NewAccountRepository(db *sql.DB) AccountRepository {
+	return AccountRepository{db: db}
+}
+
+// AccountSale - строка vw_sales_full_info для личного кабинета
+type AccountSale struct {
+	SaleID         int
+	ContractNumber sql.NullString
+	SaleDate       time.Time
+	VIN            sql.NullString
+	ModelName      string
+	TypeName       string
+	FinalPrice     float64
+	PaymentType    string
+	Status         string
+}
+
+// GetSales возвращает покупки клиента из vw_sales_full_info
+func (r *AccountRepository) GetSales(ctx context.Context, link *models.ClientLink) ([]AccountSale, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT vs.sale_id, vs.contract_number, vs.sale_date, vs.vin, vs.model_name, vs.type_name,
+		       vs.final_price, vs.payment_type, vs.status
+		FROM vw_sales_full_info vs
+		INNER JOIN sales s ON s.sale_id = vs.sale_id
+		WHERE s.customer_id = $1 OR s.corporate_client_id = $2
+		ORDER BY vs.sale_date DESC, vs.sale_id DESC`,
+		link.CustomerID, link.CorporateClientID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying client sales: %w", err)
+	}
+	defer rows.Close()
+
+	var sales []AccountSale
+	for rows.Next() {
+		var s AccountSale
+		err := rows.Scan(&s.SaleID, &s.ContractNumber, &s.SaleDate, &s.VIN, &s.ModelName,
+			&s.TypeName, &s.FinalPrice, &s.PaymentType, &s.Status)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning client sale: %w", err)
+		}
+		sales = append(sales, s)
+	}
+
+	return sales, rows.Err()
+}
+
+// GetTestDrives возвращает тест-драйвы клиента
+func (r *AccountRepository) GetTestDrives(ctx context.Context, link *models.ClientLink) ([]models.AccountTestDrive, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT td.test_drive_id, td.vehicle_id, vm.model_name, td.scheduled_date,
+		       COALESCE(td.duration, 60), td.status, e.last_name || ' ' || e.first_name,
+		       td.feedback_rating
+		FROM test_drives td
+		INNER JOIN vehicles v ON v.vehicle_id = td.vehicle_id
+		INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+		INNER JOIN employees e ON e.employee_id = td.employee_id
+		WHERE td.customer_id = $1 OR td.corporate_client_id = $2
+		ORDER BY td.scheduled_date DESC`,
+		link.CustomerID, link.CorporateClientID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying client test drives: %w", err)
+	}
+	defer rows.Close()
+
+	drives := []models.AccountTestDrive{}
+	for rows.Next() {
+		var td models.AccountTestDrive
+		var rating sql.NullInt64
+		err := rows.Scan(&td.ID, &td.VehicleID, &td.VehicleName, &td.ScheduledDate,
+			&td.Duration, &td.Status, &td.ManagerName, &rating)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning client test drive: %w", err)
+		}
+		td.FeedbackRating = nullIntPtr(rating)
+		drives = append(drives, td)
+	}
+
+	return drives, rows.Err()
+}
+
+// GetServiceOrders возвращает сервисные заказы клиента
+func (r *AccountRepository) GetServiceOrders(ctx context.Context, link *models.ClientLink) ([]models.AccountServiceOrder, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT so.service_order_id, so.vehicle_id, vm.model_name, so.service_type,
+		       COALESCE(so.description, ''), so.order_date, so.completion_date,
+		       COALESCE(so.cost, 0), so.status, e.last_name || ' ' || e.first_name
+		FROM service_orders so
+		INNER JOIN vehicles v ON v.vehicle_id = so.vehicle_id
+		INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+		INNER JOIN employees e ON e.employee_id = so.employee_id
+		WHERE so.customer_id = $1 OR so.corporate_client_id = $2
+		ORDER BY so.order_date DESC, so.service_order_id DESC`,
+		link.CustomerID, link.CorporateClientID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying client service orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []models.AccountServiceOrder{}
+	for rows.Next() {
+		var so models.AccountServiceOrder
+		err := rows.Scan(&so.ID, &so.VehicleID, &so.VehicleName, &so.ServiceType,
+			&so.Description, &so.OrderDate, &so.CompletionDate, &so.Cost, &so.Status, &so.MasterName)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning client service order: %w", err)
+		}
+		orders = append(orders, so)
+	}
+
+	return orders, rows.Err()
+}
+
+// GetFleet возвращает технику, купленную клиентом, со сводкой по сервису
+func (r *AccountRepository) GetFleet(ctx context.Context, link *models.ClientLink) ([]models.FleetVehicle, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT v.vehicle_id, vm.model_name, vt.type_name, COALESCE(v.vin, ''),
+		       COALESCE(v.serial_number, ''), v.manufacture_year, s.sale_id, s.sale_date,
+		       s.final_price,
+		       COUNT(so.service_order_id),
+		       COUNT(so.service_order_id) FILTER (WHERE so.status IN ('В работе', 'Приостановлен')),
+		       MAX(so.order_date)
+		FROM sales s
+		INNER JOIN vehicles v ON v.vehicle_id = s.vehicle_id
+		INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+		INNER JOIN vehicle_types vt ON vt.type_id = vm.type_id
+		LEFT JOIN service_orders so ON so.vehicle_id = v.vehicle_id
+		WHERE s.status = 'Завершена' AND (s.customer_id = $1 OR s.corporate_client_id = $2)
+		GROUP BY v.vehicle_id, vm.model_name, vt.type_name, v.vin, v.serial_number,
+		         v.manufacture_year, s.sale_id, s.sale_date, s.final_price
+		ORDER BY s.sale_date DESC, s.sale_id DESC`,
+		link.CustomerID, link.CorporateClientID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying client fleet: %w", err)
+	}
+	defer rows.Close()
+
+	fleet := []models.FleetVehicle{}
+	for rows.Next() {
+		var fv models.FleetVehicle
+		err := rows.Scan(&fv.VehicleID, &fv.ModelName, &fv.TypeName, &fv.VIN, &fv.SerialNumber,
+			&fv.Year, &fv.SaleID, &fv.PurchaseDate, &fv.Price, &fv.ServiceOrders,
+			&fv.OpenServiceOrders, &fv.LastServiceDate)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning fleet vehicle: %w", err)
+		}
+		fleet = append(fleet, fv)
+	}
+
+	return fleet, rows.Err()
+}
+
+// GetStats считает завершённые покупки, визиты в сервис и тест-драйвы клиента
+func (r *AccountRepository) GetStats(ctx context.Context, link *models.ClientLink) (*models.AccountStats, error) {
+	stats := &models.AccountStats{Linked: true}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM sales
+			 WHERE status = 'Завершена' AND (customer_id = $1 OR corporate_client_id = $2)),
+			(SELECT COALESCE(SUM(final_price), 0) FROM sales
+			 WHERE status = 'Завершена' AND (customer_id = $1 OR corporate_client_id = $2)),
+			(SELECT COUNT(*) FROM service_orders
+			 WHERE status <> 'Отменен' AND (customer_id = $1 OR corporate_client_id = $2)),
+			(SELECT COUNT(*) FROM test_drives
+			 WHERE customer_id = $1 OR corporate_client_id = $2)`,
+		link.CustomerID, link.CorporateClientID,
+	).Scan(&stats.TotalOrders, &stats.TotalSpent, &stats.ServiceVisits, &stats.TestDrives)
+	if err != nil {
+		return nil, fmt.Errorf("error querying client stats: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrCalendarFeedNotFound - подписки нет или она отозвана
+	ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+	// ErrCalendarFeedReference - сотрудник или склад подписки не найден
+	ErrCalendarFeedReference = errors.New("calendar feed employee or warehouse not found")
+)
+
+// CalendarTestDrive - тест-драйв для календарной ленты из vw_test_drives_full_info
+type CalendarTestDrive struct {
+	ID               int
+	Start            time.Time
+	Duration         int
+	Status           string
+	ModelName        string
+	TypeName         string
+	VIN              string
+	ClientName       string
+	ClientPhone      string
+	ManagerName      string
+	ManagerPhone     string
+	WarehouseName    string
+	WarehouseCity    string
+	WarehouseAddress string
+	CancelReason     string
+	Sequence         int
+}
+
+// CalendarServiceOrder - сервисный заказ для календарной ленты
+type CalendarServiceOrder struct {
+	ID               int
+	OrderDate        time.Time
+	CompletionDate   *time.Time
+	ServiceType      string
+	Description      string
+	Status           string
+	ModelName        string
+	VIN              string
+	ClientName       string
+	ClientPhone      string
+	MasterName       string
+	WarehouseName    string
+	WarehouseAddress string
+	Sequence         int
+}
+
+const calendarFeedSelect = `
+	SELECT f.feed_id, f.employee_id, f.warehouse_id,
+	       COALESCE(e.last_name || ' ' || e.first_name, w.warehouse_name, ''),
+	       f.created_by, f.created_at, f.last_accessed_at
+	FROM calendar_feeds f
+	LEFT JOIN employees e ON e.employee_id = f.employee_id
+	LEFT JOIN warehouses w ON w.warehouse_id = f.warehouse_id`
+
+const calendarTestDriveSelect = `
+	SELECT test_drive_id, scheduled_date, COALESCE(duration, 60), status,
+	       model_name, type_name, COALESCE(vin, ''),
+	       COALESCE(client_name, ''), COALESCE(client_phone, ''),
+	       manager_name, COALESCE(manager_phone, ''),
+	       warehouse_name, warehouse_city, warehouse_address,
+	       COALESCE(cancel_reason, ''), ical_sequence
+	FROM vw_test_drives_full_info`
+
+type CalendarFeedRepository struct {
+	db *sql.DB
+}
+
+func NewCalendarFeedRepository(db *sql.DB) CalendarFeedRepository {
+	return CalendarFeedRepository{db: db}
+}
+
+// Create сохраняет подписку с хешем токена
+func (r *CalendarFeedRepository) Create(ctx context.Context, employeeID, warehouseID *int, tokenHash string, createdBy int) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO calendar_feeds (token_hash, employee_id, warehouse_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING feed_id`,
+		tokenHash, employeeID, warehouseID, createdBy,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return 0, ErrCalendarFeedReference
+		}
+		return 0, fmt.Errorf("error creating calendar feed: %w", err)
+	}
+	return id, nil
+}
+
+// GetByID возвращает действующую подписку
+func (r *CalendarFeedRepository) GetByID(ctx context.Context, id int) (*models.CalendarFeed, error) {
+	feed, err := scanCalendarFeed(r.db.QueryRowContext(ctx,
+		calendarFeedSelect+` WHERE f.feed_id = $1 AND f.revoked_at IS NULL`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting calendar feed: %w", err)
+	}
+	return feed, nil
+}
+
+// GetAll возвращает действующие подписки; createdBy ограничивает выборку
+// подписками, созданными сотрудником
+func (r *CalendarFeedRepository) GetAll(ctx context.Context, createdBy *int) ([]models.CalendarFeed, error) {
+	rows, err := r.db.QueryContext(ctx, calendarFeedSelect+`
+		WHERE f.revoked_at IS NULL
+		  AND ($1::INTEGER IS NULL OR f.created_by = $1)
+		ORDER BY f.created_at DESC, f.feed_id DESC`, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("error querying calendar feeds: %w", err)
+	}
+	defer rows.Close()
+
+	feeds := []models.CalendarFeed{}
+	for rows.Next() {
+		feed, err := scanCalendarFeed(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning calendar feed: %w", err)
+		}
+		feeds = append(feeds, *feed)
+	}
+
+	return feeds, rows.Err()
+}
+
+// Revoke отзывает подписку: ссылка с её токеном перестаёт работать
+func (r *CalendarFeedRepository) Revoke(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE calendar_feeds SET revoked_at = CURRENT_TIMESTAMP
+		WHERE feed_id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("error revoking calendar feed: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error revoking calendar feed: %w", err)
+	}
+	if affected == 0 {
+		return ErrCalendarFeedNotFound
+	}
+	return nil
+}
+
+// Touch находит действующую подписку по хешу токена и отмечает обращение к ней
+func (r *CalendarFeedRepository) Touch(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE calendar_feeds SET last_accessed_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND revoked_at IS NULL
+		RETURNING feed_id`, tokenHash,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting calendar feed: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// GetTestDrives возвращает тест-драйвы сотрудника или техники склада,
+// начинающиеся не раньше from, включая отменённые
+func (r *CalendarFeedRepository) GetTestDrives(ctx context.Context, employeeID, warehouseID *int, from time.Time) ([]CalendarTestDrive, error) {
+	rows, err := r.db.QueryContext(ctx, calendarTestDriveSelect+`
+		WHERE ($1::INTEGER IS NULL OR employee_id = $1)
+		  AND ($2::INTEGER IS NULL OR warehouse_id = $2)
+		  AND scheduled_date >= $3
+		ORDER BY scheduled_date, test_drive_id`,
+		employeeID, warehouseID, from.In(utils.DealershipLocation))
+	if err != nil {
+		return nil, fmt.Errorf("error querying calendar test drives: %w", err)
+	}
+	defer rows.Close()
+
+	drives := []CalendarTestDrive{}
+	for rows.Next() {
+		td, err := scanCalendarTestDrive(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning calendar test drive: %w", err)
+		}
+		drives = append(drives, *td)
+	}
+
+	return drives, rows.Err()
+}
+
+// GetTestDrive возвращает тест-драйв для отдельного события календаря
+func (r *CalendarFeedRepository) GetTestDrive(ctx context.Context, id int) (*CalendarTestDrive, error) {
+	td, err := scanCalendarTestDrive(r.db.QueryRowContext(ctx,
+		calendarTestDriveSelect+` WHERE test_drive_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTestDriveNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting calendar test drive: %w", err)
+	}
+	return td, nil
+}
+
+// GetServiceOrders возвращает сервисные заказы мастера или мастеров склада:
+// незакрытые и закрытые не раньше from
+func (r *CalendarFeedRepository) GetServiceOrders(ctx context.Context, employeeID, warehouseID *int, from time.Time) ([]CalendarServiceOrder, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT so.service_order_id, so.order_date, so.completion_date, so.service_type,
+		       COALESCE(so.description, ''), so.status, vm.model_name, COALESCE(v.vin, ''),
+		       COALESCE(c.last_name || ' ' || c.first_name, cc.company_name, ''),
+		       COALESCE(c.phone, cc.phone, ''),
+		       e.last_name || ' ' || e.first_name,
+		       COALESCE(w.warehouse_name, ''), COALESCE(w.address, ''), so.ical_sequence
+		FROM service_orders so
+		INNER JOIN vehicles v ON v.vehicle_id = so.vehicle_id
+		INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+		INNER JOIN employees e ON e.employee_id = so.employee_id
+		LEFT JOIN warehouses w ON w.warehouse_id = e.warehouse_id
+		LEFT JOIN customers c ON c.customer_id = so.customer_id
+		LEFT JOIN corporate_clients cc ON cc.corporate_client_id = so.corporate_client_id
+		WHERE ($1::INTEGER IS NULL OR so.employee_id = $1)
+		  AND ($2::INTEGER IS NULL OR e.warehouse_id = $2)
+		  AND (so.status IN ('В работе', 'Приостановлен')
+		       OR COALESCE(so.completion_date, so.order_date) >= $3::date)
+		ORDER BY so.order_date, so.service_order_id`,
+		employeeID, warehouseID, from)
+	if err != nil {
+		return nil, fmt.Errorf("error querying calendar service orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []CalendarServiceOrder{}
+	for rows.Next() {
+		var so CalendarServiceOrder
+		err := rows.Scan(&so.ID, &so.OrderDate, &so.CompletionDate, &so.ServiceType,
+			&so.Description, &so.Status, &so.ModelName, &so.VIN,
+			&so.ClientName, &so.ClientPhone, &so.MasterName,
+			&so.WarehouseName, &so.WarehouseAddress, &so.Sequence)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning calendar service order: %w", err)
+		}
+		orders = append(orders, so)
+	}
+
+	return orders, rows.Err()
+}
+
+func scanCalendarFeed(row rowScanner) (*models.CalendarFeed, error) {
+	var f models.CalendarFeed
+	var employeeID, warehouseID, createdBy sql.NullInt64
+	err := row.Scan(&f.ID, &employeeID, &warehouseID, &f.Name, &createdBy, &f.CreatedAt, &f.LastAccessedAt)
+	if err != nil {
+		return nil, err
+	}
+	f.EmployeeID = nullIntPtr(employeeID)
+	f.WarehouseID = nullIntPtr(warehouseID)
+	f.CreatedBy = nullIntPtr(createdBy)
+	if f.EmployeeID != nil {
+		f.Scope = models.CalendarFeedEmployee
+	} else {
+		f.Scope = models.CalendarFeedWarehouse
+	}
+	return &f, nil
+}
+
+func scanCalendarTestDrive(row rowScanner) (*CalendarTestDrive, error) {
+	var td CalendarTestDrive
+	err := row.Scan(&td.ID, &td.Start, &td.Duration, &td.Status,
+		&td.ModelName, &td.TypeName, &td.VIN, &td.ClientName, &td.ClientPhone,
+		&td.ManagerName, &td.ManagerPhone, &td.WarehouseName, &td.WarehouseCity,
+		&td.WarehouseAddress, &td.CancelReason, &td.Sequence)
+	if err != nil {
+		return nil, err
+	}
+	td.Start = utils.DealershipWallTime(td.Start)
+	return &td, nil
+}
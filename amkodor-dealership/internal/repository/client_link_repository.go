@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrClientLinkNotFound = errors.New("user is not linked to a client")
+	// ErrClientAlreadyLinked - физическое лицо уже привязано к другому пользователю
+	ErrClientAlreadyLinked = errors.New("client already linked to another user")
+	ErrLinkedClientMissing = errors.New("customer or corporate client not found")
+	ErrLinkedUserMissing   = errors.New("user not found")
+	ErrClientClaimNotFound = errors.New("client link claim not found")
+)
+
+const clientLinkSelect = `
+	SELECT l.user_id,
+	       CASE WHEN l.customer_id IS NOT NULL THEN 'CUSTOMER' ELSE 'CORPORATE' END,
+	       l.customer_id, l.corporate_client_id,
+	       COALESCE(c.last_name || ' ' || c.first_name, cc.company_name),
+	       l.method, l.created_at
+	FROM user_client_links l
+	LEFT JOIN customers c ON c.customer_id = l.customer_id
+	LEFT JOIN corporate_clients cc ON cc.corporate_client_id = l.corporate_client_id
+`
+
+// ClientMatch - клиент CRM, найденный по контактам пользователя
+type ClientMatch struct {
+	CustomerID        *int
+	CorporateClientID *int
+	Name              string
+}
+
+type ClientLinkRepository struct {
+	db *sql.DB
+}
+
+func NewClientLinkRepository(db *sql.DB) ClientLinkRepository {
+	return ClientLinkRepository{db: db}
+}
+
+// GetByUser возвращает привязку пользователя к клиенту
+func (r *ClientLinkRepository) GetByUser(ctx context.Context, userID int) (*models.ClientLink, error) {
+	var link models.ClientLink
+	var customerID, corporateID sql.NullInt64
+	err := r.db.QueryRowContext(ctx, clientLinkSelect+` WHERE l.user_id = $1`, userID).Scan(
+		&link.UserID, &link.ClientType, &customerID, &corporateID,
+		&link.ClientName, &link.Method, &link.LinkedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrClientLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying client link: %w", err)
+	}
+
+	link.CustomerID = nullIntPtr(customerID)
+	link.CorporateClientID = nullIntPtr(corporateID)
+	return &link, nil
+}
+
+// Link привязывает пользователя к клиенту, заменяя прежнюю привязку.
+// linkedBy - сотрудник, сделавший привязку, nil - привязка подтверждена кодом.
+func (r *ClientLinkRepository) Link(ctx context.Context, userID int, customerID, corporateID *int, method string, linkedBy *int) error {
+	return linkClient(ctx, r.db, userID, customerID, corporateID, method, linkedBy)
+}
+
+// Unlink удаляет привязку пользователя
+func (r *ClientLinkRepository) Unlink(ctx context.Context, userID int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_client_links WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting client link: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrClientLinkNotFound
+	}
+	return nil
+}
+
+// FindByContact ищет клиентов по телефону (только цифры) или email.
+// Если указан УНП, поиск ведётся только среди юридических лиц.
+func (r *ClientLinkRepository) FindByContact(ctx context.Context, channel, value, taxID string) ([]ClientMatch, error) {
+	// Колонки контактов называются так же, как каналы: phone и email
+	contact := `regexp_replace(%s, '\D', '', 'g') = $1`
+	if channel == "email" {
+		contact = `LOWER(%s) = LOWER($1)`
+	}
+
+	query := `SELECT NULL::INTEGER, corporate_client_id, company_name
+			  FROM corporate_clients
+			  WHERE ` + fmt.Sprintf(contact, channel) + ` AND ($2 = '' OR tax_id = $2)`
+	if taxID == "" {
+		query = `SELECT customer_id, NULL::INTEGER, last_name || ' ' || first_name
+				 FROM customers
+				 WHERE ` + fmt.Sprintf(contact, channel) + `
+				 UNION ALL ` + query
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, value, taxID)
+	if err != nil {
+		return nil, fmt.Errorf("error searching clients: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []ClientMatch
+	for rows.Next() {
+		var m ClientMatch
+		var customerID, corporateID sql.NullInt64
+		if err := rows.Scan(&customerID, &corporateID, &m.Name); err != nil {
+			return nil, fmt.Errorf("error scanning client: %w", err)
+		}
+		m.CustomerID = nullIntPtr(customerID)
+		m.CorporateClientID = nullIntPtr(corporateID)
+		matches = append(matches, m)
+	}
+
+	return matches, rows.Err()
+}
+
+// CreateClaim сохраняет запрос на привязку с хешем кода подтверждения
+func (r *ClientLinkRepository) CreateClaim(ctx context.Context, claim *models.ClientLinkClaim) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO client_link_claims
+			(user_id, customer_id, corporate_client_id, channel, destination, code_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING claim_id`,
+		claim.UserID, claim.CustomerID, claim.CorporateClientID, claim.Channel,
+		claim.Destination, claim.CodeHash, claim.ExpiresAt,
+	).Scan(&claim.ID)
+	if err != nil {
+		return fmt.Errorf("error creating client link claim: %w", err)
+	}
+	return nil
+}
+
+// GetClaim возвращает запрос на привязку пользователя
+func (r *ClientLinkRepository) GetClaim(ctx context.Context, id, userID int) (*models.ClientLinkClaim, error) {
+	var claim models.ClientLinkClaim
+	var customerID, corporateID sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT claim_id, user_id, customer_id, corporate_client_id, channel, destination,
+		       code_hash, attempts, expires_at, confirmed_at
+		FROM client_link_claims
+		WHERE claim_id = $1 AND user_id = $2`, id, userID,
+	).Scan(&claim.ID, &claim.UserID, &customerID, &corporateID, &claim.Channel, &claim.Destination,
+		&claim.CodeHash, &claim.Attempts, &claim.ExpiresAt, &claim.ConfirmedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrClientClaimNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying client link claim: %w", err)
+	}
+
+	claim.CustomerID = nullIntPtr(customerID)
+	claim.CorporateClientID = nullIntPtr(corporateID)
+	return &claim, nil
+}
+
+// RegisterFailedAttempt увеличивает счётчик неверных кодов
+func (r *ClientLinkRepository) RegisterFailedAttempt(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE client_link_claims SET attempts = attempts + 1 WHERE claim_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error updating client link claim: %w", err)
+	}
+	return nil
+}
+
+// ConfirmClaim отмечает запрос подтверждённым и создаёт привязку
+func (r *ClientLinkRepository) ConfirmClaim(ctx context.Context, claim *models.ClientLinkClaim) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE client_link_claims SET confirmed_at = CURRENT_TIMESTAMP
+		WHERE claim_id = $1 AND confirmed_at IS NULL`, claim.ID)
+	if err != nil {
+		return fmt.Errorf("error confirming client link claim: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrClientClaimNotFound
+	}
+
+	err = linkClient(ctx, tx, claim.UserID, claim.CustomerID, claim.CorporateClientID,
+		models.ClientLinkVerification, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func linkClient(ctx context.Context, db execer, userID int, customerID, corporateID *int, method string, linkedBy *int) error {
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO user_client_links (user_id, customer_id, corporate_client_id, method, linked_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET customer_id = EXCLUDED.customer_id,
+		    corporate_client_id = EXCLUDED.corporate_client_id,
+		    method = EXCLUDED.method,
+		    linked_by = EXCLUDED.linked_by,
+		    created_at = CURRENT_TIMESTAMP`,
+		userID, customerID, corporateID, method, linkedBy)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return ErrClientAlreadyLinked
+			case "23503":
+				if pqErr.Constraint == "user_client_links_user_id_fkey" {
+					return ErrLinkedUserMissing
+				}
+				return ErrLinkedClientMissing
+			}
+		}
+		return fmt.Errorf("error linking client: %w", err)
+	}
+	return nil
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	id := int(v.Int64)
+	return &id
+}
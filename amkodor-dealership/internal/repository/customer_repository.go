@@ -2,9 +2,26 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	// ErrCustomerInUse - у клиента есть продажи, тест-драйвы или сервисные заказы
+	ErrCustomerInUse = errors.New("customer has related records")
+	// ErrCustomerMergeConflict - оба клиента привязаны к учётным записям личного кабинета
+	ErrCustomerMergeConflict = errors.New("both customers are linked to user accounts")
+
+	ErrCorporateClientNotFound = errors.New("corporate client not found")
+	ErrDuplicateTaxID          = errors.New("corporate client with this tax id already exists")
+	ErrDuplicateContractNumber = errors.New("contract number already used")
+	// ErrCorporateClientInUse - у клиента есть продажи, тест-драйвы или сервисные заказы
+	ErrCorporateClientInUse = errors.New("corporate client has related records")
 )
 
 type CustomerRepository struct {
@@ -15,67 +32,94 @@ func NewCustomerRepository(db *sql.DB) CustomerRepository {
 	return CustomerRepository{db: db}
 }
 
-// GetAll возвращает всех клиентов
-func (r *CustomerRepository) GetAll(limit, offset int) ([]models.Customer, error) {
-	query := `
-		SELECT 
-			customer_id, first_name, last_name, middle_name, phone, email,
-			passport_number, address, date_of_birth, discount_percent, is_vip,
-			created_at, updated_at
-		FROM customers
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+// customerColumns - карточка клиента с итогами покупок из vw_all_clients
+const customerColumns = `
+	c.customer_id, c.first_name, c.last_name, c.middle_name, c.phone, c.email,
+	c.passport_number, c.address, c.date_of_birth, c.discount_percent, c.is_vip,
+	c.created_at, c.updated_at,
+	ac.total_purchases, ac.total_spent, ac.last_purchase_date
+`
+
+const customerFrom = `
+	FROM customers c
+	INNER JOIN vw_all_clients ac
+		ON ac.client_type = 'CUSTOMER' AND ac.client_id = c.customer_id
+`
+
+// customerQuickSearch - поиск по ФИО, email и паспорту; $2 - цифры запроса
+// для поиска по телефону
+const customerQuickSearch = `
+	$1 = ''
+	OR c.last_name || ' ' || c.first_name || COALESCE(' ' || c.middle_name, '') ILIKE '%' || $1 || '%'
+	OR c.email ILIKE '%' || $1 || '%'
+	OR UPPER(c.passport_number) = UPPER($1)
+	OR ($2 <> '' AND regexp_replace(c.phone, '\D', '', 'g') LIKE '%' || $2 || '%')
+`
+
+func scanCustomer(row rowScanner, extra ...interface{}) (*models.Customer, error) {
+	var c models.Customer
+	dest := []interface{}{
+		&c.CustomerID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Phone,
+		&c.Email, &c.PassportNumber, &c.Address, &c.DateOfBirth,
+		&c.DiscountPercent, &c.IsVIP, &c.CreatedAt, &c.UpdatedAt,
+		&c.TotalPurchases, &c.TotalSpent, &c.LastPurchaseDate,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetPage возвращает страницу клиентов и общее количество записей.
+// search ищет по ФИО, email и паспорту, phoneDigits - по цифрам телефона.
+func (r *CustomerRepository) GetPage(search, phoneDigits string, limit, offset int) ([]models.Customer, int, error) {
+	query := `SELECT ` + customerColumns + `, COUNT(*) OVER()` + customerFrom + `
+		WHERE ` + customerQuickSearch + `
+		ORDER BY c.created_at DESC, c.customer_id DESC
+		LIMIT $3 OFFSET $4`
 
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.Query(query, search, phoneDigits, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("error querying customers: %w", err)
+		return nil, 0, fmt.Errorf("error querying customers: %w", err)
 	}
 	defer rows.Close()
 
+	total := 0
 	customers := []models.Customer{}
 	for rows.Next() {
-		var c models.Customer
-		err := rows.Scan(
-			&c.CustomerID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Phone,
-			&c.Email, &c.PassportNumber, &c.Address, &c.DateOfBirth,
-			&c.DiscountPercent, &c.IsVIP, &c.CreatedAt, &c.UpdatedAt,
-		)
+		c, err := scanCustomer(rows, &total)
 		if err != nil {
-			return nil, fmt.Errorf("error scanning customer: %w", err)
+			return nil, 0, fmt.Errorf("error scanning customer: %w", err)
+		}
+		customers = append(customers, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating customers: %w", err)
+	}
+
+	// За пределами последней страницы COUNT(*) OVER() недоступен
+	if len(customers) == 0 && offset > 0 {
+		err := r.db.QueryRow(`SELECT COUNT(*) FROM customers c WHERE `+customerQuickSearch, search, phoneDigits).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error counting customers: %w", err)
 		}
-		customers = append(customers, c)
 	}
 
-	return customers, nil
+	return customers, total, nil
 }
 
 // GetByID возвращает клиента по ID
 func (r *CustomerRepository) GetByID(id int) (*models.Customer, error) {
-	query := `
-		SELECT 
-			customer_id, first_name, last_name, middle_name, phone, email,
-			passport_number, address, date_of_birth, discount_percent, is_vip,
-			created_at, updated_at
-		FROM customers
-		WHERE customer_id = $1
-	`
-
-	var c models.Customer
-	err := r.db.QueryRow(query, id).Scan(
-		&c.CustomerID, &c.FirstName, &c.LastName, &c.MiddleName, &c.Phone,
-		&c.Email, &c.PassportNumber, &c.Address, &c.DateOfBirth,
-		&c.DiscountPercent, &c.IsVIP, &c.CreatedAt, &c.UpdatedAt,
-	)
-
+	c, err := scanCustomer(r.db.QueryRow(
+		`SELECT `+customerColumns+customerFrom+` WHERE c.customer_id = $1`, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("customer not found")
+		return nil, ErrCustomerNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error querying customer: %w", err)
 	}
 
-	return &c, nil
+	return c, nil
 }
 
 // Create создает нового клиента
@@ -137,18 +181,23 @@ func (r *CustomerRepository) Update(c *models.Customer) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("customer not found")
+		return ErrCustomerNotFound
 	}
 
 	return nil
 }
 
-// Delete удаляет клиента
+// Delete удаляет клиента без продаж, тест-драйвов и сервисных заказов
 func (r *CustomerRepository) Delete(id int) error {
 	query := `DELETE FROM customers WHERE customer_id = $1`
 
 	result, err := r.db.Exec(query, id)
 	if err != nil {
+		// ON DELETE SET NULL нарушает CHECK "ровно один клиент"
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23514" {
+			return ErrCustomerInUse
+		}
 		return fmt.Errorf("error deleting customer: %w", err)
 	}
 
@@ -158,25 +207,26 @@ func (r *CustomerRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("customer not found")
+		return ErrCustomerNotFound
 	}
 
 	return nil
 }
 
 // Search ищет клиентов (используя процедуру)
-func (r *CustomerRepository) Search(params map[string]interface{}) ([]models.Customer, error) {
+func (r *CustomerRepository) Search(params models.CustomerSearchParams) ([]models.Customer, error) {
 	query := `
-		SELECT * FROM sp_search_customers($1, $2, $3, $4, $5)
+		SELECT * FROM sp_search_customers($1, $2, $3, $4, $5, $6)
 	`
 
 	rows, err := r.db.Query(
 		query,
-		params["search_term"],
-		params["phone"],
-		params["email"],
-		params["is_vip"],
-		params["min_discount"],
+		nullIfEmpty(params.Name),
+		nullIfEmpty(params.Phone),
+		nullIfEmpty(params.Email),
+		params.IsVIP,
+		params.MinDiscount,
+		nullIfEmpty(params.Passport),
 	)
 
 	if err != nil {
@@ -198,7 +248,7 @@ func (r *CustomerRepository) Search(params map[string]interface{}) ([]models.Cus
 		customers = append(customers, c)
 	}
 
-	return customers, nil
+	return customers, rows.Err()
 }
 
 // Count возвращает общее количество клиентов
@@ -212,99 +262,366 @@ func (r *CustomerRepository) Count() (int, error) {
 	return count, nil
 }
 
-// GetAllCorporate возвращает всех корпоративных клиентов
-func (r *CustomerRepository) GetAllCorporate(limit, offset int) ([]models.CorporateClient, error) {
+// FindDuplicates ищет карточки с тем же телефоном, email, паспортом или
+// ФИО и датой рождения. excludeID исключает саму проверяемую карточку.
+func (r *CustomerRepository) FindDuplicates(c *models.Customer, excludeID int) ([]models.CustomerDuplicate, error) {
 	query := `
-		SELECT 
-			corporate_client_id, company_name, tax_id, legal_address,
-			contact_person, phone, email, bank_account, bank_name,
-			discount_percent, contract_number, contract_date,
-			created_at, updated_at
-		FROM corporate_clients
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
+		SELECT customer_id, last_name || ' ' || first_name || COALESCE(' ' || middle_name, ''),
+		       phone, COALESCE(email, ''),
+		       regexp_replace(phone, '\D', '', 'g') = regexp_replace($2, '\D', '', 'g'),
+		       COALESCE($3 <> '' AND LOWER(email) = LOWER($3), FALSE),
+		       COALESCE($4 <> '' AND UPPER(passport_number) = UPPER($4), FALSE),
+		       COALESCE(LOWER(last_name) = LOWER($5) AND LOWER(first_name) = LOWER($6)
+		           AND LOWER(COALESCE(middle_name, '')) = LOWER($7)
+		           AND date_of_birth = $8, FALSE)
+		FROM customers
+		WHERE customer_id <> $1
+		  AND (regexp_replace(phone, '\D', '', 'g') = regexp_replace($2, '\D', '', 'g')
+		       OR ($3 <> '' AND LOWER(email) = LOWER($3))
+		       OR ($4 <> '' AND UPPER(passport_number) = UPPER($4))
+		       OR (LOWER(last_name) = LOWER($5) AND LOWER(first_name) = LOWER($6)
+		           AND LOWER(COALESCE(middle_name, '')) = LOWER($7)
+		           AND date_of_birth = $8))
+		ORDER BY customer_id
 	`
 
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.Query(query, excludeID, c.Phone, c.Email.String, c.PassportNumber.String,
+		c.LastName, c.FirstName, c.MiddleName.String, c.DateOfBirth)
 	if err != nil {
-		return nil, fmt.Errorf("error querying corporate clients: %w", err)
+		return nil, fmt.Errorf("error searching duplicate customers: %w", err)
 	}
 	defer rows.Close()
 
-	clients := []models.CorporateClient{}
+	duplicates := []models.CustomerDuplicate{}
 	for rows.Next() {
-		var c models.CorporateClient
-		err := rows.Scan(
-			&c.CorporateClientID, &c.CompanyName, &c.TaxID, &c.LegalAddress,
-			&c.ContactPerson, &c.Phone, &c.Email, &c.BankAccount, &c.BankName,
-			&c.DiscountPercent, &c.ContractNumber, &c.ContractDate,
-			&c.CreatedAt, &c.UpdatedAt,
+		var d models.CustomerDuplicate
+		var byPhone, byEmail, byPassport, byName bool
+		err := rows.Scan(&d.ID, &d.FullName, &d.Phone, &d.Email,
+			&byPhone, &byEmail, &byPassport, &byName)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning duplicate customer: %w", err)
+		}
+
+		d.Reasons = []string{}
+		if byPhone {
+			d.Reasons = append(d.Reasons, models.DuplicateByPhone)
+		}
+		if byEmail {
+			d.Reasons = append(d.Reasons, models.DuplicateByEmail)
+		}
+		if byPassport {
+			d.Reasons = append(d.Reasons, models.DuplicateByPassport)
+		}
+		if byName {
+			d.Reasons = append(d.Reasons, models.DuplicateByName)
+		}
+		duplicates = append(duplicates, d)
+	}
+
+	return duplicates, rows.Err()
+}
+
+// Merge переносит продажи, тест-драйвы, сервисные заказы и привязку к
+// учётной записи дубля на оставшегося клиента, дополняет его пустые поля
+// данными дубля и удаляет дубль. Снимок удалённой карточки сохраняется
+// в customer_merges.
+func (r *CustomerRepository) Merge(survivorID, duplicateID int, mergedBy *int) (*models.CustomerMerge, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT customer_id FROM customers
+			WHERE customer_id IN ($1, $2)
+			ORDER BY customer_id
+			FOR UPDATE
+		) c`, survivorID, duplicateID).Scan(&locked)
+	if err != nil {
+		return nil, fmt.Errorf("error locking customers: %w", err)
+	}
+	if locked != 2 {
+		return nil, ErrCustomerNotFound
+	}
+
+	var linked int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM user_client_links WHERE customer_id IN ($1, $2)`,
+		survivorID, duplicateID).Scan(&linked)
+	if err != nil {
+		return nil, fmt.Errorf("error querying client links: %w", err)
+	}
+	if linked == 2 {
+		return nil, ErrCustomerMergeConflict
+	}
+
+	merge := &models.CustomerMerge{SurvivorID: &survivorID, MergedCustomerID: duplicateID, MergedBy: mergedBy}
+	moves := []struct {
+		table string
+		count *int
+	}{
+		{"sales", &merge.SalesMoved},
+		{"test_drives", &merge.TestDrivesMoved},
+		{"service_orders", &merge.ServiceOrdersMoved},
+		{"vehicle_reservations", nil},
+		{"user_client_links", nil},
+		{"client_link_claims", nil},
+	}
+	for _, m := range moves {
+		result, err := tx.Exec(`UPDATE `+m.table+` SET customer_id = $1 WHERE customer_id = $2`,
+			survivorID, duplicateID)
+		if err != nil {
+			return nil, fmt.Errorf("error moving %s: %w", m.table, err)
+		}
+		if m.count != nil {
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("error getting rows affected: %w", err)
+			}
+			*m.count = int(affected)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE customers s SET
+			middle_name = COALESCE(s.middle_name, d.middle_name),
+			email = COALESCE(s.email, d.email),
+			passport_number = COALESCE(s.passport_number, d.passport_number),
+			address = COALESCE(s.address, d.address),
+			date_of_birth = COALESCE(s.date_of_birth, d.date_of_birth),
+			discount_percent = GREATEST(s.discount_percent, d.discount_percent),
+			is_vip = s.is_vip OR d.is_vip,
+			updated_at = CURRENT_TIMESTAMP
+		FROM customers d
+		WHERE s.customer_id = $1 AND d.customer_id = $2`, survivorID, duplicateID)
+	if err != nil {
+		return nil, fmt.Errorf("error updating surviving customer: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO customer_merges (
+			survivor_id, merged_customer_id, merged_data,
+			sales_moved, test_drives_moved, service_orders_moved, merged_by
 		)
+		SELECT $1, c.customer_id, row_to_json(c)::jsonb, $3, $4, $5, $6
+		FROM customers c
+		WHERE c.customer_id = $2
+		RETURNING merge_id, merged_data, merged_at`,
+		survivorID, duplicateID, merge.SalesMoved, merge.TestDrivesMoved,
+		merge.ServiceOrdersMoved, mergedBy,
+	).Scan(&merge.ID, &merge.MergedData, &merge.MergedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error recording customer merge: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM customers WHERE customer_id = $1`, duplicateID); err != nil {
+		return nil, fmt.Errorf("error deleting merged customer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return merge, nil
+}
+
+// GetMerges возвращает журнал объединений, в которых клиент был оставлен
+func (r *CustomerRepository) GetMerges(customerID int) ([]models.CustomerMerge, error) {
+	rows, err := r.db.Query(`
+		SELECT merge_id, survivor_id, merged_customer_id, merged_data,
+		       sales_moved, test_drives_moved, service_orders_moved, merged_by, merged_at
+		FROM customer_merges
+		WHERE survivor_id = $1
+		ORDER BY merged_at DESC, merge_id DESC`, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying customer merges: %w", err)
+	}
+	defer rows.Close()
+
+	merges := []models.CustomerMerge{}
+	for rows.Next() {
+		var m models.CustomerMerge
+		var survivorID, mergedBy sql.NullInt64
+		err := rows.Scan(&m.ID, &survivorID, &m.MergedCustomerID, &m.MergedData,
+			&m.SalesMoved, &m.TestDrivesMoved, &m.ServiceOrdersMoved, &mergedBy, &m.MergedAt)
 		if err != nil {
-			return nil, fmt.Errorf("error scanning corporate client: %w", err)
+			return nil, fmt.Errorf("error scanning customer merge: %w", err)
 		}
-		clients = append(clients, c)
+		m.SurvivorID = nullIntPtr(survivorID)
+		m.MergedBy = nullIntPtr(mergedBy)
+		merges = append(merges, m)
 	}
 
-	return clients, nil
+	return merges, rows.Err()
 }
 
-// GetCorporateByID возвращает корпоративного клиента по ID
-func (r *CustomerRepository) GetCorporateByID(id int) (*models.CorporateClient, error) {
-	query := `
-		SELECT 
-			corporate_client_id, company_name, tax_id, legal_address,
-			contact_person, phone, email, bank_account, bank_name,
-			discount_percent, contract_number, contract_date,
-			created_at, updated_at
-		FROM corporate_clients
-		WHERE corporate_client_id = $1
-	`
+// nullIfEmpty передаёт пустую строку в процедуру как NULL
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
 
+// corporateClientColumns - карточка юр. лица с итогами покупок из vw_all_clients
+const corporateClientColumns = `
+	cc.corporate_client_id, cc.company_name, cc.tax_id, cc.legal_address,
+	cc.contact_person, cc.phone, cc.email, cc.bank_account, cc.bank_name, cc.bank_bic,
+	cc.discount_percent, cc.contract_number, cc.contract_date,
+	cc.created_at, cc.updated_at,
+	ac.total_purchases, ac.total_spent, ac.last_purchase_date
+`
+
+const corporateClientFrom = `
+	FROM corporate_clients cc
+	INNER JOIN vw_all_clients ac
+		ON ac.client_type = 'CORPORATE' AND ac.client_id = cc.corporate_client_id
+`
+
+// corporateClientSearch - поиск по названию, УНП и номеру договора
+const corporateClientSearch = `
+	$1 = ''
+	OR cc.company_name ILIKE '%' || $1 || '%'
+	OR cc.tax_id = $1
+	OR cc.contract_number ILIKE '%' || $1 || '%'
+`
+
+func scanCorporateClient(row rowScanner, extra ...interface{}) (*models.CorporateClient, error) {
 	var c models.CorporateClient
-	err := r.db.QueryRow(query, id).Scan(
+	dest := []interface{}{
 		&c.CorporateClientID, &c.CompanyName, &c.TaxID, &c.LegalAddress,
-		&c.ContactPerson, &c.Phone, &c.Email, &c.BankAccount, &c.BankName,
+		&c.ContactPerson, &c.Phone, &c.Email, &c.BankAccount, &c.BankName, &c.BankBIC,
 		&c.DiscountPercent, &c.ContractNumber, &c.ContractDate,
 		&c.CreatedAt, &c.UpdatedAt,
-	)
+		&c.TotalPurchases, &c.TotalSpent, &c.LastPurchaseDate,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetAllCorporate возвращает страницу корпоративных клиентов и общее
+// количество записей. search ищет по названию, УНП и номеру договора.
+func (r *CustomerRepository) GetAllCorporate(search string, limit, offset int) ([]models.CorporateClient, int, error) {
+	query := `SELECT ` + corporateClientColumns + `, COUNT(*) OVER()` + corporateClientFrom + `
+		WHERE ` + corporateClientSearch + `
+		ORDER BY cc.company_name, cc.corporate_client_id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(query, search, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying corporate clients: %w", err)
+	}
+	defer rows.Close()
+
+	total := 0
+	clients := []models.CorporateClient{}
+	for rows.Next() {
+		c, err := scanCorporateClient(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning corporate client: %w", err)
+		}
+		clients = append(clients, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating corporate clients: %w", err)
+	}
+
+	// За пределами последней страницы COUNT(*) OVER() недоступен
+	if len(clients) == 0 && offset > 0 {
+		err := r.db.QueryRow(`SELECT COUNT(*) FROM corporate_clients cc WHERE `+corporateClientSearch, search).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error counting corporate clients: %w", err)
+		}
+	}
 
+	return clients, total, nil
+}
+
+// GetCorporateByID возвращает корпоративного клиента с контактными лицами
+func (r *CustomerRepository) GetCorporateByID(id int) (*models.CorporateClient, error) {
+	c, err := scanCorporateClient(r.db.QueryRow(
+		`SELECT `+corporateClientColumns+corporateClientFrom+` WHERE cc.corporate_client_id = $1`, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("corporate client not found")
+		return nil, ErrCorporateClientNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error querying corporate client: %w", err)
 	}
 
-	return &c, nil
+	rows, err := r.db.Query(`
+		SELECT contact_id, full_name, COALESCE(position, ''), COALESCE(phone, ''),
+		       COALESCE(email, ''), is_primary
+		FROM corporate_client_contacts
+		WHERE corporate_client_id = $1
+		ORDER BY is_primary DESC, contact_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying corporate contacts: %w", err)
+	}
+	defer rows.Close()
+
+	c.Contacts = []models.CorporateContact{}
+	for rows.Next() {
+		var contact models.CorporateContact
+		err := rows.Scan(&contact.ID, &contact.FullName, &contact.Position,
+			&contact.Phone, &contact.Email, &contact.IsPrimary)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning corporate contact: %w", err)
+		}
+		c.Contacts = append(c.Contacts, contact)
+	}
+
+	return c, rows.Err()
 }
 
-// CreateCorporate создает корпоративного клиента
+// CreateCorporate создает корпоративного клиента вместе с контактными лицами
 func (r *CustomerRepository) CreateCorporate(c *models.CorporateClient) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO corporate_clients (
 			company_name, tax_id, legal_address, contact_person, phone, email,
-			bank_account, bank_name, discount_percent, contract_number, contract_date
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			bank_account, bank_name, bank_bic, discount_percent, contract_number, contract_date
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING corporate_client_id
 	`
 
 	var id int
-	err := r.db.QueryRow(
+	err = tx.QueryRow(
 		query,
 		c.CompanyName, c.TaxID, c.LegalAddress, c.ContactPerson, c.Phone, c.Email,
-		c.BankAccount, c.BankName, c.DiscountPercent, c.ContractNumber, c.ContractDate,
+		c.BankAccount, c.BankName, c.BankBIC, c.DiscountPercent, c.ContractNumber, c.ContractDate,
 	).Scan(&id)
-
 	if err != nil {
-		return 0, fmt.Errorf("error creating corporate client: %w", err)
+		return 0, corporateClientError("error creating corporate client", err)
+	}
+
+	if err := replaceCorporateContacts(tx, id, c.Contacts); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
 	}
 
 	return id, nil
 }
 
-// UpdateCorporate обновляет корпоративного клиента
+// UpdateCorporate обновляет корпоративного клиента и заменяет контактных лиц
 func (r *CustomerRepository) UpdateCorporate(c *models.CorporateClient) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE corporate_clients SET
 			company_name = $1,
@@ -315,42 +632,55 @@ func (r *CustomerRepository) UpdateCorporate(c *models.CorporateClient) error {
 			email = $6,
 			bank_account = $7,
 			bank_name = $8,
-			discount_percent = $9,
-			contract_number = $10,
-			contract_date = $11,
+			bank_bic = $9,
+			discount_percent = $10,
+			contract_number = $11,
+			contract_date = $12,
 			updated_at = CURRENT_TIMESTAMP
-		WHERE corporate_client_id = $12
+		WHERE corporate_client_id = $13
 	`
 
-	result, err := r.db.Exec(
+	result, err := tx.Exec(
 		query,
 		c.CompanyName, c.TaxID, c.LegalAddress, c.ContactPerson, c.Phone, c.Email,
-		c.BankAccount, c.BankName, c.DiscountPercent, c.ContractNumber, c.ContractDate,
+		c.BankAccount, c.BankName, c.BankBIC, c.DiscountPercent, c.ContractNumber, c.ContractDate,
 		c.CorporateClientID,
 	)
-
 	if err != nil {
-		return fmt.Errorf("error updating corporate client: %w", err)
+		return corporateClientError("error updating corporate client", err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("error getting rows affected: %w", err)
 	}
-
 	if rows == 0 {
-		return fmt.Errorf("corporate client not found")
+		return ErrCorporateClientNotFound
+	}
+
+	if err := replaceCorporateContacts(tx, c.CorporateClientID, c.Contacts); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
 	}
 
 	return nil
 }
 
-// DeleteCorporate удаляет корпоративного клиента
+// DeleteCorporate удаляет корпоративного клиента без продаж, тест-драйвов и сервисных заказов
 func (r *CustomerRepository) DeleteCorporate(id int) error {
 	query := `DELETE FROM corporate_clients WHERE corporate_client_id = $1`
 
 	result, err := r.db.Exec(query, id)
 	if err != nil {
+		// ON DELETE SET NULL нарушает CHECK "ровно один клиент" в продажах,
+		// тест-драйвах и сервисных заказах
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23514" {
+			return ErrCorporateClientInUse
+		}
 		return fmt.Errorf("error deleting corporate client: %w", err)
 	}
 
@@ -360,8 +690,41 @@ func (r *CustomerRepository) DeleteCorporate(id int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("corporate client not found")
+		return ErrCorporateClientNotFound
+	}
+
+	return nil
+}
+
+func replaceCorporateContacts(tx *sql.Tx, clientID int, contacts []models.CorporateContact) error {
+	if _, err := tx.Exec(`DELETE FROM corporate_client_contacts WHERE corporate_client_id = $1`, clientID); err != nil {
+		return fmt.Errorf("error deleting corporate contacts: %w", err)
+	}
+
+	for _, contact := range contacts {
+		_, err := tx.Exec(`
+			INSERT INTO corporate_client_contacts
+				(corporate_client_id, full_name, position, phone, email, is_primary)
+			VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6)`,
+			clientID, contact.FullName, contact.Position, contact.Phone, contact.Email, contact.IsPrimary)
+		if err != nil {
+			return fmt.Errorf("error creating corporate contact: %w", err)
+		}
 	}
 
 	return nil
 }
+
+// corporateClientError переводит нарушения уникальности УНП и номера договора в ошибки репозитория
+func corporateClientError(msg string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23505" && pqErr.Constraint == "corporate_clients_tax_id_key":
+			return ErrDuplicateTaxID
+		case pqErr.Code == "23505" && pqErr.Constraint == "idx_corporate_clients_contract_number":
+			return ErrDuplicateContractNumber
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+)
+
+// dashboardStatisticsColumns - колонки vw_dashboard_statistics в порядке models.DashboardStatistics
+const dashboardStatisticsColumns = `
+	available_vehicles, sales_last_month, revenue_last_month, total_customers,
+	total_corporate_clients, upcoming_test_drives, active_service_orders,
+	total_vehicles, total_sales, total_revenue, total_employees
+`
+
+type dashboardRepository struct {
+	db *sql.DB
+}
+
+func NewDashboardRepository(db *sql.DB) DashboardRepository {
+	return &dashboardRepository{db: db}
+}
+
+// GetDashboardStatistics возвращает сводку из vw_dashboard_statistics, а для
+// отдельного склада - из fn_dashboard_statistics, на которой построено представление
+func (r *dashboardRepository) GetDashboardStatistics(ctx context.Context, warehouseID *int) (*models.DashboardStatistics, error) {
+	query := `SELECT ` + dashboardStatisticsColumns + ` FROM vw_dashboard_statistics`
+	args := []interface{}{}
+	if warehouseID != nil {
+		query = `SELECT ` + dashboardStatisticsColumns + ` FROM fn_dashboard_statistics($1)`
+		args = append(args, *warehouseID)
+	}
+
+	var st models.DashboardStatistics
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(
+		&st.AvailableVehicles, &st.SalesLastMonth, &st.RevenueLastMonth, &st.TotalCustomers,
+		&st.TotalCorporateClients, &st.UpcomingTestDrives, &st.ActiveServiceOrders,
+		&st.TotalVehicles, &st.TotalSales, &st.TotalRevenue, &st.TotalEmployees,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error querying dashboard statistics: %w", err)
+	}
+
+	return &st, nil
+}
+
+// GetSalesChartData группирует завершённые продажи по интервалам period
+// (day, week, month) от from до to. Интервалы без продаж возвращаются с нулями.
+func (r *dashboardRepository) GetSalesChartData(ctx context.Context, period string, from, to time.Time, warehouseID *int) ([]models.ChartData, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT b.bucket::DATE, COUNT(s.sale_id), COALESCE(SUM(s.final_price), 0)
+		FROM generate_series(date_trunc($1, $2::TIMESTAMP), $3::TIMESTAMP, ('1 ' || $1)::INTERVAL) AS b(bucket)
+		LEFT JOIN (
+			SELECT s.sale_id, s.sale_date, s.final_price
+			FROM sales s
+			INNER JOIN vehicles v ON v.vehicle_id = s.vehicle_id
+			WHERE s.status = 'Завершена' AND ($4::INTEGER IS NULL OR v.warehouse_id = $4)
+		) s ON date_trunc($1, s.sale_date::TIMESTAMP) = b.bucket
+		GROUP BY b.bucket
+		ORDER BY b.bucket`,
+		period, from, to, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sales chart: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.ChartData{}
+	for rows.Next() {
+		var p models.ChartData
+		var bucket time.Time
+		if err := rows.Scan(&bucket, &p.Count, &p.Value); err != nil {
+			return nil, fmt.Errorf("error scanning sales chart point: %w", err)
+		}
+		p.Date = bucket.Format("2006-01-02")
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// GetCategorySales возвращает продажи по категориям техники
+func (r *dashboardRepository) GetCategorySales(ctx context.Context, warehouseID *int) ([]models.CategorySales, error) {
+	query := `
+		SELECT category_id, category_name, total_sales, total_revenue, average_sale_price
+		FROM vw_sales_statistics_by_category
+		ORDER BY total_revenue DESC, category_name`
+	args := []interface{}{}
+	if warehouseID != nil {
+		query = `
+			SELECT category_id, category_name, SUM(total_sales), SUM(total_revenue),
+			       COALESCE(SUM(total_revenue) / NULLIF(SUM(total_sales), 0), 0)
+			FROM vw_sales_statistics_by_category_warehouse
+			WHERE warehouse_id = $1
+			GROUP BY category_id, category_name
+			ORDER BY 4 DESC, category_name`
+		args = append(args, *warehouseID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying category sales: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.CategorySales{}
+	for rows.Next() {
+		var c models.CategorySales
+		if err := rows.Scan(&c.CategoryID, &c.CategoryName, &c.SalesCount, &c.Revenue, &c.AverageSalePrice); err != nil {
+			return nil, fmt.Errorf("error scanning category sales: %w", err)
+		}
+		categories = append(categories, c)
+	}
+
+	return categories, rows.Err()
+}
+
+// GetTopEmployees возвращает менеджеров с наибольшей выручкой
+func (r *dashboardRepository) GetTopEmployees(ctx context.Context, limit int, warehouseID *int) ([]models.TopEmployee, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT employee_id, manager_name, COALESCE(warehouse_name, ''), total_sales,
+		       total_revenue, average_sale_price, last_sale_date
+		FROM vw_sales_statistics_by_manager
+		WHERE $2::INTEGER IS NULL OR warehouse_id = $2
+		ORDER BY total_revenue DESC, total_sales DESC, manager_name
+		LIMIT $1`, limit, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying top employees: %w", err)
+	}
+	defer rows.Close()
+
+	employees := []models.TopEmployee{}
+	for rows.Next() {
+		var e models.TopEmployee
+		err := rows.Scan(&e.EmployeeID, &e.EmployeeName, &e.WarehouseName, &e.SalesCount,
+			&e.Revenue, &e.AverageSalePrice, &e.LastSaleDate)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning top employee: %w", err)
+		}
+		employees = append(employees, e)
+	}
+
+	return employees, rows.Err()
+}
+
+// GetRecentSales возвращает последние продажи из vw_sales_full_info
+func (r *dashboardRepository) GetRecentSales(ctx context.Context, limit int, warehouseID *int) ([]models.RecentSale, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT vs.sale_id, COALESCE(vs.contract_number, ''), vs.sale_date, vs.model_name,
+		       COALESCE(vs.client_name, ''), vs.manager_name, vs.warehouse_name, vs.final_price, vs.status
+		FROM vw_sales_full_info vs
+		INNER JOIN sales s ON s.sale_id = vs.sale_id
+		INNER JOIN vehicles v ON v.vehicle_id = s.vehicle_id
+		WHERE $2::INTEGER IS NULL OR v.warehouse_id = $2
+		ORDER BY vs.sale_date DESC, vs.sale_id DESC
+		LIMIT $1`, limit, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying recent sales: %w", err)
+	}
+	defer rows.Close()
+
+	sales := []models.RecentSale{}
+	for rows.Next() {
+		var s models.RecentSale
+		err := rows.Scan(&s.SaleID, &s.ContractNumber, &s.SaleDate, &s.ModelName, &s.ClientName,
+			&s.ManagerName, &s.WarehouseName, &s.FinalPrice, &s.Status)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning recent sale: %w", err)
+		}
+		sales = append(sales, s)
+	}
+
+	return sales, rows.Err()
+}
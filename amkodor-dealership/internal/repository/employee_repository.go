@@ -2,9 +2,21 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrEmployeeNotFound       = errors.New("employee not found")
+	ErrDuplicateEmployeeEmail = errors.New("employee with this email already exists")
+	ErrPositionNotFound       = errors.New("position not found")
+	ErrDuplicatePosition      = errors.New("position already exists")
+	// ErrPositionInUse - на должность назначены сотрудники, в том числе уволенные
+	ErrPositionInUse = errors.New("position has employees")
 )
 
 type EmployeeRepository struct {
@@ -15,59 +27,88 @@ func NewEmployeeRepository(db *sql.DB) EmployeeRepository {
 	return EmployeeRepository{db: db}
 }
 
-// GetAll возвращает всех сотрудников
-func (r *EmployeeRepository) GetAll(limit, offset int) ([]models.Employee, error) {
-	query := `
-		SELECT * FROM vw_employees_full_info
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
+// employeeColumns - колонки vw_employees_full_info в порядке scanEmployee
+const employeeColumns = `
+	employee_id, full_name, first_name, last_name, middle_name,
+	position_id, position_name, COALESCE(base_salary, 0), salary,
+	warehouse_id, COALESCE(warehouse_name, ''), COALESCE(warehouse_city, ''),
+	email, phone, hire_date, dismissal_date,
+	years_of_service::INTEGER, tenure_months, is_active, created_at
+`
+
+// employeeSearch - поиск по ФИО, email и телефону; $2 - фильтр по активности
+const employeeSearch = `
+	($1 = '' OR full_name ILIKE '%' || $1 || '%' OR email ILIKE '%' || $1 || '%' OR phone ILIKE '%' || $1 || '%')
+	AND ($2::BOOLEAN IS NULL OR is_active = $2)
+`
+
+func scanEmployee(row rowScanner, extra ...interface{}) (*models.Employee, error) {
+	var e models.Employee
+	dest := []interface{}{
+		&e.EmployeeID, &e.FullName, &e.FirstName, &e.LastName, &e.MiddleName,
+		&e.PositionID, &e.PositionName, &e.BaseSalary, &e.Salary,
+		&e.WarehouseID, &e.WarehouseName, &e.WarehouseCity,
+		&e.Email, &e.Phone, &e.HireDate, &e.DismissalDate,
+		&e.YearsOfService, &e.TenureMonths, &e.IsActive, &e.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// GetPage возвращает страницу сотрудников и общее количество записей.
+// active == nil - сотрудники в любом статусе.
+func (r *EmployeeRepository) GetPage(search string, active *bool, limit, offset int) ([]models.Employee, int, error) {
+	query := `SELECT ` + employeeColumns + `, COUNT(*) OVER()
+		FROM vw_employees_full_info
+		WHERE ` + employeeSearch + `
+		ORDER BY is_active DESC, full_name, employee_id
+		LIMIT $3 OFFSET $4`
 
-	rows, err := r.db.Query(query, limit, offset)
+	rows, err := r.db.Query(query, search, active, limit, offset)
 	if err != nil {
-		return nil, fmt.Errorf("error querying employees: %w", err)
+		return nil, 0, fmt.Errorf("error querying employees: %w", err)
 	}
 	defer rows.Close()
 
+	total := 0
 	employees := []models.Employee{}
 	for rows.Next() {
-		var e models.Employee
-		err := rows.Scan(
-			&e.EmployeeID, &e.FullName, &e.FirstName, &e.LastName, &e.MiddleName,
-			&e.PositionName, &e.Salary, &e.WarehouseName, &e.WarehouseCity,
-			&e.Email, &e.Phone, &e.HireDate, &e.YearsOfService, &e.IsActive,
-		)
+		e, err := scanEmployee(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning employee: %w", err)
+		}
+		employees = append(employees, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating employees: %w", err)
+	}
+
+	// За пределами последней страницы COUNT(*) OVER() недоступен
+	if len(employees) == 0 && offset > 0 {
+		err := r.db.QueryRow(`SELECT COUNT(*) FROM vw_employees_full_info WHERE `+employeeSearch,
+			search, active).Scan(&total)
 		if err != nil {
-			return nil, fmt.Errorf("error scanning employee: %w", err)
+			return nil, 0, fmt.Errorf("error counting employees: %w", err)
 		}
-		employees = append(employees, e)
 	}
 
-	return employees, nil
+	return employees, total, nil
 }
 
 // GetByID возвращает сотрудника по ID
 func (r *EmployeeRepository) GetByID(id int) (*models.Employee, error) {
-	query := `
-		SELECT * FROM vw_employees_full_info
-		WHERE employee_id = $1
-	`
-
-	var e models.Employee
-	err := r.db.QueryRow(query, id).Scan(
-		&e.EmployeeID, &e.FullName, &e.FirstName, &e.LastName, &e.MiddleName,
-		&e.PositionName, &e.Salary, &e.WarehouseName, &e.WarehouseCity,
-		&e.Email, &e.Phone, &e.HireDate, &e.YearsOfService, &e.IsActive,
-	)
-
+	e, err := scanEmployee(r.db.QueryRow(
+		`SELECT `+employeeColumns+` FROM vw_employees_full_info WHERE employee_id = $1`, id))
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("employee not found")
+		return nil, ErrEmployeeNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error querying employee: %w", err)
 	}
 
-	return &e, nil
+	return e, nil
 }
 
 // GetByEmail возвращает сотрудника по email (для аутентификации)
@@ -75,7 +116,8 @@ func (r *EmployeeRepository) GetByEmail(email string) (*models.Employee, error)
 	query := `
 		SELECT 
 			employee_id, first_name, last_name, middle_name, position_id,
-			warehouse_id, email, phone, password_hash, hire_date, salary, is_active, created_at
+			warehouse_id, email, phone, password_hash, hire_date, salary, is_active, created_at,
+			dismissal_date
 		FROM employees
 		WHERE email = $1
 	`
@@ -84,11 +126,11 @@ func (r *EmployeeRepository) GetByEmail(email string) (*models.Employee, error)
 	err := r.db.QueryRow(query, email).Scan(
 		&e.EmployeeID, &e.FirstName, &e.LastName, &e.MiddleName, &e.PositionID,
 		&e.WarehouseID, &e.Email, &e.Phone, &e.PasswordHash, &e.HireDate,
-		&e.Salary, &e.IsActive, &e.CreatedAt,
+		&e.Salary, &e.IsActive, &e.CreatedAt, &e.DismissalDate,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("employee not found")
+		return nil, ErrEmployeeNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error querying employee: %w", err)
@@ -102,8 +144,8 @@ func (r *EmployeeRepository) Create(e *models.Employee) (int, error) {
 	query := `
 		INSERT INTO employees (
 			first_name, last_name, middle_name, position_id, warehouse_id,
-			email, phone, password_hash, salary
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			email, phone, password_hash, salary, hire_date
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING employee_id
 	`
 
@@ -111,17 +153,17 @@ func (r *EmployeeRepository) Create(e *models.Employee) (int, error) {
 	err := r.db.QueryRow(
 		query,
 		e.FirstName, e.LastName, e.MiddleName, e.PositionID, e.WarehouseID,
-		e.Email, e.Phone, e.PasswordHash, e.Salary,
+		e.Email, e.Phone, e.PasswordHash, e.Salary, e.HireDate,
 	).Scan(&employeeID)
 
 	if err != nil {
-		return 0, fmt.Errorf("error creating employee: %w", err)
+		return 0, employeeError("error creating employee", err)
 	}
 
 	return employeeID, nil
 }
 
-// Update обновляет сотрудника
+// Update обновляет сотрудника. Статус меняется через Deactivate и Restore.
 func (r *EmployeeRepository) Update(e *models.Employee) error {
 	query := `
 		UPDATE employees SET
@@ -133,18 +175,18 @@ func (r *EmployeeRepository) Update(e *models.Employee) error {
 			email = $6,
 			phone = $7,
 			salary = $8,
-			is_active = $9
+			hire_date = $9
 		WHERE employee_id = $10
 	`
 
 	result, err := r.db.Exec(
 		query,
 		e.FirstName, e.LastName, e.MiddleName, e.PositionID, e.WarehouseID,
-		e.Email, e.Phone, e.Salary, e.IsActive, e.EmployeeID,
+		e.Email, e.Phone, e.Salary, e.HireDate, e.EmployeeID,
 	)
 
 	if err != nil {
-		return fmt.Errorf("error updating employee: %w", err)
+		return employeeError("error updating employee", err)
 	}
 
 	rows, err := result.RowsAffected()
@@ -153,12 +195,51 @@ func (r *EmployeeRepository) Update(e *models.Employee) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("employee not found")
+		return ErrEmployeeNotFound
 	}
 
 	return nil
 }
 
+// staffAccountSelect - учётные данные сотрудника с должностью и ролью доступа
+const staffAccountSelect = `
+	SELECT e.employee_id, e.last_name || ' ' || e.first_name, e.position_id, p.position_name,
+	       e.warehouse_id, COALESCE(p.access_role, ''), COALESCE(e.email, ''), e.password_hash,
+	       COALESCE(e.is_active, FALSE), u.user_id
+	FROM employees e
+	INNER JOIN positions p ON p.position_id = e.position_id
+	LEFT JOIN users u ON u.employee_id = e.employee_id
+`
+
+// GetStaffByEmail возвращает учётные данные сотрудника для входа
+func (r *EmployeeRepository) GetStaffByEmail(email string) (*models.StaffAccount, error) {
+	return r.getStaff(staffAccountSelect+` WHERE LOWER(e.email) = LOWER($1)`, email)
+}
+
+// GetStaffByID возвращает учётные данные сотрудника (при обновлении токена)
+func (r *EmployeeRepository) GetStaffByID(id int) (*models.StaffAccount, error) {
+	return r.getStaff(staffAccountSelect+` WHERE e.employee_id = $1`, id)
+}
+
+func (r *EmployeeRepository) getStaff(query string, arg interface{}) (*models.StaffAccount, error) {
+	var a models.StaffAccount
+	var warehouseID, userID sql.NullInt64
+	err := r.db.QueryRow(query, arg).Scan(
+		&a.Identity.EmployeeID, &a.Identity.FullName, &a.Identity.PositionID, &a.Identity.PositionName,
+		&warehouseID, &a.Identity.Role, &a.Email, &a.PasswordHash, &a.IsActive, &userID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrEmployeeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying staff account: %w", err)
+	}
+
+	a.Identity.WarehouseID = nullIntPtr(warehouseID)
+	a.UserID = nullIntPtr(userID)
+	return &a, nil
+}
+
 // UpdatePassword обновляет пароль сотрудника
 func (r *EmployeeRepository) UpdatePassword(employeeID int, passwordHash string) error {
 	query := `
@@ -178,19 +259,43 @@ func (r *EmployeeRepository) UpdatePassword(employeeID int, passwordHash string)
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("employee not found")
+		return ErrEmployeeNotFound
 	}
 
 	return nil
 }
 
-// Delete удаляет сотрудника
-func (r *EmployeeRepository) Delete(id int) error {
-	query := `DELETE FROM employees WHERE employee_id = $1`
+// Deactivate увольняет сотрудника и отзывает его сессии. Запись остаётся
+// в базе: на неё ссылаются продажи, тест-драйвы и сервисные заказы.
+func (r *EmployeeRepository) Deactivate(id int) error {
+	query := `
+		WITH revoked AS (
+			UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+			WHERE employee_id = $1 AND revoked_at IS NULL
+		)
+		UPDATE employees SET
+			is_active = FALSE,
+			dismissal_date = COALESCE(dismissal_date, CURRENT_DATE)
+		WHERE employee_id = $1
+	`
+	return r.setStatus(query, id)
+}
+
+// Restore возвращает уволенного сотрудника в штат
+func (r *EmployeeRepository) Restore(id int) error {
+	query := `
+		UPDATE employees SET
+			is_active = TRUE,
+			dismissal_date = NULL
+		WHERE employee_id = $1
+	`
+	return r.setStatus(query, id)
+}
 
+func (r *EmployeeRepository) setStatus(query string, id int) error {
 	result, err := r.db.Exec(query, id)
 	if err != nil {
-		return fmt.Errorf("error deleting employee: %w", err)
+		return fmt.Errorf("error updating employee status: %w", err)
 	}
 
 	rows, err := result.RowsAffected()
@@ -199,7 +304,7 @@ func (r *EmployeeRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("employee not found")
+		return ErrEmployeeNotFound
 	}
 
 	return nil
@@ -215,3 +320,115 @@ func (r *EmployeeRepository) Count() (int, error) {
 	}
 	return count, nil
 }
+
+// GetPositions возвращает должности с количеством работающих сотрудников
+func (r *EmployeeRepository) GetPositions() ([]models.Position, error) {
+	rows, err := r.db.Query(`
+		SELECT p.position_id, p.position_name, COALESCE(p.base_salary, 0),
+		       COALESCE(p.description, ''), COALESCE(p.access_role, ''), COUNT(e.employee_id), p.created_at
+		FROM positions p
+		LEFT JOIN employees e ON e.position_id = p.position_id AND e.is_active
+		GROUP BY p.position_id
+		ORDER BY p.position_name`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying positions: %w", err)
+	}
+	defer rows.Close()
+
+	positions := []models.Position{}
+	for rows.Next() {
+		var p models.Position
+		err := rows.Scan(&p.ID, &p.Name, &p.BaseSalary, &p.Description, &p.AccessRole,
+			&p.EmployeeCount, &p.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning position: %w", err)
+		}
+		positions = append(positions, p)
+	}
+
+	return positions, rows.Err()
+}
+
+// CreatePosition создает должность
+func (r *EmployeeRepository) CreatePosition(p *models.Position) error {
+	err := r.db.QueryRow(`
+		INSERT INTO positions (position_name, base_salary, description, access_role)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		RETURNING position_id, created_at`,
+		p.Name, p.BaseSalary, p.Description, p.AccessRole,
+	).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return positionError("error creating position", err)
+	}
+	return nil
+}
+
+// UpdatePosition изменяет должность
+func (r *EmployeeRepository) UpdatePosition(p *models.Position) error {
+	err := r.db.QueryRow(`
+		UPDATE positions SET
+			position_name = $1,
+			base_salary = $2,
+			description = NULLIF($3, ''),
+			access_role = NULLIF($4, '')
+		WHERE position_id = $5
+		RETURNING created_at`,
+		p.Name, p.BaseSalary, p.Description, p.AccessRole, p.ID,
+	).Scan(&p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrPositionNotFound
+	}
+	if err != nil {
+		return positionError("error updating position", err)
+	}
+	return nil
+}
+
+// DeletePosition удаляет должность, на которую не назначены сотрудники
+func (r *EmployeeRepository) DeletePosition(id int) error {
+	result, err := r.db.Exec(`DELETE FROM positions WHERE position_id = $1`, id)
+	if err != nil {
+		return positionError("error deleting position", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrPositionNotFound
+	}
+
+	return nil
+}
+
+// employeeError переводит нарушения ограничений employees в ошибки репозитория
+func employeeError(msg string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrDuplicateEmployeeEmail
+		case "23503":
+			if pqErr.Constraint == "employees_warehouse_id_fkey" {
+				return ErrWarehouseNotFound
+			}
+			return ErrPositionNotFound
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+func positionError(msg string, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return ErrDuplicatePosition
+		case "23503":
+			return ErrPositionInUse
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -50,11 +50,17 @@ func (r *FavoriteRepository) GetUserFavorites(ctx context.Context, userID int) (
 			v.manufacture_year as year,
 			v.color,
 			v.serial_number,
-			v.vin
+			v.vin,
+			r.expires_at,
+			r.reservation_id IS NOT NULL AND l.user_id IS NOT NULL
 		FROM favorites f
 		JOIN vehicles v ON f.vehicle_id = v.vehicle_id
 		JOIN vehicle_models vm ON v.model_id = vm.model_id
 		JOIN vehicle_types vt ON vm.type_id = vt.type_id
+		LEFT JOIN vehicle_reservations r ON r.vehicle_id = v.vehicle_id
+			AND r.status = 'Активна' AND r.expires_at > CURRENT_TIMESTAMP
+		LEFT JOIN user_client_links l ON l.user_id = f.user_id
+			AND (l.customer_id = r.customer_id OR l.corporate_client_id = r.corporate_client_id)
 		WHERE f.user_id = $1
 		ORDER BY f.created_at DESC
 	`
@@ -82,6 +88,8 @@ func (r *FavoriteRepository) GetUserFavorites(ctx context.Context, userID int) (
 			&fav.Color,
 			&fav.SerialNumber,
 			&fav.VIN,
+			&fav.ReservedUntil,
+			&fav.ReservedByMe,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning favorite: %w", err)
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+)
+
+var ErrLiveEventNotFound = errors.New("live event not found")
+
+const liveEventSelect = `
+	SELECT event_id, event_type, warehouse_id, payload, created_at
+	FROM live_events`
+
+type LiveEventRepository struct {
+	db *sql.DB
+}
+
+func NewLiveEventRepository(db *sql.DB) LiveEventRepository {
+	return LiveEventRepository{db: db}
+}
+
+// GetByID возвращает событие по идентификатору из уведомления
+func (r *LiveEventRepository) GetByID(ctx context.Context, id int64) (*models.LiveEvent, error) {
+	event, err := scanLiveEvent(r.db.QueryRowContext(ctx, liveEventSelect+` WHERE event_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrLiveEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting live event: %w", err)
+	}
+
+	return event, nil
+}
+
+// GetAfter возвращает не более limit событий с идентификатором больше afterID
+// в порядке возрастания
+func (r *LiveEventRepository) GetAfter(ctx context.Context, afterID int64, limit int) ([]models.LiveEvent, error) {
+	rows, err := r.db.QueryContext(ctx, liveEventSelect+`
+		WHERE event_id > $1
+		ORDER BY event_id
+		LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying live events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.LiveEvent{}
+	for rows.Next() {
+		event, err := scanLiveEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning live event: %w", err)
+		}
+		events = append(events, *event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetLastID возвращает идентификатор последнего события или 0
+func (r *LiveEventRepository) GetLastID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(event_id), 0) FROM live_events`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("error getting last live event: %w", err)
+	}
+	return id, nil
+}
+
+// DeleteBefore удаляет события, созданные раньше before
+func (r *LiveEventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM live_events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting live events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func scanLiveEvent(row rowScanner) (*models.LiveEvent, error) {
+	var event models.LiveEvent
+	var warehouseID sql.NullInt64
+	var payload []byte
+	if err := row.Scan(&event.ID, &event.Type, &warehouseID, &payload, &event.CreatedAt); err != nil {
+		return nil, err
+	}
+	event.WarehouseID = nullIntPtr(warehouseID)
+	event.Payload = payload
+	return &event, nil
+}
@@ -0,0 +1,460 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	// ErrPurchaseOrderState - действие недопустимо в текущем статусе заказа
+	// или по заказу уже что-то получено
+	ErrPurchaseOrderState = errors.New("purchase order status does not allow this action")
+	// ErrPurchaseOrderPart - запчасть не найдена на складе заказа
+	ErrPurchaseOrderPart = errors.New("spare part is not stored on the order warehouse")
+	// ErrPurchaseOrderQuantity - строка не из этого заказа или количество
+	// больше недополученного остатка
+	ErrPurchaseOrderQuantity = errors.New("invalid receive quantity")
+	// ErrPurchaseOrderReference - поставщик или склад заказа не найдены
+	ErrPurchaseOrderReference = errors.New("purchase order references missing supplier or warehouse")
+)
+
+const purchaseOrderSelect = `
+	SELECT po.purchase_order_id, po.order_number, po.supplier_id, s.supplier_name,
+	       po.warehouse_id, w.warehouse_name, po.status, po.order_date, po.expected_date,
+	       po.received_date, po.created_by, COALESCE(e.last_name || ' ' || e.first_name, ''),
+	       COALESCE(po.notes, ''),
+	       COALESCE((SELECT SUM(l.quantity_ordered * l.unit_price)
+	                 FROM purchase_order_lines l
+	                 WHERE l.purchase_order_id = po.purchase_order_id), 0),
+	       po.created_at, po.updated_at
+	FROM purchase_orders po
+	INNER JOIN suppliers s ON s.supplier_id = po.supplier_id
+	INNER JOIN warehouses w ON w.warehouse_id = po.warehouse_id
+	LEFT JOIN employees e ON e.employee_id = po.created_by`
+
+type PurchaseOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) PurchaseOrderRepository {
+	return PurchaseOrderRepository{db: db}
+}
+
+// GetAll возвращает заказы без строк. Пустой status и nil-фильтры не ограничивают выборку.
+func (r *PurchaseOrderRepository) GetAll(ctx context.Context, status string, warehouseID, supplierID *int) ([]models.PurchaseOrder, error) {
+	rows, err := r.db.QueryContext(ctx, purchaseOrderSelect+`
+		WHERE ($1 = '' OR po.status = $1)
+		  AND ($2::INTEGER IS NULL OR po.warehouse_id = $2)
+		  AND ($3::INTEGER IS NULL OR po.supplier_id = $3)
+		ORDER BY po.order_date DESC, po.purchase_order_id DESC`, status, warehouseID, supplierID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying purchase orders: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []models.PurchaseOrder{}
+	for rows.Next() {
+		po, err := scanPurchaseOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning purchase order: %w", err)
+		}
+		orders = append(orders, *po)
+	}
+
+	return orders, rows.Err()
+}
+
+// GetByID возвращает заказ вместе со строками
+func (r *PurchaseOrderRepository) GetByID(ctx context.Context, id int) (*models.PurchaseOrder, error) {
+	po, err := scanPurchaseOrder(r.db.QueryRowContext(ctx, purchaseOrderSelect+` WHERE po.purchase_order_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting purchase order: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.purchase_order_line_id, l.spare_part_id, sp.part_number, sp.part_name,
+		       l.quantity_ordered, l.quantity_received, l.unit_price
+		FROM purchase_order_lines l
+		INNER JOIN spare_parts sp ON sp.spare_part_id = l.spare_part_id
+		WHERE l.purchase_order_id = $1
+		ORDER BY l.purchase_order_line_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying purchase order lines: %w", err)
+	}
+	defer rows.Close()
+
+	po.Lines = []models.PurchaseOrderLine{}
+	for rows.Next() {
+		var l models.PurchaseOrderLine
+		if err := rows.Scan(&l.ID, &l.SparePartID, &l.PartNumber, &l.PartName,
+			&l.QuantityOrdered, &l.QuantityReceived, &l.UnitPrice); err != nil {
+			return nil, fmt.Errorf("error scanning purchase order line: %w", err)
+		}
+		l.QuantityRemaining = l.QuantityOrdered - l.QuantityReceived
+		l.Amount = float64(l.QuantityOrdered) * l.UnitPrice
+		po.Lines = append(po.Lines, l)
+	}
+
+	return po, rows.Err()
+}
+
+// Create создаёт заказ со статусом 'Заказано' и возвращает его ID. Все
+// запчасти должны храниться на складе заказа.
+func (r *PurchaseOrderRepository) Create(ctx context.Context, req *models.PurchaseOrderRequest, expectedDate *time.Time, createdBy int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO purchase_orders (supplier_id, warehouse_id, expected_date, created_by, notes)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING purchase_order_id`,
+		req.SupplierID, req.WarehouseID, expectedDate, createdBy, req.Notes,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return 0, ErrPurchaseOrderReference
+		}
+		return 0, fmt.Errorf("error creating purchase order: %w", err)
+	}
+
+	if err := insertPurchaseOrderLines(ctx, tx, id, req.WarehouseID, req.Lines); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// Update заменяет шапку и строки заказа, пока по нему ничего не получено
+func (r *PurchaseOrderRepository) Update(ctx context.Context, id int, req *models.PurchaseOrderRequest, expectedDate *time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockEditablePurchaseOrder(ctx, tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE purchase_orders
+		SET supplier_id = $2, warehouse_id = $3, expected_date = $4, notes = NULLIF($5, ''),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE purchase_order_id = $1`,
+		id, req.SupplierID, req.WarehouseID, expectedDate, req.Notes)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrPurchaseOrderReference
+		}
+		return fmt.Errorf("error updating purchase order: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM purchase_order_lines WHERE purchase_order_id = $1`, id); err != nil {
+		return fmt.Errorf("error deleting purchase order lines: %w", err)
+	}
+	if err := insertPurchaseOrderLines(ctx, tx, id, req.WarehouseID, req.Lines); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Cancel отменяет заказ, пока по нему ничего не получено
+func (r *PurchaseOrderRepository) Cancel(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockEditablePurchaseOrder(ctx, tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE purchase_orders SET status = 'Отменено', updated_at = CURRENT_TIMESTAMP
+		WHERE purchase_order_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error cancelling purchase order: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Receive принимает запчасти по заказу. Каждая принятая строка записывается
+// движением 'receipt', которое и увеличивает остаток. Пустой lines принимает
+// весь недополученный остаток. Заказ становится 'Получено', когда получены
+// все строки, иначе 'Частично получено'.
+func (r *PurchaseOrderRepository) Receive(ctx context.Context, id, employeeID int, lines []models.ReceivePurchaseOrderLine) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status, orderNumber string
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, order_number FROM purchase_orders
+		WHERE purchase_order_id = $1
+		FOR UPDATE`, id,
+	).Scan(&status, &orderNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error locking purchase order: %w", err)
+	}
+	if status != models.PurchaseOrderOrdered && status != models.PurchaseOrderPartial {
+		return ErrPurchaseOrderState
+	}
+
+	type orderLine struct {
+		sparePartID int
+		remaining   int
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT purchase_order_line_id, spare_part_id, quantity_ordered - quantity_received
+		FROM purchase_order_lines
+		WHERE purchase_order_id = $1
+		ORDER BY purchase_order_line_id
+		FOR UPDATE`, id)
+	if err != nil {
+		return fmt.Errorf("error querying purchase order lines: %w", err)
+	}
+	orderLines := map[int]orderLine{}
+	var lineIDs []int
+	for rows.Next() {
+		var lineID int
+		var l orderLine
+		if err := rows.Scan(&lineID, &l.sparePartID, &l.remaining); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning purchase order line: %w", err)
+		}
+		orderLines[lineID] = l
+		lineIDs = append(lineIDs, lineID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error querying purchase order lines: %w", err)
+	}
+
+	received := map[int]int{}
+	if len(lines) == 0 {
+		for _, lineID := range lineIDs {
+			received[lineID] = orderLines[lineID].remaining
+		}
+	} else {
+		for _, l := range lines {
+			if _, ok := orderLines[l.LineID]; !ok {
+				return ErrPurchaseOrderQuantity
+			}
+			received[l.LineID] += l.Quantity
+		}
+	}
+
+	total := 0
+	for _, lineID := range lineIDs {
+		quantity := received[lineID]
+		if quantity == 0 {
+			continue
+		}
+		if quantity > orderLines[lineID].remaining {
+			return ErrPurchaseOrderQuantity
+		}
+		total += quantity
+
+		_, err := tx.ExecContext(ctx, `
+			UPDATE purchase_order_lines SET quantity_received = quantity_received + $2
+			WHERE purchase_order_line_id = $1`, lineID, quantity)
+		if err != nil {
+			return fmt.Errorf("error updating purchase order line: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO spare_part_movements
+			    (spare_part_id, movement_type, quantity, purchase_order_line_id, employee_id, reason)
+			VALUES ($1, 'receipt', $2, $3, $4, $5)`,
+			orderLines[lineID].sparePartID, quantity, lineID, employeeID, "Приход по заказу "+orderNumber)
+		if err != nil {
+			return fmt.Errorf("error recording spare part receipt: %w", err)
+		}
+	}
+	if total == 0 {
+		return ErrPurchaseOrderQuantity
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE purchase_orders po
+		SET status = CASE WHEN complete THEN 'Получено' ELSE 'Частично получено' END,
+		    received_date = CASE WHEN complete THEN CURRENT_DATE END,
+		    updated_at = CURRENT_TIMESTAMP
+		FROM (SELECT bool_and(quantity_received = quantity_ordered) AS complete
+		      FROM purchase_order_lines WHERE purchase_order_id = $1) l
+		WHERE po.purchase_order_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error updating purchase order status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// GetReorderSuggestions рассчитывает дозаказ по запчастям. Средний расход
+// считается по service_order_parts неотменённых заказов за windowDays дней,
+// рекомендуемое количество покрывает coverDays дней расхода сверх минимума
+// за вычетом остатка и недополученного по открытым заказам.
+func (r *PurchaseOrderRepository) GetReorderSuggestions(ctx context.Context, warehouseID *int, windowDays, coverDays int) ([]models.ReorderSuggestion, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH consumption AS (
+		    SELECT sop.spare_part_id, SUM(sop.quantity) AS consumed
+		    FROM service_order_parts sop
+		    INNER JOIN service_orders so ON so.service_order_id = sop.service_order_id
+		    WHERE so.order_date > CURRENT_DATE - $2::INTEGER
+		      AND so.status <> 'Отменен'
+		    GROUP BY sop.spare_part_id
+		), on_order AS (
+		    SELECT l.spare_part_id, SUM(l.quantity_ordered - l.quantity_received) AS quantity
+		    FROM purchase_order_lines l
+		    INNER JOIN purchase_orders po ON po.purchase_order_id = l.purchase_order_id
+		    WHERE po.status IN ('Заказано', 'Частично получено')
+		    GROUP BY l.spare_part_id
+		), last_purchase AS (
+		    SELECT DISTINCT ON (l.spare_part_id) l.spare_part_id, s.supplier_id, s.supplier_name, l.unit_price
+		    FROM purchase_order_lines l
+		    INNER JOIN purchase_orders po ON po.purchase_order_id = l.purchase_order_id
+		    INNER JOIN suppliers s ON s.supplier_id = po.supplier_id
+		    WHERE po.status <> 'Отменено'
+		    ORDER BY l.spare_part_id, po.order_date DESC, po.purchase_order_id DESC
+		), demand AS (
+		    SELECT sp.spare_part_id, sp.part_number, sp.part_name, sp.warehouse_id,
+		           w.warehouse_name, sp.quantity_in_stock, COALESCE(sp.min_quantity, 0) AS min_quantity,
+		           COALESCE(c.consumed, 0) AS consumed,
+		           COALESCE(c.consumed, 0)::NUMERIC / $2 AS avg_daily,
+		           COALESCE(o.quantity, 0) AS on_order,
+		           lp.supplier_id, lp.supplier_name, lp.unit_price
+		    FROM spare_parts sp
+		    INNER JOIN warehouses w ON w.warehouse_id = sp.warehouse_id
+		    LEFT JOIN consumption c ON c.spare_part_id = sp.spare_part_id
+		    LEFT JOIN on_order o ON o.spare_part_id = sp.spare_part_id
+		    LEFT JOIN last_purchase lp ON lp.spare_part_id = sp.spare_part_id
+		    WHERE $1::INTEGER IS NULL OR sp.warehouse_id = $1
+		), suggestion AS (
+		    SELECT d.*,
+		           (CEIL(d.avg_daily * $3) + d.min_quantity - d.quantity_in_stock - d.on_order)::INTEGER AS suggested
+		    FROM demand d
+		)
+		SELECT spare_part_id, part_number, part_name, warehouse_id, warehouse_name,
+		       quantity_in_stock, min_quantity, consumed, ROUND(avg_daily, 3), on_order, suggested,
+		       supplier_id, COALESCE(supplier_name, ''), unit_price
+		FROM suggestion
+		WHERE suggested > 0
+		ORDER BY warehouse_name, quantity_in_stock - min_quantity, part_name`,
+		warehouseID, windowDays, coverDays)
+	if err != nil {
+		return nil, fmt.Errorf("error querying reorder suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.ReorderSuggestion{}
+	for rows.Next() {
+		var s models.ReorderSuggestion
+		if err := rows.Scan(
+			&s.SparePartID, &s.PartNumber, &s.PartName, &s.WarehouseID, &s.WarehouseName,
+			&s.QuantityInStock, &s.MinQuantity, &s.Consumed, &s.AvgDailyConsumption, &s.OnOrder,
+			&s.SuggestedQuantity, &s.LastSupplierID, &s.LastSupplierName, &s.LastUnitPrice,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning reorder suggestion: %w", err)
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// lockEditablePurchaseOrder блокирует заказ, который ещё можно изменить или
+// отменить: статус 'Заказано' и ни одна строка не получена
+func lockEditablePurchaseOrder(ctx context.Context, tx *sql.Tx, id int) error {
+	var status string
+	var anyReceived bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT po.status,
+		       EXISTS(SELECT 1 FROM purchase_order_lines l
+		              WHERE l.purchase_order_id = po.purchase_order_id AND l.quantity_received > 0)
+		FROM purchase_orders po
+		WHERE po.purchase_order_id = $1
+		FOR UPDATE`, id,
+	).Scan(&status, &anyReceived)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error locking purchase order: %w", err)
+	}
+	if status != models.PurchaseOrderOrdered || anyReceived {
+		return ErrPurchaseOrderState
+	}
+	return nil
+}
+
+// insertPurchaseOrderLines добавляет строки заказа. Цена по умолчанию берётся
+// из карточки запчасти; запчасть с другого склада отклоняется.
+func insertPurchaseOrderLines(ctx context.Context, tx *sql.Tx, orderID, warehouseID int, lines []models.PurchaseOrderLineRequest) error {
+	for _, l := range lines {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO purchase_order_lines (purchase_order_id, spare_part_id, quantity_ordered, unit_price)
+			SELECT $1, sp.spare_part_id, $3, COALESCE($4, sp.price)
+			FROM spare_parts sp
+			WHERE sp.spare_part_id = $2 AND sp.warehouse_id = $5`,
+			orderID, l.SparePartID, l.Quantity, l.UnitPrice, warehouseID)
+		if err != nil {
+			return fmt.Errorf("error creating purchase order line: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrPurchaseOrderPart
+		}
+	}
+	return nil
+}
+
+func scanPurchaseOrder(row rowScanner) (*models.PurchaseOrder, error) {
+	var po models.PurchaseOrder
+	err := row.Scan(
+		&po.ID, &po.OrderNumber, &po.SupplierID, &po.SupplierName,
+		&po.WarehouseID, &po.WarehouseName, &po.Status, &po.OrderDate, &po.ExpectedDate,
+		&po.ReceivedDate, &po.CreatedBy, &po.CreatedByName,
+		&po.Notes, &po.TotalAmount,
+		&po.CreatedAt, &po.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+)
+
+var (
+	// ErrRefreshTokenInvalid - токен не найден или истёк
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused - предъявлен уже обменянный или отозванный токен
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return RefreshTokenRepository{db: db}
+}
+
+// Create сохраняет новый refresh-токен пользователя или сотрудника
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (user_id, employee_id, family_id, token_hash, expires_at)
+			  VALUES ($1, $2, $3, $4, $5) RETURNING token_id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query,
+		token.UserID, token.EmployeeID, token.FamilyID, token.TokenHash, token.ExpiresAt,
+	).Scan(&token.TokenID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// Rotate обменивает токен с хешем oldHash на next в рамках одной транзакции.
+// next получает владельца и family_id предъявленного токена. Если предъявленный
+// токен уже был обменян или отозван, всё семейство отзывается и возвращается
+// ErrRefreshTokenReused.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, oldHash string, next *models.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current models.RefreshToken
+	err = tx.QueryRowContext(ctx, `
+		SELECT token_id, user_id, employee_id, family_id, expires_at, used_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`, oldHash,
+	).Scan(&current.TokenID, &current.UserID, &current.EmployeeID, &current.FamilyID,
+		&current.ExpiresAt, &current.UsedAt, &current.RevokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrRefreshTokenInvalid
+		}
+		return fmt.Errorf("error getting refresh token: %w", err)
+	}
+
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+			WHERE family_id = $1 AND revoked_at IS NULL`, current.FamilyID); err != nil {
+			return fmt.Errorf("error revoking token family: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error committing transaction: %w", err)
+		}
+		return ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return ErrRefreshTokenInvalid
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET used_at = CURRENT_TIMESTAMP WHERE token_id = $1`,
+		current.TokenID); err != nil {
+		return fmt.Errorf("error marking refresh token as used: %w", err)
+	}
+
+	next.UserID = current.UserID
+	next.EmployeeID = current.EmployeeID
+	next.FamilyID = current.FamilyID
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, employee_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING token_id, created_at`,
+		next.UserID, next.EmployeeID, next.FamilyID, next.TokenHash, next.ExpiresAt,
+	).Scan(&next.TokenID, &next.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFamily отзывает всё семейство, к которому относится токен с указанным хешем
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, tokenHash string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+			  WHERE revoked_at IS NULL
+			    AND family_id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1)`
+
+	if _, err := r.db.ExecContext(ctx, query, tokenHash); err != nil {
+		return fmt.Errorf("error revoking token family: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAllForUser отзывает все активные refresh-токены пользователя, а если
+// учётная запись связана с сотрудником - и его сессии служебного входа
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID int) (int64, error) {
+	query := `UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+			  WHERE revoked_at IS NULL AND used_at IS NULL
+			    AND (user_id = $1
+			         OR employee_id = (SELECT employee_id FROM users WHERE user_id = $1))`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error revoking user sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -12,21 +12,36 @@ import (
 
 // Repository главная структура, содержащая все репозитории
 type Repository struct {
-	Vehicle   VehicleRepository
-	Customer  CustomerRepository
-	Sale      SaleRepository
-	Employee  EmployeeRepository
-	Warehouse WarehouseRepository
-	Service   ServiceRepository
-	Dashboard DashboardRepository
-	Report    ReportRepository
-	User      UserRepository
-	Favorite  FavoriteRepository
+	Vehicle        VehicleRepository
+	Customer       CustomerRepository
+	Sale           SaleRepository
+	Employee       EmployeeRepository
+	Warehouse      WarehouseRepository
+	Service        ServiceRepository
+	Dashboard      DashboardRepository
+	Report         ReportRepository
+	User           UserRepository
+	RefreshToken   RefreshTokenRepository
+	Favorite       FavoriteRepository
+	ServiceRequest ServiceRequestRepository
+	ClientLink     ClientLinkRepository
+	Account        AccountRepository
+	LiveEvent      LiveEventRepository
+	StockAlert     StockAlertRepository
+	Supplier       SupplierRepository
+	PurchaseOrder  PurchaseOrderRepository
+	Movement       SparePartMovementRepository
+	Supply         SupplyRepository
+	Transfer       VehicleTransferRepository
+	Reservation    ReservationRepository
+	TestDrive      TestDriveRepository
+	CalendarFeed   CalendarFeedRepository
+	ServiceWork    ServiceOrderWorkRepository
 }
 
 // Интерфейсы репозиториев
 type SaleRepository interface {
-	GetAll(ctx context.Context) ([]models.Sale, error)
+	GetAll(ctx context.Context, limit, offset int) ([]models.Sale, error)
 	GetByID(ctx context.Context, id int) (*models.Sale, error)
 	Create(ctx context.Context, sale *models.Sale) (int, error)
 	Update(ctx context.Context, sale *models.Sale) error
@@ -35,11 +50,13 @@ type SaleRepository interface {
 	GetHistory(ctx context.Context, saleID int) ([]models.SaleHistory, error)
 }
 
+// DashboardRepository - статистика дашборда. warehouseID == nil - по всем складам.
 type DashboardRepository interface {
-	GetDashboardStats(ctx context.Context) (*models.DashboardStats, error)
-	GetSalesChartData(ctx context.Context, startDate time.Time) ([]models.ChartData, error)
-	GetTopEmployees(ctx context.Context, limit int) ([]models.TopEmployee, error)
-	GetRecentSales(ctx context.Context, limit int) ([]models.Sale, error)
+	GetDashboardStatistics(ctx context.Context, warehouseID *int) (*models.DashboardStatistics, error)
+	GetSalesChartData(ctx context.Context, period string, from, to time.Time, warehouseID *int) ([]models.ChartData, error)
+	GetCategorySales(ctx context.Context, warehouseID *int) ([]models.CategorySales, error)
+	GetTopEmployees(ctx context.Context, limit int, warehouseID *int) ([]models.TopEmployee, error)
+	GetRecentSales(ctx context.Context, limit int, warehouseID *int) ([]models.RecentSale, error)
 }
 
 type ReportRepository interface {
@@ -51,85 +68,44 @@ type ReportRepository interface {
 // NewRepository создаёт новый экземпляр Repository
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{
-		Vehicle:   NewVehicleRepository(db),
-		Customer:  NewCustomerRepository(db),
-		Sale:      NewSaleRepository(db),
-		Employee:  NewEmployeeRepository(db),
-		Warehouse: NewWarehouseRepository(db),
-		Service:   NewServiceRepository(db),
-		Dashboard: NewDashboardRepository(db),
-		Report:    NewReportRepository(db),
-		User:      NewUserRepository(db),
-		Favorite:  NewFavoriteRepository(db),
+		Vehicle:        NewVehicleRepository(db),
+		Customer:       NewCustomerRepository(db),
+		Sale:           NewSaleRepository(db),
+		Employee:       NewEmployeeRepository(db),
+		Warehouse:      NewWarehouseRepository(db),
+		Service:        NewServiceRepository(db),
+		Dashboard:      NewDashboardRepository(db),
+		Report:         NewReportRepository(db),
+		User:           NewUserRepository(db),
+		RefreshToken:   NewRefreshTokenRepository(db),
+		Favorite:       NewFavoriteRepository(db),
+		ServiceRequest: NewServiceRequestRepository(db),
+		ClientLink:     NewClientLinkRepository(db),
+		Account:        NewAccountRepository(db),
+		LiveEvent:      NewLiveEventRepository(db),
+		StockAlert:     NewStockAlertRepository(db),
+		Supplier:       NewSupplierRepository(db),
+		PurchaseOrder:  NewPurchaseOrderRepository(db),
+		Movement:       NewSparePartMovementRepository(db),
+		Supply:         NewSupplyRepository(db),
+		Transfer:       NewVehicleTransferRepository(db),
+		Reservation:    NewReservationRepository(db),
+		TestDrive:      NewTestDriveRepository(db),
+		CalendarFeed:   NewCalendarFeedRepository(db),
+		ServiceWork:    NewServiceOrderWorkRepository(db),
 	}
 }
 
-// Заглушки для недостающих функций
 func NewSaleRepository(db *sql.DB) SaleRepository {
 	return &saleRepository{db: db}
 }
 
-func NewDashboardRepository(db *sql.DB) DashboardRepository {
-	return &dashboardRepository{db: db}
-}
-
+// Заглушки для недостающих функций
 func NewReportRepository(db *sql.DB) ReportRepository {
 	return &reportRepository{db: db}
 }
 
 // Заглушки для репозиториев
-type saleRepository struct {
-	db *sql.DB
-}
-
-func (r *saleRepository) GetAll(ctx context.Context) ([]models.Sale, error) {
-	return []models.Sale{}, nil
-}
-
-func (r *saleRepository) GetByID(ctx context.Context, id int) (*models.Sale, error) {
-	return &models.Sale{}, nil
-}
-
-func (r *saleRepository) Create(ctx context.Context, sale *models.Sale) (int, error) {
-	return 1, nil
-}
-
-func (r *saleRepository) Update(ctx context.Context, sale *models.Sale) error {
-	return nil
-}
-
-func (r *saleRepository) Cancel(ctx context.Context, id int) error {
-	return nil
-}
-
-func (r *saleRepository) GetCount(ctx context.Context) (int, error) {
-	return 0, nil
-}
-
-func (r *saleRepository) GetHistory(ctx context.Context, saleID int) ([]models.SaleHistory, error) {
-	return []models.SaleHistory{}, nil
-}
-
-type dashboardRepository struct {
-	db *sql.DB
-}
-
-func (r *dashboardRepository) GetDashboardStats(ctx context.Context) (*models.DashboardStats, error) {
-	return &models.DashboardStats{}, nil
-}
-
-func (r *dashboardRepository) GetSalesChartData(ctx context.Context, startDate time.Time) ([]models.ChartData, error) {
-	return []models.ChartData{}, nil
-}
-
-func (r *dashboardRepository) GetTopEmployees(ctx context.Context, limit int) ([]models.TopEmployee, error) {
-	return []models.TopEmployee{}, nil
-}
-
-func (r *dashboardRepository) GetRecentSales(ctx context.Context, limit int) ([]models.Sale, error) {
-	return []models.Sale{}, nil
-}
-
 type reportRepository struct {
 	db *sql.DB
 }
@@ -144,4 +120,4 @@ func (r *reportRepository) GenerateEmployeesReport(ctx context.Context, startDat
 
 func (r *reportRepository) GenerateVehiclesReport(ctx context.Context) ([]models.VehicleReportRow, error) {
 	return []models.VehicleReportRow{}, nil
-}
\ No newline at end of file
+}
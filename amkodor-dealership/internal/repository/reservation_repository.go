@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationState - бронь уже выкуплена, истекла или отменена
+	ErrReservationState = errors.New("reservation is not active")
+	// ErrReservationVehicle - бронировать можно только технику в наличии
+	ErrReservationVehicle = errors.New("vehicle is not available for reservation")
+	// ErrReservationClient - клиент брони не найден
+	ErrReservationClient = errors.New("reservation client not found")
+)
+
+const reservationSelect = `
+	SELECT r.reservation_id, r.vehicle_id, vm.model_name, COALESCE(v.vin, ''), w.warehouse_name,
+	       r.customer_id, r.corporate_client_id,
+	       COALESCE(c.last_name || ' ' || c.first_name, cc.company_name, ''),
+	       r.employee_id, COALESCE(e.last_name || ' ' || e.first_name, ''),
+	       r.deposit_amount, r.expires_at, r.status, r.sale_id, COALESCE(r.notes, ''),
+	       r.created_at, r.closed_at
+	FROM vehicle_reservations r
+	INNER JOIN vehicles v ON v.vehicle_id = r.vehicle_id
+	INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+	INNER JOIN warehouses w ON w.warehouse_id = v.warehouse_id
+	LEFT JOIN customers c ON c.customer_id = r.customer_id
+	LEFT JOIN corporate_clients cc ON cc.corporate_client_id = r.corporate_client_id
+	LEFT JOIN employees e ON e.employee_id = r.employee_id`
+
+type ReservationRepository struct {
+	db *sql.DB
+}
+
+func NewReservationRepository(db *sql.DB) ReservationRepository {
+	return ReservationRepository{db: db}
+}
+
+// GetAll возвращает брони от новых к старым. Пустой status и nil-фильтры не ограничивают выборку.
+func (r *ReservationRepository) GetAll(ctx context.Context, status string, vehicleID, customerID, corporateClientID *int) ([]models.VehicleReservation, error) {
+	rows, err := r.db.QueryContext(ctx, reservationSelect+`
+		WHERE ($1 = '' OR r.status = $1)
+		  AND ($2::INTEGER IS NULL OR r.vehicle_id = $2)
+		  AND ($3::INTEGER IS NULL OR r.customer_id = $3)
+		  AND ($4::INTEGER IS NULL OR r.corporate_client_id = $4)
+		ORDER BY r.created_at DESC, r.reservation_id DESC`,
+		status, vehicleID, customerID, corporateClientID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying reservations: %w", err)
+	}
+	defer rows.Close()
+
+	reservations := []models.VehicleReservation{}
+	for rows.Next() {
+		res, err := scanReservation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning reservation: %w", err)
+		}
+		reservations = append(reservations, *res)
+	}
+
+	return reservations, rows.Err()
+}
+
+// GetByID возвращает бронь по ID
+func (r *ReservationRepository) GetByID(ctx context.Context, id int) (*models.VehicleReservation, error) {
+	res, err := scanReservation(r.db.QueryRowContext(ctx, reservationSelect+` WHERE r.reservation_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting reservation: %w", err)
+	}
+	return res, nil
+}
+
+// Create бронирует технику в наличии и переводит её в 'Зарезервировано'.
+// Просроченная бронь этой техники предварительно снимается.
+func (r *ReservationRepository) Create(ctx context.Context, req *models.VehicleReservationRequest, expiresAt time.Time, employeeID int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := expireReservations(ctx, tx, &req.VehicleID); err != nil {
+		return 0, err
+	}
+
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT status FROM vehicles WHERE vehicle_id = $1 FOR UPDATE`, req.VehicleID,
+	).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrVehicleNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error locking vehicle: %w", err)
+	}
+	if status != "В наличии" {
+		return 0, fmt.Errorf("%w: vehicle status is %s", ErrReservationVehicle, status)
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO vehicle_reservations (vehicle_id, customer_id, corporate_client_id, employee_id,
+		                                  deposit_amount, expires_at, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+		RETURNING reservation_id`,
+		req.VehicleID, req.CustomerID, req.CorporateClientID, employeeID,
+		req.DepositAmount, expiresAt, req.Notes,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23503":
+				return 0, ErrReservationClient
+			case "23505":
+				return 0, fmt.Errorf("%w: vehicle is already reserved", ErrReservationVehicle)
+			}
+		}
+		return 0, fmt.Errorf("error creating reservation: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicles SET status = 'Зарезервировано', updated_at = CURRENT_TIMESTAMP
+		WHERE vehicle_id = $1`, req.VehicleID)
+	if err != nil {
+		return 0, fmt.Errorf("error reserving vehicle: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return id, nil
+}
+
+// Extend переносит срок действующей брони
+func (r *ReservationRepository) Extend(ctx context.Context, id int, expiresAt time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE vehicle_reservations SET expires_at = $2
+		WHERE reservation_id = $1 AND status = 'Активна' AND expires_at > CURRENT_TIMESTAMP`, id, expiresAt)
+	if err != nil {
+		return fmt.Errorf("error extending reservation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return r.inactiveReservationError(ctx, id)
+	}
+	return nil
+}
+
+// Cancel снимает действующую бронь и возвращает технику в 'В наличии'
+func (r *ReservationRepository) Cancel(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var vehicleID int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE vehicle_reservations SET status = 'Отменена', closed_at = CURRENT_TIMESTAMP
+		WHERE reservation_id = $1 AND status = 'Активна'
+		RETURNING vehicle_id`, id,
+	).Scan(&vehicleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r.inactiveReservationError(ctx, id)
+	}
+	if err != nil {
+		return fmt.Errorf("error cancelling reservation: %w", err)
+	}
+
+	if err := releaseReservedVehicles(ctx, tx, []int{vehicleID}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ExpireDue снимает все просроченные брони и возвращает их количество
+func (r *ReservationRepository) ExpireDue(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	n, err := expireReservations(ctx, tx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return n, nil
+}
+
+// inactiveReservationError отличает отсутствующую бронь от закрытой
+func (r *ReservationRepository) inactiveReservationError(ctx context.Context, id int) error {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM vehicle_reservations WHERE reservation_id = $1)`, id,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking reservation: %w", err)
+	}
+	if !exists {
+		return ErrReservationNotFound
+	}
+	return ErrReservationState
+}
+
+// expireReservations переводит просроченные брони в 'Истекла' и освобождает
+// их технику. vehicleID ограничивает снятие одной единицей техники.
+func expireReservations(ctx context.Context, tx *sql.Tx, vehicleID *int) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE vehicle_reservations SET status = 'Истекла', closed_at = CURRENT_TIMESTAMP
+		WHERE status = 'Активна' AND expires_at <= CURRENT_TIMESTAMP
+		  AND ($1::INTEGER IS NULL OR vehicle_id = $1)
+		RETURNING vehicle_id`, vehicleID)
+	if err != nil {
+		return 0, fmt.Errorf("error expiring reservations: %w", err)
+	}
+	vehicleIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning expired reservation: %w", err)
+		}
+		vehicleIDs = append(vehicleIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error expiring reservations: %w", err)
+	}
+
+	if len(vehicleIDs) == 0 {
+		return 0, nil
+	}
+	if err := releaseReservedVehicles(ctx, tx, vehicleIDs); err != nil {
+		return 0, err
+	}
+	return len(vehicleIDs), nil
+}
+
+// releaseReservedVehicles возвращает технику без брони в 'В наличии'. Для
+// техники в пути меняется статус, который она получит после перемещения.
+func releaseReservedVehicles(ctx context.Context, tx *sql.Tx, vehicleIDs []int) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE vehicles SET status = 'В наличии', updated_at = CURRENT_TIMESTAMP
+		WHERE vehicle_id = ANY($1) AND status = 'Зарезервировано'`, pq.Array(vehicleIDs))
+	if err != nil {
+		return fmt.Errorf("error releasing reserved vehicles: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicle_transfer_items i SET previous_status = 'В наличии'
+		FROM vehicle_transfers t
+		WHERE t.transfer_id = i.transfer_id AND t.status = 'В пути'
+		  AND i.vehicle_id = ANY($1) AND i.previous_status = 'Зарезервировано'`, pq.Array(vehicleIDs))
+	if err != nil {
+		return fmt.Errorf("error releasing reserved vehicles in transit: %w", err)
+	}
+	return nil
+}
+
+func scanReservation(row rowScanner) (*models.VehicleReservation, error) {
+	var res models.VehicleReservation
+	err := row.Scan(
+		&res.ID, &res.VehicleID, &res.ModelName, &res.VIN, &res.WarehouseName,
+		&res.CustomerID, &res.CorporateClientID, &res.ClientName,
+		&res.EmployeeID, &res.ManagerName,
+		&res.DepositAmount, &res.ExpiresAt, &res.Status, &res.SaleID, &res.Notes,
+		&res.CreatedAt, &res.ClosedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
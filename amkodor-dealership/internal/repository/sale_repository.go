@@ -1 +1,245 @@
 package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSaleNotFound         = errors.New("sale not found")
+	ErrSaleAlreadyCancelled = errors.New("sale already cancelled")
+	// ErrSaleVehicleReserved - у техники действующая бронь другого клиента
+	// (validate_sale, object_in_use)
+	ErrSaleVehicleReserved = errors.New("vehicle is reserved for another client")
+	// ErrSaleVehicleUnavailable - техника не продаётся в текущем статусе
+	// (sp_create_sale, validate_sale: object_not_in_prerequisite_state)
+	ErrSaleVehicleUnavailable = errors.New("vehicle is not available for sale")
+	// ErrInvalidSale - неверный клиент или итоговая цена (validate_sale: check_violation)
+	ErrInvalidSale = errors.New("invalid sale")
+	// ErrSaleReference - клиент или сотрудник продажи не найден
+	ErrSaleReference = errors.New("sale references a missing client or employee")
+)
+
+type saleRepository struct {
+	db *sql.DB
+}
+
+// saleSelect - поля таблицы sales, дополненные данными из vw_sales_full_info
+const saleSelect = `
+	SELECT s.sale_id, s.vehicle_id, s.customer_id, s.corporate_client_id, s.employee_id,
+	       s.sale_date, s.base_price, s.discount_amount, s.final_price, s.payment_type,
+	       s.status, s.contract_number, s.notes, s.created_at, s.updated_at,
+	       i.vin, i.model_name, i.type_name, COALESCE(i.client_name, ''),
+	       COALESCE(i.client_phone, ''), i.client_type, i.manager_name, i.position_name,
+	       i.warehouse_name, i.warehouse_city
+	FROM sales s
+	INNER JOIN vw_sales_full_info i ON i.sale_id = s.sale_id
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSale(row rowScanner) (*models.Sale, error) {
+	var s models.Sale
+	err := row.Scan(
+		&s.SaleID, &s.VehicleID, &s.CustomerID, &s.CorporateClientID, &s.EmployeeID,
+		&s.SaleDate, &s.BasePrice, &s.DiscountAmount, &s.FinalPrice, &s.PaymentType,
+		&s.Status, &s.ContractNumber, &s.Notes, &s.CreatedAt, &s.UpdatedAt,
+		&s.VIN, &s.ModelName, &s.TypeName, &s.ClientName,
+		&s.ClientPhone, &s.ClientType, &s.ManagerName, &s.PositionName,
+		&s.WarehouseName, &s.WarehouseCity,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetAll возвращает продажи, начиная с последних
+func (r *saleRepository) GetAll(ctx context.Context, limit, offset int) ([]models.Sale, error) {
+	query := saleSelect + `
+		ORDER BY s.sale_date DESC, s.sale_id DESC
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sales: %w", err)
+	}
+	defer rows.Close()
+
+	sales := []models.Sale{}
+	for rows.Next() {
+		s, err := scanSale(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning sale: %w", err)
+		}
+		sales = append(sales, *s)
+	}
+
+	return sales, rows.Err()
+}
+
+// GetByID возвращает продажу по ID
+func (r *saleRepository) GetByID(ctx context.Context, id int) (*models.Sale, error) {
+	s, err := scanSale(r.db.QueryRowContext(ctx, saleSelect+` WHERE s.sale_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrSaleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying sale: %w", err)
+	}
+
+	return s, nil
+}
+
+// Create оформляет продажу через sp_create_sale: расчет скидок, вставка
+// и перевод техники в статус 'Продано' выполняются одной транзакцией в БД
+func (r *saleRepository) Create(ctx context.Context, sale *models.Sale) (int, error) {
+	query := `SELECT sp_create_sale($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	var saleID int
+	err := r.db.QueryRowContext(ctx, query,
+		sale.VehicleID, sale.CustomerID, sale.CorporateClientID, sale.EmployeeID,
+		sale.PaymentType, sale.AdditionalDiscount, sale.ContractNumber, sale.Notes,
+	).Scan(&saleID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "55006":
+				return 0, ErrSaleVehicleReserved
+			case "55000":
+				return 0, ErrSaleVehicleUnavailable
+			case "23514":
+				return 0, fmt.Errorf("%w: %s", ErrInvalidSale, pqErr.Message)
+			case "23503":
+				return 0, ErrSaleReference
+			}
+		}
+		return 0, fmt.Errorf("error creating sale: %w", err)
+	}
+
+	return saleID, nil
+}
+
+// Update обновляет реквизиты продажи. Незаполненные поля не меняются,
+// цены и техника не редактируются, для отмены используется Cancel.
+func (r *saleRepository) Update(ctx context.Context, sale *models.Sale) error {
+	query := `
+		UPDATE sales
+		SET payment_type = COALESCE(NULLIF($1, ''), payment_type),
+		    status = COALESCE(NULLIF($2, ''), status),
+		    contract_number = COALESCE($3, contract_number),
+		    notes = COALESCE($4, notes)
+		WHERE sale_id = $5 AND status <> 'Отменена'
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		sale.PaymentType, sale.Status, sale.ContractNumber, sale.Notes, sale.SaleID)
+	if err != nil {
+		return fmt.Errorf("error updating sale: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrSaleNotFound
+	}
+
+	return nil
+}
+
+// Cancel отменяет продажу и возвращает технику в продажу.
+// Изменение фиксируется в sales_history триггером trg_sales_history.
+func (r *saleRepository) Cancel(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var vehicleID int
+	var status string
+	err = tx.QueryRowContext(ctx,
+		`SELECT vehicle_id, status FROM sales WHERE sale_id = $1 FOR UPDATE`, id,
+	).Scan(&vehicleID, &status)
+	if err == sql.ErrNoRows {
+		return ErrSaleNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error querying sale: %w", err)
+	}
+
+	if status == "Отменена" {
+		return ErrSaleAlreadyCancelled
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE sales SET status = 'Отменена' WHERE sale_id = $1`, id); err != nil {
+		return fmt.Errorf("error cancelling sale: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE vehicles SET status = 'В наличии', updated_at = CURRENT_TIMESTAMP
+		WHERE vehicle_id = $1 AND status = 'Продано'`, vehicleID); err != nil {
+		return fmt.Errorf("error restoring vehicle status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetCount возвращает количество продаж
+func (r *saleRepository) GetCount(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sales`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting sales: %w", err)
+	}
+	return count, nil
+}
+
+// GetHistory возвращает журнал изменений продажи из sales_history
+func (r *saleRepository) GetHistory(ctx context.Context, saleID int) ([]models.SaleHistory, error) {
+	query := `
+		SELECT history_id, sale_id, operation_type, operation_date,
+		       old_value, new_value, COALESCE(username, ''), hostname, application_name
+		FROM sales_history
+		WHERE sale_id = $1
+		ORDER BY operation_date, history_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, saleID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying sale history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.SaleHistory{}
+	for rows.Next() {
+		var h models.SaleHistory
+		err := rows.Scan(
+			&h.HistoryID, &h.SaleID, &h.OperationType, &h.OperationDate,
+			&h.OldValue, &h.NewValue, &h.Username, &h.Hostname, &h.ApplicationName,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning sale history: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
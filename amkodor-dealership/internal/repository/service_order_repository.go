@@ -3,10 +3,17 @@ package repository
 import (
 	"amkodor-dealership/internal/models"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+
+	"github.com/lib/pq"
 )
 
+// ErrSparePartInUse - запчасть есть в журнале движений или в сервисных
+// заказах; такие ссылки объявлены ON DELETE RESTRICT
+var ErrSparePartInUse = errors.New("spare part is referenced by movements or service orders")
+
 type ServiceOrderRepository struct {
 	db *sql.DB
 }
@@ -208,12 +215,20 @@ func (r *ServiceOrderRepository) GetAllSpareParts() ([]models.SparePart, error)
 	return parts, nil
 }
 
-// DeleteSparePart удаляет запчасть
+// DeleteSparePart удаляет запчасть. Удалить можно только запчасть без
+// истории: у любой запчасти с движениями возвращается ErrSparePartInUse.
 func (r *ServiceOrderRepository) DeleteSparePart(id int) error {
 	query := `DELETE FROM spare_parts WHERE spare_part_id = $1`
-	_, err := r.db.Exec(query, id)
+	result, err := r.db.Exec(query, id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrSparePartInUse
+		}
 		return fmt.Errorf("failed to delete spare part: %w", err)
 	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrSparePartNotFound
+	}
 	return nil
 }
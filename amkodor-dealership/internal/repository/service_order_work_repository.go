@@ -0,0 +1,345 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrServiceOrderNotFound = errors.New("service order not found")
+	// ErrServiceOrderClosed - заказ завершён или отменён, запчасти и работы не меняются
+	ErrServiceOrderClosed = errors.New("service order is not open")
+	// ErrServiceOrderLineNotFound - строки запчасти или работы нет в заказе
+	ErrServiceOrderLineNotFound = errors.New("service order line not found")
+)
+
+// ServiceOrderWorkRepository - запчасти, работы и завершение сервисного
+// заказа. Остатки, стоимость заказа и запрет изменений после завершения
+// обеспечивают триггеры из 028_service_order_work.sql.
+type ServiceOrderWorkRepository struct {
+	db *sql.DB
+}
+
+func NewServiceOrderWorkRepository(db *sql.DB) ServiceOrderWorkRepository {
+	return ServiceOrderWorkRepository{db: db}
+}
+
+// GetByID возвращает карточку заказа с запчастями и работами
+func (r *ServiceOrderWorkRepository) GetByID(ctx context.Context, id int) (*models.ServiceOrderDetail, error) {
+	var o models.ServiceOrderDetail
+	var completionDate sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT so.service_order_id, so.vehicle_id, vm.model_name, COALESCE(v.vin, ''),
+		       COALESCE(CASE WHEN so.customer_id IS NOT NULL THEN c.last_name || ' ' || c.first_name
+		                     ELSE cc.company_name END, ''),
+		       COALESCE(CASE WHEN so.customer_id IS NOT NULL THEN c.phone ELSE cc.phone END, ''),
+		       so.employee_id, e.last_name || ' ' || e.first_name,
+		       so.order_date, so.completion_date, so.service_type, COALESCE(so.description, ''),
+		       so.status, COALESCE(so.cost, 0)
+		FROM service_orders so
+		INNER JOIN vehicles v ON v.vehicle_id = so.vehicle_id
+		INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+		INNER JOIN employees e ON e.employee_id = so.employee_id
+		LEFT JOIN customers c ON c.customer_id = so.customer_id
+		LEFT JOIN corporate_clients cc ON cc.corporate_client_id = so.corporate_client_id
+		WHERE so.service_order_id = $1`, id,
+	).Scan(
+		&o.ID, &o.VehicleID, &o.ModelName, &o.VIN,
+		&o.ClientName, &o.ClientPhone,
+		&o.EmployeeID, &o.MasterName,
+		&o.OrderDate, &completionDate, &o.ServiceType, &o.Description,
+		&o.Status, &o.Cost,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrServiceOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting service order: %w", err)
+	}
+	if completionDate.Valid {
+		o.CompletionDate = &completionDate.Time
+	}
+
+	if o.Parts, err = r.getParts(ctx, id); err != nil {
+		return nil, err
+	}
+	if o.Labour, err = r.getLabour(ctx, id); err != nil {
+		return nil, err
+	}
+	for _, p := range o.Parts {
+		o.PartsCost += p.Amount
+	}
+	for _, l := range o.Labour {
+		o.LabourCost += l.Amount
+	}
+
+	return &o, nil
+}
+
+func (r *ServiceOrderWorkRepository) getParts(ctx context.Context, orderID int) ([]models.ServiceOrderPart, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT sop.service_order_part_id, sop.spare_part_id, sp.part_number, sp.part_name,
+		       sop.quantity, sop.unit_price, sop.quantity * sop.unit_price, sop.created_at
+		FROM service_order_parts sop
+		INNER JOIN spare_parts sp ON sp.spare_part_id = sop.spare_part_id
+		WHERE sop.service_order_id = $1
+		ORDER BY sop.service_order_part_id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying service order parts: %w", err)
+	}
+	defer rows.Close()
+
+	parts := []models.ServiceOrderPart{}
+	for rows.Next() {
+		var p models.ServiceOrderPart
+		if err := rows.Scan(
+			&p.ID, &p.SparePartID, &p.PartNumber, &p.PartName,
+			&p.Quantity, &p.UnitPrice, &p.Amount, &p.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning service order part: %w", err)
+		}
+		parts = append(parts, p)
+	}
+
+	return parts, rows.Err()
+}
+
+func (r *ServiceOrderWorkRepository) getLabour(ctx context.Context, orderID int) ([]models.ServiceOrderLabour, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT l.labour_id, l.description, l.hours, l.rate, ROUND(l.hours * l.rate, 2),
+		       l.employee_id, COALESCE(e.last_name || ' ' || e.first_name, ''), l.created_at
+		FROM service_order_labour l
+		LEFT JOIN employees e ON e.employee_id = l.employee_id
+		WHERE l.service_order_id = $1
+		ORDER BY l.labour_id`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying service order labour: %w", err)
+	}
+	defer rows.Close()
+
+	labour := []models.ServiceOrderLabour{}
+	for rows.Next() {
+		var l models.ServiceOrderLabour
+		var employeeID sql.NullInt64
+		if err := rows.Scan(
+			&l.ID, &l.Description, &l.Hours, &l.Rate, &l.Amount,
+			&employeeID, &l.EmployeeName, &l.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning service order labour: %w", err)
+		}
+		l.EmployeeID = nullIntPtr(employeeID)
+		labour = append(labour, l)
+	}
+
+	return labour, rows.Err()
+}
+
+// Update меняет мастера, тип работ, описание и статус открытого заказа.
+// Стоимость пересчитывает, а завершённый заказ защищает trg_service_order_guard.
+// При отмене запчасти заказа возвращаются на склад до смены статуса, пока
+// заказ ещё открыт: триггер списания пишет по ним движения 'return'.
+func (r *ServiceOrderWorkRepository) Update(ctx context.Context, id int, req *models.UpdateServiceOrderRequest) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenServiceOrder(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if req.Status == models.ServiceOrderCancelled {
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM service_order_parts WHERE service_order_id = $1`, id); err != nil {
+			return mapServiceOrderWorkError(err, "error returning service order parts")
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE service_orders
+		SET employee_id = COALESCE(NULLIF($2, 0), employee_id),
+		    service_type = COALESCE(NULLIF($3, ''), service_type),
+		    description = COALESCE(NULLIF($4, ''), description),
+		    status = COALESCE(NULLIF($5, ''), status)
+		WHERE service_order_id = $1`,
+		id, req.EmployeeID, req.ServiceType, req.Description, req.Status)
+	if err != nil {
+		return mapServiceOrderWorkError(err, "error updating service order")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// AddPart добавляет запчасть в открытый заказ через sp_add_spare_parts_to_service
+// по текущей цене. Нехватка на складе возвращает ErrInsufficientStock.
+func (r *ServiceOrderWorkRepository) AddPart(ctx context.Context, orderID, sparePartID, quantity int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenServiceOrder(ctx, tx, orderID); err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM spare_parts WHERE spare_part_id = $1)`, sparePartID,
+	).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("error checking spare part: %w", err)
+	}
+	if !exists {
+		return 0, ErrSparePartNotFound
+	}
+
+	var lineID int
+	err = tx.QueryRowContext(ctx, `SELECT sp_add_spare_parts_to_service($1, $2, $3)`,
+		orderID, sparePartID, quantity,
+	).Scan(&lineID)
+	if err != nil {
+		return 0, mapServiceOrderWorkError(err, "error adding spare part to service order")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return lineID, nil
+}
+
+// RemovePart удаляет запчасть из открытого заказа; триггер возвращает её на склад
+func (r *ServiceOrderWorkRepository) RemovePart(ctx context.Context, orderID, lineID int) error {
+	return r.removeLine(ctx, orderID, `
+		DELETE FROM service_order_parts
+		WHERE service_order_part_id = $1 AND service_order_id = $2`, lineID)
+}
+
+// AddLabour добавляет работу в открытый заказ
+func (r *ServiceOrderWorkRepository) AddLabour(ctx context.Context, orderID int, l *models.ServiceOrderLabour) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenServiceOrder(ctx, tx, orderID); err != nil {
+		return 0, err
+	}
+
+	var lineID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO service_order_labour (service_order_id, description, hours, rate, employee_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING labour_id`,
+		orderID, l.Description, l.Hours, l.Rate, l.EmployeeID,
+	).Scan(&lineID)
+	if err != nil {
+		return 0, mapServiceOrderWorkError(err, "error adding service order labour")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return lineID, nil
+}
+
+// RemoveLabour удаляет работу из открытого заказа
+func (r *ServiceOrderWorkRepository) RemoveLabour(ctx context.Context, orderID, lineID int) error {
+	return r.removeLine(ctx, orderID, `
+		DELETE FROM service_order_labour
+		WHERE labour_id = $1 AND service_order_id = $2`, lineID)
+}
+
+func (r *ServiceOrderWorkRepository) removeLine(ctx context.Context, orderID int, query string, lineID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenServiceOrder(ctx, tx, orderID); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, query, lineID, orderID)
+	if err != nil {
+		return mapServiceOrderWorkError(err, "error removing service order line")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrServiceOrderLineNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Complete завершает открытый заказ через sp_complete_service_order: статус
+// 'Завершен' и сегодняшняя дата завершения. После этого заказ не меняется.
+func (r *ServiceOrderWorkRepository) Complete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenServiceOrder(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT sp_complete_service_order($1)`, id); err != nil {
+		return mapServiceOrderWorkError(err, "error completing service order")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// lockOpenServiceOrder блокирует заказ до конца транзакции и проверяет, что
+// он открыт. Триггеры проверяют то же самое, здесь - для понятной ошибки.
+func lockOpenServiceOrder(ctx context.Context, tx *sql.Tx, id int) error {
+	var status string
+	err := tx.QueryRowContext(ctx,
+		`SELECT status FROM service_orders WHERE service_order_id = $1 FOR UPDATE`, id,
+	).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrServiceOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error querying service order: %w", err)
+	}
+	if status != models.ServiceOrderInProgress && status != models.ServiceOrderSuspended {
+		return ErrServiceOrderClosed
+	}
+	return nil
+}
+
+// mapServiceOrderWorkError переводит ошибки триггеров: check_violation -
+// нехватка запчастей, object_not_in_prerequisite_state - заказ закрыт
+func mapServiceOrderWorkError(err error, msg string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23514":
+			return ErrInsufficientStock
+		case "55000":
+			return ErrServiceOrderClosed
+		case "23503":
+			return ErrServiceOrderReference
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
@@ -297,7 +297,7 @@ func (r *ServiceRepository) CreatePart(sp *models.SparePart) (int, error) {
 	return partID, nil
 }
 
-// UpdatePart обновляет запчасть
+// UpdatePart обновляет запчасть. Остаток меняется только движениями по журналу.
 func (r *ServiceRepository) UpdatePart(sp *models.SparePart) error {
 	query := `
 		UPDATE spare_parts SET
@@ -305,16 +305,15 @@ func (r *ServiceRepository) UpdatePart(sp *models.SparePart) error {
 			part_name = $2,
 			model_id = $3,
 			price = $4,
-			quantity_in_stock = $5,
-			min_quantity = $6,
-			warehouse_id = $7
-		WHERE spare_part_id = $8
+			min_quantity = $5,
+			warehouse_id = $6
+		WHERE spare_part_id = $7
 	`
 
 	result, err := r.db.Exec(
 		query,
 		sp.PartNumber, sp.PartName, sp.ModelID, sp.Price,
-		sp.QuantityInStock, sp.MinQuantity, sp.WarehouseID, sp.SparePartID,
+		sp.MinQuantity, sp.WarehouseID, sp.SparePartID,
 	)
 
 	if err != nil {
@@ -336,7 +335,11 @@ func (r *ServiceRepository) UpdatePart(sp *models.SparePart) error {
 // GetAllTestDrives возвращает все тест-драйвы
 func (r *ServiceRepository) GetAllTestDrives(limit, offset int) ([]models.TestDrive, error) {
 	query := `
-		SELECT * FROM vw_test_drives_full_info
+		SELECT test_drive_id, scheduled_date, duration, status,
+		       model_name, type_name, color, manufacture_year,
+		       client_name, client_phone, manager_name, manager_phone,
+		       feedback_rating, feedback_comment, warehouse_name, warehouse_city
+		FROM vw_test_drives_full_info
 		ORDER BY scheduled_date DESC
 		LIMIT $1 OFFSET $2
 	`
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrServiceRequestNotFound   = errors.New("service request not found")
+	ErrServiceRequestNotPending = errors.New("service request already processed")
+	// ErrServiceOrderReference - техника, мастер или клиент заказа не найдены
+	ErrServiceOrderReference = errors.New("service order references missing vehicle, employee or client")
+)
+
+// serviceRequestStatus - статус заявки с учётом созданного по ней заказа
+const serviceRequestStatus = `
+	CASE
+		WHEN so.status = 'Завершен' THEN 'completed'
+		WHEN so.status = 'Отменен' THEN 'cancelled'
+		ELSE sr.status
+	END`
+
+const serviceRequestSelect = `
+	SELECT sr.service_request_id, sr.user_id, sr.title, COALESCE(sr.description, ''),
+	       COALESCE(sr.service_type, ''), ` + serviceRequestStatus + `, sr.created_at,
+	       COALESCE(sr.staff_comment, ''), sr.processed_at, sr.service_order_id,
+	       COALESCE(so.status, ''), COALESCE(vm.model_name, ''),
+	       COALESCE(e.last_name || ' ' || e.first_name, ''),
+	       u.name, u.email, COALESCE(u.phone, '')
+	FROM service_requests sr
+	INNER JOIN users u ON u.user_id = sr.user_id
+	LEFT JOIN service_orders so ON so.service_order_id = sr.service_order_id
+	LEFT JOIN vehicles v ON v.vehicle_id = so.vehicle_id
+	LEFT JOIN vehicle_models vm ON vm.model_id = v.model_id
+	LEFT JOIN employees e ON e.employee_id = so.employee_id
+`
+
+type ServiceRequestRepository struct {
+	db *sql.DB
+}
+
+func NewServiceRequestRepository(db *sql.DB) ServiceRequestRepository {
+	return ServiceRequestRepository{db: db}
+}
+
+func scanServiceRequest(row rowScanner) (*models.ServiceRequest, error) {
+	var sr models.ServiceRequest
+	var orderID sql.NullInt64
+	err := row.Scan(
+		&sr.ID, &sr.UserID, &sr.Title, &sr.Description,
+		&sr.ServiceType, &sr.Status, &sr.Date,
+		&sr.StaffComment, &sr.ProcessedAt, &orderID,
+		&sr.ServiceOrderStatus, &sr.VehicleName,
+		&sr.MasterName,
+		&sr.Customer, &sr.Email, &sr.Phone,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if orderID.Valid {
+		id := int(orderID.Int64)
+		sr.ServiceOrderID = &id
+	}
+	return &sr, nil
+}
+
+func (r *ServiceRequestRepository) query(ctx context.Context, where string, args ...interface{}) ([]models.ServiceRequest, error) {
+	rows, err := r.db.QueryContext(ctx, serviceRequestSelect+where+` ORDER BY sr.created_at DESC, sr.service_request_id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying service requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.ServiceRequest{}
+	for rows.Next() {
+		sr, err := scanServiceRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning service request: %w", err)
+		}
+		requests = append(requests, *sr)
+	}
+
+	return requests, rows.Err()
+}
+
+// Create сохраняет новую заявку в статусе pending
+func (r *ServiceRequestRepository) Create(ctx context.Context, sr *models.ServiceRequest) error {
+	query := `INSERT INTO service_requests (user_id, title, description, service_type)
+			  VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+			  RETURNING service_request_id, status, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, sr.UserID, sr.Title, sr.Description, sr.ServiceType).
+		Scan(&sr.ID, &sr.Status, &sr.Date)
+	if err != nil {
+		return fmt.Errorf("error creating service request: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUser возвращает заявки пользователя, начиная с последних
+func (r *ServiceRequestRepository) GetByUser(ctx context.Context, userID int) ([]models.ServiceRequest, error) {
+	return r.query(ctx, ` WHERE sr.user_id = $1`, userID)
+}
+
+// GetAll возвращает все заявки; status фильтрует по итоговому статусу
+func (r *ServiceRequestRepository) GetAll(ctx context.Context, status string) ([]models.ServiceRequest, error) {
+	if status == "" {
+		return r.query(ctx, "")
+	}
+	return r.query(ctx, ` WHERE `+serviceRequestStatus+` = $1`, status)
+}
+
+// GetByID возвращает заявку по ID
+func (r *ServiceRequestRepository) GetByID(ctx context.Context, id int) (*models.ServiceRequest, error) {
+	sr, err := scanServiceRequest(r.db.QueryRowContext(ctx, serviceRequestSelect+` WHERE sr.service_request_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrServiceRequestNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error querying service request: %w", err)
+	}
+
+	return sr, nil
+}
+
+// Cancel отменяет необработанную заявку по просьбе её автора
+func (r *ServiceRequestRepository) Cancel(ctx context.Context, id, userID int) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE service_requests
+		SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+		WHERE service_request_id = $1 AND user_id = $2 AND status = 'pending'`, id, userID)
+	if err != nil {
+		return fmt.Errorf("error cancelling service request: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("error cancelling service request: %w", err)
+	} else if affected > 0 {
+		return nil
+	}
+
+	// Заявка не обновлена: либо её нет у пользователя, либо она уже разобрана
+	var exists bool
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM service_requests WHERE service_request_id = $1 AND user_id = $2)`,
+		id, userID).Scan(&exists); err != nil {
+		return fmt.Errorf("error querying service request: %w", err)
+	}
+	if !exists {
+		return ErrServiceRequestNotFound
+	}
+	return ErrServiceRequestNotPending
+}
+
+// Convert создаёт по заявке сервисный заказ через sp_create_service_order
+// и переводит заявку в работу от имени сотрудника employeeID. Обе операции
+// выполняются одной транзакцией.
+func (r *ServiceRequestRepository) Convert(ctx context.Context, id, employeeID int, req *models.ConvertServiceRequestRequest) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockPendingServiceRequest(ctx, tx, id); err != nil {
+		return 0, err
+	}
+
+	var orderID int
+	err = tx.QueryRowContext(ctx, `SELECT sp_create_service_order($1, $2, $3, $4, $5, $6, $7)`,
+		req.VehicleID, req.EmployeeID, req.ServiceType, req.CustomerID, req.CorporateClientID,
+		req.Description, req.Cost,
+	).Scan(&orderID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return 0, ErrServiceOrderReference
+		}
+		return 0, fmt.Errorf("error creating service order: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE service_requests
+		SET status = 'in-progress', service_order_id = $2, processed_by = $3,
+		    staff_comment = NULLIF($4, ''), processed_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE service_request_id = $1`, id, orderID, employeeID, req.Comment)
+	if err != nil {
+		return 0, fmt.Errorf("error updating service request: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return orderID, nil
+}
+
+// Reject отклоняет необработанную заявку с комментарием для клиента
+func (r *ServiceRequestRepository) Reject(ctx context.Context, id, employeeID int, comment string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := lockPendingServiceRequest(ctx, tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE service_requests
+		SET status = 'rejected', processed_by = $2, staff_comment = NULLIF($3, ''),
+		    processed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE service_request_id = $1`, id, employeeID, comment)
+	if err != nil {
+		return fmt.Errorf("error rejecting service request: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// lockPendingServiceRequest блокирует заявку и проверяет, что она ещё не разобрана
+func lockPendingServiceRequest(ctx context.Context, tx *sql.Tx, id int) error {
+	var status string
+	err := tx.QueryRowContext(ctx,
+		`SELECT status FROM service_requests WHERE service_request_id = $1 FOR UPDATE`, id,
+	).Scan(&status)
+	if err == sql.ErrNoRows {
+		return ErrServiceRequestNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error querying service request: %w", err)
+	}
+	if status != models.ServiceRequestPending {
+		return ErrServiceRequestNotPending
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSparePartNotFound = errors.New("spare part not found")
+	// ErrInsufficientStock - движение увело бы остаток запчасти в минус
+	ErrInsufficientStock = errors.New("insufficient spare part stock")
+	// ErrTransferWarehouse - перемещение возможно только на запчасть другого склада
+	ErrTransferWarehouse = errors.New("transfer target must be stored on another warehouse")
+)
+
+type SparePartMovementRepository struct {
+	db *sql.DB
+}
+
+func NewSparePartMovementRepository(db *sql.DB) SparePartMovementRepository {
+	return SparePartMovementRepository{db: db}
+}
+
+// GetByPart возвращает страницу движений запчасти от новых к старым и их
+// общее число. movementType ограничивает выборку одним типом движения.
+func (r *SparePartMovementRepository) GetByPart(ctx context.Context, sparePartID int, movementType string, limit, offset int) ([]models.SparePartMovement, int, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM spare_parts WHERE spare_part_id = $1)`, sparePartID,
+	).Scan(&exists); err != nil {
+		return nil, 0, fmt.Errorf("error checking spare part: %w", err)
+	}
+	if !exists {
+		return nil, 0, ErrSparePartNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.movement_id, m.spare_part_id, m.movement_type, m.quantity, m.balance_after,
+		       m.purchase_order_line_id, COALESCE(po.order_number, ''), m.service_order_id,
+		       m.related_movement_id, m.employee_id, COALESCE(e.last_name || ' ' || e.first_name, ''),
+		       COALESCE(m.reason, ''), m.created_at, COUNT(*) OVER()
+		FROM (
+		    SELECT *, SUM(quantity) OVER (ORDER BY movement_id) AS balance_after
+		    FROM spare_part_movements
+		    WHERE spare_part_id = $1
+		) m
+		LEFT JOIN purchase_order_lines l ON l.purchase_order_line_id = m.purchase_order_line_id
+		LEFT JOIN purchase_orders po ON po.purchase_order_id = l.purchase_order_id
+		LEFT JOIN employees e ON e.employee_id = m.employee_id
+		WHERE $2 = '' OR m.movement_type = $2
+		ORDER BY m.movement_id DESC
+		LIMIT $3 OFFSET $4`, sparePartID, movementType, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying spare part movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []models.SparePartMovement{}
+	total := 0
+	for rows.Next() {
+		var m models.SparePartMovement
+		if err := rows.Scan(
+			&m.ID, &m.SparePartID, &m.Type, &m.Quantity, &m.BalanceAfter,
+			&m.PurchaseOrderLineID, &m.PurchaseOrderNumber, &m.ServiceOrderID,
+			&m.RelatedMovementID, &m.EmployeeID, &m.EmployeeName,
+			&m.Reason, &m.CreatedAt, &total,
+		); err != nil {
+			return nil, 0, fmt.Errorf("error scanning spare part movement: %w", err)
+		}
+		movements = append(movements, m)
+	}
+
+	return movements, total, rows.Err()
+}
+
+// Record записывает движение в журнал; триггер применяет его к остатку
+func (r *SparePartMovementRepository) Record(ctx context.Context, m *models.SparePartMovement) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, employee_id, reason)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING movement_id`,
+		m.SparePartID, m.Type, m.Quantity, m.EmployeeID, m.Reason,
+	).Scan(&id)
+	if err != nil {
+		return 0, mapMovementError(err)
+	}
+
+	return id, nil
+}
+
+// Transfer перемещает количество с запчасти одного склада на запчасть другого
+// парой движений 'transfer'. Приход ссылается на расход через related_movement_id.
+func (r *SparePartMovementRepository) Transfer(ctx context.Context, fromID, toID, quantity, employeeID int, reason string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var fromWarehouse, toWarehouse int
+	err = tx.QueryRowContext(ctx, `
+		SELECT f.warehouse_id, t.warehouse_id
+		FROM spare_parts f, spare_parts t
+		WHERE f.spare_part_id = $1 AND t.spare_part_id = $2`, fromID, toID,
+	).Scan(&fromWarehouse, &toWarehouse)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrSparePartNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error getting spare parts: %w", err)
+	}
+	if fromWarehouse == toWarehouse {
+		return 0, ErrTransferWarehouse
+	}
+
+	var outID int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, employee_id, reason)
+		VALUES ($1, 'transfer', $2, $3, $4)
+		RETURNING movement_id`, fromID, -quantity, employeeID, reason,
+	).Scan(&outID)
+	if err != nil {
+		return 0, mapMovementError(err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO spare_part_movements (spare_part_id, movement_type, quantity, related_movement_id, employee_id, reason)
+		VALUES ($1, 'transfer', $2, $3, $4, $5)`, toID, quantity, outID, employeeID, reason)
+	if err != nil {
+		return 0, mapMovementError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return outID, nil
+}
+
+// GetStock возвращает остатки по журналу из vw_spare_parts_stock. driftOnly
+// оставляет только запчасти, где хранимый остаток расходится с журналом.
+func (r *SparePartMovementRepository) GetStock(ctx context.Context, warehouseID *int, driftOnly bool) ([]models.SparePartStock, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT spare_part_id, part_number, part_name, warehouse_id, warehouse_name,
+		       stored_quantity, ledger_quantity, drift, COALESCE(min_quantity, 0),
+		       COALESCE(movements_count, 0), last_movement_at
+		FROM vw_spare_parts_stock
+		WHERE ($1::INTEGER IS NULL OR warehouse_id = $1)
+		  AND (NOT $2 OR drift <> 0)
+		ORDER BY warehouse_name, part_name`, warehouseID, driftOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error querying spare parts stock: %w", err)
+	}
+	defer rows.Close()
+
+	stock := []models.SparePartStock{}
+	for rows.Next() {
+		var s models.SparePartStock
+		if err := rows.Scan(
+			&s.SparePartID, &s.PartNumber, &s.PartName, &s.WarehouseID, &s.WarehouseName,
+			&s.StoredQuantity, &s.LedgerQuantity, &s.Drift, &s.MinQuantity,
+			&s.MovementsCount, &s.LastMovementAt,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning spare part stock: %w", err)
+		}
+		stock = append(stock, s)
+	}
+
+	return stock, rows.Err()
+}
+
+// mapMovementError переводит нарушения ограничений при записи движения в ошибки репозитория
+func mapMovementError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23514":
+			return ErrInsufficientStock
+		case "23503":
+			return ErrSparePartNotFound
+		}
+	}
+	return fmt.Errorf("error recording spare part movement: %w", err)
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrStockAlertNotFound = errors.New("stock alert not found")
+	// ErrStockAlertState - переход недопустим из текущего статуса оповещения
+	ErrStockAlertState = errors.New("stock alert status does not allow this action")
+)
+
+const stockAlertSelect = `
+	SELECT sa.stock_alert_id, sa.spare_part_id, sp.part_number, sp.part_name,
+	       sa.warehouse_id, w.warehouse_name, sa.quantity_in_stock, sa.min_quantity,
+	       sp.quantity_in_stock, sa.status, sa.occurrences, sa.created_at, sa.last_raised_at,
+	       sa.acknowledged_by, COALESCE(ea.last_name || ' ' || ea.first_name, ''), sa.acknowledged_at,
+	       sa.resolved_by, COALESCE(er.last_name || ' ' || er.first_name, ''), sa.resolved_at,
+	       COALESCE(sa.notified_email, ''), sa.notified_at
+	FROM stock_alerts sa
+	INNER JOIN spare_parts sp ON sp.spare_part_id = sa.spare_part_id
+	INNER JOIN warehouses w ON w.warehouse_id = sa.warehouse_id
+	LEFT JOIN employees ea ON ea.employee_id = sa.acknowledged_by
+	LEFT JOIN employees er ON er.employee_id = sa.resolved_by`
+
+// stockAlertInsert создаёт оповещения по запчастям с остатком не выше минимума
+const stockAlertInsert = `
+	INSERT INTO stock_alerts (spare_part_id, warehouse_id, quantity_in_stock, min_quantity)
+	SELECT sp.spare_part_id, sp.warehouse_id, sp.quantity_in_stock, sp.min_quantity
+	FROM spare_parts sp
+	WHERE sp.quantity_in_stock <= sp.min_quantity`
+
+type StockAlertRepository struct {
+	db *sql.DB
+}
+
+func NewStockAlertRepository(db *sql.DB) StockAlertRepository {
+	return StockAlertRepository{db: db}
+}
+
+// Raise создаёт оповещение по запчасти или, если незакрытое оповещение уже
+// есть, обновляет в нём остаток и увеличивает счётчик повторов. created
+// сообщает, что оповещение новое. Если остаток уже выше минимума, возвращается id = 0.
+func (r *StockAlertRepository) Raise(ctx context.Context, sparePartID int) (id int, created bool, err error) {
+	err = r.db.QueryRowContext(ctx, stockAlertInsert+` AND sp.spare_part_id = $1
+		ON CONFLICT (spare_part_id) WHERE status <> 'resolved' DO UPDATE
+		SET quantity_in_stock = EXCLUDED.quantity_in_stock,
+		    min_quantity = EXCLUDED.min_quantity,
+		    occurrences = stock_alerts.occurrences + 1,
+		    last_raised_at = CURRENT_TIMESTAMP
+		RETURNING stock_alert_id, xmax = 0`, sparePartID,
+	).Scan(&id, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("error raising stock alert: %w", err)
+	}
+
+	return id, created, nil
+}
+
+// RaiseMissing создаёт оповещения по всем запчастям с низким остатком, у которых
+// нет незакрытого оповещения, и возвращает ID созданных
+func (r *StockAlertRepository) RaiseMissing(ctx context.Context) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, stockAlertInsert+`
+		ON CONFLICT (spare_part_id) WHERE status <> 'resolved' DO NOTHING
+		RETURNING stock_alert_id`)
+	if err != nil {
+		return nil, fmt.Errorf("error raising stock alerts: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning stock alert id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// GetByID возвращает оповещение по ID
+func (r *StockAlertRepository) GetByID(ctx context.Context, id int) (*models.StockAlert, error) {
+	alert, err := scanStockAlert(r.db.QueryRowContext(ctx, stockAlertSelect+` WHERE sa.stock_alert_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStockAlertNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting stock alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+// GetAll возвращает оповещения в статусах statuses (все, если список пуст),
+// warehouseID ограничивает их складом
+func (r *StockAlertRepository) GetAll(ctx context.Context, statuses []string, warehouseID *int) ([]models.StockAlert, error) {
+	rows, err := r.db.QueryContext(ctx, stockAlertSelect+`
+		WHERE (cardinality($1::TEXT[]) = 0 OR sa.status = ANY($1))
+		  AND ($2::INTEGER IS NULL OR sa.warehouse_id = $2)
+		ORDER BY CASE sa.status WHEN 'open' THEN 0 WHEN 'acknowledged' THEN 1 ELSE 2 END,
+		         sa.last_raised_at DESC`, pq.Array(statuses), warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying stock alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []models.StockAlert{}
+	for rows.Next() {
+		alert, err := scanStockAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning stock alert: %w", err)
+		}
+		alerts = append(alerts, *alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// Acknowledge отмечает открытое оповещение как принятое сотрудником
+func (r *StockAlertRepository) Acknowledge(ctx context.Context, id, employeeID int) error {
+	return r.transition(ctx, id, `
+		UPDATE stock_alerts
+		SET status = 'acknowledged', acknowledged_by = $2, acknowledged_at = CURRENT_TIMESTAMP
+		WHERE stock_alert_id = $1 AND status = 'open'`, employeeID)
+}
+
+// Resolve закрывает оповещение. Принятие, если его не было, фиксируется тем же сотрудником.
+func (r *StockAlertRepository) Resolve(ctx context.Context, id, employeeID int) error {
+	return r.transition(ctx, id, `
+		UPDATE stock_alerts
+		SET status = 'resolved', resolved_by = $2, resolved_at = CURRENT_TIMESTAMP,
+		    acknowledged_by = COALESCE(acknowledged_by, $2),
+		    acknowledged_at = COALESCE(acknowledged_at, CURRENT_TIMESTAMP)
+		WHERE stock_alert_id = $1 AND status <> 'resolved'`, employeeID)
+}
+
+func (r *StockAlertRepository) transition(ctx context.Context, id int, query string, employeeID int) error {
+	res, err := r.db.ExecContext(ctx, query, id, employeeID)
+	if err != nil {
+		return fmt.Errorf("error updating stock alert: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM stock_alerts WHERE stock_alert_id = $1)`, id,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking stock alert: %w", err)
+	}
+	if !exists {
+		return ErrStockAlertNotFound
+	}
+	return ErrStockAlertState
+}
+
+// MarkNotified запоминает, кому отправлено письмо об оповещении
+func (r *StockAlertRepository) MarkNotified(ctx context.Context, id int, email string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE stock_alerts SET notified_email = $2, notified_at = CURRENT_TIMESTAMP
+		WHERE stock_alert_id = $1`, id, email)
+	if err != nil {
+		return fmt.Errorf("error marking stock alert notified: %w", err)
+	}
+	return nil
+}
+
+// GetManagerContact находит email заведующего складом: сотрудника этого склада,
+// чьё ФИО совпадает с warehouses.manager_name. Email пуст, если такого нет.
+func (r *StockAlertRepository) GetManagerContact(ctx context.Context, warehouseID int) (*models.WarehouseManagerContact, error) {
+	var contact models.WarehouseManagerContact
+	err := r.db.QueryRowContext(ctx, `
+		SELECT w.warehouse_name, COALESCE(w.manager_name, ''), COALESCE(e.email, '')
+		FROM warehouses w
+		LEFT JOIN employees e ON e.warehouse_id = w.warehouse_id
+		                     AND e.is_active AND e.email IS NOT NULL
+		                     AND LOWER(e.last_name || ' ' || e.first_name || COALESCE(' ' || e.middle_name, ''))
+		                         = LOWER(TRIM(w.manager_name))
+		WHERE w.warehouse_id = $1
+		ORDER BY e.employee_id
+		LIMIT 1`, warehouseID,
+	).Scan(&contact.WarehouseName, &contact.ManagerName, &contact.Email)
+	if err != nil {
+		return nil, fmt.Errorf("error getting warehouse manager: %w", err)
+	}
+
+	return &contact, nil
+}
+
+func scanStockAlert(row rowScanner) (*models.StockAlert, error) {
+	var a models.StockAlert
+	err := row.Scan(
+		&a.ID, &a.SparePartID, &a.PartNumber, &a.PartName,
+		&a.WarehouseID, &a.WarehouseName, &a.QuantityInStock, &a.MinQuantity,
+		&a.CurrentQuantity, &a.Status, &a.Occurrences, &a.CreatedAt, &a.LastRaisedAt,
+		&a.AcknowledgedBy, &a.AcknowledgedByName, &a.AcknowledgedAt,
+		&a.ResolvedBy, &a.ResolvedByName, &a.ResolvedAt,
+		&a.NotifiedEmail, &a.NotifiedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
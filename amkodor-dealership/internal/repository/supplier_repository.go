@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"amkodor-dealership/internal/models"
+)
+
+var ErrSupplierNotFound = errors.New("supplier not found")
+
+const supplierSelect = `
+	SELECT supplier_id, supplier_name, COALESCE(tax_id, ''), COALESCE(contact_person, ''),
+	       COALESCE(phone, ''), COALESCE(email, ''), is_active, created_at
+	FROM suppliers`
+
+type SupplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) SupplierRepository {
+	return SupplierRepository{db: db}
+}
+
+// GetAll возвращает поставщиков; activeOnly скрывает неактивных
+func (r *SupplierRepository) GetAll(ctx context.Context, activeOnly bool) ([]models.Supplier, error) {
+	rows, err := r.db.QueryContext(ctx, supplierSelect+`
+		WHERE NOT $1 OR is_active
+		ORDER BY supplier_name`, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error querying suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	suppliers := []models.Supplier{}
+	for rows.Next() {
+		s, err := scanSupplier(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning supplier: %w", err)
+		}
+		suppliers = append(suppliers, *s)
+	}
+
+	return suppliers, rows.Err()
+}
+
+// GetByID возвращает поставщика по ID
+func (r *SupplierRepository) GetByID(ctx context.Context, id int) (*models.Supplier, error) {
+	s, err := scanSupplier(r.db.QueryRowContext(ctx, supplierSelect+` WHERE supplier_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSupplierNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting supplier: %w", err)
+	}
+
+	return s, nil
+}
+
+// Create добавляет поставщика и возвращает его ID
+func (r *SupplierRepository) Create(ctx context.Context, s *models.Supplier) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO suppliers (supplier_name, tax_id, contact_person, phone, email, is_active)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6)
+		RETURNING supplier_id`,
+		s.Name, s.TaxID, s.ContactPerson, s.Phone, s.Email, s.IsActive,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error creating supplier: %w", err)
+	}
+
+	return id, nil
+}
+
+// Update изменяет данные поставщика
+func (r *SupplierRepository) Update(ctx context.Context, s *models.Supplier) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE suppliers
+		SET supplier_name = $2, tax_id = NULLIF($3, ''), contact_person = NULLIF($4, ''),
+		    phone = NULLIF($5, ''), email = NULLIF($6, ''), is_active = $7
+		WHERE supplier_id = $1`,
+		s.ID, s.Name, s.TaxID, s.ContactPerson, s.Phone, s.Email, s.IsActive,
+	)
+	if err != nil {
+		return fmt.Errorf("error updating supplier: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSupplierNotFound
+	}
+
+	return nil
+}
+
+func scanSupplier(row rowScanner) (*models.Supplier, error) {
+	var s models.Supplier
+	err := row.Scan(&s.ID, &s.Name, &s.TaxID, &s.ContactPerson, &s.Phone, &s.Email, &s.IsActive, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
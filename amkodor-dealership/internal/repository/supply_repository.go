@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSupplyNotFound = errors.New("supply not found")
+	// ErrSupplyState - действие недопустимо в текущем статусе поставки
+	ErrSupplyState = errors.New("supply status does not allow this action")
+	// ErrSupplyModel - модель не выпускается производителем поставки
+	ErrSupplyModel = errors.New("vehicle model is not made by the supply manufacturer")
+	// ErrSupplyUnits - строка не из этой поставки или единиц больше, чем в строке
+	ErrSupplyUnits = errors.New("supply units do not match supply items")
+	// ErrSupplyIncomplete - при приёме заведены не все единицы поставки
+	ErrSupplyIncomplete = errors.New("not all supply units are registered")
+	// ErrSupplyReference - производитель или склад поставки не найдены
+	ErrSupplyReference = errors.New("supply references missing manufacturer or warehouse")
+	// ErrDuplicateVIN - техника с таким VIN уже есть
+	ErrDuplicateVIN = errors.New("vehicle with this VIN already exists")
+)
+
+const supplySelect = `
+	SELECT supply_id, manufacturer_id, manufacturer_name, COALESCE(country, ''),
+	       warehouse_id, warehouse_name, warehouse_city, supply_date,
+	       expected_arrival_date, actual_arrival_date, status, COALESCE(total_cost, 0),
+	       COALESCE(invoice_number, ''), COALESCE(notes, ''), items_count, total_quantity,
+	       registered_quantity, created_at
+	FROM vw_supplies_full_info`
+
+type SupplyRepository struct {
+	db *sql.DB
+}
+
+func NewSupplyRepository(db *sql.DB) SupplyRepository {
+	return SupplyRepository{db: db}
+}
+
+// GetAll возвращает поставки без строк. Пустой status и nil-фильтры не ограничивают выборку.
+func (r *SupplyRepository) GetAll(ctx context.Context, status string, warehouseID, manufacturerID *int) ([]models.Supply, error) {
+	rows, err := r.db.QueryContext(ctx, supplySelect+`
+		WHERE ($1 = '' OR status = $1)
+		  AND ($2::INTEGER IS NULL OR warehouse_id = $2)
+		  AND ($3::INTEGER IS NULL OR manufacturer_id = $3)
+		ORDER BY supply_date DESC, supply_id DESC`, status, warehouseID, manufacturerID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying supplies: %w", err)
+	}
+	defer rows.Close()
+
+	supplies := []models.Supply{}
+	for rows.Next() {
+		s, err := scanSupply(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning supply: %w", err)
+		}
+		supplies = append(supplies, *s)
+	}
+
+	return supplies, rows.Err()
+}
+
+// GetByID возвращает поставку со строками и заведённой по ним техникой
+func (r *SupplyRepository) GetByID(ctx context.Context, id int) (*models.Supply, error) {
+	s, err := scanSupply(r.db.QueryRowContext(ctx, supplySelect+` WHERE supply_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSupplyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting supply: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT si.supply_item_id, si.model_id, vm.model_name, si.quantity, si.unit_price,
+		       v.vehicle_id, v.serial_number, COALESCE(v.vin, ''), v.manufacture_year,
+		       COALESCE(v.color, ''), v.price, v.status
+		FROM supply_items si
+		INNER JOIN vehicle_models vm ON vm.model_id = si.model_id
+		LEFT JOIN vehicles v ON v.supply_item_id = si.supply_item_id
+		WHERE si.supply_id = $1
+		ORDER BY si.supply_item_id, v.vehicle_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying supply items: %w", err)
+	}
+	defer rows.Close()
+
+	s.Items = []models.SupplyItem{}
+	for rows.Next() {
+		var item models.SupplyItem
+		var vehicleID, year sql.NullInt64
+		var serial, vin, color, status sql.NullString
+		var price sql.NullFloat64
+		if err := rows.Scan(
+			&item.ID, &item.ModelID, &item.ModelName, &item.Quantity, &item.UnitPrice,
+			&vehicleID, &serial, &vin, &year, &color, &price, &status,
+		); err != nil {
+			return nil, fmt.Errorf("error scanning supply item: %w", err)
+		}
+
+		if n := len(s.Items); n == 0 || s.Items[n-1].ID != item.ID {
+			item.Vehicles = []models.SupplyVehicle{}
+			s.Items = append(s.Items, item)
+		}
+		if vehicleID.Valid {
+			last := &s.Items[len(s.Items)-1]
+			last.Vehicles = append(last.Vehicles, models.SupplyVehicle{
+				VehicleID:       int(vehicleID.Int64),
+				SerialNumber:    serial.String,
+				VIN:             vin.String,
+				ManufactureYear: int(year.Int64),
+				Color:           color.String,
+				Price:           price.Float64,
+				Status:          status.String,
+			})
+		}
+	}
+
+	return s, rows.Err()
+}
+
+// Create оформляет поставку со статусом 'Заказано' и возвращает её ID.
+// Модели всех строк должны выпускаться производителем поставки.
+func (r *SupplyRepository) Create(ctx context.Context, req *models.SupplyRequest, expectedDate *time.Time, createdBy int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var totalCost float64
+	for _, item := range req.Items {
+		totalCost += float64(item.Quantity) * item.UnitPrice
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO supplies (manufacturer_id, warehouse_id, expected_arrival_date, total_cost,
+		                      status, invoice_number, notes, created_by)
+		VALUES ($1, $2, $3, $4, 'Заказано', NULLIF($5, ''), NULLIF($6, ''), $7)
+		RETURNING supply_id`,
+		req.ManufacturerID, req.WarehouseID, expectedDate, totalCost,
+		req.InvoiceNumber, req.Notes, createdBy,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return 0, ErrSupplyReference
+		}
+		return 0, fmt.Errorf("error creating supply: %w", err)
+	}
+
+	for _, item := range req.Items {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO supply_items (supply_id, model_id, quantity, unit_price)
+			SELECT $1, model_id, $3, $4
+			FROM vehicle_models
+			WHERE model_id = $2 AND manufacturer_id = $5`,
+			id, item.ModelID, item.Quantity, item.UnitPrice, req.ManufacturerID)
+		if err != nil {
+			return 0, fmt.Errorf("error creating supply item: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return 0, ErrSupplyModel
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// Cancel отменяет поставку, пока она не отгружена
+func (r *SupplyRepository) Cancel(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE supplies SET status = 'Отменено'
+		WHERE supply_id = $1 AND status = 'Заказано'`, id)
+	if err != nil {
+		return fmt.Errorf("error cancelling supply: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM supplies WHERE supply_id = $1)`, id,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("error checking supply: %w", err)
+	}
+	if !exists {
+		return ErrSupplyNotFound
+	}
+	return ErrSupplyState
+}
+
+// Ship переводит поставку в 'В пути' и заводит переданные единицы техники
+// со статусом 'В пути' на складе назначения
+func (r *SupplyRepository) Ship(ctx context.Context, id int, invoiceNumber string, units []models.SupplyUnit) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	warehouseID, err := lockSupply(ctx, tx, id, models.SupplyOrdered)
+	if err != nil {
+		return err
+	}
+	if err := registerSupplyUnits(ctx, tx, id, warehouseID, units); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE supplies
+		SET status = 'В пути', invoice_number = COALESCE(NULLIF($2, ''), invoice_number)
+		WHERE supply_id = $1`, id, invoiceNumber)
+	if err != nil {
+		return fmt.Errorf("error shipping supply: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Receive принимает поставку на склад. Переданные единицы заводятся так же,
+// как при отгрузке; после этого по каждой строке должно быть заведено ровно
+// quantity единиц. Техника поставки переходит из 'В пути' в 'В наличии'.
+func (r *SupplyRepository) Receive(ctx context.Context, id, employeeID int, invoiceNumber string, units []models.SupplyUnit) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	warehouseID, err := lockSupply(ctx, tx, id, models.SupplyOrdered, models.SupplyInTransit)
+	if err != nil {
+		return err
+	}
+	if err := registerSupplyUnits(ctx, tx, id, warehouseID, units); err != nil {
+		return err
+	}
+
+	var missing int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(si.quantity - (SELECT COUNT(*) FROM vehicles v
+		                                   WHERE v.supply_item_id = si.supply_item_id)), 0)
+		FROM supply_items si
+		WHERE si.supply_id = $1`, id,
+	).Scan(&missing); err != nil {
+		return fmt.Errorf("error counting supply units: %w", err)
+	}
+	if missing > 0 {
+		return fmt.Errorf("%w: %d more units expected", ErrSupplyIncomplete, missing)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicles
+		SET status = 'В наличии', arrival_date = CURRENT_DATE, updated_at = CURRENT_TIMESTAMP
+		WHERE status = 'В пути'
+		  AND supply_item_id IN (SELECT supply_item_id FROM supply_items WHERE supply_id = $1)`, id)
+	if err != nil {
+		return fmt.Errorf("error updating supply vehicles: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE supplies
+		SET status = 'Получено', actual_arrival_date = CURRENT_DATE, received_by = $2,
+		    invoice_number = COALESCE(NULLIF($3, ''), invoice_number)
+		WHERE supply_id = $1`, id, employeeID, invoiceNumber)
+	if err != nil {
+		return fmt.Errorf("error receiving supply: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// lockSupply блокирует поставку в одном из статусов statuses и возвращает её склад
+func lockSupply(ctx context.Context, tx *sql.Tx, id int, statuses ...string) (int, error) {
+	var status string
+	var warehouseID int
+	err := tx.QueryRowContext(ctx, `
+		SELECT status, warehouse_id FROM supplies
+		WHERE supply_id = $1
+		FOR UPDATE`, id,
+	).Scan(&status, &warehouseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrSupplyNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error locking supply: %w", err)
+	}
+
+	for _, s := range statuses {
+		if status == s {
+			return warehouseID, nil
+		}
+	}
+	return 0, ErrSupplyState
+}
+
+// registerSupplyUnits заводит технику по строкам поставки со статусом 'В пути'
+// на складе поставки с учётом его вместимости
+func registerSupplyUnits(ctx context.Context, tx *sql.Tx, supplyID, warehouseID int, units []models.SupplyUnit) error {
+	if len(units) == 0 {
+		return nil
+	}
+
+	type supplyItem struct {
+		modelID   int
+		unitPrice float64
+		free      int
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT si.supply_item_id, si.model_id, si.unit_price,
+		       si.quantity - (SELECT COUNT(*) FROM vehicles v WHERE v.supply_item_id = si.supply_item_id)
+		FROM supply_items si
+		WHERE si.supply_id = $1`, supplyID)
+	if err != nil {
+		return fmt.Errorf("error querying supply items: %w", err)
+	}
+	items := map[int]*supplyItem{}
+	for rows.Next() {
+		var itemID int
+		var item supplyItem
+		if err := rows.Scan(&itemID, &item.modelID, &item.unitPrice, &item.free); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning supply item: %w", err)
+		}
+		items[itemID] = &item
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error querying supply items: %w", err)
+	}
+
+	for _, u := range units {
+		item, ok := items[u.SupplyItemID]
+		if !ok || item.free == 0 {
+			return ErrSupplyUnits
+		}
+		item.free--
+	}
+
+	if err := reserveWarehouseSpace(ctx, tx, warehouseID, len(units)); err != nil {
+		return err
+	}
+
+	for _, u := range units {
+		item := items[u.SupplyItemID]
+		price := item.unitPrice
+		if u.Price != nil {
+			price = *u.Price
+		}
+
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO vehicles (model_id, warehouse_id, vin, serial_number, manufacture_year,
+			                      color, price, status, supply_item_id)
+			VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, 'В пути', $8)`,
+			item.modelID, warehouseID, u.VIN, u.SerialNumber, u.ManufactureYear,
+			u.Color, price, u.SupplyItemID)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return fmt.Errorf("%w: %s", ErrDuplicateVIN, u.VIN)
+			}
+			return fmt.Errorf("error creating supply vehicle: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanSupply(row rowScanner) (*models.Supply, error) {
+	var s models.Supply
+	err := row.Scan(
+		&s.ID, &s.ManufacturerID, &s.ManufacturerName, &s.Country,
+		&s.WarehouseID, &s.WarehouseName, &s.WarehouseCity, &s.SupplyDate,
+		&s.ExpectedArrivalDate, &s.ActualArrivalDate, &s.Status, &s.TotalCost,
+		&s.InvoiceNumber, &s.Notes, &s.ItemsCount, &s.TotalQuantity,
+		&s.RegisteredQuantity, &s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,474 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/utils"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrTestDriveNotFound = errors.New("test drive not found")
+	// ErrTestDriveState - тест-драйв уже завершён, отменён или клиент не явился
+	ErrTestDriveState = errors.New("test drive is not scheduled")
+	// ErrTestDriveVehicle - техника продана, в ремонте или в пути
+	ErrTestDriveVehicle = errors.New("vehicle is not available for test drive")
+	// ErrTestDriveVehicleBusy - на это время технику уже взяли на тест-драйв
+	ErrTestDriveVehicleBusy = errors.New("vehicle already booked for this time")
+	// ErrTestDriveManagerBusy - у менеджера на это время уже есть тест-драйв
+	ErrTestDriveManagerBusy = errors.New("manager already booked for this time")
+	// ErrTestDriveInPast - тест-драйв назначается только на будущее время
+	ErrTestDriveInPast = errors.New("test drive must be scheduled in the future")
+	// ErrTestDriveReference - клиент или менеджер не найден
+	ErrTestDriveReference = errors.New("test drive client or manager not found")
+	// ErrFeedbackLinkNotFound - ссылки на отзыв с таким токеном нет
+	ErrFeedbackLinkNotFound = errors.New("feedback link not found")
+	// ErrFeedbackLinkExpired - срок действия ссылки на отзыв истёк
+	ErrFeedbackLinkExpired = errors.New("feedback link expired")
+	// ErrFeedbackSubmitted - отзыв по ссылке уже оставлен
+	ErrFeedbackSubmitted = errors.New("feedback already submitted")
+)
+
+// TestDriveInterval - время, занятое запланированным тест-драйвом
+type TestDriveInterval struct {
+	VehicleID  int
+	EmployeeID int
+	Start      time.Time
+	End        time.Time
+}
+
+// Время тест-драйвов хранится в TIMESTAMP без часового пояса по Минску:
+// параметры передаются в utils.DealershipLocation, прочитанные значения
+// переводятся через utils.DealershipWallTime.
+const testDriveSelect = `
+	SELECT td.test_drive_id, td.vehicle_id, vm.model_name, COALESCE(v.vin, ''),
+	       w.warehouse_id, w.warehouse_name, td.customer_id, td.corporate_client_id,
+	       COALESCE(c.last_name || ' ' || c.first_name, cc.company_name, ''),
+	       COALESCE(c.phone, cc.phone, ''), COALESCE(c.email, cc.email, ''),
+	       td.employee_id, e.last_name || ' ' || e.first_name,
+	       td.scheduled_date, COALESCE(td.duration, 60), td.status, COALESCE(td.cancel_reason, ''),
+	       td.feedback_rating, COALESCE(td.feedback_comment, ''), td.booked_by_user_id,
+	       td.created_at, COALESCE(td.updated_at, td.created_at)
+	FROM test_drives td
+	INNER JOIN vehicles v ON v.vehicle_id = td.vehicle_id
+	INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+	INNER JOIN warehouses w ON w.warehouse_id = v.warehouse_id
+	INNER JOIN employees e ON e.employee_id = td.employee_id
+	LEFT JOIN customers c ON c.customer_id = td.customer_id
+	LEFT JOIN corporate_clients cc ON cc.corporate_client_id = td.corporate_client_id`
+
+type TestDriveRepository struct {
+	db *sql.DB
+}
+
+func NewTestDriveRepository(db *sql.DB) TestDriveRepository {
+	return TestDriveRepository{db: db}
+}
+
+// GetAll возвращает тест-драйвы в интервале [from, to) по времени начала.
+// Пустой status и nil-фильтры не ограничивают выборку.
+func (r *TestDriveRepository) GetAll(ctx context.Context, status string, vehicleID, employeeID *int, from, to *time.Time) ([]models.TestDriveBooking, error) {
+	rows, err := r.db.QueryContext(ctx, testDriveSelect+`
+		WHERE ($1 = '' OR td.status = $1)
+		  AND ($2::INTEGER IS NULL OR td.vehicle_id = $2)
+		  AND ($3::INTEGER IS NULL OR td.employee_id = $3)
+		  AND ($4::TIMESTAMP IS NULL OR td.scheduled_date >= $4)
+		  AND ($5::TIMESTAMP IS NULL OR td.scheduled_date < $5)
+		ORDER BY td.scheduled_date, td.test_drive_id`,
+		status, vehicleID, employeeID, dealershipParam(from), dealershipParam(to))
+	if err != nil {
+		return nil, fmt.Errorf("error querying test drives: %w", err)
+	}
+	defer rows.Close()
+
+	drives := []models.TestDriveBooking{}
+	for rows.Next() {
+		td, err := scanTestDrive(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning test drive: %w", err)
+		}
+		drives = append(drives, *td)
+	}
+
+	return drives, rows.Err()
+}
+
+// GetByID возвращает тест-драйв по ID
+func (r *TestDriveRepository) GetByID(ctx context.Context, id int) (*models.TestDriveBooking, error) {
+	td, err := scanTestDrive(r.db.QueryRowContext(ctx, testDriveSelect+` WHERE td.test_drive_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTestDriveNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting test drive: %w", err)
+	}
+	return td, nil
+}
+
+// GetVehicle возвращает склад и статус техники для записи на тест-драйв
+func (r *TestDriveRepository) GetVehicle(ctx context.Context, vehicleID int) (warehouseID int, status string, err error) {
+	err = r.db.QueryRowContext(ctx,
+		`SELECT warehouse_id, status FROM vehicles WHERE vehicle_id = $1`, vehicleID,
+	).Scan(&warehouseID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", ErrVehicleNotFound
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting vehicle: %w", err)
+	}
+	return warehouseID, status, nil
+}
+
+// GetManagers возвращает действующих менеджеров по продажам. warehouseID
+// ограничивает выборку менеджерами склада и менеджерами без склада,
+// employeeID - одним сотрудником.
+func (r *TestDriveRepository) GetManagers(ctx context.Context, warehouseID, employeeID *int) ([]models.TestDriveManager, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT e.employee_id, e.last_name || ' ' || e.first_name
+		FROM employees e
+		INNER JOIN positions p ON p.position_id = e.position_id
+		WHERE e.is_active AND p.access_role = $1
+		  AND ($2::INTEGER IS NULL OR e.warehouse_id = $2 OR e.warehouse_id IS NULL)
+		  AND ($3::INTEGER IS NULL OR e.employee_id = $3)
+		ORDER BY e.last_name, e.first_name, e.employee_id`,
+		models.RoleSalesManager, warehouseID, employeeID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying managers: %w", err)
+	}
+	defer rows.Close()
+
+	managers := []models.TestDriveManager{}
+	for rows.Next() {
+		var m models.TestDriveManager
+		if err := rows.Scan(&m.ID, &m.Name); err != nil {
+			return nil, fmt.Errorf("error scanning manager: %w", err)
+		}
+		managers = append(managers, m)
+	}
+
+	return managers, rows.Err()
+}
+
+// GetWorkingHours возвращает расписание сотрудников. Сотрудники без
+// расписания в результат не попадают.
+func (r *TestDriveRepository) GetWorkingHours(ctx context.Context, employeeIDs []int) (map[int][]models.WorkingHours, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT employee_id, weekday, TO_CHAR(start_time, 'HH24:MI'), TO_CHAR(end_time, 'HH24:MI')
+		FROM employee_working_hours
+		WHERE employee_id = ANY($1)
+		ORDER BY employee_id, weekday`,
+		pq.Array(employeeIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error querying working hours: %w", err)
+	}
+	defer rows.Close()
+
+	hours := make(map[int][]models.WorkingHours)
+	for rows.Next() {
+		var employeeID int
+		var h models.WorkingHours
+		if err := rows.Scan(&employeeID, &h.Weekday, &h.Start, &h.End); err != nil {
+			return nil, fmt.Errorf("error scanning working hours: %w", err)
+		}
+		hours[employeeID] = append(hours[employeeID], h)
+	}
+
+	return hours, rows.Err()
+}
+
+// SetWorkingHours заменяет расписание сотрудника. Пустой список сбрасывает
+// расписание к рабочему времени по умолчанию.
+func (r *TestDriveRepository) SetWorkingHours(ctx context.Context, employeeID int, hours []models.WorkingHours) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM employees WHERE employee_id = $1)`, employeeID,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking employee: %w", err)
+	}
+	if !exists {
+		return ErrEmployeeNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM employee_working_hours WHERE employee_id = $1`, employeeID); err != nil {
+		return fmt.Errorf("error clearing working hours: %w", err)
+	}
+	for _, h := range hours {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO employee_working_hours (employee_id, weekday, start_time, end_time)
+			VALUES ($1, $2, $3, $4)`,
+			employeeID, h.Weekday, h.Start, h.End)
+		if err != nil {
+			return fmt.Errorf("error saving working hours: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetBusy возвращает запланированные тест-драйвы техники или менеджеров,
+// пересекающиеся с [from, to). Тест-драйв excludeID не учитывается.
+func (r *TestDriveRepository) GetBusy(ctx context.Context, vehicleID *int, employeeIDs []int, from, to time.Time, excludeID int) ([]TestDriveInterval, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT vehicle_id, employee_id, scheduled_date,
+		       scheduled_date + (COALESCE(duration, 60) || ' minutes')::INTERVAL
+		FROM test_drives
+		WHERE status = 'Запланирован'
+		  AND test_drive_id <> $5
+		  AND (vehicle_id = $1 OR employee_id = ANY($2))
+		  AND scheduled_date < $4
+		  AND scheduled_date + (COALESCE(duration, 60) || ' minutes')::INTERVAL > $3`,
+		vehicleID, pq.Array(employeeIDs), from.In(utils.DealershipLocation), to.In(utils.DealershipLocation), excludeID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying booked test drives: %w", err)
+	}
+	defer rows.Close()
+
+	busy := []TestDriveInterval{}
+	for rows.Next() {
+		var b TestDriveInterval
+		if err := rows.Scan(&b.VehicleID, &b.EmployeeID, &b.Start, &b.End); err != nil {
+			return nil, fmt.Errorf("error scanning booked test drive: %w", err)
+		}
+		b.Start = utils.DealershipWallTime(b.Start)
+		b.End = utils.DealershipWallTime(b.End)
+		busy = append(busy, b)
+	}
+
+	return busy, rows.Err()
+}
+
+// Create записывает клиента на тест-драйв. Пересечения по технике и
+// менеджеру проверяет триггер check_vehicle_for_test_drive.
+func (r *TestDriveRepository) Create(ctx context.Context, req *models.BookTestDriveRequest, employeeID int, bookedBy *int) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO test_drives (vehicle_id, customer_id, corporate_client_id, employee_id,
+		                         scheduled_date, duration, status, booked_by_user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, 'Запланирован', $7)
+		RETURNING test_drive_id`,
+		req.VehicleID, req.CustomerID, req.CorporateClientID, employeeID,
+		req.ScheduledDate.In(utils.DealershipLocation), req.Duration, bookedBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, testDriveWriteError(err, "error creating test drive")
+	}
+	return id, nil
+}
+
+// Reschedule переносит запланированный тест-драйв
+func (r *TestDriveRepository) Reschedule(ctx context.Context, id int, start time.Time, duration, employeeID int) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE test_drives
+		SET scheduled_date = $2, duration = $3, employee_id = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE test_drive_id = $1 AND status = 'Запланирован'`,
+		id, start.In(utils.DealershipLocation), duration, employeeID)
+	if err != nil {
+		return testDriveWriteError(err, "error rescheduling test drive")
+	}
+	return r.checkScheduledUpdate(ctx, result, id)
+}
+
+// SetStatus переводит запланированный тест-драйв в status. reason
+// сохраняется как причина отмены.
+func (r *TestDriveRepository) SetStatus(ctx context.Context, id int, status, reason string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE test_drives
+		SET status = $2, cancel_reason = NULLIF($3, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE test_drive_id = $1 AND status = 'Запланирован'`,
+		id, status, reason)
+	if err != nil {
+		return fmt.Errorf("error updating test drive status: %w", err)
+	}
+	return r.checkScheduledUpdate(ctx, result, id)
+}
+
+// Complete отмечает, что запланированный тест-драйв состоялся, и создаёт
+// ссылку на отзыв с хешем токена, действующую ttlDays дней
+func (r *TestDriveRepository) Complete(ctx context.Context, id int, tokenHash string, ttlDays int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE test_drives
+		SET status = 'Завершен', updated_at = CURRENT_TIMESTAMP
+		WHERE test_drive_id = $1 AND status = 'Запланирован'`, id)
+	if err != nil {
+		return fmt.Errorf("error completing test drive: %w", err)
+	}
+	if err := r.checkScheduledUpdate(ctx, result, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO test_drive_feedback_links (test_drive_id, token_hash, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(days => $3))`,
+		id, tokenHash, ttlDays)
+	if err != nil {
+		return fmt.Errorf("error creating feedback link: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetFeedbackForm возвращает тест-драйв по хешу токена ссылки на отзыв
+func (r *TestDriveRepository) GetFeedbackForm(ctx context.Context, tokenHash string) (*models.TestDriveFeedbackForm, error) {
+	var form models.TestDriveFeedbackForm
+	var rating sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT vm.model_name, w.warehouse_name, e.last_name || ' ' || e.first_name,
+		       td.scheduled_date, l.expires_at, l.submitted_at IS NOT NULL,
+		       td.feedback_rating, COALESCE(td.feedback_comment, '')
+		FROM test_drive_feedback_links l
+		INNER JOIN test_drives td ON td.test_drive_id = l.test_drive_id
+		INNER JOIN vehicles v ON v.vehicle_id = td.vehicle_id
+		INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+		INNER JOIN warehouses w ON w.warehouse_id = v.warehouse_id
+		INNER JOIN employees e ON e.employee_id = td.employee_id
+		WHERE l.token_hash = $1`, tokenHash,
+	).Scan(&form.ModelName, &form.WarehouseName, &form.ManagerName,
+		&form.ScheduledDate, &form.ExpiresAt, &form.Submitted, &rating, &form.Comment)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFeedbackLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting feedback link: %w", err)
+	}
+	form.ScheduledDate = utils.DealershipWallTime(form.ScheduledDate)
+	form.Rating = nullIntPtr(rating)
+	return &form, nil
+}
+
+// SubmitFeedback сохраняет оценку и комментарий по ссылке. Отзыв по ссылке
+// оставляется один раз.
+func (r *TestDriveRepository) SubmitFeedback(ctx context.Context, tokenHash string, rating int, comment string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var testDriveID int
+	var submitted, expired bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT test_drive_id, submitted_at IS NOT NULL, expires_at <= CURRENT_TIMESTAMP
+		FROM test_drive_feedback_links
+		WHERE token_hash = $1
+		FOR UPDATE`, tokenHash,
+	).Scan(&testDriveID, &submitted, &expired)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrFeedbackLinkNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error locking feedback link: %w", err)
+	}
+	if submitted {
+		return ErrFeedbackSubmitted
+	}
+	if expired {
+		return ErrFeedbackLinkExpired
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE test_drives
+		SET feedback_rating = $2, feedback_comment = NULLIF($3, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE test_drive_id = $1`, testDriveID, rating, comment); err != nil {
+		return fmt.Errorf("error saving feedback: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE test_drive_feedback_links SET submitted_at = CURRENT_TIMESTAMP
+		WHERE test_drive_id = $1`, testDriveID); err != nil {
+		return fmt.Errorf("error closing feedback link: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// checkScheduledUpdate отличает несуществующий тест-драйв от уже закрытого,
+// если UPDATE по запланированному тест-драйву не затронул строк
+func (r *TestDriveRepository) checkScheduledUpdate(ctx context.Context, result sql.Result, id int) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM test_drives WHERE test_drive_id = $1)`, id,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking test drive: %w", err)
+	}
+	if !exists {
+		return ErrTestDriveNotFound
+	}
+	return ErrTestDriveState
+}
+
+// testDriveWriteError переводит ошибки триггера check_vehicle_for_test_drive
+// и внешних ключей в ошибки репозитория
+func testDriveWriteError(err error, msg string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "23P01" && pqErr.Constraint == "test_drives_vehicle_overlap":
+			return ErrTestDriveVehicleBusy
+		case pqErr.Code == "23P01" && pqErr.Constraint == "test_drives_employee_overlap":
+			return ErrTestDriveManagerBusy
+		case pqErr.Code == "23514" && pqErr.Constraint == "test_drives_scheduled_in_future":
+			return ErrTestDriveInPast
+		case pqErr.Code == "55006":
+			return ErrTestDriveVehicle
+		case pqErr.Code == "23503":
+			return ErrTestDriveReference
+		}
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// dealershipParam передаёт необязательное время в базу по Минску
+func dealershipParam(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.In(utils.DealershipLocation)
+}
+
+func scanTestDrive(row rowScanner) (*models.TestDriveBooking, error) {
+	var td models.TestDriveBooking
+	var rating sql.NullInt64
+	err := row.Scan(
+		&td.ID, &td.VehicleID, &td.ModelName, &td.VIN,
+		&td.WarehouseID, &td.WarehouseName, &td.CustomerID, &td.CorporateClientID,
+		&td.ClientName, &td.ClientPhone, &td.ClientEmail, &td.EmployeeID, &td.ManagerName,
+		&td.ScheduledDate, &td.Duration, &td.Status, &td.CancelReason,
+		&rating, &td.FeedbackComment, &td.BookedByUserID,
+		&td.CreatedAt, &td.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	td.ScheduledDate = utils.DealershipWallTime(td.ScheduledDate)
+	td.EndDate = td.ScheduledDate.Add(time.Duration(td.Duration) * time.Minute)
+	td.FeedbackRating = nullIntPtr(rating)
+	return &td, nil
+}
@@ -37,13 +37,13 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) (int, er
 
 // GetByEmail получает пользователя по email
 func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `SELECT user_id, name, email, phone, password_hash, role, created_at, updated_at 
+	query := `SELECT user_id, name, email, phone, password_hash, role, employee_id, created_at, updated_at 
 			  FROM users WHERE email = $1`
 	
 	var user models.User
 	err := r.db.QueryRow(query, email).Scan(
 		&user.UserID, &user.Name, &user.Email, &user.Phone, 
-		&user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		&user.PasswordHash, &user.Role, &user.EmployeeID, &user.CreatedAt, &user.UpdatedAt)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -57,13 +57,13 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 
 // GetByID получает пользователя по ID
 func (r *UserRepository) GetByID(ctx context.Context, userID int) (*models.User, error) {
-	query := `SELECT user_id, name, email, phone, password_hash, role, created_at, updated_at 
+	query := `SELECT user_id, name, email, phone, password_hash, role, employee_id, created_at, updated_at 
 			  FROM users WHERE user_id = $1`
 	
 	var user models.User
 	err := r.db.QueryRow(query, userID).Scan(
 		&user.UserID, &user.Name, &user.Email, &user.Phone, 
-		&user.PasswordHash, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		&user.PasswordHash, &user.Role, &user.EmployeeID, &user.CreatedAt, &user.UpdatedAt)
 	
 	if err != nil {
 		if err == sql.ErrNoRows {
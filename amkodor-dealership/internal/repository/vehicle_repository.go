@@ -3,11 +3,30 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
 )
 
+// activeReservationExpiry - срок действующей брони техники из vw_vehicles_full_info vw
+const activeReservationExpiry = `(
+	SELECT r.expires_at FROM vehicle_reservations r
+	WHERE r.vehicle_id = vw.vehicle_id AND r.status = 'Активна' AND r.expires_at > CURRENT_TIMESTAMP)`
+
+// modelRating - средняя оценка модели техники из vw_vehicles_full_info vw
+// по отзывам о тест-драйвах и число оценок
+const modelRating = `(
+	SELECT mr.average_rating FROM vw_model_test_drive_ratings mr
+	INNER JOIN vehicles mv ON mv.model_id = mr.model_id
+	WHERE mv.vehicle_id = vw.vehicle_id), COALESCE((
+	SELECT mr.ratings_count FROM vw_model_test_drive_ratings mr
+	INNER JOIN vehicles mv ON mv.model_id = mr.model_id
+	WHERE mv.vehicle_id = vw.vehicle_id), 0)`
+
 type VehicleRepository struct {
 	db *sql.DB
 }
@@ -52,8 +71,9 @@ func (r *VehicleRepository) GetAll(limit, offset int) ([]models.Vehicle, error)
 // GetByID возвращает технику по ID
 func (r *VehicleRepository) GetByID(ctx context.Context, id int) (*models.Vehicle, error) {
 	query := `
-		SELECT * FROM vw_vehicles_full_info
-		WHERE vehicle_id = $1
+		SELECT vw.*, ` + activeReservationExpiry + `, ` + modelRating + `
+		FROM vw_vehicles_full_info vw
+		WHERE vw.vehicle_id = $1
 	`
 
 	var v models.Vehicle
@@ -62,11 +82,11 @@ func (r *VehicleRepository) GetByID(ctx context.Context, id int) (*models.Vehicl
 		&v.CategoryName, &v.ManufacturerName, &v.ManufactureYear, &v.Color,
 		&v.Price, &v.Discount, &v.FinalPrice, &v.Status, &v.WarehouseName,
 		&v.WarehouseCity, &v.ArrivalDate, &v.CreatedAt, &v.Description,
-		&v.Specifications,
+		&v.Specifications, &v.ReservedUntil, &v.Rating, &v.RatingsCount,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("vehicle not found")
+		return nil, ErrVehicleNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("error querying vehicle: %w", err)
@@ -132,7 +152,7 @@ func (r *VehicleRepository) Update(v *models.Vehicle) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("vehicle not found")
+		return ErrVehicleNotFound
 	}
 
 	return nil
@@ -153,7 +173,7 @@ func (r *VehicleRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("vehicle not found")
+		return ErrVehicleNotFound
 	}
 
 	return nil
@@ -301,8 +321,335 @@ func (r *VehicleRepository) UpdateStatus(vehicleID int, status string) error {
 	}
 
 	if rows == 0 {
-		return fmt.Errorf("vehicle not found")
+		return ErrVehicleNotFound
 	}
 
 	return nil
 }
+
+// Ошибки сопоставления данных каталога со справочниками
+var (
+	ErrVehicleNotFound        = errors.New("vehicle not found")
+	ErrUnknownVehicleCategory = errors.New("unknown vehicle category")
+	ErrVehicleModelNotFound   = errors.New("vehicle model not found")
+	ErrWarehouseNotFound      = errors.New("warehouse not found")
+	// ErrVehicleInTransfer - склад и статус техники в пути меняются только перемещением
+	ErrVehicleInTransfer = errors.New("vehicle is in transit between warehouses")
+	// ErrVehicleStatusLocked - проданная или забронированная техника меняет
+	// статус только отменой продажи или снятием брони
+	ErrVehicleStatusLocked = errors.New("vehicle status is locked by a sale or reservation")
+)
+
+// GetPage возвращает страницу техники и общее количество записей.
+// Пустой status означает технику в любом статусе.
+func (r *VehicleRepository) GetPage(ctx context.Context, status string, limit, offset int) ([]models.Vehicle, int, error) {
+	query := `
+		SELECT vehicle_id, vin, serial_number, model_name, type_name,
+		       category_name, manufacturer_name, manufacture_year, color,
+		       price, discount, final_price, status, warehouse_name,
+		       warehouse_city, arrival_date, created_at, description,
+		       specifications, ` + activeReservationExpiry + `, ` + modelRating + `, COUNT(*) OVER()
+		FROM vw_vehicles_full_info vw
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC, vehicle_id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error querying vehicles: %w", err)
+	}
+	defer rows.Close()
+
+	total := 0
+	vehicles := []models.Vehicle{}
+	for rows.Next() {
+		var v models.Vehicle
+		var specs []byte
+		err := rows.Scan(
+			&v.VehicleID, &v.VIN, &v.SerialNumber, &v.ModelName, &v.TypeName,
+			&v.CategoryName, &v.ManufacturerName, &v.ManufactureYear, &v.Color,
+			&v.Price, &v.Discount, &v.FinalPrice, &v.Status, &v.WarehouseName,
+			&v.WarehouseCity, &v.ArrivalDate, &v.CreatedAt, &v.Description,
+			&specs, &v.ReservedUntil, &v.Rating, &v.RatingsCount, &total,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning vehicle: %w", err)
+		}
+		v.Specifications = specs
+		vehicles = append(vehicles, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating vehicles: %w", err)
+	}
+
+	// За пределами последней страницы COUNT(*) OVER() недоступен
+	if len(vehicles) == 0 && offset > 0 {
+		if err := r.db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM vehicles WHERE $1 = '' OR status = $1`, status,
+		).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("error counting vehicles: %w", err)
+		}
+	}
+
+	return vehicles, total, nil
+}
+
+// CreateFromRequest создает единицу техники по запросу каталога,
+// при необходимости заводя модель в vehicle_models
+func (r *VehicleRepository) CreateFromRequest(ctx context.Context, req *models.CreateVehicleRequest) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	modelID, err := resolveVehicleModel(ctx, tx, req)
+	if err != nil {
+		return 0, err
+	}
+
+	warehouseID, err := resolveWarehouse(ctx, tx, req.WarehouseID)
+	if err != nil {
+		return 0, err
+	}
+
+	specs, err := vehicleSpecifications(req)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		INSERT INTO vehicles (
+			model_id, warehouse_id, vin, serial_number, manufacture_year,
+			color, price, discount, status, specifications
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, NULLIF($6, ''), $7, COALESCE($8, 0), $9, $10::jsonb)
+		RETURNING vehicle_id
+	`
+
+	var vehicleID int
+	err = tx.QueryRowContext(ctx, query,
+		modelID, warehouseID, req.VIN, req.SerialNumber, req.Year,
+		req.Color, req.Price, req.Discount, req.Status, specs,
+	).Scan(&vehicleID)
+	if err != nil {
+		return 0, fmt.Errorf("error creating vehicle: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return vehicleID, nil
+}
+
+// UpdateFromRequest обновляет единицу техники по запросу каталога.
+// Незаполненные склад, VIN, серийный номер, год, цвет, цена, скидка, статус
+// и характеристики не меняются.
+func (r *VehicleRepository) UpdateFromRequest(ctx context.Context, id int, req *models.CreateVehicleRequest) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	modelID, err := resolveVehicleModel(ctx, tx, req)
+	if err != nil {
+		return err
+	}
+
+	var warehouseID sql.NullInt64
+	if req.WarehouseID > 0 {
+		resolvedID, err := resolveWarehouse(ctx, tx, req.WarehouseID)
+		if err != nil {
+			return err
+		}
+		warehouseID = sql.NullInt64{Int64: int64(resolvedID), Valid: true}
+	}
+
+	specs, err := vehicleSpecifications(req)
+	if err != nil {
+		return err
+	}
+
+	if req.Status != "" {
+		var status string
+		err := tx.QueryRowContext(ctx,
+			`SELECT status FROM vehicles WHERE vehicle_id = $1 FOR UPDATE`, id).Scan(&status)
+		if err == sql.ErrNoRows {
+			return ErrVehicleNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error locking vehicle: %w", err)
+		}
+		if status != req.Status && (status == "Продано" || status == "Зарезервировано") {
+			return ErrVehicleStatusLocked
+		}
+	}
+
+	query := `
+		UPDATE vehicles SET
+			model_id = $1,
+			warehouse_id = COALESCE($2, warehouse_id),
+			vin = COALESCE(NULLIF($3, ''), vin),
+			serial_number = COALESCE(NULLIF($4, ''), serial_number),
+			manufacture_year = COALESCE(NULLIF($5, 0), manufacture_year),
+			color = COALESCE(NULLIF($6, ''), color),
+			price = COALESCE(NULLIF($7, 0), price),
+			discount = COALESCE($8, discount),
+			status = COALESCE(NULLIF($9, ''), status),
+			specifications = specifications || $10::jsonb,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE vehicle_id = $11
+	`
+
+	result, err := tx.ExecContext(ctx, query,
+		modelID, warehouseID, req.VIN, req.SerialNumber, req.Year,
+		req.Color, req.Price, req.Discount, req.Status, specs, id,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "55006" {
+			return ErrVehicleInTransfer
+		}
+		return fmt.Errorf("error updating vehicle: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rows == 0 {
+		return ErrVehicleNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// vehicleSpecifications собирает характеристики единицы техники из запроса
+// каталога. Незаполненные поля в JSON не попадают.
+func vehicleSpecifications(req *models.CreateVehicleRequest) (string, error) {
+	specs := map[string]string{}
+	for key, value := range map[string]string{
+		"engine": req.Engine,
+		"power":  req.Power,
+		"weight": req.Weight,
+		"image":  req.Image,
+	} {
+		if value != "" {
+			specs[key] = value
+		}
+	}
+	specsJSON, err := json.Marshal(specs)
+	if err != nil {
+		return "", fmt.Errorf("error encoding specifications: %w", err)
+	}
+	return string(specsJSON), nil
+}
+
+// resolveVehicleModel находит модель по ID или имени. Неизвестная по имени
+// модель создается в типе, соответствующем категории запроса. Характеристики
+// из запроса относятся к единице техники и в модель не пишутся.
+func resolveVehicleModel(ctx context.Context, tx *sql.Tx, req *models.CreateVehicleRequest) (int, error) {
+	var modelID int
+	var err error
+	if req.ModelID > 0 {
+		modelID = req.ModelID
+	} else {
+		err = tx.QueryRowContext(ctx,
+			`SELECT model_id FROM vehicle_models WHERE LOWER(model_name) = LOWER($1) ORDER BY model_id LIMIT 1`,
+			req.Name,
+		).Scan(&modelID)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, fmt.Errorf("error querying vehicle model: %w", err)
+		}
+	}
+
+	if modelID > 0 {
+		var exists bool
+		if err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS(SELECT 1 FROM vehicle_models WHERE model_id = $1)`, modelID,
+		).Scan(&exists); err != nil {
+			return 0, fmt.Errorf("error querying vehicle model: %w", err)
+		}
+		if !exists {
+			return 0, ErrVehicleModelNotFound
+		}
+		return modelID, nil
+	}
+
+	// Категория из каталога может быть как типом ("Экскаваторы"), так и
+	// категорией ("Лесная техника") или частью названия типа ("Погрузчики")
+	var typeID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT vt.type_id
+		FROM vehicle_types vt
+		INNER JOIN vehicle_categories vc ON vt.category_id = vc.category_id
+		WHERE LOWER(vt.type_name) = LOWER($1)
+		   OR LOWER(vc.category_name) = LOWER($1)
+		   OR vt.type_name ILIKE '%' || $1 || '%'
+		ORDER BY
+			CASE
+				WHEN LOWER(vt.type_name) = LOWER($1) THEN 0
+				WHEN LOWER(vc.category_name) = LOWER($1) THEN 1
+				ELSE 2
+			END,
+			vt.type_id
+		LIMIT 1`, req.Category,
+	).Scan(&typeID)
+	if err == sql.ErrNoRows {
+		return 0, ErrUnknownVehicleCategory
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error querying vehicle type: %w", err)
+	}
+
+	manufacturerID := req.ManufacturerID
+	if manufacturerID == 0 {
+		err = tx.QueryRowContext(ctx, `
+			SELECT manufacturer_id FROM manufacturers
+			ORDER BY (manufacturer_name = 'Амкодор') DESC, manufacturer_id
+			LIMIT 1`,
+		).Scan(&manufacturerID)
+		if err != nil {
+			return 0, fmt.Errorf("error querying manufacturer: %w", err)
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO vehicle_models (model_name, type_id, manufacturer_id)
+		VALUES ($1, $2, $3)
+		RETURNING model_id`,
+		req.Name, typeID, manufacturerID,
+	).Scan(&modelID)
+	if err != nil {
+		return 0, fmt.Errorf("error creating vehicle model: %w", err)
+	}
+
+	return modelID, nil
+}
+
+// resolveWarehouse проверяет склад или выбирает основной активный склад
+func resolveWarehouse(ctx context.Context, tx *sql.Tx, warehouseID int) (int, error) {
+	query := `SELECT warehouse_id FROM warehouses WHERE is_active ORDER BY warehouse_id LIMIT 1`
+	args := []interface{}{}
+	if warehouseID > 0 {
+		query = `SELECT warehouse_id FROM warehouses WHERE warehouse_id = $1 AND is_active`
+		args = append(args, warehouseID)
+	}
+
+	var id int
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrWarehouseNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error querying warehouse: %w", err)
+	}
+
+	return id, nil
+}
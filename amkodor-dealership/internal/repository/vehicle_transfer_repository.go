@@ -0,0 +1,401 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"amkodor-dealership/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrVehicleTransferNotFound = errors.New("vehicle transfer not found")
+	// ErrVehicleTransferState - действие недопустимо в текущем статусе перемещения
+	ErrVehicleTransferState = errors.New("vehicle transfer status does not allow this action")
+	// ErrVehicleTransferVehicle - техника не на складе отправки, недоступна
+	// или уже включена в другое незавершённое перемещение
+	ErrVehicleTransferVehicle = errors.New("vehicle cannot be transferred")
+)
+
+const vehicleTransferSelect = `
+	SELECT t.transfer_id, t.transfer_number, t.from_warehouse_id, fw.warehouse_name,
+	       t.to_warehouse_id, tw.warehouse_name, t.status, t.planned_departure_date,
+	       t.planned_arrival_date, t.actual_departure_date, t.actual_arrival_date,
+	       COALESCE(t.driver_name, ''), t.transport_cost, COALESCE(t.notes, ''), t.created_by,
+	       COALESCE(e.last_name || ' ' || e.first_name, ''),
+	       (SELECT COUNT(*) FROM vehicle_transfer_items i WHERE i.transfer_id = t.transfer_id),
+	       t.created_at, t.updated_at
+	FROM vehicle_transfers t
+	INNER JOIN warehouses fw ON fw.warehouse_id = t.from_warehouse_id
+	INNER JOIN warehouses tw ON tw.warehouse_id = t.to_warehouse_id
+	LEFT JOIN employees e ON e.employee_id = t.created_by`
+
+type VehicleTransferRepository struct {
+	db *sql.DB
+}
+
+func NewVehicleTransferRepository(db *sql.DB) VehicleTransferRepository {
+	return VehicleTransferRepository{db: db}
+}
+
+// GetAll возвращает перемещения без техники. warehouseID отбирает перемещения
+// со склада и на склад, vehicleID - перемещения, в которые входит техника.
+func (r *VehicleTransferRepository) GetAll(ctx context.Context, status string, warehouseID, vehicleID *int) ([]models.VehicleTransfer, error) {
+	rows, err := r.db.QueryContext(ctx, vehicleTransferSelect+`
+		WHERE ($1 = '' OR t.status = $1)
+		  AND ($2::INTEGER IS NULL OR $2 IN (t.from_warehouse_id, t.to_warehouse_id))
+		  AND ($3::INTEGER IS NULL OR EXISTS (
+		      SELECT 1 FROM vehicle_transfer_items i
+		      WHERE i.transfer_id = t.transfer_id AND i.vehicle_id = $3))
+		ORDER BY t.created_at DESC, t.transfer_id DESC`, status, warehouseID, vehicleID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying vehicle transfers: %w", err)
+	}
+	defer rows.Close()
+
+	transfers := []models.VehicleTransfer{}
+	for rows.Next() {
+		t, err := scanVehicleTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning vehicle transfer: %w", err)
+		}
+		transfers = append(transfers, *t)
+	}
+
+	return transfers, rows.Err()
+}
+
+// GetByID возвращает перемещение с техникой
+func (r *VehicleTransferRepository) GetByID(ctx context.Context, id int) (*models.VehicleTransfer, error) {
+	t, err := scanVehicleTransfer(r.db.QueryRowContext(ctx, vehicleTransferSelect+` WHERE t.transfer_id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrVehicleTransferNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting vehicle transfer: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT v.vehicle_id, vm.model_name, COALESCE(v.vin, ''), v.serial_number, v.status,
+		       COALESCE(i.previous_status, '')
+		FROM vehicle_transfer_items i
+		INNER JOIN vehicles v ON v.vehicle_id = i.vehicle_id
+		INNER JOIN vehicle_models vm ON vm.model_id = v.model_id
+		WHERE i.transfer_id = $1
+		ORDER BY v.vehicle_id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("error querying vehicle transfer items: %w", err)
+	}
+	defer rows.Close()
+
+	t.Vehicles = []models.VehicleTransferItem{}
+	for rows.Next() {
+		var item models.VehicleTransferItem
+		if err := rows.Scan(&item.VehicleID, &item.ModelName, &item.VIN, &item.SerialNumber,
+			&item.Status, &item.PreviousStatus); err != nil {
+			return nil, fmt.Errorf("error scanning vehicle transfer item: %w", err)
+		}
+		t.Vehicles = append(t.Vehicles, item)
+	}
+
+	return t, rows.Err()
+}
+
+// Create оформляет перемещение со статусом 'Запланировано' и возвращает его ID.
+// Место на складе назначения резервируется сразу: техника перемещения
+// учитывается в его заполненности до приёма или отмены.
+func (r *VehicleTransferRepository) Create(ctx context.Context, req *models.VehicleTransferRequest, plannedDeparture, plannedArrival *time.Time, createdBy int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := resolveWarehouse(ctx, tx, req.FromWarehouseID); err != nil {
+		return 0, err
+	}
+	if err := reserveWarehouseSpace(ctx, tx, req.ToWarehouseID, len(req.VehicleIDs)); err != nil {
+		return 0, err
+	}
+	if err := lockTransferVehicles(ctx, tx, req.VehicleIDs, req.FromWarehouseID); err != nil {
+		return 0, err
+	}
+
+	var busy int
+	err = tx.QueryRowContext(ctx, `
+		SELECT i.vehicle_id
+		FROM vehicle_transfer_items i
+		INNER JOIN vehicle_transfers t ON t.transfer_id = i.transfer_id
+		WHERE i.vehicle_id = ANY($1) AND t.status IN ('Запланировано', 'В пути')
+		LIMIT 1`, pq.Array(req.VehicleIDs),
+	).Scan(&busy)
+	if err == nil {
+		return 0, fmt.Errorf("%w: vehicle %d is already in another transfer", ErrVehicleTransferVehicle, busy)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("error checking vehicle transfers: %w", err)
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO vehicle_transfers (from_warehouse_id, to_warehouse_id, planned_departure_date,
+		                               planned_arrival_date, driver_name, transport_cost, notes, created_by)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''), $8)
+		RETURNING transfer_id`,
+		req.FromWarehouseID, req.ToWarehouseID, plannedDeparture, plannedArrival,
+		req.DriverName, req.TransportCost, req.Notes, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error creating vehicle transfer: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO vehicle_transfer_items (transfer_id, vehicle_id)
+		SELECT $1, UNNEST($2::INTEGER[])`, id, pq.Array(req.VehicleIDs))
+	if err != nil {
+		return 0, fmt.Errorf("error creating vehicle transfer items: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return id, nil
+}
+
+// Dispatch отправляет технику: запоминает её статус и переводит в 'В пути'.
+// Техника должна оставаться на складе отправки и быть доступной.
+func (r *VehicleTransferRepository) Dispatch(ctx context.Context, id, employeeID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	fromWarehouseID, err := lockVehicleTransfer(ctx, tx, id, models.TransferPlanned)
+	if err != nil {
+		return err
+	}
+
+	var vehicleIDs pq.Int64Array
+	if err := tx.QueryRowContext(ctx,
+		`SELECT ARRAY_AGG(vehicle_id) FROM vehicle_transfer_items WHERE transfer_id = $1`, id,
+	).Scan(&vehicleIDs); err != nil {
+		return fmt.Errorf("error getting vehicle transfer items: %w", err)
+	}
+	ids := make([]int, len(vehicleIDs))
+	for i, v := range vehicleIDs {
+		ids[i] = int(v)
+	}
+	if err := lockTransferVehicles(ctx, tx, ids, fromWarehouseID); err != nil {
+		return err
+	}
+
+	if err := bindVehicleTransfer(ctx, tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicle_transfer_items i
+		SET previous_status = v.status
+		FROM vehicles v
+		WHERE v.vehicle_id = i.vehicle_id AND i.transfer_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error saving vehicle statuses: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicles
+		SET status = 'В пути', updated_at = CURRENT_TIMESTAMP
+		WHERE vehicle_id IN (SELECT vehicle_id FROM vehicle_transfer_items WHERE transfer_id = $1)`, id)
+	if err != nil {
+		return fmt.Errorf("error updating transfer vehicles: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicle_transfers
+		SET status = 'В пути', actual_departure_date = CURRENT_DATE, dispatched_by = $2,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE transfer_id = $1`, id, employeeID)
+	if err != nil {
+		return fmt.Errorf("error dispatching vehicle transfer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Receive принимает технику на складе назначения и возвращает ей статус до отправки
+func (r *VehicleTransferRepository) Receive(ctx context.Context, id, employeeID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockVehicleTransfer(ctx, tx, id, models.TransferInTransit); err != nil {
+		return err
+	}
+	if err := bindVehicleTransfer(ctx, tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicles v
+		SET warehouse_id = t.to_warehouse_id, status = i.previous_status,
+		    arrival_date = CURRENT_DATE, updated_at = CURRENT_TIMESTAMP
+		FROM vehicle_transfer_items i
+		INNER JOIN vehicle_transfers t ON t.transfer_id = i.transfer_id
+		WHERE i.transfer_id = $1 AND v.vehicle_id = i.vehicle_id`, id)
+	if err != nil {
+		return fmt.Errorf("error moving transfer vehicles: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicle_transfers
+		SET status = 'Получено', actual_arrival_date = CURRENT_DATE, received_by = $2,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE transfer_id = $1`, id, employeeID)
+	if err != nil {
+		return fmt.Errorf("error receiving vehicle transfer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// Cancel отменяет перемещение. Техника в пути остаётся на складе отправки
+// и получает статус до отправки.
+func (r *VehicleTransferRepository) Cancel(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockVehicleTransfer(ctx, tx, id, models.TransferPlanned, models.TransferInTransit); err != nil {
+		return err
+	}
+	if err := bindVehicleTransfer(ctx, tx, id); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicles v
+		SET status = i.previous_status, updated_at = CURRENT_TIMESTAMP
+		FROM vehicle_transfer_items i
+		WHERE i.transfer_id = $1 AND v.vehicle_id = i.vehicle_id
+		  AND i.previous_status IS NOT NULL AND v.status = 'В пути'`, id)
+	if err != nil {
+		return fmt.Errorf("error restoring transfer vehicles: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE vehicle_transfers SET status = 'Отменено', updated_at = CURRENT_TIMESTAMP
+		WHERE transfer_id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error cancelling vehicle transfer: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// lockVehicleTransfer блокирует перемещение в одном из статусов statuses и
+// возвращает склад отправки
+func lockVehicleTransfer(ctx context.Context, tx *sql.Tx, id int, statuses ...string) (int, error) {
+	var status string
+	var fromWarehouseID int
+	err := tx.QueryRowContext(ctx, `
+		SELECT status, from_warehouse_id FROM vehicle_transfers
+		WHERE transfer_id = $1
+		FOR UPDATE`, id,
+	).Scan(&status, &fromWarehouseID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrVehicleTransferNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error locking vehicle transfer: %w", err)
+	}
+
+	for _, s := range statuses {
+		if status == s {
+			return fromWarehouseID, nil
+		}
+	}
+	return 0, ErrVehicleTransferState
+}
+
+// lockTransferVehicles блокирует технику и проверяет, что вся она на складе
+// отправки и доступна: 'В наличии' или 'Зарезервировано'
+func lockTransferVehicles(ctx context.Context, tx *sql.Tx, vehicleIDs []int, fromWarehouseID int) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT vehicle_id, warehouse_id, status FROM vehicles
+		WHERE vehicle_id = ANY($1)
+		ORDER BY vehicle_id
+		FOR UPDATE`, pq.Array(vehicleIDs))
+	if err != nil {
+		return fmt.Errorf("error locking vehicles: %w", err)
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() {
+		var vehicleID, warehouseID int
+		var status string
+		if err := rows.Scan(&vehicleID, &warehouseID, &status); err != nil {
+			return fmt.Errorf("error scanning vehicle: %w", err)
+		}
+		if warehouseID != fromWarehouseID {
+			return fmt.Errorf("%w: vehicle %d is not on the source warehouse", ErrVehicleTransferVehicle, vehicleID)
+		}
+		if status != "В наличии" && status != "Зарезервировано" {
+			return fmt.Errorf("%w: vehicle %d has status %s", ErrVehicleTransferVehicle, vehicleID, status)
+		}
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error locking vehicles: %w", err)
+	}
+	if found != len(vehicleIDs) {
+		return ErrVehicleNotFound
+	}
+	return nil
+}
+
+// bindVehicleTransfer помечает транзакцию документом перемещения: триггеры
+// пропускают смену склада и статуса техники в пути и пишут документ в vehicles_history
+func bindVehicleTransfer(ctx context.Context, tx *sql.Tx, id int) error {
+	if _, err := tx.ExecContext(ctx,
+		`SELECT set_config('amkodor.vehicle_transfer_id', $1, TRUE)`, strconv.Itoa(id),
+	); err != nil {
+		return fmt.Errorf("error binding vehicle transfer: %w", err)
+	}
+	return nil
+}
+
+func scanVehicleTransfer(row rowScanner) (*models.VehicleTransfer, error) {
+	var t models.VehicleTransfer
+	err := row.Scan(
+		&t.ID, &t.TransferNumber, &t.FromWarehouseID, &t.FromWarehouseName,
+		&t.ToWarehouseID, &t.ToWarehouseName, &t.Status, &t.PlannedDepartureDate,
+		&t.PlannedArrivalDate, &t.ActualDepartureDate, &t.ActualArrivalDate,
+		&t.DriverName, &t.TransportCost, &t.Notes, &t.CreatedBy,
+		&t.CreatedByName, &t.VehiclesCount, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"amkodor-dealership/internal/models"
@@ -163,3 +165,44 @@ func (r *WarehouseRepository) GetStatistics(id int) (*models.Warehouse, error) {
 
 	return &w, nil
 }
+
+// ErrWarehouseCapacity - на складе не хватает места для техники
+var ErrWarehouseCapacity = errors.New("warehouse capacity exceeded")
+
+// reserveWarehouseSpace блокирует склад до конца транзакции и проверяет, что
+// к технике на нём поместятся ещё incoming единиц. Место занимает вся
+// непроданная техника склада, включая ещё не прибывшую, и техника
+// незавершённых перемещений на этот склад; capacity <= 0 - вместимость
+// не задана. Неактивный склад считается не найденным.
+func reserveWarehouseSpace(ctx context.Context, tx *sql.Tx, warehouseID, incoming int) error {
+	var capacity int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(capacity, 0) FROM warehouses
+		WHERE warehouse_id = $1 AND is_active
+		FOR UPDATE`, warehouseID,
+	).Scan(&capacity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWarehouseNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error locking warehouse: %w", err)
+	}
+	if capacity <= 0 {
+		return nil
+	}
+
+	var occupied int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM vehicles
+		        WHERE warehouse_id = $1 AND status <> 'Продано')
+		     + (SELECT COUNT(*) FROM vehicle_transfer_items i
+		        INNER JOIN vehicle_transfers t ON t.transfer_id = i.transfer_id
+		        WHERE t.to_warehouse_id = $1 AND t.status IN ('Запланировано', 'В пути'))`, warehouseID,
+	).Scan(&occupied); err != nil {
+		return fmt.Errorf("error counting warehouse vehicles: %w", err)
+	}
+	if occupied+incoming > capacity {
+		return fmt.Errorf("%w: %d of %d places taken, %d more requested", ErrWarehouseCapacity, occupied, capacity, incoming)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/utils"
+)
+
+const (
+	clientClaimTTL         = 15 * time.Minute
+	clientClaimMaxAttempts = 5
+)
+
+var (
+	// ErrInvalidClientLink - ошибка валидации запроса на привязку
+	ErrInvalidClientLink = errors.New("invalid client link request")
+	// ErrClientNotMatched - по указанному контакту клиент не найден
+	ErrClientNotMatched = errors.New("no client matches the contact")
+	// ErrClientMatchAmbiguous - контакт есть у нескольких клиентов, нужна помощь сотрудника
+	ErrClientMatchAmbiguous  = errors.New("contact matches several clients")
+	ErrUserAlreadyLinked     = errors.New("user already linked to a client")
+	ErrClaimExpired          = errors.New("client link claim expired")
+	ErrClaimCodeMismatch     = errors.New("invalid confirmation code")
+	ErrClaimAttemptsExceeded = errors.New("too many confirmation attempts")
+)
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// VerificationSender доставляет код подтверждения на телефон или email клиента
+type VerificationSender interface {
+	SendVerificationCode(ctx context.Context, channel, destination, code string) error
+}
+
+// LogVerificationSender пишет код в лог сервера. Используется, пока не
+// подключён SMS/email-шлюз.
+type LogVerificationSender struct{}
+
+func (LogVerificationSender) SendVerificationCode(ctx context.Context, channel, destination, code string) error {
+	log.Printf("Код привязки клиента для %s (%s): %s", destination, channel, code)
+	return nil
+}
+
+// AccountService - привязка пользователей к клиентам CRM и данные личного кабинета
+type AccountService struct {
+	links     *repository.ClientLinkRepository
+	account   *repository.AccountRepository
+	favorites *repository.FavoriteRepository
+	sender    VerificationSender
+}
+
+func NewAccountService(links *repository.ClientLinkRepository, account *repository.AccountRepository,
+	favorites *repository.FavoriteRepository, sender VerificationSender) *AccountService {
+	return &AccountService{links: links, account: account, favorites: favorites, sender: sender}
+}
+
+// GetLink возвращает привязку пользователя или nil, если её нет
+func (s *AccountService) GetLink(ctx context.Context, userID int) (*models.ClientLink, error) {
+	link, err := s.links.GetByUser(ctx, userID)
+	if errors.Is(err, repository.ErrClientLinkNotFound) {
+		return nil, nil
+	}
+	return link, err
+}
+
+// StartClaim находит клиента по контакту и отправляет на этот контакт код подтверждения
+func (s *AccountService) StartClaim(ctx context.Context, userID int, req *models.ClientLinkClaimRequest) (*models.ClientLinkClaim, error) {
+	value := strings.TrimSpace(req.Value)
+	switch req.Channel {
+	case "phone":
+		value = nonDigits.ReplaceAllString(value, "")
+		if len(value) < 7 {
+			return nil, fmt.Errorf("%w: invalid phone", ErrInvalidClientLink)
+		}
+	case "email":
+		if !strings.Contains(value, "@") {
+			return nil, fmt.Errorf("%w: invalid email", ErrInvalidClientLink)
+		}
+	default:
+		return nil, fmt.Errorf("%w: channel must be phone or email", ErrInvalidClientLink)
+	}
+
+	link, err := s.GetLink(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if link != nil {
+		return nil, ErrUserAlreadyLinked
+	}
+
+	matches, err := s.links.FindByContact(ctx, req.Channel, value, strings.TrimSpace(req.TaxID))
+	if err != nil {
+		return nil, err
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrClientNotMatched
+	case 1:
+	default:
+		return nil, ErrClientMatchAmbiguous
+	}
+
+	code, err := verificationCode()
+	if err != nil {
+		return nil, err
+	}
+	codeHash, err := utils.HashPassword(code)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing confirmation code: %w", err)
+	}
+
+	claim := &models.ClientLinkClaim{
+		UserID:            userID,
+		CustomerID:        matches[0].CustomerID,
+		CorporateClientID: matches[0].CorporateClientID,
+		Channel:           req.Channel,
+		Destination:       value,
+		CodeHash:          codeHash,
+		ExpiresAt:         time.Now().Add(clientClaimTTL),
+	}
+	if err := s.links.CreateClaim(ctx, claim); err != nil {
+		return nil, err
+	}
+	if err := s.sender.SendVerificationCode(ctx, claim.Channel, claim.Destination, code); err != nil {
+		return nil, fmt.Errorf("error sending confirmation code: %w", err)
+	}
+
+	claim.Destination = maskContact(claim.Channel, claim.Destination)
+	return claim, nil
+}
+
+// ConfirmClaim проверяет код и привязывает пользователя к клиенту
+func (s *AccountService) ConfirmClaim(ctx context.Context, userID int, req *models.ClientLinkConfirmRequest) (*models.ClientLink, error) {
+	if req.ClaimID <= 0 || strings.TrimSpace(req.Code) == "" {
+		return nil, fmt.Errorf("%w: claim and code are required", ErrInvalidClientLink)
+	}
+
+	claim, err := s.links.GetClaim(ctx, req.ClaimID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if claim.ConfirmedAt != nil {
+		return nil, repository.ErrClientClaimNotFound
+	}
+	if time.Now().After(claim.ExpiresAt) {
+		return nil, ErrClaimExpired
+	}
+	if claim.Attempts >= clientClaimMaxAttempts {
+		return nil, ErrClaimAttemptsExceeded
+	}
+	if !utils.CheckPassword(claim.CodeHash, strings.TrimSpace(req.Code)) {
+		if err := s.links.RegisterFailedAttempt(ctx, claim.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrClaimCodeMismatch
+	}
+
+	if err := s.links.ConfirmClaim(ctx, claim); err != nil {
+		return nil, err
+	}
+	return s.links.GetByUser(ctx, userID)
+}
+
+// LinkByStaff привязывает пользователя к клиенту по решению сотрудника employeeID
+func (s *AccountService) LinkByStaff(ctx context.Context, userID, employeeID int, req *models.LinkClientRequest) (*models.ClientLink, error) {
+	if (req.CustomerID == nil) == (req.CorporateClientID == nil) {
+		return nil, fmt.Errorf("%w: exactly one of customer and corporate client is required", ErrInvalidClientLink)
+	}
+
+	err := s.links.Link(ctx, userID, req.CustomerID, req.CorporateClientID, models.ClientLinkStaff, &employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.links.GetByUser(ctx, userID)
+}
+
+// Unlink снимает привязку пользователя
+func (s *AccountService) Unlink(ctx context.Context, userID int) error {
+	return s.links.Unlink(ctx, userID)
+}
+
+// GetOrders возвращает покупки клиента в формате страницы заказов
+func (s *AccountService) GetOrders(ctx context.Context, userID int) ([]models.AccountOrder, error) {
+	orders := []models.AccountOrder{}
+	link, err := s.GetLink(ctx, userID)
+	if err != nil || link == nil {
+		return orders, err
+	}
+
+	sales, err := s.account.GetSales(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sale := range sales {
+		number := sale.ContractNumber.String
+		if number == "" {
+			number = fmt.Sprintf("SALE-%d", sale.SaleID)
+		}
+		specs := sale.TypeName
+		if sale.VIN.String != "" {
+			specs += ", VIN " + sale.VIN.String
+		}
+		orders = append(orders, models.AccountOrder{
+			ID:          sale.SaleID,
+			Number:      number,
+			Date:        sale.SaleDate,
+			Status:      accountOrderStatus(sale.Status),
+			PaymentType: sale.PaymentType,
+			Items: []models.AccountOrderItem{{
+				Name:  sale.ModelName,
+				Specs: specs,
+				Price: sale.FinalPrice,
+				Image: "🚜",
+			}},
+			Total: sale.FinalPrice,
+		})
+	}
+
+	return orders, nil
+}
+
+// GetTestDrives возвращает тест-драйвы клиента
+func (s *AccountService) GetTestDrives(ctx context.Context, userID int) ([]models.AccountTestDrive, error) {
+	link, err := s.GetLink(ctx, userID)
+	if err != nil || link == nil {
+		return []models.AccountTestDrive{}, err
+	}
+	return s.account.GetTestDrives(ctx, link)
+}
+
+// GetServiceOrders возвращает сервисные заказы клиента
+func (s *AccountService) GetServiceOrders(ctx context.Context, userID int) ([]models.AccountServiceOrder, error) {
+	link, err := s.GetLink(ctx, userID)
+	if err != nil || link == nil {
+		return []models.AccountServiceOrder{}, err
+	}
+	return s.account.GetServiceOrders(ctx, link)
+}
+
+// GetFleet возвращает технику клиента. Для юр. лица это парк всей компании.
+func (s *AccountService) GetFleet(ctx context.Context, userID int) ([]models.FleetVehicle, error) {
+	link, err := s.GetLink(ctx, userID)
+	if err != nil || link == nil {
+		return []models.FleetVehicle{}, err
+	}
+	return s.account.GetFleet(ctx, link)
+}
+
+// GetStats возвращает сводку личного кабинета. Без привязки считается только избранное.
+func (s *AccountService) GetStats(ctx context.Context, userID int) (*models.AccountStats, error) {
+	stats := &models.AccountStats{}
+
+	link, err := s.GetLink(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if link != nil {
+		if stats, err = s.account.GetStats(ctx, link); err != nil {
+			return nil, err
+		}
+	}
+
+	if stats.FavoriteItems, err = s.favorites.GetFavoriteCount(ctx, userID); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// accountOrderStatus переводит статус продажи в статус страницы заказов
+func accountOrderStatus(status string) string {
+	switch status {
+	case "Завершена":
+		return "delivered"
+	case "Отменена":
+		return "cancelled"
+	}
+	return "processing"
+}
+
+func verificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("error generating confirmation code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// maskContact скрывает контакт в ответе: +*******67, i***@example.com
+func maskContact(channel, value string) string {
+	if channel == "email" {
+		at := strings.Index(value, "@")
+		if at <= 1 {
+			return "***" + value[at:]
+		}
+		return value[:1] + "***" + value[at:]
+	}
+	if len(value) <= 2 {
+		return value
+	}
+	return "+" + strings.Repeat("*", len(value)-2) + value[len(value)-2:]
+}
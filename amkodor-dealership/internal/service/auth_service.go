@@ -2,41 +2,219 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
 	"amkodor-dealership/internal/utils"
-	"fmt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrNoStaffAccess - должность сотрудника не даёт доступа к системе
+	ErrNoStaffAccess = errors.New("position has no access role")
 )
 
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret string
+	userRepo     *repository.UserRepository
+	employeeRepo *repository.EmployeeRepository
+	refreshRepo  *repository.RefreshTokenRepository
+	jwtSecret    string
+	accessTTL    time.Duration
+	refreshTTL   time.Duration
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string) *AuthService {
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	employeeRepo *repository.EmployeeRepository,
+	refreshRepo *repository.RefreshTokenRepository,
+	jwtSecret string,
+	accessTTL, refreshTTL time.Duration,
+) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		userRepo:     userRepo,
+		employeeRepo: employeeRepo,
+		refreshRepo:  refreshRepo,
+		jwtSecret:    jwtSecret,
+		accessTTL:    accessTTL,
+		refreshTTL:   refreshTTL,
 	}
 }
 
-func (s *AuthService) Login(email, password string) (*models.User, string, error) {
+func (s *AuthService) Login(ctx context.Context, email, password string) (*models.Session, error) {
 	// Получение пользователя по email
-	user, err := s.userRepo.GetByEmail(context.Background(), email)
+	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Проверка пароля
 	if !utils.CheckPasswordHash(password, user.PasswordHash) {
-		return nil, "", fmt.Errorf("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	refreshToken, err := s.startSession(ctx, &models.RefreshToken{UserID: &user.UserID})
+	if err != nil {
+		return nil, err
 	}
 
-	// Генерация JWT токена
-	token, err := utils.GenerateJWT(user.UserID, email, s.jwtSecret, 24)
+	return s.userSession(user, refreshToken)
+}
+
+// StaffLogin выполняет вход сотрудника по email и паролю из employees.
+// Уволенные сотрудники и сотрудники на должностях без роли доступа не входят.
+func (s *AuthService) StaffLogin(ctx context.Context, email, password string) (*models.Session, error) {
+	account, err := s.employeeRepo.GetStaffByEmail(email)
+	if errors.Is(err, repository.ErrEmployeeNotFound) {
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		return nil, "", fmt.Errorf("error generating token: %w", err)
+		return nil, err
+	}
+
+	if !account.IsActive || !utils.CheckPasswordHash(password, account.PasswordHash) {
+		return nil, ErrInvalidCredentials
+	}
+	if account.Identity.Role == "" {
+		return nil, ErrNoStaffAccess
 	}
 
-	return user, token, nil
+	refreshToken, err := s.startSession(ctx, &models.RefreshToken{EmployeeID: &account.Identity.EmployeeID})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.staffSession(account, refreshToken)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов. Старый токен
+// становится недействительным; его повторное предъявление отзывает все
+// токены семейства.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.Session, error) {
+	nextToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	next := &models.RefreshToken{
+		TokenHash: utils.HashRefreshToken(nextToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}
+	if err := s.refreshRepo.Rotate(ctx, utils.HashRefreshToken(refreshToken), next); err != nil {
+		return nil, err
+	}
+
+	// Роль, должность и склад перечитываются из БД, чтобы их изменение
+	// вступало в силу при обновлении
+	if next.EmployeeID != nil {
+		account, err := s.employeeRepo.GetStaffByID(*next.EmployeeID)
+		if err != nil {
+			return nil, err
+		}
+		if !account.IsActive || account.Identity.Role == "" {
+			return nil, repository.ErrRefreshTokenInvalid
+		}
+		return s.staffSession(account, nextToken)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, *next.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userSession(user, nextToken)
+}
+
+// Logout отзывает семейство, к которому относится refresh-токен
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	return s.refreshRepo.RevokeFamily(ctx, utils.HashRefreshToken(refreshToken))
+}
+
+// RevokeAllSessions отзывает все refresh-токены пользователя, включая сессии
+// связанного с ним сотрудника.
+// Уже выданные access-токены доживают свой короткий срок.
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID int) (int64, error) {
+	return s.refreshRepo.RevokeAllForUser(ctx, userID)
+}
+
+// startSession открывает новое семейство refresh-токенов для владельца из token
+// и возвращает сам токен
+func (s *AuthService) startSession(ctx context.Context, token *models.RefreshToken) (string, error) {
+	familyID, err := utils.GenerateTokenFamilyID()
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	token.FamilyID = familyID
+	token.TokenHash = utils.HashRefreshToken(refreshToken)
+	token.ExpiresAt = time.Now().Add(s.refreshTTL)
+	if err := s.refreshRepo.Create(ctx, token); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// userSession выдаёт access-токен пользователю. Если пользователь связан с
+// работающим сотрудником, токен дополнительно несёт сотрудника; роль при
+// этом остаётся ролью пользователя.
+func (s *AuthService) userSession(user *models.User, refreshToken string) (*models.Session, error) {
+	session := &models.Session{Name: user.Name, Role: user.Role}
+
+	if user.EmployeeID != nil {
+		account, err := s.employeeRepo.GetStaffByID(*user.EmployeeID)
+		if err != nil && !errors.Is(err, repository.ErrEmployeeNotFound) {
+			return nil, err
+		}
+		if account != nil && account.IsActive {
+			staff := account.Identity
+			staff.Role = user.Role
+			session.Staff = &staff
+		}
+	}
+
+	var accessToken string
+	var err error
+	if session.Staff != nil {
+		accessToken, err = utils.GenerateStaffJWT(&user.UserID, user.Email, session.Staff, s.jwtSecret, s.accessTTL)
+	} else {
+		accessToken, err = utils.GenerateJWT(user.UserID, user.Email, user.Role, s.jwtSecret, s.accessTTL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	session.Tokens = s.tokenPair(accessToken, refreshToken)
+	return session, nil
+}
+
+// staffSession выдаёт access-токен сотруднику с ролью его должности
+func (s *AuthService) staffSession(account *models.StaffAccount, refreshToken string) (*models.Session, error) {
+	staff := account.Identity
+	accessToken, err := utils.GenerateStaffJWT(account.UserID, account.Email, &staff, s.jwtSecret, s.accessTTL)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	return &models.Session{
+		Name:   staff.FullName,
+		Role:   staff.Role,
+		Staff:  &staff,
+		Tokens: s.tokenPair(accessToken, refreshToken),
+	}, nil
+}
+
+func (s *AuthService) tokenPair(accessToken, refreshToken string) *models.TokenPair {
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+	}
 }
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/pkg/ical"
+)
+
+var (
+	// ErrInvalidCalendarFeed - ошибка в параметрах подписки
+	ErrInvalidCalendarFeed = errors.New("invalid calendar feed")
+	// ErrCalendarFeedForbidden - подписка на чужой календарь без права управления сотрудниками
+	ErrCalendarFeedForbidden = errors.New("calendar feed of another employee or warehouse")
+)
+
+const (
+	// calendarFeedPastDays - за сколько дней назад в ленту попадают прошедшие события
+	calendarFeedPastDays = 30
+	calendarProdID       = "-//Amkodor//Dealership//RU"
+)
+
+// CalendarService - календарные ленты (iCalendar) тест-драйвов и сервисных
+// заказов. Событие сохраняет UID при изменениях, а SEQUENCE растёт с каждым
+// переносом или сменой статуса, поэтому подписанные календари обновляют и
+// отменяют события, а не дублируют их.
+type CalendarService struct {
+	repo       *repository.CalendarFeedRepository
+	testDrives *TestDriveService
+	// publicURL - адрес сайта для ссылок на подписку и домена UID событий
+	publicURL string
+	uidDomain string
+}
+
+func NewCalendarService(repo *repository.CalendarFeedRepository, testDrives *TestDriveService, publicURL string) *CalendarService {
+	publicURL = strings.TrimRight(publicURL, "/")
+	uidDomain := "amkodor-dealership"
+	if u, err := url.Parse(publicURL); err == nil && u.Hostname() != "" {
+		uidDomain = u.Hostname()
+	}
+	return &CalendarService{repo: repo, testDrives: testDrives, publicURL: publicURL, uidDomain: uidDomain}
+}
+
+// CreateFeed создаёт подписку на календарь сотрудника или склада. Без
+// manageAll сотрудник подписывается только на свой календарь и свой склад.
+// Ссылка с токеном возвращается в url один раз.
+func (s *CalendarService) CreateFeed(ctx context.Context, staff *models.StaffIdentity, manageAll bool, req *models.CreateCalendarFeedRequest) (*models.CalendarFeed, error) {
+	var employeeID, warehouseID *int
+	switch req.Scope {
+	case "", models.CalendarFeedEmployee:
+		if req.WarehouseID != nil {
+			return nil, fmt.Errorf("%w: для склада укажите scope warehouse", ErrInvalidCalendarFeed)
+		}
+		employeeID = &staff.EmployeeID
+		if req.EmployeeID != nil {
+			employeeID = req.EmployeeID
+		}
+		if *employeeID != staff.EmployeeID && !manageAll {
+			return nil, ErrCalendarFeedForbidden
+		}
+	case models.CalendarFeedWarehouse:
+		if req.EmployeeID != nil {
+			return nil, fmt.Errorf("%w: для сотрудника укажите scope employee", ErrInvalidCalendarFeed)
+		}
+		warehouseID = staff.WarehouseID
+		if req.WarehouseID != nil {
+			warehouseID = req.WarehouseID
+		}
+		if warehouseID == nil {
+			return nil, fmt.Errorf("%w: склад не указан", ErrInvalidCalendarFeed)
+		}
+		if !manageAll && (staff.WarehouseID == nil || *staff.WarehouseID != *warehouseID) {
+			return nil, ErrCalendarFeedForbidden
+		}
+	default:
+		return nil, fmt.Errorf("%w: scope должен быть employee или warehouse", ErrInvalidCalendarFeed)
+	}
+
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.repo.Create(ctx, employeeID, warehouseID, hashLinkToken(token), staff.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	feed.URL = s.publicURL + "/api/calendar/" + token + ".ics"
+	return feed, nil
+}
+
+// GetFeeds возвращает действующие подписки: все при manageAll, иначе
+// созданные сотрудником
+func (s *CalendarService) GetFeeds(ctx context.Context, staff *models.StaffIdentity, manageAll bool) ([]models.CalendarFeed, error) {
+	if manageAll {
+		return s.repo.GetAll(ctx, nil)
+	}
+	return s.repo.GetAll(ctx, &staff.EmployeeID)
+}
+
+// RevokeFeed отзывает подписку. Чужая подписка без manageAll считается не найденной.
+func (s *CalendarService) RevokeFeed(ctx context.Context, staff *models.StaffIdentity, manageAll bool, id int) error {
+	feed, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !manageAll && (feed.CreatedBy == nil || *feed.CreatedBy != staff.EmployeeID) {
+		return repository.ErrCalendarFeedNotFound
+	}
+	return s.repo.Revoke(ctx, id)
+}
+
+// Feed строит календарь по токену подписки: тест-драйвы и сервисные заказы
+// за последние calendarFeedPastDays дней и все будущие
+func (s *CalendarService) Feed(ctx context.Context, token string) (*ical.Calendar, error) {
+	feed, err := s.repo.Touch(ctx, hashLinkToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	from := time.Now().AddDate(0, 0, -calendarFeedPastDays)
+	drives, err := s.repo.GetTestDrives(ctx, feed.EmployeeID, feed.WarehouseID, from)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.repo.GetServiceOrders(ctx, feed.EmployeeID, feed.WarehouseID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	stamp := time.Now()
+	cal := &ical.Calendar{ProdID: calendarProdID, Name: "Амкодор: " + feed.Name}
+	for _, td := range drives {
+		cal.Events = append(cal.Events, s.testDriveEvent(td, stamp))
+	}
+	for _, so := range orders {
+		cal.Events = append(cal.Events, s.serviceOrderEvent(so, stamp))
+	}
+	return cal, nil
+}
+
+// TestDriveEvent возвращает календарь с одним тест-драйвом для подтверждения записи
+func (s *CalendarService) TestDriveEvent(ctx context.Context, id int) (*ical.Calendar, error) {
+	td, err := s.repo.GetTestDrive(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &ical.Calendar{
+		ProdID: calendarProdID,
+		Events: []ical.Event{s.testDriveEvent(*td, time.Now())},
+	}, nil
+}
+
+// TestDriveEventMine возвращает событие тест-драйва клиента, к которому
+// привязан пользователь
+func (s *CalendarService) TestDriveEventMine(ctx context.Context, userID, id int) (*ical.Calendar, error) {
+	if _, err := s.testDrives.GetMine(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.TestDriveEvent(ctx, id)
+}
+
+func (s *CalendarService) testDriveEvent(td repository.CalendarTestDrive, stamp time.Time) ical.Event {
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "Техника: %s (%s)", td.ModelName, td.TypeName)
+	if td.VIN != "" {
+		fmt.Fprintf(&desc, ", VIN %s", td.VIN)
+	}
+	fmt.Fprintf(&desc, "\nКлиент: %s", td.ClientName)
+	if td.ClientPhone != "" {
+		fmt.Fprintf(&desc, ", %s", td.ClientPhone)
+	}
+	fmt.Fprintf(&desc, "\nМенеджер: %s", td.ManagerName)
+	if td.ManagerPhone != "" {
+		fmt.Fprintf(&desc, ", %s", td.ManagerPhone)
+	}
+	fmt.Fprintf(&desc, "\nСтатус: %s", td.Status)
+	if td.CancelReason != "" {
+		fmt.Fprintf(&desc, "\nПричина отмены: %s", td.CancelReason)
+	}
+
+	status := ical.StatusConfirmed
+	if td.Status == models.TestDriveCancelled {
+		status = ical.StatusCancelled
+	}
+
+	return ical.Event{
+		UID:         fmt.Sprintf("test-drive-%d@%s", td.ID, s.uidDomain),
+		Sequence:    td.Sequence,
+		Stamp:       stamp,
+		Start:       td.Start,
+		End:         td.Start.Add(time.Duration(td.Duration) * time.Minute),
+		Summary:     fmt.Sprintf("Тест-драйв: %s - %s", td.ModelName, td.ClientName),
+		Description: desc.String(),
+		Location:    joinNonEmpty(td.WarehouseName, td.WarehouseCity, td.WarehouseAddress),
+		Status:      status,
+	}
+}
+
+// serviceOrderEvent - заказ на целые дни от приёма до завершения; у
+// незавершённого заказа - только день приёма
+func (s *CalendarService) serviceOrderEvent(so repository.CalendarServiceOrder, stamp time.Time) ical.Event {
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "Техника: %s", so.ModelName)
+	if so.VIN != "" {
+		fmt.Fprintf(&desc, ", VIN %s", so.VIN)
+	}
+	fmt.Fprintf(&desc, "\nКлиент: %s", so.ClientName)
+	if so.ClientPhone != "" {
+		fmt.Fprintf(&desc, ", %s", so.ClientPhone)
+	}
+	fmt.Fprintf(&desc, "\nМастер: %s\nСтатус: %s", so.MasterName, so.Status)
+	if so.Description != "" {
+		fmt.Fprintf(&desc, "\n\n%s", so.Description)
+	}
+
+	last := so.OrderDate
+	if so.CompletionDate != nil {
+		last = *so.CompletionDate
+	}
+
+	status := ical.StatusConfirmed
+	switch so.Status {
+	case "Отменен":
+		status = ical.StatusCancelled
+	case "Приостановлен":
+		status = ical.StatusTentative
+	}
+
+	return ical.Event{
+		UID:         fmt.Sprintf("service-order-%d@%s", so.ID, s.uidDomain),
+		Sequence:    so.Sequence,
+		Stamp:       stamp,
+		Start:       so.OrderDate,
+		End:         last.AddDate(0, 0, 1),
+		AllDay:      true,
+		Summary:     fmt.Sprintf("Сервис: %s - %s", so.ServiceType, so.ModelName),
+		Description: desc.String(),
+		Location:    joinNonEmpty(so.WarehouseName, so.WarehouseAddress),
+		Status:      status,
+	}
+}
+
+func joinNonEmpty(parts ...string) string {
+	nonEmpty := parts[:0]
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
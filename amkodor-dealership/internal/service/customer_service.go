@@ -1,8 +1,35 @@
 package service
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/utils"
+)
+
+var (
+	// ErrInvalidCustomer - ошибка валидации данных клиента
+	ErrInvalidCustomer = errors.New("invalid customer")
+	// ErrPossibleDuplicate - найдены похожие карточки клиентов
+	ErrPossibleDuplicate = errors.New("possible duplicate customer")
+	// ErrInvalidCorporateClient - ошибка валидации данных корпоративного клиента
+	ErrInvalidCorporateClient = errors.New("invalid corporate client")
+)
+
+var (
+	phoneNonDigits     = regexp.MustCompile(`\D`)
+	passportSeparators = regexp.MustCompile(`[\s-]`)
+	// УНП Республики Беларусь - 9 цифр
+	taxIDPattern = regexp.MustCompile(`^\d{9}$`)
+	// Расчётный счёт в формате IBAN: BY, 2 контрольные цифры, 4 символа БИК банка и 20 символов счёта
+	bankAccountPattern = regexp.MustCompile(`^BY\d{2}[A-Z0-9]{24}$`)
+	bankBICPattern     = regexp.MustCompile(`^[A-Z0-9]{8}([A-Z0-9]{3})?$`)
 )
 
 type CustomerService struct {
@@ -13,22 +40,396 @@ func NewCustomerService(repo repository.CustomerRepository) *CustomerService {
 	return &CustomerService{repo: repo}
 }
 
-func (s *CustomerService) GetAll(limit, offset int) ([]models.Customer, error) {
-	return s.repo.GetAll(limit, offset)
+// GetPage возвращает страницу клиентов. search ищет по ФИО, email и
+// паспорту, а если в запросе есть хотя бы 3 цифры - ещё и по телефону.
+func (s *CustomerService) GetPage(search string, page, perPage int) ([]models.CustomerCard, models.Pagination, error) {
+	search = strings.TrimSpace(search)
+	phoneDigits := phoneNonDigits.ReplaceAllString(search, "")
+	if len(phoneDigits) < 3 {
+		phoneDigits = ""
+	}
+
+	customers, total, err := s.repo.GetPage(search, phoneDigits, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	cards := make([]models.CustomerCard, 0, len(customers))
+	for _, c := range customers {
+		cards = append(cards, toCustomerCard(&c))
+	}
+
+	pagination := models.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		TotalItems: total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+
+	return cards, pagination, nil
 }
 
-func (s *CustomerService) GetByID(id int) (*models.Customer, error) {
-	return s.repo.GetByID(id)
+// GetByID возвращает карточку клиента
+func (s *CustomerService) GetByID(id int) (*models.CustomerCard, error) {
+	c, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	card := toCustomerCard(c)
+	return &card, nil
 }
 
-func (s *CustomerService) Create(customer *models.Customer) (int, error) {
-	return s.repo.Create(customer)
+// Create заводит клиента. Если найдены похожие карточки и создание не
+// подтверждено флагом AllowDuplicate, возвращает их вместе с ErrPossibleDuplicate.
+func (s *CustomerService) Create(req *models.CustomerRequest) (*models.CustomerCard, []models.CustomerDuplicate, error) {
+	c, err := customerFromRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !req.AllowDuplicate {
+		duplicates, err := s.repo.FindDuplicates(c, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(duplicates) > 0 {
+			return nil, duplicates, ErrPossibleDuplicate
+		}
+	}
+
+	id, err := s.repo.Create(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	card, err := s.GetByID(id)
+	return card, nil, err
 }
 
-func (s *CustomerService) Update(customer *models.Customer) error {
-	return s.repo.Update(customer)
+// Update изменяет данные клиента
+func (s *CustomerService) Update(id int, req *models.CustomerRequest) (*models.CustomerCard, error) {
+	c, err := customerFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.CustomerID = id
+	if err := s.repo.Update(c); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
 }
 
+// Delete удаляет клиента без истории покупок и обслуживания
 func (s *CustomerService) Delete(id int) error {
 	return s.repo.Delete(id)
-}
\ No newline at end of file
+}
+
+// Search ищет клиентов по отдельным полям через sp_search_customers
+func (s *CustomerService) Search(params models.CustomerSearchParams) ([]models.CustomerCard, error) {
+	params.Name = strings.TrimSpace(params.Name)
+	params.Phone = phoneNonDigits.ReplaceAllString(params.Phone, "")
+	params.Email = strings.TrimSpace(params.Email)
+	params.Passport = normalizePassport(params.Passport)
+
+	customers, err := s.repo.Search(params)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]models.CustomerCard, 0, len(customers))
+	for _, c := range customers {
+		cards = append(cards, toCustomerCard(&c))
+	}
+	return cards, nil
+}
+
+// FindDuplicates возвращает карточки, похожие на клиента
+func (s *CustomerService) FindDuplicates(id int) ([]models.CustomerDuplicate, error) {
+	c, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindDuplicates(c, id)
+}
+
+// Merge объединяет дубль с клиентом survivorID. Документы дубля переходят
+// к оставшемуся клиенту, сам дубль удаляется. mergedBy - ID сотрудника.
+func (s *CustomerService) Merge(survivorID int, req *models.CustomerMergeRequest, mergedBy *int) (*models.CustomerMerge, error) {
+	if req.DuplicateID <= 0 {
+		return nil, fmt.Errorf("%w: duplicate_id is required", ErrInvalidCustomer)
+	}
+	if req.DuplicateID == survivorID {
+		return nil, fmt.Errorf("%w: customer cannot be merged with itself", ErrInvalidCustomer)
+	}
+	return s.repo.Merge(survivorID, req.DuplicateID, mergedBy)
+}
+
+// GetMerges возвращает журнал объединений клиента
+func (s *CustomerService) GetMerges(id int) ([]models.CustomerMerge, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetMerges(id)
+}
+
+// GetAllCorporate возвращает страницу корпоративных клиентов с итогами покупок
+func (s *CustomerService) GetAllCorporate(search string, page, perPage int) ([]models.CorporateClientCard, models.Pagination, error) {
+	clients, total, err := s.repo.GetAllCorporate(strings.TrimSpace(search), perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	cards := make([]models.CorporateClientCard, 0, len(clients))
+	for _, c := range clients {
+		cards = append(cards, toCorporateClientCard(&c))
+	}
+
+	pagination := models.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		TotalItems: total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+
+	return cards, pagination, nil
+}
+
+// GetCorporateByID возвращает карточку корпоративного клиента с контактными лицами
+func (s *CustomerService) GetCorporateByID(id int) (*models.CorporateClientCard, error) {
+	c, err := s.repo.GetCorporateByID(id)
+	if err != nil {
+		return nil, err
+	}
+	card := toCorporateClientCard(c)
+	return &card, nil
+}
+
+// CreateCorporate заводит корпоративного клиента
+func (s *CustomerService) CreateCorporate(req *models.CorporateClientRequest) (*models.CorporateClientCard, error) {
+	c, err := corporateClientFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.CreateCorporate(c)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetCorporateByID(id)
+}
+
+// UpdateCorporate изменяет реквизиты, договор и контактных лиц клиента
+func (s *CustomerService) UpdateCorporate(id int, req *models.CorporateClientRequest) (*models.CorporateClientCard, error) {
+	c, err := corporateClientFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.CorporateClientID = id
+	if err := s.repo.UpdateCorporate(c); err != nil {
+		return nil, err
+	}
+	return s.GetCorporateByID(id)
+}
+
+// DeleteCorporate удаляет корпоративного клиента без истории покупок и обслуживания
+func (s *CustomerService) DeleteCorporate(id int) error {
+	return s.repo.DeleteCorporate(id)
+}
+
+// customerFromRequest проверяет запрос и приводит телефон и паспорт к формату хранения
+func customerFromRequest(req *models.CustomerRequest) (*models.Customer, error) {
+	c := &models.Customer{
+		FirstName:       strings.TrimSpace(req.FirstName),
+		LastName:        strings.TrimSpace(req.LastName),
+		MiddleName:      nullString(req.MiddleName),
+		Email:           nullString(req.Email),
+		PassportNumber:  nullString(normalizePassport(req.PassportNumber)),
+		Address:         nullString(req.Address),
+		DiscountPercent: req.DiscountPercent,
+		IsVIP:           req.IsVIP,
+	}
+
+	switch {
+	case c.FirstName == "" || c.LastName == "":
+		return nil, fmt.Errorf("%w: first and last name are required", ErrInvalidCustomer)
+	case c.Email.Valid && !strings.Contains(c.Email.String, "@"):
+		return nil, fmt.Errorf("%w: invalid email", ErrInvalidCustomer)
+	case c.DiscountPercent < 0 || c.DiscountPercent > 100:
+		return nil, fmt.Errorf("%w: discount must be between 0 and 100", ErrInvalidCustomer)
+	}
+
+	phone, err := utils.NormalizePhone(req.Phone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: phone must be a Belarusian number", ErrInvalidCustomer)
+	}
+	c.Phone = phone
+
+	if date := strings.TrimSpace(req.DateOfBirth); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: date of birth must be YYYY-MM-DD", ErrInvalidCustomer)
+		}
+		if parsed.After(time.Now()) {
+			return nil, fmt.Errorf("%w: date of birth is in the future", ErrInvalidCustomer)
+		}
+		c.DateOfBirth = sql.NullTime{Time: parsed, Valid: true}
+	}
+
+	return c, nil
+}
+
+func toCustomerCard(c *models.Customer) models.CustomerCard {
+	card := models.CustomerCard{
+		ID:              c.CustomerID,
+		FirstName:       c.FirstName,
+		LastName:        c.LastName,
+		MiddleName:      c.MiddleName.String,
+		FullName:        c.FullName,
+		Phone:           c.Phone,
+		Email:           c.Email.String,
+		PassportNumber:  c.PassportNumber.String,
+		Address:         c.Address.String,
+		DiscountPercent: c.DiscountPercent,
+		IsVIP:           c.IsVIP,
+		CustomerLevel:   c.CustomerLevel,
+		TotalPurchases:  c.TotalPurchases,
+		TotalSpent:      c.TotalSpent,
+		CreatedAt:       c.CreatedAt,
+		UpdatedAt:       c.UpdatedAt,
+	}
+	if card.FullName == "" {
+		card.FullName = strings.TrimSpace(c.LastName + " " + c.FirstName + " " + c.MiddleName.String)
+	}
+	if c.DateOfBirth.Valid {
+		card.DateOfBirth = &c.DateOfBirth.Time
+	}
+	if c.LastPurchaseDate.Valid {
+		card.LastPurchaseDate = &c.LastPurchaseDate.Time
+	}
+	return card
+}
+
+// normalizePassport убирает пробелы и дефисы: "mp 1234567" -> "MP1234567"
+func normalizePassport(passport string) string {
+	return strings.ToUpper(passportSeparators.ReplaceAllString(passport, ""))
+}
+
+// corporateClientFromRequest проверяет запрос и приводит реквизиты к формату хранения
+func corporateClientFromRequest(req *models.CorporateClientRequest) (*models.CorporateClient, error) {
+	c := &models.CorporateClient{
+		CompanyName:     strings.TrimSpace(req.CompanyName),
+		TaxID:           strings.TrimSpace(req.TaxID),
+		LegalAddress:    strings.TrimSpace(req.LegalAddress),
+		Phone:           strings.TrimSpace(req.Phone),
+		Email:           nullString(req.Email),
+		BankName:        nullString(req.BankName),
+		DiscountPercent: req.DiscountPercent,
+		ContractNumber:  nullString(req.ContractNumber),
+	}
+
+	switch {
+	case c.CompanyName == "":
+		return nil, fmt.Errorf("%w: company name is required", ErrInvalidCorporateClient)
+	case !taxIDPattern.MatchString(c.TaxID):
+		return nil, fmt.Errorf("%w: tax id must be 9 digits", ErrInvalidCorporateClient)
+	case c.LegalAddress == "":
+		return nil, fmt.Errorf("%w: legal address is required", ErrInvalidCorporateClient)
+	case c.Phone == "":
+		return nil, fmt.Errorf("%w: phone is required", ErrInvalidCorporateClient)
+	case c.Email.Valid && !strings.Contains(c.Email.String, "@"):
+		return nil, fmt.Errorf("%w: invalid email", ErrInvalidCorporateClient)
+	case c.DiscountPercent < 0 || c.DiscountPercent > 100:
+		return nil, fmt.Errorf("%w: discount must be between 0 and 100", ErrInvalidCorporateClient)
+	}
+
+	account := strings.ToUpper(strings.ReplaceAll(req.BankAccount, " ", ""))
+	if account != "" && !bankAccountPattern.MatchString(account) {
+		return nil, fmt.Errorf("%w: bank account must be a BY IBAN", ErrInvalidCorporateClient)
+	}
+	c.BankAccount = nullString(account)
+
+	bic := strings.ToUpper(strings.TrimSpace(req.BankBIC))
+	if bic != "" && !bankBICPattern.MatchString(bic) {
+		return nil, fmt.Errorf("%w: invalid bank BIC", ErrInvalidCorporateClient)
+	}
+	c.BankBIC = nullString(bic)
+
+	if date := strings.TrimSpace(req.ContractDate); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: contract date must be YYYY-MM-DD", ErrInvalidCorporateClient)
+		}
+		c.ContractDate = sql.NullTime{Time: parsed, Valid: true}
+	}
+	if c.ContractNumber.Valid != c.ContractDate.Valid {
+		return nil, fmt.Errorf("%w: contract number and date are set together", ErrInvalidCorporateClient)
+	}
+
+	primary := -1
+	for i, contact := range req.Contacts {
+		contact.ID = 0
+		contact.FullName = strings.TrimSpace(contact.FullName)
+		contact.Position = strings.TrimSpace(contact.Position)
+		contact.Phone = strings.TrimSpace(contact.Phone)
+		contact.Email = strings.TrimSpace(contact.Email)
+		if contact.FullName == "" {
+			return nil, fmt.Errorf("%w: contact name is required", ErrInvalidCorporateClient)
+		}
+		if contact.IsPrimary {
+			if primary >= 0 {
+				return nil, fmt.Errorf("%w: only one primary contact is allowed", ErrInvalidCorporateClient)
+			}
+			primary = i
+		}
+		c.Contacts = append(c.Contacts, contact)
+	}
+	// Без явного выбора основным считается первый контакт
+	if primary < 0 && len(c.Contacts) > 0 {
+		primary = 0
+		c.Contacts[0].IsPrimary = true
+	}
+	if primary >= 0 {
+		c.ContactPerson = nullString(c.Contacts[primary].FullName)
+	}
+
+	return c, nil
+}
+
+func toCorporateClientCard(c *models.CorporateClient) models.CorporateClientCard {
+	card := models.CorporateClientCard{
+		ID:              c.CorporateClientID,
+		CompanyName:     c.CompanyName,
+		TaxID:           c.TaxID,
+		LegalAddress:    c.LegalAddress,
+		ContactPerson:   c.ContactPerson.String,
+		Phone:           c.Phone,
+		Email:           c.Email.String,
+		BankAccount:     c.BankAccount.String,
+		BankName:        c.BankName.String,
+		BankBIC:         c.BankBIC.String,
+		DiscountPercent: c.DiscountPercent,
+		ContractNumber:  c.ContractNumber.String,
+		TotalPurchases:  c.TotalPurchases,
+		TotalSpent:      c.TotalSpent,
+		Contacts:        c.Contacts,
+		CreatedAt:       c.CreatedAt,
+		UpdatedAt:       c.UpdatedAt,
+	}
+	if c.ContractDate.Valid {
+		card.ContractDate = &c.ContractDate.Time
+	}
+	if c.LastPurchaseDate.Valid {
+		card.LastPurchaseDate = &c.LastPurchaseDate.Time
+	}
+	if card.Contacts == nil {
+		card.Contacts = []models.CorporateContact{}
+	}
+	return card
+}
+
+// nullString возвращает NULL для пустой строки
+func nullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
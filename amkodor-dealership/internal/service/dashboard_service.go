@@ -1,11 +1,21 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
+
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
 )
 
+// ErrInvalidChartQuery - неверный шаг или диапазон графика
+var ErrInvalidChartQuery = errors.New("invalid chart query")
+
+// maxChartPoints ограничивает число интервалов на графике
+const maxChartPoints = 366
+
 type DashboardService struct {
 	repo repository.DashboardRepository
 }
@@ -14,14 +24,98 @@ func NewDashboardService(repo repository.DashboardRepository) *DashboardService
 	return &DashboardService{repo: repo}
 }
 
-func (s *DashboardService) GetStats() (*models.DashboardStats, error) {
-	return s.repo.GetDashboardStats(nil)
+// GetStatistics возвращает сводку дашборда; warehouseID == nil - по всем складам
+func (s *DashboardService) GetStatistics(ctx context.Context, warehouseID *int) (*models.DashboardStatistics, error) {
+	return s.repo.GetDashboardStatistics(ctx, warehouseID)
+}
+
+// GetCharts строит график продаж с шагом period за период from-to (YYYY-MM-DD)
+// и распределение продаж по категориям. По умолчанию to - сегодня, а from
+// отстоит на 30 дней, 12 недель или 12 месяцев в зависимости от шага.
+func (s *DashboardService) GetCharts(ctx context.Context, period, from, to string, warehouseID *int) (*models.DashboardCharts, error) {
+	if period == "" {
+		period = models.ChartPeriodDay
+	}
+
+	end := time.Now()
+	if to != "" {
+		parsed, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid end date", ErrInvalidChartQuery)
+		}
+		end = parsed
+	}
+
+	var start time.Time
+	var points int
+	switch period {
+	case models.ChartPeriodDay:
+		start = end.AddDate(0, 0, -29)
+	case models.ChartPeriodWeek:
+		start = end.AddDate(0, 0, -7*11)
+	case models.ChartPeriodMonth:
+		start = end.AddDate(0, -11, 0)
+	default:
+		return nil, fmt.Errorf("%w: period must be day, week or month", ErrInvalidChartQuery)
+	}
+	if from != "" {
+		parsed, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid start date", ErrInvalidChartQuery)
+		}
+		start = parsed
+	}
+	if start.After(end) {
+		return nil, fmt.Errorf("%w: start date is after end date", ErrInvalidChartQuery)
+	}
+
+	days := int(end.Sub(start).Hours() / 24)
+	switch period {
+	case models.ChartPeriodDay:
+		points = days
+	case models.ChartPeriodWeek:
+		points = days / 7
+	case models.ChartPeriodMonth:
+		points = days / 28
+	}
+	if points > maxChartPoints {
+		return nil, fmt.Errorf("%w: too many points, use a longer period", ErrInvalidChartQuery)
+	}
+
+	sales, err := s.repo.GetSalesChartData(ctx, period, start, end, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sales {
+		sales[i].Label = chartLabel(period, sales[i].Date)
+	}
+
+	categories, err := s.repo.GetCategorySales(ctx, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DashboardCharts{Period: period, Sales: sales, Categories: categories}, nil
+}
+
+// GetTopEmployees возвращает менеджеров с наибольшей выручкой
+func (s *DashboardService) GetTopEmployees(ctx context.Context, limit int, warehouseID *int) ([]models.TopEmployee, error) {
+	return s.repo.GetTopEmployees(ctx, limit, warehouseID)
 }
 
-func (s *DashboardService) GetCharts() ([]models.ChartData, error) {
-	return s.repo.GetSalesChartData(nil, time.Now().AddDate(0, -1, 0))
+// GetRecentSales возвращает последние продажи
+func (s *DashboardService) GetRecentSales(ctx context.Context, limit int, warehouseID *int) ([]models.RecentSale, error) {
+	return s.repo.GetRecentSales(ctx, limit, warehouseID)
 }
 
-func (s *DashboardService) GetTopEmployees(limit int) ([]models.TopEmployee, error) {
-	return s.repo.GetTopEmployees(nil, limit)
+// chartLabel - подпись точки графика: 02.01 для дней и недель, 01.2006 для месяцев
+func chartLabel(period, date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	if period == models.ChartPeriodMonth {
+		return t.Format("01.2006")
+	}
+	return t.Format("02.01")
 }
@@ -1,10 +1,31 @@
 package service
 
 import (
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/utils"
+)
+
+var (
+	// ErrInvalidEmployee - ошибка валидации данных сотрудника
+	ErrInvalidEmployee = errors.New("invalid employee")
+	// ErrWeakPassword - пароль короче 8 символов или без заглавных, строчных букв и цифр
+	ErrWeakPassword = errors.New("password is too weak")
+	// ErrInvalidPosition - ошибка валидации должности
+	ErrInvalidPosition = errors.New("invalid position")
 )
 
+// Алфавит временных паролей без похожих символов (0/O, 1/l/I)
+const tempPasswordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+
 type EmployeeService struct {
 	repo repository.EmployeeRepository
 }
@@ -13,22 +34,279 @@ func NewEmployeeService(repo repository.EmployeeRepository) *EmployeeService {
 	return &EmployeeService{repo: repo}
 }
 
-func (s *EmployeeService) GetAll(limit, offset int) ([]models.Employee, error) {
-	return s.repo.GetAll(limit, offset)
+// GetPage возвращает страницу сотрудников со стажем
+func (s *EmployeeService) GetPage(search string, active *bool, page, perPage int) ([]models.EmployeeCard, models.Pagination, error) {
+	employees, total, err := s.repo.GetPage(strings.TrimSpace(search), active, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	cards := make([]models.EmployeeCard, 0, len(employees))
+	for _, e := range employees {
+		cards = append(cards, toEmployeeCard(&e))
+	}
+
+	pagination := models.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		TotalItems: total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+
+	return cards, pagination, nil
+}
+
+// GetByID возвращает карточку сотрудника
+func (s *EmployeeService) GetByID(id int) (*models.EmployeeCard, error) {
+	e, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	card := toEmployeeCard(e)
+	return &card, nil
+}
+
+// Create принимает сотрудника на работу с начальным паролем
+func (s *EmployeeService) Create(req *models.EmployeeRequest) (*models.EmployeeCard, error) {
+	e, err := employeeFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.PasswordHash, err = hashEmployeePassword(req.Password); err != nil {
+		return nil, err
+	}
+	if e.HireDate.IsZero() {
+		e.HireDate = time.Now()
+	}
+
+	id, err := s.repo.Create(e)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Update изменяет данные сотрудника, должность и склад
+func (s *EmployeeService) Update(id int, req *models.EmployeeRequest) (*models.EmployeeCard, error) {
+	e, err := employeeFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	e.EmployeeID = id
+	if e.HireDate.IsZero() {
+		current, err := s.repo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		e.HireDate = current.HireDate
+	}
+	if err := s.repo.Update(e); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Deactivate увольняет сотрудника
+func (s *EmployeeService) Deactivate(id int) error {
+	return s.repo.Deactivate(id)
+}
+
+// Restore возвращает сотрудника в штат
+func (s *EmployeeService) Restore(id int) (*models.EmployeeCard, error) {
+	if err := s.repo.Restore(id); err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
 }
 
-func (s *EmployeeService) GetByID(id int) (*models.Employee, error) {
-	return s.repo.GetByID(id)
+// SetPassword задаёт сотруднику новый пароль
+func (s *EmployeeService) SetPassword(id int, password string) error {
+	hash, err := hashEmployeePassword(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePassword(id, hash)
 }
 
-func (s *EmployeeService) Create(employee *models.Employee) (int, error) {
-	return s.repo.Create(employee)
+// ResetPassword генерирует временный пароль. Он возвращается один раз,
+// в базе хранится только хеш.
+func (s *EmployeeService) ResetPassword(id int) (*models.PasswordReset, error) {
+	password, err := temporaryPassword()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SetPassword(id, password); err != nil {
+		return nil, err
+	}
+	return &models.PasswordReset{TemporaryPassword: password}, nil
 }
 
-func (s *EmployeeService) Update(employee *models.Employee) error {
-	return s.repo.Update(employee)
+// GetPositions возвращает справочник должностей
+func (s *EmployeeService) GetPositions() ([]models.Position, error) {
+	return s.repo.GetPositions()
 }
 
-func (s *EmployeeService) Delete(id int) error {
-	return s.repo.Delete(id)
-}
\ No newline at end of file
+// CreatePosition добавляет должность
+func (s *EmployeeService) CreatePosition(req *models.PositionRequest) (*models.Position, error) {
+	p, err := positionFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreatePosition(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpdatePosition изменяет должность
+func (s *EmployeeService) UpdatePosition(id int, req *models.PositionRequest) (*models.Position, error) {
+	p, err := positionFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+	if err := s.repo.UpdatePosition(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DeletePosition удаляет должность без сотрудников
+func (s *EmployeeService) DeletePosition(id int) error {
+	return s.repo.DeletePosition(id)
+}
+
+func employeeFromRequest(req *models.EmployeeRequest) (*models.Employee, error) {
+	e := &models.Employee{
+		FirstName:  strings.TrimSpace(req.FirstName),
+		LastName:   strings.TrimSpace(req.LastName),
+		MiddleName: nullString(req.MiddleName),
+		PositionID: req.PositionID,
+		Email:      nullString(strings.ToLower(req.Email)),
+		Phone:      nullString(req.Phone),
+	}
+
+	switch {
+	case e.FirstName == "" || e.LastName == "":
+		return nil, fmt.Errorf("%w: first and last name are required", ErrInvalidEmployee)
+	case e.PositionID <= 0:
+		return nil, fmt.Errorf("%w: position is required", ErrInvalidEmployee)
+	case e.Email.Valid && !strings.Contains(e.Email.String, "@"):
+		return nil, fmt.Errorf("%w: invalid email", ErrInvalidEmployee)
+	case req.Salary != nil && *req.Salary < 0:
+		return nil, fmt.Errorf("%w: salary cannot be negative", ErrInvalidEmployee)
+	}
+
+	if e.Phone.Valid {
+		phone, err := utils.NormalizePhone(e.Phone.String)
+		if err != nil {
+			return nil, fmt.Errorf("%w: phone must be a Belarusian number", ErrInvalidEmployee)
+		}
+		e.Phone.String = phone
+	}
+	if req.WarehouseID != nil {
+		e.WarehouseID = sql.NullInt64{Int64: int64(*req.WarehouseID), Valid: true}
+	}
+	if req.Salary != nil {
+		e.Salary = sql.NullFloat64{Float64: *req.Salary, Valid: true}
+	}
+	if date := strings.TrimSpace(req.HireDate); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, fmt.Errorf("%w: hire date must be YYYY-MM-DD", ErrInvalidEmployee)
+		}
+		e.HireDate = parsed
+	}
+
+	return e, nil
+}
+
+func positionFromRequest(req *models.PositionRequest) (*models.Position, error) {
+	p := &models.Position{
+		Name:        strings.TrimSpace(req.Name),
+		BaseSalary:  req.BaseSalary,
+		Description: strings.TrimSpace(req.Description),
+		AccessRole:  strings.TrimSpace(req.AccessRole),
+	}
+	if p.Name == "" {
+		return nil, fmt.Errorf("%w: position name is required", ErrInvalidPosition)
+	}
+	if p.BaseSalary < 0 {
+		return nil, fmt.Errorf("%w: base salary cannot be negative", ErrInvalidPosition)
+	}
+	switch p.AccessRole {
+	case "", models.RoleAdmin, models.RoleSalesManager, models.RoleServiceMaster, models.RoleWarehouseKeeper:
+	default:
+		return nil, fmt.Errorf("%w: unknown access role %q", ErrInvalidPosition, p.AccessRole)
+	}
+	return p, nil
+}
+
+func hashEmployeePassword(password string) (string, error) {
+	if !utils.ValidatePasswordStrength(password) {
+		return "", ErrWeakPassword
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+	return hash, nil
+}
+
+// temporaryPassword генерирует пароль из 12 символов, проходящий
+// ValidatePasswordStrength
+func temporaryPassword() (string, error) {
+	for {
+		b := make([]byte, 12)
+		for i := range b {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(tempPasswordAlphabet))))
+			if err != nil {
+				return "", fmt.Errorf("error generating password: %w", err)
+			}
+			b[i] = tempPasswordAlphabet[n.Int64()]
+		}
+		if password := string(b); utils.ValidatePasswordStrength(password) {
+			return password, nil
+		}
+	}
+}
+
+func toEmployeeCard(e *models.Employee) models.EmployeeCard {
+	card := models.EmployeeCard{
+		ID:             e.EmployeeID,
+		FullName:       e.FullName,
+		FirstName:      e.FirstName,
+		LastName:       e.LastName,
+		MiddleName:     e.MiddleName.String,
+		PositionID:     e.PositionID,
+		PositionName:   e.PositionName,
+		BaseSalary:     e.BaseSalary,
+		WarehouseID:    nullIntValue(e.WarehouseID),
+		WarehouseName:  e.WarehouseName,
+		WarehouseCity:  e.WarehouseCity,
+		Email:          e.Email.String,
+		Phone:          e.Phone.String,
+		HireDate:       e.HireDate,
+		YearsOfService: e.YearsOfService,
+		TenureMonths:   e.TenureMonths,
+		IsActive:       e.IsActive,
+		CreatedAt:      e.CreatedAt,
+	}
+	if e.Salary.Valid {
+		card.Salary = &e.Salary.Float64
+	}
+	if e.DismissalDate.Valid {
+		card.DismissalDate = &e.DismissalDate.Time
+	}
+	return card
+}
+
+func nullIntValue(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	id := int(v.Int64)
+	return &id
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+
+	"github.com/lib/pq"
+)
+
+// ErrLiveEventsGap - с Last-Event-ID накопилось больше событий, чем можно
+// отдать при переподключении; клиенту нужно перечитать данные целиком
+var ErrLiveEventsGap = errors.New("too many missed live events")
+
+const (
+	// LiveEventsChannel - канал NOTIFY, в который fn_publish_live_event
+	// отправляет идентификаторы событий
+	LiveEventsChannel = "live_events"
+
+	liveEventRetention   = 24 * time.Hour
+	liveEventReplayLimit = 500
+	// liveEventBuffer - очередь подписчика; отстающий подписчик отключается
+	liveEventBuffer = 64
+)
+
+// LiveEventHook обрабатывает событие на сервере, например сохраняет оповещение.
+// Вызывается в цикле слушателя, поэтому долгую работу нужно уводить в горутину.
+type LiveEventHook func(ctx context.Context, event models.LiveEvent)
+
+// LiveEventService слушает LISTEN live_events и рассылает события подписчикам
+type LiveEventService struct {
+	repo    *repository.LiveEventRepository
+	connStr string
+	hooks   map[string][]LiveEventHook
+
+	mu          sync.Mutex
+	subscribers map[chan models.LiveEvent]struct{}
+	lastID      int64
+}
+
+func NewLiveEventService(repo *repository.LiveEventRepository, connStr string) *LiveEventService {
+	return &LiveEventService{
+		repo:        repo,
+		connStr:     connStr,
+		hooks:       make(map[string][]LiveEventHook),
+		subscribers: make(map[chan models.LiveEvent]struct{}),
+	}
+}
+
+// OnEvent регистрирует обработчик событий типа eventType. Обработчики
+// регистрируются до запуска Run.
+func (s *LiveEventService) OnEvent(eventType string, hook LiveEventHook) {
+	s.hooks[eventType] = append(s.hooks[eventType], hook)
+}
+
+// Run слушает канал до отмены ctx. После восстановления соединения события,
+// пропущенные за время разрыва, дочитываются из live_events. Заодно раз в час
+// удаляются события старше срока хранения.
+func (s *LiveEventService) Run(ctx context.Context) {
+	listener := pq.NewListener(s.connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Live events listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(LiveEventsChannel); err != nil {
+		log.Printf("Live events listener: error listening on %s: %v", LiveEventsChannel, err)
+		return
+	}
+
+	lastID, err := s.repo.GetLastID(ctx)
+	if err != nil {
+		log.Printf("Live events listener: %v", err)
+	}
+	s.mu.Lock()
+	s.lastID = lastID
+	s.mu.Unlock()
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				s.catchUp(ctx)
+				continue
+			}
+			id, err := strconv.ParseInt(n.Extra, 10, 64)
+			if err != nil {
+				log.Printf("Live events listener: invalid event id %q", n.Extra)
+				continue
+			}
+			event, err := s.repo.GetByID(ctx, id)
+			if err != nil {
+				log.Printf("Live events listener: %v", err)
+				continue
+			}
+			s.dispatch(ctx, *event)
+		case <-ping.C:
+			go listener.Ping()
+		case <-cleanup.C:
+			if _, err := s.repo.DeleteBefore(ctx, time.Now().Add(-liveEventRetention)); err != nil {
+				log.Printf("Live events cleanup: %v", err)
+			}
+		}
+	}
+}
+
+// Subscribe регистрирует подписчика. Канал закрывается, если подписчик
+// не успевает забирать события; вторая функция отменяет подписку.
+func (s *LiveEventService) Subscribe() (<-chan models.LiveEvent, func()) {
+	ch := make(chan models.LiveEvent, liveEventBuffer)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Replay возвращает события после afterID для переподключившегося клиента
+func (s *LiveEventService) Replay(ctx context.Context, afterID int64) ([]models.LiveEvent, error) {
+	events, err := s.repo.GetAfter(ctx, afterID, liveEventReplayLimit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > liveEventReplayLimit {
+		return nil, ErrLiveEventsGap
+	}
+	return events, nil
+}
+
+// catchUp рассылает события, записанные после последнего полученного
+func (s *LiveEventService) catchUp(ctx context.Context) {
+	s.mu.Lock()
+	lastID := s.lastID
+	s.mu.Unlock()
+
+	events, err := s.repo.GetAfter(ctx, lastID, liveEventReplayLimit)
+	if err != nil {
+		log.Printf("Live events listener: %v", err)
+		return
+	}
+	for _, event := range events {
+		s.dispatch(ctx, event)
+	}
+}
+
+// dispatch передаёт событие серверным обработчикам и подписчикам
+func (s *LiveEventService) dispatch(ctx context.Context, event models.LiveEvent) {
+	for _, hook := range s.hooks[event.Type] {
+		hook(ctx, event)
+	}
+	s.publish(event)
+}
+
+func (s *LiveEventService) publish(event models.LiveEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event.ID > s.lastID {
+		s.lastID = event.ID
+	}
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Mailer отправляет служебные письма сотрудникам и клиентам
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
+
+// LogMailer пишет письмо в лог сервера. Используется, пока не настроен SMTP.
+type LogMailer struct{}
+
+func (LogMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	log.Printf("Письмо для %s: %s\n%s", to, subject, body)
+	return nil
+}
+
+// SMTPMailer отправляет письма через SMTP-сервер с PLAIN-аутентификацией
+type SMTPMailer struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer создаёт отправителя; без username письма уходят без аутентификации
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &SMTPMailer{host: host, addr: net.JoinHostPort(host, port), auth: auth, from: from}
+}
+
+// SendMail отправляет письмо так же, как smtp.SendMail, но соединение
+// открывается и обслуживается в пределах ctx: срок ctx становится дедлайном
+// соединения, а отмена ctx закрывает его. Зависший сервер не блокирует
+// вызывающего дольше, чем позволяет ctx.
+func (m *SMTPMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: =?UTF-8?B?%s?=\r\n", base64.StdEncoding.EncodeToString([]byte(subject)))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := m.send(ctx, to, msg.String()); err != nil {
+		return fmt.Errorf("error sending mail to %s: %w", to, err)
+	}
+	return nil
+}
+
+func (m *SMTPMailer) send(ctx context.Context, to, msg string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(m.auth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := c.Mail(m.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+var (
+	// ErrInvalidSupplier - ошибка валидации данных поставщика
+	ErrInvalidSupplier = errors.New("invalid supplier")
+	// ErrInvalidPurchaseOrder - ошибка валидации заказа или приёма запчастей
+	ErrInvalidPurchaseOrder = errors.New("invalid purchase order")
+)
+
+// Окно расчёта расхода и период покрытия для рекомендаций по дозаказу, в днях
+const (
+	DefaultReorderWindowDays = 90
+	DefaultReorderCoverDays  = 30
+	maxReorderDays           = 730
+)
+
+// PurchaseOrderService - поставщики, заказы запчастей и рекомендации по дозаказу
+type PurchaseOrderService struct {
+	repo      *repository.PurchaseOrderRepository
+	suppliers *repository.SupplierRepository
+}
+
+func NewPurchaseOrderService(repo *repository.PurchaseOrderRepository, suppliers *repository.SupplierRepository) *PurchaseOrderService {
+	return &PurchaseOrderService{repo: repo, suppliers: suppliers}
+}
+
+// GetSuppliers возвращает поставщиков; activeOnly скрывает неактивных
+func (s *PurchaseOrderService) GetSuppliers(ctx context.Context, activeOnly bool) ([]models.Supplier, error) {
+	return s.suppliers.GetAll(ctx, activeOnly)
+}
+
+// CreateSupplier добавляет поставщика
+func (s *PurchaseOrderService) CreateSupplier(ctx context.Context, req *models.SupplierRequest) (*models.Supplier, error) {
+	supplier, err := supplierFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.suppliers.Create(ctx, supplier)
+	if err != nil {
+		return nil, err
+	}
+	return s.suppliers.GetByID(ctx, id)
+}
+
+// UpdateSupplier изменяет данные поставщика
+func (s *PurchaseOrderService) UpdateSupplier(ctx context.Context, id int, req *models.SupplierRequest) (*models.Supplier, error) {
+	supplier, err := supplierFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	supplier.ID = id
+	if err := s.suppliers.Update(ctx, supplier); err != nil {
+		return nil, err
+	}
+	return s.suppliers.GetByID(ctx, id)
+}
+
+// GetAll возвращает заказы с фильтром по статусу, складу и поставщику
+func (s *PurchaseOrderService) GetAll(ctx context.Context, status string, warehouseID, supplierID *int) ([]models.PurchaseOrder, error) {
+	switch status {
+	case "", models.PurchaseOrderOrdered, models.PurchaseOrderPartial,
+		models.PurchaseOrderReceived, models.PurchaseOrderCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidPurchaseOrder, status)
+	}
+	return s.repo.GetAll(ctx, status, warehouseID, supplierID)
+}
+
+// GetByID возвращает заказ со строками
+func (s *PurchaseOrderService) GetByID(ctx context.Context, id int) (*models.PurchaseOrder, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Create оформляет заказ у активного поставщика от имени сотрудника
+func (s *PurchaseOrderService) Create(ctx context.Context, employeeID int, req *models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	expectedDate, err := s.validateOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.Create(ctx, req, expectedDate, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Update изменяет заказ, пока по нему ничего не получено
+func (s *PurchaseOrderService) Update(ctx context.Context, id int, req *models.PurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	expectedDate, err := s.validateOrder(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, id, req, expectedDate); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Cancel отменяет заказ, пока по нему ничего не получено
+func (s *PurchaseOrderService) Cancel(ctx context.Context, id int) (*models.PurchaseOrder, error) {
+	if err := s.repo.Cancel(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Receive принимает запчасти по заказу на склад. Без строк принимается
+// весь недополученный остаток.
+func (s *PurchaseOrderService) Receive(ctx context.Context, id, employeeID int, req *models.ReceivePurchaseOrderRequest) (*models.PurchaseOrder, error) {
+	for _, l := range req.Lines {
+		if l.LineID <= 0 || l.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: line and positive quantity are required", ErrInvalidPurchaseOrder)
+		}
+	}
+
+	if err := s.repo.Receive(ctx, id, employeeID, req.Lines); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetReorderSuggestions рассчитывает дозаказ. windowDays и coverDays
+// по умолчанию DefaultReorderWindowDays и DefaultReorderCoverDays.
+func (s *PurchaseOrderService) GetReorderSuggestions(ctx context.Context, warehouseID, windowDays, coverDays *int) ([]models.ReorderSuggestion, error) {
+	window, cover := DefaultReorderWindowDays, DefaultReorderCoverDays
+	if windowDays != nil {
+		window = *windowDays
+	}
+	if coverDays != nil {
+		cover = *coverDays
+	}
+	if window < 1 || window > maxReorderDays || cover < 1 || cover > maxReorderDays {
+		return nil, fmt.Errorf("%w: window_days and cover_days must be between 1 and %d", ErrInvalidPurchaseOrder, maxReorderDays)
+	}
+
+	return s.repo.GetReorderSuggestions(ctx, warehouseID, window, cover)
+}
+
+// validateOrder проверяет заказ и возвращает ожидаемую дату поставки
+func (s *PurchaseOrderService) validateOrder(ctx context.Context, req *models.PurchaseOrderRequest) (*time.Time, error) {
+	if req.SupplierID <= 0 || req.WarehouseID <= 0 {
+		return nil, fmt.Errorf("%w: supplier and warehouse are required", ErrInvalidPurchaseOrder)
+	}
+	if len(req.Lines) == 0 {
+		return nil, fmt.Errorf("%w: at least one line is required", ErrInvalidPurchaseOrder)
+	}
+
+	parts := make(map[int]bool, len(req.Lines))
+	for _, l := range req.Lines {
+		if l.SparePartID <= 0 || l.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: spare part and positive quantity are required", ErrInvalidPurchaseOrder)
+		}
+		if l.UnitPrice != nil && *l.UnitPrice < 0 {
+			return nil, fmt.Errorf("%w: unit price cannot be negative", ErrInvalidPurchaseOrder)
+		}
+		if parts[l.SparePartID] {
+			return nil, fmt.Errorf("%w: spare part %d is listed twice", ErrInvalidPurchaseOrder, l.SparePartID)
+		}
+		parts[l.SparePartID] = true
+	}
+
+	var expectedDate *time.Time
+	if req.ExpectedDate != "" {
+		date, err := time.Parse("2006-01-02", req.ExpectedDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected date must be YYYY-MM-DD", ErrInvalidPurchaseOrder)
+		}
+		if date.Before(time.Now().Truncate(24 * time.Hour)) {
+			return nil, fmt.Errorf("%w: expected date is in the past", ErrInvalidPurchaseOrder)
+		}
+		expectedDate = &date
+	}
+
+	supplier, err := s.suppliers.GetByID(ctx, req.SupplierID)
+	if err != nil {
+		return nil, err
+	}
+	if !supplier.IsActive {
+		return nil, fmt.Errorf("%w: supplier is inactive", ErrInvalidPurchaseOrder)
+	}
+
+	return expectedDate, nil
+}
+
+func supplierFromRequest(req *models.SupplierRequest) (*models.Supplier, error) {
+	s := &models.Supplier{
+		Name:          strings.TrimSpace(req.Name),
+		TaxID:         strings.TrimSpace(req.TaxID),
+		ContactPerson: strings.TrimSpace(req.ContactPerson),
+		Phone:         strings.TrimSpace(req.Phone),
+		Email:         strings.TrimSpace(req.Email),
+		IsActive:      req.IsActive == nil || *req.IsActive,
+	}
+
+	switch {
+	case s.Name == "":
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidSupplier)
+	case s.Email != "" && !strings.Contains(s.Email, "@"):
+		return nil, fmt.Errorf("%w: invalid email", ErrInvalidSupplier)
+	}
+	return s, nil
+}
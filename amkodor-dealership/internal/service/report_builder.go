@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"amkodor-dealership/pkg/report"
+)
+
+// ErrUnknownReportType - запрошен неизвестный тип отчета
+var ErrUnknownReportType = errors.New("unknown report type")
+
+// reportBuilder выбирает строки отчета за период
+type reportBuilder func(s *ReportService, ctx context.Context, from, to time.Time) (*report.Table, error)
+
+var reportBuilders = map[string]reportBuilder{
+	"sales":       (*ReportService).salesTable,
+	"vehicles":    (*ReportService).vehiclesTable,
+	"customers":   (*ReportService).customersTable,
+	"financial":   (*ReportService).financialTable,
+	"inventory":   (*ReportService).inventoryTable,
+	"test_drives": (*ReportService).testDrivesTable,
+}
+
+// BuildReport формирует табличный отчет указанного типа за период [from, to]
+func (s *ReportService) BuildReport(ctx context.Context, reportType string, from, to time.Time) (*report.Table, error) {
+	build, ok := reportBuilders[reportType]
+	if !ok {
+		return nil, ErrUnknownReportType
+	}
+	return build(s, ctx, from, to)
+}
+
+// salesTable - завершенные продажи из sp_generate_sales_report
+func (s *ReportService) salesTable(ctx context.Context, from, to time.Time) (*report.Table, error) {
+	t := report.NewTable("sales_report", "Отчет по продажам", from, to,
+		report.Column{Title: "№", Type: report.Integer, Width: 6},
+		report.Column{Title: "Дата", Type: report.Date},
+		report.Column{Title: "Договор", Width: 14},
+		report.Column{Title: "Модель"},
+		report.Column{Title: "Категория", Width: 16},
+		report.Column{Title: "Клиент", Width: 24},
+		report.Column{Title: "Менеджер"},
+		report.Column{Title: "Склад", Width: 16},
+		report.Column{Title: "Базовая цена", Type: report.Money, Total: true},
+		report.Column{Title: "Скидка", Type: report.Money, Total: true},
+		report.Column{Title: "Итого", Type: report.Money, Total: true},
+		report.Column{Title: "Оплата", Width: 12},
+	)
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT * FROM sp_generate_sales_report($1, $2, NULL, NULL, NULL)`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error generating sales report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			saleID                                     int64
+			contract                                   sql.NullString
+			saleDate                                   time.Time
+			model, typeName, category, client, manager string
+			warehouse, city, paymentType, status       string
+			basePrice, discount, finalPrice            float64
+		)
+		err := rows.Scan(&saleID, &contract, &saleDate, &model, &typeName, &category,
+			&client, &manager, &warehouse, &city, &basePrice, &discount, &finalPrice,
+			&paymentType, &status)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning sale: %w", err)
+		}
+		t.AddRow(saleID, saleDate, contract.String, model, category, client, manager,
+			warehouse+", "+city, basePrice, discount, finalPrice, paymentType)
+	}
+
+	return t, rows.Err()
+}
+
+// vehiclesTable - техника, поступившая на склады за период
+func (s *ReportService) vehiclesTable(ctx context.Context, from, to time.Time) (*report.Table, error) {
+	t := report.NewTable("vehicles_report", "Отчет по технике", from, to,
+		report.Column{Title: "ID", Type: report.Integer, Width: 6},
+		report.Column{Title: "Модель"},
+		report.Column{Title: "Категория", Width: 16},
+		report.Column{Title: "VIN", Width: 18},
+		report.Column{Title: "Год", Type: report.Integer, Width: 6},
+		report.Column{Title: "Поступление", Type: report.Date},
+		report.Column{Title: "Склад", Width: 16},
+		report.Column{Title: "Статус", Width: 14},
+		report.Column{Title: "Цена", Type: report.Money, Total: true},
+		report.Column{Title: "Скидка", Type: report.Percent},
+		report.Column{Title: "Цена со скидкой", Type: report.Money, Total: true},
+	)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT vehicle_id, model_name, category_name, COALESCE(vin, ''), manufacture_year,
+		       arrival_date, warehouse_name, warehouse_city, status, price,
+		       COALESCE(discount, 0), final_price
+		FROM vw_vehicles_full_info
+		WHERE arrival_date BETWEEN $1 AND $2
+		ORDER BY arrival_date DESC, vehicle_id`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error querying vehicles report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id, year                              int64
+			model, category, vin, warehouse, city string
+			status                                sql.NullString
+			arrival                               sql.NullTime
+			price, discount, finalPrice           float64
+		)
+		err := rows.Scan(&id, &model, &category, &vin, &year, &arrival, &warehouse, &city,
+			&status, &price, &discount, &finalPrice)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning vehicle: %w", err)
+		}
+		t.AddRow(id, model, category, vin, year, nullTime(arrival), warehouse+", "+city,
+			status.String, price, discount, finalPrice)
+	}
+
+	return t, rows.Err()
+}
+
+// customersTable - клиенты из vw_all_clients с покупками за период
+func (s *ReportService) customersTable(ctx context.Context, from, to time.Time) (*report.Table, error) {
+	t := report.NewTable("customers_report", "Отчет по клиентам", from, to,
+		report.Column{Title: "Тип", Width: 10},
+		report.Column{Title: "Клиент", Width: 28},
+		report.Column{Title: "Телефон", Width: 16},
+		report.Column{Title: "Email", Width: 22},
+		report.Column{Title: "Категория", Width: 14},
+		report.Column{Title: "Скидка", Type: report.Percent},
+		report.Column{Title: "Покупок", Type: report.Integer, Total: true},
+		report.Column{Title: "Сумма покупок", Type: report.Money, Total: true},
+	)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.client_type, c.client_name, COALESCE(c.phone, ''), COALESCE(c.email, ''),
+		       c.client_category, COALESCE(c.discount_percent, 0),
+		       COUNT(s.sale_id), COALESCE(SUM(s.final_price), 0)
+		FROM vw_all_clients c
+		LEFT JOIN sales s
+		       ON s.status = 'Завершена'
+		      AND s.sale_date BETWEEN $1 AND $2
+		      AND ((c.client_type = 'CUSTOMER' AND s.customer_id = c.client_id)
+		        OR (c.client_type = 'CORPORATE' AND s.corporate_client_id = c.client_id))
+		GROUP BY c.client_type, c.client_id, c.client_name, c.phone, c.email,
+		         c.client_category, c.discount_percent
+		ORDER BY 8 DESC, c.client_name`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error querying customers report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			clientType, name, phone, email, category string
+			discount, total                          float64
+			purchases                                int64
+		)
+		err := rows.Scan(&clientType, &name, &phone, &email, &category, &discount, &purchases, &total)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning client: %w", err)
+		}
+		if clientType == "CORPORATE" {
+			clientType = "Юр. лицо"
+		} else {
+			clientType = "Физ. лицо"
+		}
+		t.AddRow(clientType, name, phone, email, category, discount, purchases, total)
+	}
+
+	return t, rows.Err()
+}
+
+// financialTable - помесячные доходы от продаж и сервиса
+func (s *ReportService) financialTable(ctx context.Context, from, to time.Time) (*report.Table, error) {
+	t := report.NewTable("financial_report", "Финансовый отчет", from, to,
+		report.Column{Title: "Месяц", Type: report.Date},
+		report.Column{Title: "Продаж", Type: report.Integer, Total: true},
+		report.Column{Title: "Выручка до скидок", Type: report.Money, Total: true},
+		report.Column{Title: "Скидки", Type: report.Money, Total: true},
+		report.Column{Title: "Выручка от продаж", Type: report.Money, Total: true},
+		report.Column{Title: "Сервисных заказов", Type: report.Integer, Total: true},
+		report.Column{Title: "Выручка сервиса", Type: report.Money, Total: true},
+		report.Column{Title: "Доход всего", Type: report.Money, Total: true},
+	)
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH months AS (
+			SELECT generate_series(date_trunc('month', $1::date), date_trunc('month', $2::date),
+			                       interval '1 month')::date AS month
+		),
+		sales_by_month AS (
+			SELECT date_trunc('month', sale_date)::date AS month, COUNT(*) AS cnt,
+			       SUM(base_price) AS base, SUM(discount_amount) AS discounts, SUM(final_price) AS revenue
+			FROM sales
+			WHERE status = 'Завершена' AND sale_date BETWEEN $1 AND $2
+			GROUP BY 1
+		),
+		service_by_month AS (
+			SELECT date_trunc('month', completion_date)::date AS month, COUNT(*) AS cnt, SUM(cost) AS revenue
+			FROM service_orders
+			WHERE status = 'Завершен' AND completion_date BETWEEN $1 AND $2
+			GROUP BY 1
+		)
+		SELECT m.month,
+		       COALESCE(s.cnt, 0), COALESCE(s.base, 0), COALESCE(s.discounts, 0), COALESCE(s.revenue, 0),
+		       COALESCE(so.cnt, 0), COALESCE(so.revenue, 0),
+		       COALESCE(s.revenue, 0) + COALESCE(so.revenue, 0)
+		FROM months m
+		LEFT JOIN sales_by_month s ON s.month = m.month
+		LEFT JOIN service_by_month so ON so.month = m.month
+		ORDER BY m.month`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error querying financial report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			month                                           time.Time
+			salesCount, serviceCount                        int64
+			base, discounts, revenue, serviceRevenue, total float64
+		)
+		err := rows.Scan(&month, &salesCount, &base, &discounts, &revenue,
+			&serviceCount, &serviceRevenue, &total)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning financial row: %w", err)
+		}
+		t.AddRow(month, salesCount, base, discounts, revenue, serviceCount, serviceRevenue, total)
+	}
+
+	return t, rows.Err()
+}
+
+// inventoryTable - остатки техники из sp_generate_inventory_report.
+// Это срез на текущую дату, период выводится только в заголовке.
+func (s *ReportService) inventoryTable(ctx context.Context, from, to time.Time) (*report.Table, error) {
+	t := report.NewTable("inventory_report", "Отчет по инвентарю", from, to,
+		report.Column{Title: "Склад", Width: 18},
+		report.Column{Title: "Категория", Width: 16},
+		report.Column{Title: "Модель"},
+		report.Column{Title: "Статус", Width: 14},
+		report.Column{Title: "Кол-во", Type: report.Integer, Total: true},
+		report.Column{Title: "Средняя цена", Type: report.Money},
+		report.Column{Title: "Стоимость", Type: report.Money, Total: true},
+		report.Column{Title: "Первое поступление", Type: report.Date},
+		report.Column{Title: "Последнее поступление", Type: report.Date},
+	)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM sp_generate_inventory_report(NULL, NULL, NULL)`)
+	if err != nil {
+		return nil, fmt.Errorf("error generating inventory report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			warehouse, city, category, typeName, model string
+			status                                     sql.NullString
+			quantity                                   int64
+			avgPrice, totalValue                       float64
+			oldest, newest                             sql.NullTime
+		)
+		err := rows.Scan(&warehouse, &city, &category, &typeName, &model,
+			&quantity, &status, &avgPrice, &totalValue, &oldest, &newest)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning inventory: %w", err)
+		}
+		t.AddRow(warehouse+", "+city, category, model, status.String, quantity,
+			avgPrice, totalValue, nullTime(oldest), nullTime(newest))
+	}
+
+	return t, rows.Err()
+}
+
+// testDrivesTable - качество работы менеджеров на тест-драйвах за период:
+// исходы записей и оценки клиентов по ссылкам на отзыв
+func (s *ReportService) testDrivesTable(ctx context.Context, from, to time.Time) (*report.Table, error) {
+	t := report.NewTable("test_drives_report", "Качество тест-драйвов по менеджерам", from, to,
+		report.Column{Title: "Менеджер", Width: 24},
+		report.Column{Title: "Тест-драйвов", Type: report.Integer, Total: true},
+		report.Column{Title: "Проведено", Type: report.Integer, Total: true},
+		report.Column{Title: "Не явились", Type: report.Integer, Total: true},
+		report.Column{Title: "Отменено", Type: report.Integer, Total: true},
+		report.Column{Title: "Доля неявок", Type: report.Percent},
+		report.Column{Title: "Отзывов", Type: report.Integer, Total: true},
+		report.Column{Title: "Средняя оценка", Type: report.Decimal},
+	)
+
+	// scheduled_date хранится по Минску, период сравнивается по календарным дням
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.last_name || ' ' || e.first_name,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE td.status = 'Завершен'),
+		       COUNT(*) FILTER (WHERE td.status = 'Не явился'),
+		       COUNT(*) FILTER (WHERE td.status = 'Отменен'),
+		       COUNT(td.feedback_rating) FILTER (WHERE td.status = 'Завершен'),
+		       ROUND(AVG(td.feedback_rating) FILTER (WHERE td.status = 'Завершен'), 2)
+		FROM test_drives td
+		INNER JOIN employees e ON e.employee_id = td.employee_id
+		WHERE td.scheduled_date >= $1::date AND td.scheduled_date < $2::date + 1
+		GROUP BY e.employee_id, e.last_name, e.first_name
+		ORDER BY 7 DESC NULLS LAST, 2 DESC, 1`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error querying test drives report: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			manager                                    string
+			total, completed, noShow, cancelled, rated int64
+			rating                                     sql.NullFloat64
+		)
+		err := rows.Scan(&manager, &total, &completed, &noShow, &cancelled, &rated, &rating)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning test drives row: %w", err)
+		}
+
+		// Доля неявок считается среди тест-драйвов, которые должны были состояться
+		var noShowRate interface{}
+		if held := completed + noShow; held > 0 {
+			noShowRate = float64(noShow) * 100 / float64(held)
+		}
+		var avgRating interface{}
+		if rating.Valid {
+			avgRating = rating.Float64
+		}
+		t.AddRow(manager, total, completed, noShow, cancelled, noShowRate, rated, avgRating)
+	}
+
+	return t, rows.Err()
+}
+
+func nullTime(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+// ErrInvalidReservation - ошибка валидации брони
+var ErrInvalidReservation = errors.New("invalid reservation")
+
+const (
+	// DefaultReservationPeriod - срок брони, если он не указан
+	DefaultReservationPeriod = 3 * 24 * time.Hour
+	maxReservationPeriod     = 30 * 24 * time.Hour
+	// reservationExpiryInterval - как часто снимаются просроченные брони
+	reservationExpiryInterval = time.Minute
+)
+
+// ReservationService - бронирование техники за клиентами
+type ReservationService struct {
+	repo *repository.ReservationRepository
+}
+
+func NewReservationService(repo *repository.ReservationRepository) *ReservationService {
+	return &ReservationService{repo: repo}
+}
+
+// Run раз в минуту снимает просроченные брони до отмены ctx
+func (s *ReservationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(reservationExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.repo.ExpireDue(ctx); err != nil {
+			log.Printf("Reservations: %v", err)
+		} else if n > 0 {
+			log.Printf("Reservations: %d expired reservations released", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetAll возвращает брони с фильтром по статусу, технике и клиенту
+func (s *ReservationService) GetAll(ctx context.Context, status string, vehicleID, customerID, corporateClientID *int) ([]models.VehicleReservation, error) {
+	switch status {
+	case "", models.ReservationActive, models.ReservationPurchased, models.ReservationExpired, models.ReservationCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidReservation, status)
+	}
+	return s.repo.GetAll(ctx, status, vehicleID, customerID, corporateClientID)
+}
+
+// GetByID возвращает бронь по ID
+func (s *ReservationService) GetByID(ctx context.Context, id int) (*models.VehicleReservation, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Create бронирует технику за клиентом от имени менеджера
+func (s *ReservationService) Create(ctx context.Context, employeeID int, req *models.VehicleReservationRequest) (*models.VehicleReservation, error) {
+	if req.VehicleID <= 0 {
+		return nil, fmt.Errorf("%w: vehicle is required", ErrInvalidReservation)
+	}
+	if (req.CustomerID == nil) == (req.CorporateClientID == nil) {
+		return nil, fmt.Errorf("%w: exactly one of customer_id and corporate_client_id is required", ErrInvalidReservation)
+	}
+	if req.DepositAmount < 0 {
+		return nil, fmt.Errorf("%w: deposit cannot be negative", ErrInvalidReservation)
+	}
+
+	expiresAt := time.Now().Add(DefaultReservationPeriod)
+	if req.ExpiresAt != "" {
+		var err error
+		if expiresAt, err = parseReservationExpiry(req.ExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+	req.Notes = strings.TrimSpace(req.Notes)
+
+	id, err := s.repo.Create(ctx, req, expiresAt, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Extend продлевает действующую бронь
+func (s *ReservationService) Extend(ctx context.Context, id int, req *models.ExtendReservationRequest) (*models.VehicleReservation, error) {
+	expiresAt, err := parseReservationExpiry(req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Extend(ctx, id, expiresAt); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Cancel снимает бронь до срока
+func (s *ReservationService) Cancel(ctx context.Context, id int) (*models.VehicleReservation, error) {
+	if err := s.repo.Cancel(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// parseReservationExpiry разбирает срок брони в формате RFC 3339. Срок должен
+// быть в будущем и не дальше 30 дней.
+func parseReservationExpiry(value string) (time.Time, error) {
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: expires_at must be RFC 3339", ErrInvalidReservation)
+	}
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return time.Time{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidReservation)
+	}
+	if expiresAt.Sub(now) > maxReservationPeriod {
+		return time.Time{}, fmt.Errorf("%w: reservation cannot exceed %d days", ErrInvalidReservation, int(maxReservationPeriod.Hours()/24))
+	}
+	return expiresAt, nil
+}
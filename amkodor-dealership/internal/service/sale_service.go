@@ -5,9 +5,22 @@ import (
 	"database/sql"
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
+	"errors"
 	"fmt"
 )
 
+var (
+	// ErrVehicleUnavailable - техника не может быть продана в текущем статусе
+	ErrVehicleUnavailable = errors.New("vehicle is not available for sale")
+	// ErrVehicleInTransit - техника в пути: поставка или перемещение между складами
+	ErrVehicleInTransit = fmt.Errorf("%w: vehicle is in transit", ErrVehicleUnavailable)
+	// ErrVehicleReserved - техника забронирована за другим клиентом
+	ErrVehicleReserved = fmt.Errorf("%w: vehicle is reserved for another client", ErrVehicleUnavailable)
+	// ErrUseSaleCancellation - статус "Отменена" через обновление не ставится,
+	// для отмены есть DELETE /sales/{id}
+	ErrUseSaleCancellation = errors.New("use cancellation to cancel a sale")
+)
+
 type SaleService struct {
 	repo        repository.SaleRepository
 	vehicleRepo repository.VehicleRepository
@@ -18,7 +31,7 @@ func NewSaleService(repo repository.SaleRepository, vehicleRepo repository.Vehic
 }
 
 func (s *SaleService) GetAll(limit, offset int) ([]models.Sale, error) {
-	return s.repo.GetAll(context.Background())
+	return s.repo.GetAll(context.Background(), limit, offset)
 }
 
 func (s *SaleService) GetByID(id int) (*models.Sale, error) {
@@ -31,17 +44,21 @@ func (s *SaleService) Create(vehicleID int, customerID, corporateClientID *int,
 	// Проверка доступности техники
 	vehicle, err := s.vehicleRepo.GetByID(context.Background(), vehicleID)
 	if err != nil {
-		return 0, fmt.Errorf("vehicle not found: %w", err)
+		return 0, err
 	}
 
+	if vehicle.Status == "В пути" {
+		return 0, ErrVehicleInTransit
+	}
 	if vehicle.Status != "В наличии" && vehicle.Status != "Зарезервировано" {
-		return 0, fmt.Errorf("vehicle is not available for sale")
+		return 0, ErrVehicleUnavailable
 	}
 
 	sale := &models.Sale{
-		VehicleID:  vehicleID,
-		EmployeeID: employeeID,
-		PaymentType: paymentType,
+		VehicleID:          vehicleID,
+		EmployeeID:         employeeID,
+		PaymentType:        paymentType,
+		AdditionalDiscount: additionalDiscount,
 	}
 	
 	if customerID != nil {
@@ -60,10 +77,22 @@ func (s *SaleService) Create(vehicleID int, customerID, corporateClientID *int,
 		sale.Notes = sql.NullString{String: notes, Valid: true}
 	}
 	
-	return s.repo.Create(context.Background(), sale)
+	// Технику с действующей бронью покупает только клиент брони; это
+	// проверяет триггер validate_sale в транзакции продажи
+	saleID, err := s.repo.Create(context.Background(), sale)
+	switch {
+	case errors.Is(err, repository.ErrSaleVehicleReserved):
+		return 0, ErrVehicleReserved
+	case errors.Is(err, repository.ErrSaleVehicleUnavailable):
+		return 0, ErrVehicleUnavailable
+	}
+	return saleID, err
 }
 
 func (s *SaleService) Update(sale *models.Sale) error {
+	if sale.Status == "Отменена" {
+		return ErrUseSaleCancellation
+	}
 	return s.repo.Update(context.Background(), sale)
 }
 
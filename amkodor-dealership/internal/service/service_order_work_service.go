@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+// ErrInvalidServiceOrderWork - ошибка в запчасти или работе заказа
+var ErrInvalidServiceOrderWork = errors.New("invalid service order work")
+
+const (
+	// maxLabourDescription - длина service_order_labour.description
+	maxLabourDescription = 200
+	// maxServiceType - длина service_orders.service_type
+	maxServiceType = 100
+)
+
+// Статусы, которые ставятся правкой заказа; 'Завершен' - только через Complete
+var serviceOrderEditStatuses = map[string]bool{
+	models.ServiceOrderInProgress: true,
+	models.ServiceOrderSuspended:  true,
+	models.ServiceOrderCancelled:  true,
+}
+
+// ServiceOrderWorkService - запчасти, работы и завершение сервисных заказов
+type ServiceOrderWorkService struct {
+	repo *repository.ServiceOrderWorkRepository
+}
+
+func NewServiceOrderWorkService(repo *repository.ServiceOrderWorkRepository) *ServiceOrderWorkService {
+	return &ServiceOrderWorkService{repo: repo}
+}
+
+// GetByID возвращает карточку заказа с запчастями, работами и стоимостью
+func (s *ServiceOrderWorkService) GetByID(ctx context.Context, id int) (*models.ServiceOrderDetail, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Update меняет открытый заказ и возвращает его карточку
+func (s *ServiceOrderWorkService) Update(ctx context.Context, id int, req *models.UpdateServiceOrderRequest) (*models.ServiceOrderDetail, error) {
+	req.ServiceType = strings.TrimSpace(req.ServiceType)
+	req.Description = strings.TrimSpace(req.Description)
+	if req.EmployeeID < 0 {
+		return nil, fmt.Errorf("%w: invalid employee_id", ErrInvalidServiceOrderWork)
+	}
+	if utf8.RuneCountInString(req.ServiceType) > maxServiceType {
+		return nil, fmt.Errorf("%w: service_type is longer than %d characters", ErrInvalidServiceOrderWork, maxServiceType)
+	}
+	if req.Status == models.ServiceOrderCompleted {
+		return nil, fmt.Errorf("%w: use /complete to complete an order", ErrInvalidServiceOrderWork)
+	}
+	if req.Status != "" && !serviceOrderEditStatuses[req.Status] {
+		return nil, fmt.Errorf("%w: invalid status %q", ErrInvalidServiceOrderWork, req.Status)
+	}
+
+	if err := s.repo.Update(ctx, id, req); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// AddPart списывает запчасть со склада в открытый заказ и возвращает карточку заказа
+func (s *ServiceOrderWorkService) AddPart(ctx context.Context, orderID int, req *models.ServiceOrderPartRequest) (*models.ServiceOrderDetail, error) {
+	if req.SparePartID <= 0 {
+		return nil, fmt.Errorf("%w: spare_part_id is required", ErrInvalidServiceOrderWork)
+	}
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidServiceOrderWork)
+	}
+
+	if _, err := s.repo.AddPart(ctx, orderID, req.SparePartID, req.Quantity); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, orderID)
+}
+
+// RemovePart возвращает запчасть из открытого заказа на склад
+func (s *ServiceOrderWorkService) RemovePart(ctx context.Context, orderID, lineID int) (*models.ServiceOrderDetail, error) {
+	if err := s.repo.RemovePart(ctx, orderID, lineID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, orderID)
+}
+
+// AddLabour добавляет работу в открытый заказ. Без employee_id исполнителем
+// записывается сотрудник, добавивший работу.
+func (s *ServiceOrderWorkService) AddLabour(ctx context.Context, orderID, employeeID int, req *models.ServiceOrderLabourRequest) (*models.ServiceOrderDetail, error) {
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		return nil, fmt.Errorf("%w: description is required", ErrInvalidServiceOrderWork)
+	}
+	if utf8.RuneCountInString(description) > maxLabourDescription {
+		return nil, fmt.Errorf("%w: description is longer than %d characters", ErrInvalidServiceOrderWork, maxLabourDescription)
+	}
+	if req.Hours <= 0 {
+		return nil, fmt.Errorf("%w: hours must be positive", ErrInvalidServiceOrderWork)
+	}
+	if req.Rate < 0 {
+		return nil, fmt.Errorf("%w: rate must not be negative", ErrInvalidServiceOrderWork)
+	}
+
+	labour := &models.ServiceOrderLabour{
+		Description: description,
+		Hours:       req.Hours,
+		Rate:        req.Rate,
+		EmployeeID:  req.EmployeeID,
+	}
+	if labour.EmployeeID == nil {
+		labour.EmployeeID = &employeeID
+	}
+
+	if _, err := s.repo.AddLabour(ctx, orderID, labour); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, orderID)
+}
+
+// RemoveLabour удаляет работу из открытого заказа
+func (s *ServiceOrderWorkService) RemoveLabour(ctx context.Context, orderID, lineID int) (*models.ServiceOrderDetail, error) {
+	if err := s.repo.RemoveLabour(ctx, orderID, lineID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, orderID)
+}
+
+// Complete завершает заказ и возвращает его итоговую карточку
+func (s *ServiceOrderWorkService) Complete(ctx context.Context, id int) (*models.ServiceOrderDetail, error) {
+	if err := s.repo.Complete(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
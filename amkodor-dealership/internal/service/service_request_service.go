@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+// ErrInvalidServiceRequest - ошибка валидации заявки или данных заказа
+var ErrInvalidServiceRequest = errors.New("invalid service request")
+
+// Статусы, по которым сотрудники фильтруют заявки
+var serviceRequestStatuses = map[string]bool{
+	models.ServiceRequestPending:    true,
+	models.ServiceRequestInProgress: true,
+	models.ServiceRequestCompleted:  true,
+	models.ServiceRequestRejected:   true,
+	models.ServiceRequestCancelled:  true,
+}
+
+type ServiceRequestService struct {
+	repo *repository.ServiceRequestRepository
+}
+
+func NewServiceRequestService(repo *repository.ServiceRequestRepository) *ServiceRequestService {
+	return &ServiceRequestService{repo: repo}
+}
+
+// Create регистрирует заявку от имени пользователя
+func (s *ServiceRequestService) Create(ctx context.Context, userID int, req *models.CreateServiceRequestRequest) (*models.ServiceRequest, error) {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidServiceRequest)
+	}
+
+	sr := &models.ServiceRequest{
+		UserID:      userID,
+		Title:       title,
+		Description: strings.TrimSpace(req.Description),
+		ServiceType: strings.TrimSpace(req.ServiceType),
+	}
+	if err := s.repo.Create(ctx, sr); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+// GetByUser возвращает заявки пользователя
+func (s *ServiceRequestService) GetByUser(ctx context.Context, userID int) ([]models.ServiceRequest, error) {
+	return s.repo.GetByUser(ctx, userID)
+}
+
+// Cancel отменяет заявку пользователя, пока её не разобрали
+func (s *ServiceRequestService) Cancel(ctx context.Context, id, userID int) error {
+	return s.repo.Cancel(ctx, id, userID)
+}
+
+// GetAll возвращает заявки для сотрудников с фильтром по статусу
+func (s *ServiceRequestService) GetAll(ctx context.Context, status string) ([]models.ServiceRequest, error) {
+	if status != "" && !serviceRequestStatuses[status] {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidServiceRequest, status)
+	}
+	return s.repo.GetAll(ctx, status)
+}
+
+// GetByID возвращает заявку по ID
+func (s *ServiceRequestService) GetByID(ctx context.Context, id int) (*models.ServiceRequest, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Convert создаёт по заявке сервисный заказ с назначенной техникой и мастером.
+// Тип работ и описание по умолчанию берутся из заявки.
+func (s *ServiceRequestService) Convert(ctx context.Context, id, employeeID int, req *models.ConvertServiceRequestRequest) (*models.ServiceRequest, error) {
+	if req.VehicleID <= 0 || req.EmployeeID <= 0 {
+		return nil, fmt.Errorf("%w: vehicle and master are required", ErrInvalidServiceRequest)
+	}
+	if (req.CustomerID == nil) == (req.CorporateClientID == nil) {
+		return nil, fmt.Errorf("%w: exactly one of customer and corporate client is required", ErrInvalidServiceRequest)
+	}
+	if req.Cost < 0 {
+		return nil, fmt.Errorf("%w: cost must not be negative", ErrInvalidServiceRequest)
+	}
+
+	sr, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ServiceType = strings.TrimSpace(req.ServiceType)
+	if req.ServiceType == "" {
+		req.ServiceType = sr.ServiceType
+	}
+	if req.ServiceType == "" {
+		req.ServiceType = sr.Title
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		req.Description = sr.Description
+	}
+
+	if _, err := s.repo.Convert(ctx, id, employeeID, req); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Reject отклоняет заявку; комментарий увидит клиент
+func (s *ServiceRequestService) Reject(ctx context.Context, id, employeeID int, comment string) (*models.ServiceRequest, error) {
+	comment = strings.TrimSpace(comment)
+	if comment == "" {
+		return nil, fmt.Errorf("%w: rejection comment is required", ErrInvalidServiceRequest)
+	}
+	if err := s.repo.Reject(ctx, id, employeeID, comment); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
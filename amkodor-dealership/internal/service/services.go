@@ -1,8 +1,10 @@
 package service
 
 import (
+	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
 	"database/sql"
+	"time"
 )
 
 type Services struct {
@@ -18,15 +20,28 @@ type Services struct {
 	Service          *ServiceService
 	ServiceOrderRepo *repository.ServiceOrderRepository
 	Favorite         *FavoriteService
+	ServiceRequest   *ServiceRequestService
+	Account          *AccountService
+	LiveEvent        *LiveEventService
+	StockAlert       *StockAlertService
+	PurchaseOrder    *PurchaseOrderService
+	Movement         *SparePartMovementService
+	Supply           *SupplyService
+	Transfer         *VehicleTransferService
+	Reservation      *ReservationService
+	TestDrive        *TestDriveService
+	Calendar         *CalendarService
+	ServiceWork      *ServiceOrderWorkService
 }
 
-func NewServices(db *sql.DB, repos *repository.Repository) *Services {
-	return &Services{
+// NewServices создаёт сервисы; connStr нужен слушателю LISTEN/NOTIFY
+func NewServices(db *sql.DB, repos *repository.Repository, connStr string) *Services {
+	services := &Services{
 		Vehicle:          NewVehicleService(&repos.Vehicle),
 		Customer:         NewCustomerService(repos.Customer),
 		Sale:             NewSaleService(repos.Sale, repos.Vehicle),
 		Employee:         NewEmployeeService(repos.Employee),
-		Auth:             NewAuthService(&repos.User, "amkodor-secret-key-change-in-production"),
+		Auth:             NewAuthService(&repos.User, &repos.Employee, &repos.RefreshToken, "amkodor-secret-key-change-in-production", 15*time.Minute, 7*24*time.Hour),
 		Dashboard:        NewDashboardService(repos.Dashboard),
 		Report:           NewReportService(db),
 		Admin:            NewAdminService(),
@@ -34,5 +49,20 @@ func NewServices(db *sql.DB, repos *repository.Repository) *Services {
 		Service:          NewServiceService(repos.Service),
 		ServiceOrderRepo: repository.NewServiceOrderRepository(db),
 		Favorite:         NewFavoriteService(&repos.Favorite),
+		ServiceRequest:   NewServiceRequestService(&repos.ServiceRequest),
+		Account:          NewAccountService(&repos.ClientLink, &repos.Account, &repos.Favorite, LogVerificationSender{}),
+		LiveEvent:        NewLiveEventService(&repos.LiveEvent, connStr),
+		StockAlert:       NewStockAlertService(&repos.StockAlert, LogMailer{}),
+		PurchaseOrder:    NewPurchaseOrderService(&repos.PurchaseOrder, &repos.Supplier),
+		Movement:         NewSparePartMovementService(&repos.Movement),
+		Supply:           NewSupplyService(&repos.Supply),
+		Transfer:         NewVehicleTransferService(&repos.Transfer),
+		Reservation:      NewReservationService(&repos.Reservation),
+		TestDrive:        NewTestDriveService(&repos.TestDrive, &repos.ClientLink, LogMailer{}, ""),
+		ServiceWork:      NewServiceOrderWorkService(&repos.ServiceWork),
 	}
+	services.Calendar = NewCalendarService(&repos.CalendarFeed, services.TestDrive, "")
+	services.LiveEvent.OnEvent(models.LiveEventSparePartLowStock, services.StockAlert.HandleLowStock)
+
+	return services
 }
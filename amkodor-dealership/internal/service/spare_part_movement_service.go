@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+// ErrInvalidMovement - ошибка валидации движения запчасти
+var ErrInvalidMovement = errors.New("invalid spare part movement")
+
+// Типы движений в журнале; приход записывается заказами поставщикам,
+// расход и возврат - сервисными заказами, остальные - вручную
+var movementTypes = map[string]bool{
+	models.MovementReceipt:     true,
+	models.MovementConsumption: true,
+	models.MovementReturn:      true,
+	models.MovementAdjustment:  true,
+	models.MovementTransfer:    true,
+	models.MovementWriteOff:    true,
+}
+
+// SparePartMovementService - журнал движений запчастей и сверка остатков
+type SparePartMovementService struct {
+	repo *repository.SparePartMovementRepository
+}
+
+func NewSparePartMovementService(repo *repository.SparePartMovementRepository) *SparePartMovementService {
+	return &SparePartMovementService{repo: repo}
+}
+
+// GetHistory возвращает страницу истории движений запчасти
+func (s *SparePartMovementService) GetHistory(ctx context.Context, sparePartID int, movementType string, page, perPage int) ([]models.SparePartMovement, models.Pagination, error) {
+	if movementType != "" && !movementTypes[movementType] {
+		return nil, models.Pagination{}, fmt.Errorf("%w: unknown movement type %q", ErrInvalidMovement, movementType)
+	}
+
+	movements, total, err := s.repo.GetByPart(ctx, sparePartID, movementType, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	pagination := models.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		TotalItems: total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+
+	return movements, pagination, nil
+}
+
+// Record записывает ручное движение от имени сотрудника: корректировку,
+// списание или перемещение на другой склад. Возвращает ID движения.
+func (s *SparePartMovementService) Record(ctx context.Context, sparePartID, employeeID int, req *models.SparePartMovementRequest) (int64, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return 0, fmt.Errorf("%w: reason is required", ErrInvalidMovement)
+	}
+
+	m := &models.SparePartMovement{
+		SparePartID: sparePartID,
+		Type:        req.Type,
+		Quantity:    req.Quantity,
+		EmployeeID:  &employeeID,
+		Reason:      reason,
+	}
+
+	switch req.Type {
+	case models.MovementAdjustment:
+		if req.Quantity == 0 {
+			return 0, fmt.Errorf("%w: quantity must not be zero", ErrInvalidMovement)
+		}
+	case models.MovementWriteOff:
+		if req.Quantity <= 0 {
+			return 0, fmt.Errorf("%w: quantity must be positive", ErrInvalidMovement)
+		}
+		m.Quantity = -req.Quantity
+	case models.MovementTransfer:
+		if req.Quantity <= 0 {
+			return 0, fmt.Errorf("%w: quantity must be positive", ErrInvalidMovement)
+		}
+		if req.TargetSparePartID <= 0 || req.TargetSparePartID == sparePartID {
+			return 0, fmt.Errorf("%w: target spare part is required", ErrInvalidMovement)
+		}
+		return s.repo.Transfer(ctx, sparePartID, req.TargetSparePartID, req.Quantity, employeeID, reason)
+	default:
+		return 0, fmt.Errorf("%w: movement type must be adjustment, write_off or transfer", ErrInvalidMovement)
+	}
+
+	return s.repo.Record(ctx, m)
+}
+
+// GetStock возвращает остатки по журналу рядом с хранимыми
+func (s *SparePartMovementService) GetStock(ctx context.Context, warehouseID *int) ([]models.SparePartStock, error) {
+	return s.repo.GetStock(ctx, warehouseID, false)
+}
+
+// Reconcile возвращает запчасти, у которых хранимый остаток расходится с журналом
+func (s *SparePartMovementService) Reconcile(ctx context.Context, warehouseID *int) ([]models.SparePartStock, error) {
+	return s.repo.GetStock(ctx, warehouseID, true)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+var ErrInvalidStockAlertStatus = errors.New("invalid stock alert status")
+
+// stockAlertMailTimeout ограничивает отправку одного письма
+const stockAlertMailTimeout = 30 * time.Second
+
+// StockAlertService превращает события о низком остатке в оповещения
+// и сообщает о новых оповещениях заведующему складом
+type StockAlertService struct {
+	repo   *repository.StockAlertRepository
+	mailer Mailer
+}
+
+func NewStockAlertService(repo *repository.StockAlertRepository, mailer Mailer) *StockAlertService {
+	return &StockAlertService{repo: repo, mailer: mailer}
+}
+
+// HandleLowStock - обработчик события spare_part.low_stock. Письмо
+// отправляется только для нового оповещения, повторы лишь учитываются.
+func (s *StockAlertService) HandleLowStock(ctx context.Context, event models.LiveEvent) {
+	var payload struct {
+		SparePartID int `json:"spare_part_id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil || payload.SparePartID == 0 {
+		log.Printf("Stock alerts: invalid payload of event %d", event.ID)
+		return
+	}
+
+	id, created, err := s.repo.Raise(ctx, payload.SparePartID)
+	if err != nil {
+		log.Printf("Stock alerts: %v", err)
+		return
+	}
+	if created {
+		go s.notify(id)
+	}
+}
+
+// RaiseMissing создаёт оповещения по запчастям, остаток которых опустился до
+// минимума, пока сервер не работал
+func (s *StockAlertService) RaiseMissing(ctx context.Context) error {
+	ids, err := s.repo.RaiseMissing(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for _, id := range ids {
+			s.notify(id)
+		}
+	}()
+	return nil
+}
+
+// GetAll возвращает оповещения. status: active (по умолчанию - открытые и
+// принятые), open, acknowledged, resolved или all.
+func (s *StockAlertService) GetAll(ctx context.Context, status string, warehouseID *int) ([]models.StockAlert, error) {
+	var statuses []string
+	switch status {
+	case "", "active":
+		statuses = []string{models.StockAlertOpen, models.StockAlertAcknowledged}
+	case models.StockAlertOpen, models.StockAlertAcknowledged, models.StockAlertResolved:
+		statuses = []string{status}
+	case "all":
+	default:
+		return nil, ErrInvalidStockAlertStatus
+	}
+
+	return s.repo.GetAll(ctx, statuses, warehouseID)
+}
+
+// Acknowledge отмечает оповещение как принятое сотрудником
+func (s *StockAlertService) Acknowledge(ctx context.Context, id, employeeID int) (*models.StockAlert, error) {
+	if err := s.repo.Acknowledge(ctx, id, employeeID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Resolve закрывает оповещение. Следующее падение остатка создаст новое.
+func (s *StockAlertService) Resolve(ctx context.Context, id, employeeID int) (*models.StockAlert, error) {
+	if err := s.repo.Resolve(ctx, id, employeeID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// notify отправляет письмо о новом оповещении заведующему складом
+func (s *StockAlertService) notify(alertID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), stockAlertMailTimeout)
+	defer cancel()
+
+	alert, err := s.repo.GetByID(ctx, alertID)
+	if err != nil {
+		log.Printf("Stock alerts: %v", err)
+		return
+	}
+
+	contact, err := s.repo.GetManagerContact(ctx, alert.WarehouseID)
+	if err != nil {
+		log.Printf("Stock alerts: %v", err)
+		return
+	}
+	if contact.Email == "" {
+		log.Printf("Stock alerts: no email for manager %q of warehouse %d", contact.ManagerName, alert.WarehouseID)
+		return
+	}
+
+	subject := fmt.Sprintf("Низкий остаток: %s (%s)", alert.PartName, alert.PartNumber)
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s, добрый день.\n\n", contact.ManagerName)
+	fmt.Fprintf(&body, "На складе «%s» остаток запчасти опустился до минимума.\n\n", contact.WarehouseName)
+	fmt.Fprintf(&body, "Запчасть: %s\nАртикул: %s\n", alert.PartName, alert.PartNumber)
+	fmt.Fprintf(&body, "Остаток: %d, минимум: %d\n\n", alert.QuantityInStock, alert.MinQuantity)
+	fmt.Fprintf(&body, "Оповещение №%d можно принять и закрыть в панели администратора.\n", alert.ID)
+
+	if err := s.mailer.SendMail(ctx, contact.Email, subject, body.String()); err != nil {
+		log.Printf("Stock alerts: %v", err)
+		return
+	}
+	if err := s.repo.MarkNotified(ctx, alertID, contact.Email); err != nil {
+		log.Printf("Stock alerts: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+// ErrInvalidSupply - ошибка валидации поставки техники
+var ErrInvalidSupply = errors.New("invalid supply")
+
+// Самый ранний год выпуска, который принимается при заведении техники
+const minManufactureYear = 1950
+
+// SupplyService - заказ техники у производителей и приём поставок на склад
+type SupplyService struct {
+	repo *repository.SupplyRepository
+}
+
+func NewSupplyService(repo *repository.SupplyRepository) *SupplyService {
+	return &SupplyService{repo: repo}
+}
+
+// GetAll возвращает поставки с фильтром по статусу, складу и производителю
+func (s *SupplyService) GetAll(ctx context.Context, status string, warehouseID, manufacturerID *int) ([]models.Supply, error) {
+	switch status {
+	case "", models.SupplyOrdered, models.SupplyInTransit, models.SupplyReceived, models.SupplyCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidSupply, status)
+	}
+	return s.repo.GetAll(ctx, status, warehouseID, manufacturerID)
+}
+
+// GetByID возвращает поставку со строками и заведённой техникой
+func (s *SupplyService) GetByID(ctx context.Context, id int) (*models.Supply, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Create оформляет заказ техники у производителя от имени сотрудника
+func (s *SupplyService) Create(ctx context.Context, employeeID int, req *models.SupplyRequest) (*models.Supply, error) {
+	if req.ManufacturerID <= 0 || req.WarehouseID <= 0 {
+		return nil, fmt.Errorf("%w: manufacturer and warehouse are required", ErrInvalidSupply)
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("%w: at least one item is required", ErrInvalidSupply)
+	}
+	for _, item := range req.Items {
+		if item.ModelID <= 0 || item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: model and positive quantity are required", ErrInvalidSupply)
+		}
+		if item.UnitPrice < 0 {
+			return nil, fmt.Errorf("%w: unit price cannot be negative", ErrInvalidSupply)
+		}
+	}
+
+	var expectedDate *time.Time
+	if req.ExpectedArrivalDate != "" {
+		date, err := time.Parse("2006-01-02", req.ExpectedArrivalDate)
+		if err != nil {
+			return nil, fmt.Errorf("%w: expected arrival date must be YYYY-MM-DD", ErrInvalidSupply)
+		}
+		if date.Before(time.Now().Truncate(24 * time.Hour)) {
+			return nil, fmt.Errorf("%w: expected arrival date is in the past", ErrInvalidSupply)
+		}
+		expectedDate = &date
+	}
+
+	req.InvoiceNumber = strings.TrimSpace(req.InvoiceNumber)
+	req.Notes = strings.TrimSpace(req.Notes)
+
+	id, err := s.repo.Create(ctx, req, expectedDate, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Cancel отменяет поставку, пока она не отгружена
+func (s *SupplyService) Cancel(ctx context.Context, id int) (*models.Supply, error) {
+	if err := s.repo.Cancel(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Ship отмечает отгрузку поставки производителем. Переданные единицы
+// заводятся на склад назначения со статусом 'В пути'.
+func (s *SupplyService) Ship(ctx context.Context, id int, req *models.SupplyUnitsRequest) (*models.Supply, error) {
+	if err := validateSupplyUnits(req.Units); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Ship(ctx, id, strings.TrimSpace(req.InvoiceNumber), req.Units); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Receive принимает поставку на склад. Единицы, не заведённые при отгрузке,
+// передаются в запросе; после приёма вся техника поставки 'В наличии'.
+func (s *SupplyService) Receive(ctx context.Context, id, employeeID int, req *models.SupplyUnitsRequest) (*models.Supply, error) {
+	if err := validateSupplyUnits(req.Units); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Receive(ctx, id, employeeID, strings.TrimSpace(req.InvoiceNumber), req.Units); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// validateSupplyUnits проверяет заводимые единицы и нормализует строковые поля
+func validateSupplyUnits(units []models.SupplyUnit) error {
+	maxYear := time.Now().Year() + 1
+	vins := make(map[string]bool, len(units))
+
+	for i := range units {
+		u := &units[i]
+		u.SerialNumber = strings.TrimSpace(u.SerialNumber)
+		u.VIN = strings.ToUpper(strings.TrimSpace(u.VIN))
+		u.Color = strings.TrimSpace(u.Color)
+
+		switch {
+		case u.SupplyItemID <= 0:
+			return fmt.Errorf("%w: supply item is required", ErrInvalidSupply)
+		case u.SerialNumber == "":
+			return fmt.Errorf("%w: serial number is required", ErrInvalidSupply)
+		case u.ManufactureYear < minManufactureYear || u.ManufactureYear > maxYear:
+			return fmt.Errorf("%w: manufacture year must be between %d and %d", ErrInvalidSupply, minManufactureYear, maxYear)
+		case u.Price != nil && *u.Price < 0:
+			return fmt.Errorf("%w: price cannot be negative", ErrInvalidSupply)
+		}
+
+		if u.VIN != "" {
+			if vins[u.VIN] {
+				return fmt.Errorf("%w: VIN %s is listed twice", ErrInvalidSupply, u.VIN)
+			}
+			vins[u.VIN] = true
+		}
+	}
+	return nil
+}
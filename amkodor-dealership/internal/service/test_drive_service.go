@@ -0,0 +1,650 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+	"amkodor-dealership/internal/utils"
+)
+
+var (
+	// ErrInvalidTestDrive - ошибка валидации записи на тест-драйв
+	ErrInvalidTestDrive = errors.New("invalid test drive")
+	// ErrTestDriveManager - сотрудник не является действующим менеджером по продажам
+	ErrTestDriveManager = errors.New("employee is not an active sales manager")
+	// ErrTestDriveOutsideHours - время вне рабочего времени менеджера
+	ErrTestDriveOutsideHours = errors.New("test drive is outside manager working hours")
+	// ErrNoFreeManager - на это время нет свободного менеджера
+	ErrNoFreeManager = errors.New("no manager is free at this time")
+	// ErrTestDriveNotStarted - завершить тест-драйв или отметить неявку можно только после начала
+	ErrTestDriveNotStarted = errors.New("test drive has not started yet")
+	// ErrInvalidFeedback - оценка вне диапазона 1-5 или слишком длинный комментарий
+	ErrInvalidFeedback = errors.New("invalid test drive feedback")
+)
+
+const (
+	// DefaultTestDriveDuration - длительность тест-драйва в минутах, если она не указана
+	DefaultTestDriveDuration = 60
+	minTestDriveDuration     = 15
+	maxTestDriveDuration     = 240
+	// testDriveSlotStep - шаг начала слотов от начала рабочего дня
+	testDriveSlotStep = 30 * time.Minute
+	// testDriveBookingDays - на сколько дней вперёд открыта запись
+	testDriveBookingDays = 60
+	// testDriveFeedbackDays - сколько дней действует ссылка на отзыв
+	testDriveFeedbackDays = 30
+	// maxFeedbackComment - максимальная длина комментария в символах
+	maxFeedbackComment = 2000
+	// testDriveMailTimeout - ограничение на отправку письма со ссылкой на отзыв
+	testDriveMailTimeout = 30 * time.Second
+)
+
+// defaultWorkingHours - рабочее время менеджера без своего расписания: пн-пт 09:00-18:00
+var defaultWorkingHours = []models.WorkingHours{
+	{Weekday: 1, Start: "09:00", End: "18:00"},
+	{Weekday: 2, Start: "09:00", End: "18:00"},
+	{Weekday: 3, Start: "09:00", End: "18:00"},
+	{Weekday: 4, Start: "09:00", End: "18:00"},
+	{Weekday: 5, Start: "09:00", End: "18:00"},
+}
+
+// TestDriveService - запись на тест-драйвы по свободным слотам техники и менеджеров
+type TestDriveService struct {
+	repo   *repository.TestDriveRepository
+	links  *repository.ClientLinkRepository
+	mailer Mailer
+	// publicURL - адрес сайта, от которого строятся ссылки на отзыв
+	publicURL string
+}
+
+func NewTestDriveService(repo *repository.TestDriveRepository, links *repository.ClientLinkRepository, mailer Mailer, publicURL string) *TestDriveService {
+	return &TestDriveService{repo: repo, links: links, mailer: mailer, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// GetAll возвращает тест-драйвы с фильтром по статусу, технике, менеджеру и
+// дате (YYYY-MM-DD по Минску)
+func (s *TestDriveService) GetAll(ctx context.Context, status string, vehicleID, employeeID *int, date string) ([]models.TestDriveBooking, error) {
+	switch status {
+	case "", models.TestDriveScheduled, models.TestDriveCompleted, models.TestDriveCancelled, models.TestDriveNoShow:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidTestDrive, status)
+	}
+
+	var from, to *time.Time
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, utils.DealershipLocation)
+		if err != nil {
+			return nil, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidTestDrive)
+		}
+		next := day.AddDate(0, 0, 1)
+		from, to = &day, &next
+	}
+	return s.repo.GetAll(ctx, status, vehicleID, employeeID, from, to)
+}
+
+// GetByID возвращает тест-драйв по ID
+func (s *TestDriveService) GetByID(ctx context.Context, id int) (*models.TestDriveBooking, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Availability возвращает свободные слоты на дату (YYYY-MM-DD по Минску, по
+// умолчанию сегодня) для техники, менеджера или обоих сразу. Слот свободен,
+// если техника не занята и хотя бы один менеджер работает и не занят.
+func (s *TestDriveService) Availability(ctx context.Context, vehicleID, employeeID *int, date string, duration int) (*models.TestDriveAvailability, error) {
+	if vehicleID == nil && employeeID == nil {
+		return nil, fmt.Errorf("%w: vehicle_id or employee_id is required", ErrInvalidTestDrive)
+	}
+	duration, err := normalizeTestDriveDuration(duration)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(utils.DealershipLocation)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.DealershipLocation)
+	day := today
+	if date != "" {
+		if day, err = time.ParseInLocation("2006-01-02", date, utils.DealershipLocation); err != nil {
+			return nil, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidTestDrive)
+		}
+	}
+
+	availability := &models.TestDriveAvailability{
+		Date:       day.Format("2006-01-02"),
+		TimeZone:   utils.DealershipLocation.String(),
+		VehicleID:  vehicleID,
+		EmployeeID: employeeID,
+		Duration:   duration,
+		Slots:      []models.TestDriveSlot{},
+	}
+	if day.Before(today) || day.After(today.AddDate(0, 0, testDriveBookingDays)) {
+		return availability, nil
+	}
+
+	managers, err := s.candidates(ctx, vehicleID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := s.loadSchedule(ctx, vehicleID, managers, day, day.AddDate(0, 0, 1), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	dayStart, dayEnd, ok := schedule.dayWindow(day)
+	if !ok {
+		return availability, nil
+	}
+	length := time.Duration(duration) * time.Minute
+	for start := dayStart; !start.Add(length).After(dayEnd); start = start.Add(testDriveSlotStep) {
+		end := start.Add(length)
+		if !start.After(now) || !schedule.vehicleFree(start, end) {
+			continue
+		}
+		if free := schedule.freeManagers(start, end); len(free) > 0 {
+			availability.Slots = append(availability.Slots, models.TestDriveSlot{Start: start, End: end, Managers: free})
+		}
+	}
+
+	return availability, nil
+}
+
+// Book записывает клиента на тест-драйв от имени сотрудника
+func (s *TestDriveService) Book(ctx context.Context, req *models.BookTestDriveRequest) (*models.TestDriveBooking, error) {
+	if (req.CustomerID == nil) == (req.CorporateClientID == nil) {
+		return nil, fmt.Errorf("%w: exactly one of customer_id and corporate_client_id is required", ErrInvalidTestDrive)
+	}
+	return s.book(ctx, req, nil)
+}
+
+// SelfBook записывает на тест-драйв клиента, к которому привязан пользователь
+func (s *TestDriveService) SelfBook(ctx context.Context, userID int, req *models.SelfBookTestDriveRequest) (*models.TestDriveBooking, error) {
+	link, err := s.links.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.book(ctx, &models.BookTestDriveRequest{
+		VehicleID:         req.VehicleID,
+		CustomerID:        link.CustomerID,
+		CorporateClientID: link.CorporateClientID,
+		EmployeeID:        req.EmployeeID,
+		ScheduledDate:     req.ScheduledDate,
+		Duration:          req.Duration,
+	}, &userID)
+}
+
+func (s *TestDriveService) book(ctx context.Context, req *models.BookTestDriveRequest, bookedBy *int) (*models.TestDriveBooking, error) {
+	if req.VehicleID <= 0 {
+		return nil, fmt.Errorf("%w: vehicle is required", ErrInvalidTestDrive)
+	}
+	duration, err := normalizeTestDriveDuration(req.Duration)
+	if err != nil {
+		return nil, err
+	}
+	start, err := validateTestDriveStart(req.ScheduledDate)
+	if err != nil {
+		return nil, err
+	}
+	end := start.Add(time.Duration(duration) * time.Minute)
+
+	managers, err := s.candidates(ctx, &req.VehicleID, req.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := s.loadSchedule(ctx, &req.VehicleID, managers, start, end, 0)
+	if err != nil {
+		return nil, err
+	}
+	employeeID, err := schedule.pick(req.EmployeeID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ScheduledDate, req.Duration = start, duration
+	id, err := s.repo.Create(ctx, req, employeeID, bookedBy)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Reschedule переносит запланированный тест-драйв на другое время. Без
+// employee_id тест-драйв остаётся за прежним менеджером.
+func (s *TestDriveService) Reschedule(ctx context.Context, id int, req *models.RescheduleTestDriveRequest) (*models.TestDriveBooking, error) {
+	td, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if td.Status != models.TestDriveScheduled {
+		return nil, repository.ErrTestDriveState
+	}
+
+	duration := td.Duration
+	if req.Duration != 0 {
+		if duration, err = normalizeTestDriveDuration(req.Duration); err != nil {
+			return nil, err
+		}
+	}
+	start, err := validateTestDriveStart(req.ScheduledDate)
+	if err != nil {
+		return nil, err
+	}
+	end := start.Add(time.Duration(duration) * time.Minute)
+
+	employeeID := &td.EmployeeID
+	if req.EmployeeID != nil {
+		employeeID = req.EmployeeID
+	}
+	managers, err := s.candidates(ctx, &td.VehicleID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := s.loadSchedule(ctx, &td.VehicleID, managers, start, end, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := schedule.pick(employeeID, start, end); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Reschedule(ctx, id, start, duration, *employeeID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Cancel отменяет запланированный тест-драйв
+func (s *TestDriveService) Cancel(ctx context.Context, id int, reason string) (*models.TestDriveBooking, error) {
+	if err := s.repo.SetStatus(ctx, id, models.TestDriveCancelled, strings.TrimSpace(reason)); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetMine возвращает тест-драйв клиента, к которому привязан пользователь.
+// Чужой тест-драйв считается не найденным.
+func (s *TestDriveService) GetMine(ctx context.Context, userID, id int) (*models.TestDriveBooking, error) {
+	link, err := s.links.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	td, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !sameClient(td.CustomerID, link.CustomerID) || !sameClient(td.CorporateClientID, link.CorporateClientID) {
+		return nil, repository.ErrTestDriveNotFound
+	}
+	return td, nil
+}
+
+// sameClient сравнивает необязательные идентификаторы клиента
+func sameClient(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// CancelMine отменяет тест-драйв клиента, к которому привязан пользователь
+func (s *TestDriveService) CancelMine(ctx context.Context, userID, id int, reason string) (*models.TestDriveBooking, error) {
+	if _, err := s.GetMine(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.Cancel(ctx, id, reason)
+}
+
+// Complete отмечает, что тест-драйв состоялся, и создаёт ссылку на отзыв.
+// Ссылка возвращается в feedback_url и отправляется клиенту на email.
+func (s *TestDriveService) Complete(ctx context.Context, id int) (*models.TestDriveBooking, error) {
+	td, err := s.startedTestDrive(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newLinkToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Complete(ctx, id, hashLinkToken(token), testDriveFeedbackDays); err != nil {
+		return nil, err
+	}
+
+	td, err = s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	td.FeedbackURL = s.publicURL + "/feedback/" + token
+	if td.ClientEmail != "" {
+		go s.sendFeedbackLink(*td)
+	}
+	return td, nil
+}
+
+// NoShow отмечает, что клиент не явился на тест-драйв
+func (s *TestDriveService) NoShow(ctx context.Context, id int) (*models.TestDriveBooking, error) {
+	return s.closeStarted(ctx, id, models.TestDriveNoShow)
+}
+
+func (s *TestDriveService) closeStarted(ctx context.Context, id int, status string) (*models.TestDriveBooking, error) {
+	if _, err := s.startedTestDrive(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetStatus(ctx, id, status, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// startedTestDrive возвращает запланированный тест-драйв, время которого уже наступило
+func (s *TestDriveService) startedTestDrive(ctx context.Context, id int) (*models.TestDriveBooking, error) {
+	td, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if td.Status != models.TestDriveScheduled {
+		return nil, repository.ErrTestDriveState
+	}
+	if time.Now().Before(td.ScheduledDate) {
+		return nil, ErrTestDriveNotStarted
+	}
+	return td, nil
+}
+
+// GetFeedbackForm возвращает тест-драйв по токену из ссылки на отзыв
+func (s *TestDriveService) GetFeedbackForm(ctx context.Context, token string) (*models.TestDriveFeedbackForm, error) {
+	return s.repo.GetFeedbackForm(ctx, hashLinkToken(token))
+}
+
+// SubmitFeedback сохраняет отзыв клиента по ссылке
+func (s *TestDriveService) SubmitFeedback(ctx context.Context, token string, req *models.TestDriveFeedbackRequest) error {
+	if req.Rating < 1 || req.Rating > 5 {
+		return fmt.Errorf("%w: оценка должна быть от 1 до 5", ErrInvalidFeedback)
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if len([]rune(comment)) > maxFeedbackComment {
+		return fmt.Errorf("%w: комментарий длиннее %d символов", ErrInvalidFeedback, maxFeedbackComment)
+	}
+	return s.repo.SubmitFeedback(ctx, hashLinkToken(token), req.Rating, comment)
+}
+
+// sendFeedbackLink отправляет клиенту письмо со ссылкой на отзыв. Ошибки
+// отправки только пишутся в лог: ссылка уже возвращена менеджеру.
+func (s *TestDriveService) sendFeedbackLink(td models.TestDriveBooking) {
+	ctx, cancel := context.WithTimeout(context.Background(), testDriveMailTimeout)
+	defer cancel()
+
+	subject := fmt.Sprintf("Оцените тест-драйв %s", td.ModelName)
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s, добрый день.\n\n", td.ClientName)
+	fmt.Fprintf(&body, "Спасибо, что приехали на тест-драйв %s %s.\n",
+		td.ModelName, td.ScheduledDate.Format("02.01.2006"))
+	fmt.Fprintf(&body, "Расскажите, как всё прошло: %s\n\n", td.FeedbackURL)
+	fmt.Fprintf(&body, "Ссылка действует %d дней.\n", testDriveFeedbackDays)
+
+	if err := s.mailer.SendMail(ctx, td.ClientEmail, subject, body.String()); err != nil {
+		log.Printf("Test drive feedback: %v", err)
+	}
+}
+
+// newLinkToken возвращает случайный токен для ссылки, открываемой без входа
+// (отзыв о тест-драйве, календарная подписка). В базе хранится только его хеш.
+func newLinkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating link token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetWorkingHours возвращает расписание менеджера или рабочее время по умолчанию
+func (s *TestDriveService) GetWorkingHours(ctx context.Context, employeeID int) ([]models.WorkingHours, error) {
+	if _, err := s.candidates(ctx, nil, &employeeID); err != nil {
+		return nil, err
+	}
+	hours, err := s.repo.GetWorkingHours(ctx, []int{employeeID})
+	if err != nil {
+		return nil, err
+	}
+	if h, ok := hours[employeeID]; ok {
+		return h, nil
+	}
+	return defaultWorkingHours, nil
+}
+
+// SetWorkingHours заменяет расписание менеджера. Пустой список возвращает
+// рабочее время по умолчанию.
+func (s *TestDriveService) SetWorkingHours(ctx context.Context, employeeID int, hours []models.WorkingHours) ([]models.WorkingHours, error) {
+	if _, err := s.candidates(ctx, nil, &employeeID); err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool)
+	for _, h := range hours {
+		if h.Weekday < 1 || h.Weekday > 7 {
+			return nil, fmt.Errorf("%w: weekday must be from 1 (Monday) to 7 (Sunday)", ErrInvalidTestDrive)
+		}
+		if seen[h.Weekday] {
+			return nil, fmt.Errorf("%w: weekday %d is listed twice", ErrInvalidTestDrive, h.Weekday)
+		}
+		seen[h.Weekday] = true
+
+		start, errStart := time.Parse("15:04", h.Start)
+		end, errEnd := time.Parse("15:04", h.End)
+		if errStart != nil || errEnd != nil {
+			return nil, fmt.Errorf("%w: working hours must be HH:MM", ErrInvalidTestDrive)
+		}
+		if !start.Before(end) {
+			return nil, fmt.Errorf("%w: working day must end after it starts", ErrInvalidTestDrive)
+		}
+	}
+
+	if err := s.repo.SetWorkingHours(ctx, employeeID, hours); err != nil {
+		return nil, err
+	}
+	return s.GetWorkingHours(ctx, employeeID)
+}
+
+// candidates возвращает менеджеров, которые могут провести тест-драйв: при
+// указанном employeeID - только его, иначе менеджеров склада техники.
+// Техника должна быть доступна для тест-драйва.
+func (s *TestDriveService) candidates(ctx context.Context, vehicleID, employeeID *int) ([]models.TestDriveManager, error) {
+	var warehouseID *int
+	if vehicleID != nil {
+		wh, status, err := s.repo.GetVehicle(ctx, *vehicleID)
+		if err != nil {
+			return nil, err
+		}
+		if status != "В наличии" && status != "Зарезервировано" {
+			return nil, fmt.Errorf("%w: vehicle status is %s", repository.ErrTestDriveVehicle, status)
+		}
+		if employeeID == nil {
+			warehouseID = &wh
+		}
+	}
+
+	managers, err := s.repo.GetManagers(ctx, warehouseID, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	if employeeID != nil && len(managers) == 0 {
+		return nil, ErrTestDriveManager
+	}
+	return managers, nil
+}
+
+// loadSchedule собирает расписание и занятость менеджеров и техники в [from, to)
+func (s *TestDriveService) loadSchedule(ctx context.Context, vehicleID *int, managers []models.TestDriveManager, from, to time.Time, excludeID int) (*testDriveSchedule, error) {
+	ids := make([]int, len(managers))
+	for i, m := range managers {
+		ids[i] = m.ID
+	}
+
+	hours, err := s.repo.GetWorkingHours(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	busy, err := s.repo.GetBusy(ctx, vehicleID, ids, from, to, excludeID)
+	if err != nil {
+		return nil, err
+	}
+	return &testDriveSchedule{vehicleID: vehicleID, managers: managers, hours: hours, busy: busy}, nil
+}
+
+// testDriveSchedule - рабочее время и занятость техники и менеджеров
+type testDriveSchedule struct {
+	vehicleID *int
+	managers  []models.TestDriveManager
+	hours     map[int][]models.WorkingHours
+	busy      []repository.TestDriveInterval
+}
+
+// pick выбирает менеджера на [start, end): указанного, если он работает и
+// свободен, иначе первого свободного
+func (sc *testDriveSchedule) pick(employeeID *int, start, end time.Time) (int, error) {
+	if !sc.vehicleFree(start, end) {
+		return 0, repository.ErrTestDriveVehicleBusy
+	}
+	if employeeID != nil {
+		if !sc.works(*employeeID, start, end) {
+			return 0, ErrTestDriveOutsideHours
+		}
+		if !sc.managerFree(*employeeID, start, end) {
+			return 0, repository.ErrTestDriveManagerBusy
+		}
+		return *employeeID, nil
+	}
+
+	free := sc.freeManagers(start, end)
+	if len(free) == 0 {
+		return 0, ErrNoFreeManager
+	}
+	return free[0].ID, nil
+}
+
+func (sc *testDriveSchedule) freeManagers(start, end time.Time) []models.TestDriveManager {
+	free := []models.TestDriveManager{}
+	for _, m := range sc.managers {
+		if sc.works(m.ID, start, end) && sc.managerFree(m.ID, start, end) {
+			free = append(free, m)
+		}
+	}
+	return free
+}
+
+func (sc *testDriveSchedule) vehicleFree(start, end time.Time) bool {
+	if sc.vehicleID == nil {
+		return true
+	}
+	for _, b := range sc.busy {
+		if b.VehicleID == *sc.vehicleID && b.Start.Before(end) && start.Before(b.End) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sc *testDriveSchedule) managerFree(employeeID int, start, end time.Time) bool {
+	for _, b := range sc.busy {
+		if b.EmployeeID == employeeID && b.Start.Before(end) && start.Before(b.End) {
+			return false
+		}
+	}
+	return true
+}
+
+// works проверяет, что [start, end) укладывается в рабочий день менеджера
+func (sc *testDriveSchedule) works(employeeID int, start, end time.Time) bool {
+	dayStart, dayEnd, ok := workingWindow(sc.employeeHours(employeeID), start)
+	return ok && !start.Before(dayStart) && !end.After(dayEnd)
+}
+
+// dayWindow возвращает границы дня от самого раннего начала до самого
+// позднего окончания работы менеджеров
+func (sc *testDriveSchedule) dayWindow(day time.Time) (time.Time, time.Time, bool) {
+	var starts, ends []time.Time
+	for _, m := range sc.managers {
+		if start, end, ok := workingWindow(sc.employeeHours(m.ID), day); ok {
+			starts = append(starts, start)
+			ends = append(ends, end)
+		}
+	}
+	if len(starts) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	sort.Slice(ends, func(i, j int) bool { return ends[i].After(ends[j]) })
+	return starts[0], ends[0], true
+}
+
+func (sc *testDriveSchedule) employeeHours(employeeID int) []models.WorkingHours {
+	if h, ok := sc.hours[employeeID]; ok {
+		return h
+	}
+	return defaultWorkingHours
+}
+
+// workingWindow возвращает рабочее время в день t по Минску
+func workingWindow(hours []models.WorkingHours, t time.Time) (time.Time, time.Time, bool) {
+	t = t.In(utils.DealershipLocation)
+	weekday := int(t.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+
+	for _, h := range hours {
+		if h.Weekday != weekday {
+			continue
+		}
+		start, errStart := time.Parse("15:04", h.Start)
+		end, errEnd := time.Parse("15:04", h.End)
+		if errStart != nil || errEnd != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		at := func(clock time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), clock.Hour(), clock.Minute(), 0, 0, utils.DealershipLocation)
+		}
+		return at(start), at(end), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+func normalizeTestDriveDuration(duration int) (int, error) {
+	if duration == 0 {
+		return DefaultTestDriveDuration, nil
+	}
+	if duration < minTestDriveDuration || duration > maxTestDriveDuration {
+		return 0, fmt.Errorf("%w: duration must be from %d to %d minutes",
+			ErrInvalidTestDrive, minTestDriveDuration, maxTestDriveDuration)
+	}
+	return duration, nil
+}
+
+// validateTestDriveStart проверяет, что тест-драйв назначен на будущее время
+// в пределах окна записи, и переводит время к Минску
+func validateTestDriveStart(start time.Time) (time.Time, error) {
+	if start.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: scheduled_date is required", ErrInvalidTestDrive)
+	}
+	now := time.Now()
+	if !start.After(now) {
+		return time.Time{}, repository.ErrTestDriveInPast
+	}
+	if start.After(now.AddDate(0, 0, testDriveBookingDays)) {
+		return time.Time{}, fmt.Errorf("%w: booking is open for %d days ahead", ErrInvalidTestDrive, testDriveBookingDays)
+	}
+	return start.In(utils.DealershipLocation).Truncate(time.Minute), nil
+}
@@ -40,7 +40,7 @@ func (s *UserService) Register(ctx context.Context, name, email, phone, password
 		Email:        email,
 		Phone:        phone,
 		PasswordHash: hashedPassword,
-		Role:         "user", // По умолчанию обычный пользователь
+		Role:         models.RoleCustomer, // По умолчанию обычный пользователь
 	}
 
 	userID, err := s.repo.Create(ctx, user)
@@ -2,6 +2,11 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
 	"amkodor-dealership/internal/models"
 	"amkodor-dealership/internal/repository"
 )
@@ -42,3 +47,139 @@ func (s *VehicleService) Search(params map[string]interface{}) ([]models.Vehicle
 func (s *VehicleService) GetHistory(vehicleID int) ([]models.VehicleHistory, error) {
 	return s.repo.GetHistory(vehicleID)
 }
+
+var (
+	// ErrInvalidVehicleRequest - ошибка валидации данных техники
+	ErrInvalidVehicleRequest = errors.New("invalid vehicle request")
+	// ErrVehicleStatusWorkflow - статус ставится продажей, бронью, поставкой
+	// или перемещением, а не правкой каталога
+	ErrVehicleStatusWorkflow = errors.New("vehicle status is set by its workflow")
+)
+
+// Допустимые статусы техники (ограничение CHECK таблицы vehicles)
+var vehicleStatuses = map[string]bool{
+	"В наличии":       true,
+	"Продано":         true,
+	"Зарезервировано": true,
+	"В ремонте":       true,
+	"В пути":          true,
+}
+
+// Статусы, которые ставятся правкой каталога
+var vehicleEditStatuses = map[string]bool{
+	"В наличии": true,
+	"В ремонте": true,
+}
+
+// GetCatalog возвращает страницу каталога в виде карточек техники
+func (s *VehicleService) GetCatalog(ctx context.Context, status string, page, perPage int) ([]models.CatalogVehicle, models.Pagination, error) {
+	vehicles, total, err := s.repo.GetPage(ctx, status, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, models.Pagination{}, err
+	}
+
+	catalog := make([]models.CatalogVehicle, 0, len(vehicles))
+	for _, v := range vehicles {
+		catalog = append(catalog, toCatalogVehicle(v))
+	}
+
+	pagination := models.Pagination{
+		Page:       page,
+		PerPage:    perPage,
+		TotalItems: total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}
+
+	return catalog, pagination, nil
+}
+
+// GetCatalogVehicle возвращает карточку техники по ID
+func (s *VehicleService) GetCatalogVehicle(ctx context.Context, id int) (*models.CatalogVehicle, error) {
+	v, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	card := toCatalogVehicle(*v)
+	return &card, nil
+}
+
+// CreateFromRequest добавляет технику в каталог
+func (s *VehicleService) CreateFromRequest(ctx context.Context, req *models.CreateVehicleRequest) (int, error) {
+	if req.Status == "" {
+		req.Status = "В наличии"
+	}
+	if err := validateVehicleRequest(req); err != nil {
+		return 0, err
+	}
+	if req.Year == 0 || req.Price == 0 {
+		return 0, fmt.Errorf("%w: year and price are required", ErrInvalidVehicleRequest)
+	}
+	if req.SerialNumber == "" {
+		req.SerialNumber = fmt.Sprintf("AMK-%d-%d", req.Year, time.Now().UnixNano())
+	}
+	return s.repo.CreateFromRequest(ctx, req)
+}
+
+// UpdateFromRequest обновляет технику в каталоге. Незаполненные год, цена,
+// скидка и статус оставляют текущие: иначе правка одного цвета обнулила бы
+// цену, а правка без status вернула бы проданную единицу в продажу.
+func (s *VehicleService) UpdateFromRequest(ctx context.Context, id int, req *models.CreateVehicleRequest) error {
+	if err := validateVehicleRequest(req); err != nil {
+		return err
+	}
+	if req.Status != "" && !vehicleEditStatuses[req.Status] {
+		return fmt.Errorf("%w: %q", ErrVehicleStatusWorkflow, req.Status)
+	}
+	return s.repo.UpdateFromRequest(ctx, id, req)
+}
+
+func validateVehicleRequest(req *models.CreateVehicleRequest) error {
+	if req.Name == "" || req.Category == "" {
+		return fmt.Errorf("%w: name and category are required", ErrInvalidVehicleRequest)
+	}
+	if req.Price < 0 || req.Discount != nil && (*req.Discount < 0 || *req.Discount > 100) {
+		return fmt.Errorf("%w: invalid price or discount", ErrInvalidVehicleRequest)
+	}
+	if req.Year < 0 {
+		return fmt.Errorf("%w: invalid year %d", ErrInvalidVehicleRequest, req.Year)
+	}
+	if req.Status != "" && !vehicleStatuses[req.Status] {
+		return fmt.Errorf("%w: invalid status %q", ErrInvalidVehicleRequest, req.Status)
+	}
+	return nil
+}
+
+// toCatalogVehicle преобразует запись vw_vehicles_full_info в карточку каталога
+func toCatalogVehicle(v models.Vehicle) models.CatalogVehicle {
+	var specs map[string]interface{}
+	if len(v.Specifications) > 0 {
+		_ = json.Unmarshal(v.Specifications, &specs)
+	}
+	spec := func(key string) string {
+		if value, ok := specs[key].(string); ok {
+			return value
+		}
+		return ""
+	}
+
+	return models.CatalogVehicle{
+		ID:            v.VehicleID,
+		Name:          v.ModelName,
+		Category:      v.TypeName,
+		Price:         v.Price,
+		Discount:      v.Discount,
+		FinalPrice:    v.FinalPrice,
+		Status:        v.Status,
+		Year:          v.ManufactureYear,
+		Color:         v.Color.String,
+		Engine:        spec("engine"),
+		Power:         spec("power"),
+		Weight:        spec("weight"),
+		Image:         spec("image"),
+		WarehouseName: v.WarehouseName,
+		WarehouseCity: v.WarehouseCity,
+		ReservedUntil: v.ReservedUntil,
+		Rating:        v.Rating,
+		RatingsCount:  v.RatingsCount,
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"amkodor-dealership/internal/models"
+	"amkodor-dealership/internal/repository"
+)
+
+// ErrInvalidVehicleTransfer - ошибка валидации перемещения техники
+var ErrInvalidVehicleTransfer = errors.New("invalid vehicle transfer")
+
+// VehicleTransferService - перемещение техники между складами
+type VehicleTransferService struct {
+	repo *repository.VehicleTransferRepository
+}
+
+func NewVehicleTransferService(repo *repository.VehicleTransferRepository) *VehicleTransferService {
+	return &VehicleTransferService{repo: repo}
+}
+
+// GetAll возвращает перемещения с фильтром по статусу, складу и технике
+func (s *VehicleTransferService) GetAll(ctx context.Context, status string, warehouseID, vehicleID *int) ([]models.VehicleTransfer, error) {
+	switch status {
+	case "", models.TransferPlanned, models.TransferInTransit, models.TransferReceived, models.TransferCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidVehicleTransfer, status)
+	}
+	return s.repo.GetAll(ctx, status, warehouseID, vehicleID)
+}
+
+// GetByID возвращает перемещение с техникой
+func (s *VehicleTransferService) GetByID(ctx context.Context, id int) (*models.VehicleTransfer, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Create оформляет перемещение от имени сотрудника
+func (s *VehicleTransferService) Create(ctx context.Context, employeeID int, req *models.VehicleTransferRequest) (*models.VehicleTransfer, error) {
+	if req.FromWarehouseID <= 0 || req.ToWarehouseID <= 0 {
+		return nil, fmt.Errorf("%w: source and destination warehouses are required", ErrInvalidVehicleTransfer)
+	}
+	if req.FromWarehouseID == req.ToWarehouseID {
+		return nil, fmt.Errorf("%w: destination must differ from source warehouse", ErrInvalidVehicleTransfer)
+	}
+	if len(req.VehicleIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one vehicle is required", ErrInvalidVehicleTransfer)
+	}
+	seen := make(map[int]bool, len(req.VehicleIDs))
+	for _, id := range req.VehicleIDs {
+		if id <= 0 {
+			return nil, fmt.Errorf("%w: invalid vehicle id %d", ErrInvalidVehicleTransfer, id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("%w: vehicle %d is listed twice", ErrInvalidVehicleTransfer, id)
+		}
+		seen[id] = true
+	}
+	if req.TransportCost < 0 {
+		return nil, fmt.Errorf("%w: transport cost cannot be negative", ErrInvalidVehicleTransfer)
+	}
+
+	departure, err := parseTransferDate(req.PlannedDepartureDate, "planned departure date")
+	if err != nil {
+		return nil, err
+	}
+	arrival, err := parseTransferDate(req.PlannedArrivalDate, "planned arrival date")
+	if err != nil {
+		return nil, err
+	}
+	if departure != nil && arrival != nil && arrival.Before(*departure) {
+		return nil, fmt.Errorf("%w: planned arrival is before departure", ErrInvalidVehicleTransfer)
+	}
+
+	req.DriverName = strings.TrimSpace(req.DriverName)
+	req.Notes = strings.TrimSpace(req.Notes)
+
+	id, err := s.repo.Create(ctx, req, departure, arrival, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Dispatch отправляет технику со склада; она становится недоступной для продажи
+func (s *VehicleTransferService) Dispatch(ctx context.Context, id, employeeID int) (*models.VehicleTransfer, error) {
+	if err := s.repo.Dispatch(ctx, id, employeeID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Receive принимает технику на складе назначения
+func (s *VehicleTransferService) Receive(ctx context.Context, id, employeeID int) (*models.VehicleTransfer, error) {
+	if err := s.repo.Receive(ctx, id, employeeID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// Cancel отменяет перемещение до приёма
+func (s *VehicleTransferService) Cancel(ctx context.Context, id int) (*models.VehicleTransfer, error) {
+	if err := s.repo.Cancel(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+// parseTransferDate разбирает необязательную дату в формате YYYY-MM-DD
+func parseTransferDate(value, field string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be YYYY-MM-DD", ErrInvalidVehicleTransfer, field)
+	}
+	return &date, nil
+}
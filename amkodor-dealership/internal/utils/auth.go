@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"time"
 
+	"amkodor-dealership/internal/models"
+
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -26,14 +28,38 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateJWT создает JWT токен
-func GenerateJWT(userID int, email string, secret string, expireHours int) (string, error) {
+// GenerateJWT создает access-токен с заданным временем жизни
+func GenerateJWT(userID int, email, role string, secret string, ttl time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
 		"email":   email,
-		"exp":     time.Now().Add(time.Hour * time.Duration(expireHours)).Unix(),
-		"iat":     time.Now().Unix(),
+		"role":    role,
 	}
+	return signJWT(claims, secret, ttl)
+}
+
+// GenerateStaffJWT создает access-токен сотрудника: кроме роли он несёт
+// employee_id, position_id и warehouse_id. userID указывается, если у
+// сотрудника есть связанная учётная запись users.
+func GenerateStaffJWT(userID *int, email string, staff *models.StaffIdentity, secret string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"email":       email,
+		"role":        staff.Role,
+		"employee_id": staff.EmployeeID,
+		"position_id": staff.PositionID,
+	}
+	if userID != nil {
+		claims["user_id"] = *userID
+	}
+	if staff.WarehouseID != nil {
+		claims["warehouse_id"] = *staff.WarehouseID
+	}
+	return signJWT(claims, secret, ttl)
+}
+
+func signJWT(claims jwt.MapClaims, secret string, ttl time.Duration) (string, error) {
+	claims["exp"] = time.Now().Add(ttl).Unix()
+	claims["iat"] = time.Now().Unix()
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(secret))
@@ -66,10 +92,11 @@ func ValidateJWT(tokenString string, secret string) (*jwt.Token, error) {
 
 // Response структуры для API ответов
 type Response struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Message string      `json:"message,omitempty"`
+	Success    bool               `json:"success"`
+	Data       interface{}        `json:"data,omitempty"`
+	Pagination *models.Pagination `json:"pagination,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Message    string             `json:"message,omitempty"`
 }
 
 // RespondJSON отправляет JSON ответ
@@ -87,6 +114,15 @@ func RespondSuccess(w http.ResponseWriter, data interface{}) {
 	})
 }
 
+// RespondPaginated отправляет страницу данных с метаданными пагинации
+func RespondPaginated(w http.ResponseWriter, data interface{}, pagination models.Pagination) {
+	RespondJSON(w, http.StatusOK, Response{
+		Success:    true,
+		Data:       data,
+		Pagination: &pagination,
+	})
+}
+
 // RespondError отправляет ответ с ошибкой
 func RespondError(w http.ResponseWriter, status int, message string) {
 	RespondJSON(w, status, Response{
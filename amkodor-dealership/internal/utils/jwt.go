@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -9,18 +13,32 @@ import (
 type JWTClaims struct {
 	UserID int    `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// RefreshJWT обновляет JWT токен
-func RefreshJWT(tokenString string, secret string, expireHours int) (string, error) {
-	claims, err := ValidateJWTClaims(tokenString, secret)
-	if err != nil {
-		return "", err
-	}
+// GenerateRefreshToken создает случайный непрозрачный refresh-токен
+func GenerateRefreshToken() (string, error) {
+	return randomHex(32)
+}
 
-	// Создаем новый токен с теми же данными
-	return GenerateJWT(claims.UserID, claims.Email, secret, expireHours)
+// GenerateTokenFamilyID создает идентификатор семейства refresh-токенов
+func GenerateTokenFamilyID() (string, error) {
+	return randomHex(16)
+}
+
+// HashRefreshToken возвращает SHA-256 хеш токена для хранения в БД
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating random token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
 }
 
 // ExtractUserIDFromToken извлекает UserID из токена
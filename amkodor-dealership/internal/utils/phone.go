@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrInvalidPhone - номер нельзя привести к белорусскому формату
+var ErrInvalidPhone = errors.New("invalid Belarusian phone number")
+
+var phoneNonDigits = regexp.MustCompile(`\D`)
+
+// NormalizePhone приводит белорусский номер к виду +375 XX XXX-XX-XX.
+// Принимает +375..., 8 0XX... и 9 цифр без кода страны. Совпадает с
+// fn_normalize_by_phone в базе данных.
+func NormalizePhone(phone string) (string, error) {
+	digits := phoneNonDigits.ReplaceAllString(phone, "")
+	switch {
+	case len(digits) == 12 && digits[:3] == "375":
+		digits = digits[3:]
+	case len(digits) == 11 && digits[:2] == "80":
+		digits = digits[2:]
+	case len(digits) == 9:
+	default:
+		return "", ErrInvalidPhone
+	}
+
+	return fmt.Sprintf("+375 %s %s-%s-%s", digits[:2], digits[2:5], digits[5:7], digits[7:]), nil
+}
@@ -0,0 +1,22 @@
+package utils
+
+import "time"
+
+// DealershipLocation - часовой пояс дилерского центра (Europe/Minsk). Если в
+// системе нет базы часовых поясов, используется UTC+3: перехода на летнее
+// время в Беларуси нет.
+var DealershipLocation = loadDealershipLocation()
+
+func loadDealershipLocation() *time.Location {
+	loc, err := time.LoadLocation("Europe/Minsk")
+	if err != nil {
+		return time.FixedZone("Europe/Minsk", 3*60*60)
+	}
+	return loc
+}
+
+// DealershipWallTime трактует время, прочитанное из колонки TIMESTAMP без
+// часового пояса, как время по Минску
+func DealershipWallTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), DealershipLocation)
+}
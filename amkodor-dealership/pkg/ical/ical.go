@@ -0,0 +1,147 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Статусы события (RFC 5545, 3.8.1.11)
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// ContentType - MIME-тип календаря
+const ContentType = "text/calendar; charset=utf-8"
+
+// maxLineOctets - максимальная длина строки без переноса (RFC 5545, 3.1)
+const maxLineOctets = 75
+
+// Event - событие календаря (VEVENT). UID должен быть постоянным для одной
+// записи, а Sequence - расти при каждом её изменении: так календари клиентов
+// обновляют и отменяют уже загруженные события.
+type Event struct {
+	UID      string
+	Sequence int
+	// Stamp - время формирования события (DTSTAMP)
+	Stamp        time.Time
+	LastModified time.Time
+	Start        time.Time
+	End          time.Time
+	// AllDay - событие на целые дни: Start и End выводятся датами, End не включается
+	AllDay      bool
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+}
+
+// Calendar - календарь (VCALENDAR) с событиями
+type Calendar struct {
+	ProdID string
+	// Name - название календаря, которое показывают календари при подписке
+	Name   string
+	Events []Event
+}
+
+// Write выводит календарь в формате RFC 5545. Время событий выводится в UTC.
+func (c *Calendar) Write(w io.Writer) error {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+
+	for _, e := range c.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		lw.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+		lw.line("DTSTAMP:" + formatDateTime(e.Stamp))
+		if !e.LastModified.IsZero() {
+			lw.line("LAST-MODIFIED:" + formatDateTime(e.LastModified))
+		}
+		if e.AllDay {
+			lw.line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+			lw.line("DTEND;VALUE=DATE:" + e.End.Format("20060102"))
+		} else {
+			lw.line("DTSTART:" + formatDateTime(e.Start))
+			lw.line("DTEND:" + formatDateTime(e.End))
+		}
+		lw.line("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Location != "" {
+			lw.line("LOCATION:" + escapeText(e.Location))
+		}
+		if e.Status != "" {
+			lw.line("STATUS:" + e.Status)
+		}
+		lw.line("END:VEVENT")
+	}
+
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return lw.w.Flush()
+}
+
+func formatDateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escapeText экранирует значение типа TEXT (RFC 5545, 3.3.11)
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// lineWriter пишет строки с CRLF и переносит длинные строки продолжением
+// с пробелом, не разрывая символы UTF-8. Первая ошибка запоминается.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		lw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Продолжение начинается с пробела, он входит в длину строки
+		limit = maxLineOctets - 1
+	}
+	lw.write(s + "\r\n")
+}
+
+func (lw *lineWriter) write(s string) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = lw.w.WriteString(s)
+}
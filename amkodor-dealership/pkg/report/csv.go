@@ -0,0 +1,70 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// CSVRenderer выводит заголовок, строки и итоги. Числа пишутся без
+// разделителей разрядов, даты - в ISO 8601, чтобы файл читался программно.
+type CSVRenderer struct{}
+
+func (CSVRenderer) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (CSVRenderer) Extension() string { return "csv" }
+
+func (CSVRenderer) Render(w io.Writer, t *Table) error {
+	// BOM нужен Excel, чтобы распознать UTF-8 и показать кириллицу
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Title
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		if err := cw.Write(csvRecord(t.Columns, row)); err != nil {
+			return err
+		}
+	}
+
+	if t.HasTotals() {
+		if err := cw.Write(csvRecord(t.Columns, t.Totals())); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvRecord(columns []Column, row []interface{}) []string {
+	record := make([]string, len(columns))
+	for i, v := range row {
+		switch val := v.(type) {
+		case nil:
+			record[i] = ""
+		case string:
+			record[i] = val
+		case int64:
+			record[i] = strconv.FormatInt(val, 10)
+		case float64:
+			record[i] = strconv.FormatFloat(val, 'f', 2, 64)
+		case time.Time:
+			record[i] = val.Format("2006-01-02")
+		default:
+			record[i] = fmt.Sprint(val)
+		}
+	}
+	return record
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfFont       = "DejaVu"
+	pdfRowHeight  = 6.0
+	pdfMargin     = 10.0
+	pdfFooterSize = 12.0
+)
+
+// PDFRenderer строит таблицу с повтором шапки на каждой странице.
+// Широкие отчеты выводятся в альбомной ориентации.
+type PDFRenderer struct {
+	// FontDir - каталог с DejaVuSans.ttf и DejaVuSans-Bold.ttf
+	FontDir string
+}
+
+func (PDFRenderer) ContentType() string { return "application/pdf" }
+
+func (PDFRenderer) Extension() string { return "pdf" }
+
+func (r PDFRenderer) Render(w io.Writer, t *Table) error {
+	orientation := "P"
+	if len(t.Columns) > 6 {
+		orientation = "L"
+	}
+
+	pdf := gofpdf.New(orientation, "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.AddUTF8Font(pdfFont, "", filepath.Join(r.FontDir, "DejaVuSans.ttf"))
+	pdf.AddUTF8Font(pdfFont, "B", filepath.Join(r.FontDir, "DejaVuSans-Bold.ttf"))
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("error loading PDF fonts: %w", err)
+	}
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	widths := pdfColumnWidths(t.Columns, pageWidth-2*pdfMargin)
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin - 4)
+		pdf.SetFont(pdfFont, "", 8)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Страница %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont(pdfFont, "B", 14)
+	pdf.CellFormat(0, 8, t.Title, "", 1, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 9)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Период: %s. Сформирован: %s",
+		t.Period(), t.GeneratedAt.Format("02.01.2006 15:04")), "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	writeHeader := func() {
+		pdf.SetFont(pdfFont, "B", 8)
+		pdf.SetFillColor(68, 114, 196)
+		pdf.SetTextColor(255, 255, 255)
+		for i, c := range t.Columns {
+			pdf.CellFormat(widths[i], pdfRowHeight+1, fitText(pdf, c.Title, widths[i]), "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	writeRow := func(values []interface{}, bold bool) {
+		if pdf.GetY()+pdfRowHeight > pageHeight-pdfMargin-pdfFooterSize {
+			pdf.AddPage()
+			writeHeader()
+		}
+		style := ""
+		if bold {
+			style = "B"
+		}
+		pdf.SetFont(pdfFont, style, 8)
+		for i, c := range t.Columns {
+			align := "L"
+			if c.Type != Text {
+				align = "R"
+			}
+			text := fitText(pdf, FormatValue(c, values[i]), widths[i])
+			pdf.CellFormat(widths[i], pdfRowHeight, text, "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	writeHeader()
+	if len(t.Rows) == 0 {
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.CellFormat(0, pdfRowHeight*2, "Нет данных за выбранный период", "", 1, "C", false, 0, "")
+	}
+	for _, values := range t.Rows {
+		writeRow(values, false)
+	}
+	if t.HasTotals() && len(t.Rows) > 0 {
+		writeRow(t.Totals(), true)
+	}
+
+	return pdf.Output(w)
+}
+
+// pdfColumnWidths распределяет ширину страницы пропорционально ширинам колонок
+func pdfColumnWidths(columns []Column, total float64) []float64 {
+	sum := 0.0
+	for _, c := range columns {
+		sum += columnWidth(c)
+	}
+
+	widths := make([]float64, len(columns))
+	for i, c := range columns {
+		widths[i] = total * columnWidth(c) / sum
+	}
+	return widths
+}
+
+// fitText обрезает текст с многоточием, если он не помещается в ячейку
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	const padding = 2.0
+	if pdf.GetStringWidth(text) <= width-padding {
+		return text
+	}
+
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"…") > width-padding {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrUnsupportedFormat - для формата не зарегистрирован рендерер
+var ErrUnsupportedFormat = errors.New("unsupported report format")
+
+// Renderer преобразует Table в файл конкретного формата
+type Renderer interface {
+	ContentType() string
+	Extension() string
+	Render(w io.Writer, t *Table) error
+}
+
+// Registry - набор рендереров по имени формата
+type Registry struct {
+	renderers map[string]Renderer
+}
+
+func NewRegistry() *Registry {
+	return &Registry{renderers: map[string]Renderer{}}
+}
+
+// DefaultRegistry регистрирует CSV, XLSX и PDF. fontDir - каталог со
+// шрифтами DejaVuSans, нужными PDF для кириллицы.
+func DefaultRegistry(fontDir string) *Registry {
+	r := NewRegistry()
+	r.Register(CSVRenderer{}, "csv")
+	// excel и xslx приходят от старых версий страницы отчетов
+	r.Register(XLSXRenderer{}, "xlsx", "excel", "xslx")
+	r.Register(PDFRenderer{FontDir: fontDir}, "pdf")
+	return r
+}
+
+// Register связывает рендерер с одним или несколькими именами формата
+func (r *Registry) Register(renderer Renderer, formats ...string) {
+	for _, format := range formats {
+		r.renderers[strings.ToLower(format)] = renderer
+	}
+}
+
+// Get возвращает рендерер формата или ErrUnsupportedFormat
+func (r *Registry) Get(format string) (Renderer, error) {
+	renderer, ok := r.renderers[strings.ToLower(strings.TrimSpace(format))]
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+	return renderer, nil
+}
@@ -0,0 +1,164 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType определяет, как значение колонки хранится и форматируется
+type ColumnType int
+
+const (
+	Text ColumnType = iota
+	Integer
+	Money
+	Percent
+	Date
+	// Decimal - дробное число без разделителей разрядов, например оценка 4.50
+	Decimal
+)
+
+// Column - описание колонки отчета
+type Column struct {
+	Title string
+	Type  ColumnType
+	// Total - суммировать колонку в строке итогов
+	Total bool
+	// Width - относительная ширина колонки в PDF/XLSX, 0 - по типу
+	Width float64
+}
+
+// Table - табличный результат отчета, общий для всех форматов.
+// Значения строк: string для Text, int64 для Integer, float64 для Money,
+// Percent и Decimal, time.Time для Date; nil - пустая ячейка.
+type Table struct {
+	// Name - латинское имя отчета для имени файла
+	Name        string
+	Title       string
+	From        time.Time
+	To          time.Time
+	GeneratedAt time.Time
+	Columns     []Column
+	Rows        [][]interface{}
+}
+
+// NewTable создает пустой отчет за период
+func NewTable(name, title string, from, to time.Time, columns ...Column) *Table {
+	return &Table{
+		Name:        name,
+		Title:       title,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now(),
+		Columns:     columns,
+	}
+}
+
+// AddRow добавляет строку; количество значений должно совпадать с колонками
+func (t *Table) AddRow(values ...interface{}) {
+	if len(values) != len(t.Columns) {
+		panic(fmt.Sprintf("report %s: row has %d values, want %d", t.Name, len(values), len(t.Columns)))
+	}
+	t.Rows = append(t.Rows, values)
+}
+
+// HasTotals сообщает, есть ли в отчете суммируемые колонки
+func (t *Table) HasTotals() bool {
+	for _, c := range t.Columns {
+		if c.Total {
+			return true
+		}
+	}
+	return false
+}
+
+// Totals возвращает строку итогов: суммы по колонкам с Total, подпись
+// "Итого" в первой колонке и nil в остальных
+func (t *Table) Totals() []interface{} {
+	totals := make([]interface{}, len(t.Columns))
+	for i, c := range t.Columns {
+		if !c.Total {
+			continue
+		}
+		switch c.Type {
+		case Integer:
+			var sum int64
+			for _, row := range t.Rows {
+				if v, ok := row[i].(int64); ok {
+					sum += v
+				}
+			}
+			totals[i] = sum
+		default:
+			var sum float64
+			for _, row := range t.Rows {
+				if v, ok := row[i].(float64); ok {
+					sum += v
+				}
+			}
+			totals[i] = math.Round(sum*100) / 100
+		}
+	}
+	if len(totals) > 0 && totals[0] == nil {
+		totals[0] = "Итого"
+	}
+	return totals
+}
+
+// Period возвращает период отчета в виде "01.01.2024 - 31.01.2024"
+func (t *Table) Period() string {
+	return fmt.Sprintf("%s - %s", t.From.Format("02.01.2006"), t.To.Format("02.01.2006"))
+}
+
+// Filename возвращает имя файла вида sales_report_20240101_20240131.xlsx
+func (t *Table) Filename(ext string) string {
+	return fmt.Sprintf("%s_%s_%s.%s", t.Name, t.From.Format("20060102"), t.To.Format("20060102"), ext)
+}
+
+// FormatValue форматирует значение для текстовых форматов (PDF)
+func FormatValue(c Column, v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	switch val := v.(type) {
+	case string:
+		return val
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		switch c.Type {
+		case Percent:
+			return strconv.FormatFloat(val, 'f', 2, 64) + "%"
+		case Money:
+			return formatMoney(val)
+		case Decimal:
+			return strconv.FormatFloat(val, 'f', 2, 64)
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.Format("02.01.2006")
+	}
+	return fmt.Sprint(v)
+}
+
+// formatMoney форматирует сумму с разделителем разрядов: 1 234 567.89
+func formatMoney(v float64) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+
+	var b strings.Builder
+	if v < 0 {
+		b.WriteByte('-')
+	}
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString(frac)
+	return b.String()
+}
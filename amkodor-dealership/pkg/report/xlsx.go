@@ -0,0 +1,167 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	xlsxSheet     = "Отчет"
+	xlsxHeaderRow = 4
+)
+
+// XLSXRenderer пишет типизированные ячейки: суммы и даты остаются
+// числами Excel с форматом отображения, а не строками
+type XLSXRenderer struct{}
+
+func (XLSXRenderer) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func (XLSXRenderer) Extension() string { return "xlsx" }
+
+func (XLSXRenderer) Render(w io.Writer, t *Table) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", xlsxSheet); err != nil {
+		return fmt.Errorf("error creating sheet: %w", err)
+	}
+
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return err
+	}
+
+	f.SetCellValue(xlsxSheet, "A1", t.Title)
+	f.SetCellStyle(xlsxSheet, "A1", "A1", styles.title)
+	f.SetCellValue(xlsxSheet, "A2", fmt.Sprintf("Период: %s. Сформирован: %s",
+		t.Period(), t.GeneratedAt.Format("02.01.2006 15:04")))
+
+	for i, c := range t.Columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, xlsxHeaderRow)
+		f.SetCellValue(xlsxSheet, cell, c.Title)
+
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetColWidth(xlsxSheet, col, col, columnWidth(c)*1.2)
+	}
+	first, _ := excelize.CoordinatesToCellName(1, xlsxHeaderRow)
+	last, _ := excelize.CoordinatesToCellName(len(t.Columns), xlsxHeaderRow)
+	f.SetCellStyle(xlsxSheet, first, last, styles.header)
+
+	row := xlsxHeaderRow + 1
+	for _, values := range t.Rows {
+		if err := writeXLSXRow(f, styles, t.Columns, row, values, false); err != nil {
+			return err
+		}
+		row++
+	}
+
+	if t.HasTotals() {
+		if err := writeXLSXRow(f, styles, t.Columns, row, t.Totals(), true); err != nil {
+			return err
+		}
+	}
+
+	// Закрепляем шапку таблицы
+	f.SetPanes(xlsxSheet, &excelize.Panes{
+		Freeze:      true,
+		YSplit:      xlsxHeaderRow,
+		TopLeftCell: fmt.Sprintf("A%d", xlsxHeaderRow+1),
+		ActivePane:  "bottomLeft",
+	})
+
+	return f.Write(w)
+}
+
+func writeXLSXRow(f *excelize.File, styles *xlsxStyles, columns []Column, row int, values []interface{}, total bool) error {
+	for i, v := range values {
+		cell, _ := excelize.CoordinatesToCellName(i+1, row)
+		if v != nil {
+			if err := f.SetCellValue(xlsxSheet, cell, v); err != nil {
+				return fmt.Errorf("error writing cell %s: %w", cell, err)
+			}
+		}
+		if err := f.SetCellStyle(xlsxSheet, cell, cell, styles.forColumn(columns[i], total)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type xlsxStyles struct {
+	title, header int
+	// по типу колонки: обычная строка и строка итогов
+	cell, totalCell map[ColumnType]int
+}
+
+func newXLSXStyles(f *excelize.File) (*xlsxStyles, error) {
+	s := &xlsxStyles{cell: map[ColumnType]int{}, totalCell: map[ColumnType]int{}}
+
+	var err error
+	if s.title, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}}); err != nil {
+		return nil, err
+	}
+	s.header, err = f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "#FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#4472C4"}, Pattern: 1},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+			Vertical:   "center",
+			WrapText:   true,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	formats := map[ColumnType]string{
+		Text:    "@",
+		Integer: "0",
+		Money:   "#,##0.00",
+		Percent: `0.00"%"`,
+		Date:    "dd.mm.yyyy",
+		Decimal: "0.00",
+	}
+	for columnType, format := range formats {
+		numFmt := format
+		if s.cell[columnType], err = f.NewStyle(&excelize.Style{CustomNumFmt: &numFmt}); err != nil {
+			return nil, err
+		}
+		s.totalCell[columnType], err = f.NewStyle(&excelize.Style{
+			CustomNumFmt: &numFmt,
+			Font:         &excelize.Font{Bold: true},
+			Border:       []excelize.Border{{Type: "top", Color: "#000000", Style: 1}},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *xlsxStyles) forColumn(c Column, total bool) int {
+	if total {
+		return s.totalCell[c.Type]
+	}
+	return s.cell[c.Type]
+}
+
+// columnWidth - ширина колонки в символах
+func columnWidth(c Column) float64 {
+	if c.Width > 0 {
+		return c.Width
+	}
+	switch c.Type {
+	case Integer, Percent, Decimal:
+		return 10
+	case Money:
+		return 14
+	case Date:
+		return 11
+	}
+	return 20
+}
@@ -15,10 +15,47 @@ const API = {
     // Удаление токена
     removeToken() {
         localStorage.removeItem('token');
+        localStorage.removeItem('refreshToken');
+    },
+
+    // Получение refresh-токена
+    getRefreshToken() {
+        return localStorage.getItem('refreshToken');
+    },
+
+    // Сохранение пары токенов из ответа login/refresh
+    setTokens(data) {
+        if (data.token) {
+            this.setToken(data.token);
+        }
+        if (data.refresh_token) {
+            localStorage.setItem('refreshToken', data.refresh_token);
+        }
+    },
+
+    // Обмен refresh-токена на новую пару токенов
+    async refreshTokens() {
+        const refreshToken = this.getRefreshToken();
+        if (!refreshToken) {
+            return false;
+        }
+
+        const response = await fetch(`${this.baseURL}/auth/refresh`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken }),
+        });
+        if (!response.ok) {
+            return false;
+        }
+
+        const data = await response.json();
+        this.setTokens(data.data || {});
+        return true;
     },
 
     // Базовый метод для запросов
-    async request(endpoint, options = {}) {
+    async request(endpoint, options = {}, retried = false) {
         const url = `${this.baseURL}${endpoint}`;
         const token = this.getToken();
 
@@ -40,6 +77,10 @@ const API = {
 
             // Обработка ошибок авторизации
             if (response.status === 401) {
+                // Access-токен живет недолго: пробуем обновить его один раз
+                if (!retried && await this.refreshTokens()) {
+                    return this.request(endpoint, options, true);
+                }
                 this.removeToken();
                 window.location.href = '/login';
                 throw new Error('Unauthorized');
@@ -92,13 +133,19 @@ const API = {
     auth: {
         async login(email, password) {
             const data = await API.post('/auth/login', { email, password });
-            if (data.token) {
-                API.setToken(data.token);
-            }
+            API.setTokens(data);
             return data;
         },
 
-        logout() {
+        async logout() {
+            const refreshToken = API.getRefreshToken();
+            if (refreshToken) {
+                try {
+                    await API.post('/auth/logout', { refresh_token: refreshToken });
+                } catch (error) {
+                    console.error('Logout Error:', error);
+                }
+            }
             API.removeToken();
             window.location.href = '/login';
         },
@@ -280,6 +327,22 @@ const API = {
             return API.post(`/admin/service-orders/${id}/complete`);
         },
 
+        async addOrderPart(id, data) {
+            return API.post(`/admin/service-orders/${id}/parts`, data);
+        },
+
+        async removeOrderPart(id, lineId) {
+            return API.delete(`/admin/service-orders/${id}/parts/${lineId}`);
+        },
+
+        async addOrderLabour(id, data) {
+            return API.post(`/admin/service-orders/${id}/labour`, data);
+        },
+
+        async removeOrderLabour(id, lineId) {
+            return API.delete(`/admin/service-orders/${id}/labour/${lineId}`);
+        },
+
         async getAllParts(params = {}) {
             return API.get('/admin/spare-parts', params);
         },